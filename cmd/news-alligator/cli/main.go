@@ -1,19 +1,41 @@
 package main
 
 import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/backuper"
 	"github.com/antonchaban/news-aggregator/pkg/handler/cli"
+	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/antonchaban/news-aggregator/pkg/storage"
 	"github.com/antonchaban/news-aggregator/pkg/storage/inmemory"
+	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"os"
+	"time"
 )
 
 // The main function in CLI package initializes the storage and service and creates a new CLI handler
 // for News Aggregator application.
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case cli.MigrateCommand:
+			migrate(os.Args[2:])
+			return
+		case cli.PruneCommand:
+			prune(os.Args[2:])
+			return
+		case cli.RestoreCommand:
+			restore(os.Args[2:])
+			return
+		}
+	}
+
 	// Initialize storage and service
 	db := inmemory.New()
 	svc := service.New(db)
 	srcDb := inmemory.NewSrc()
-	srcSvc := service.NewSourceService(db, srcDb)
+	srcSvc := service.NewSourceService(db, srcDb, inmemory.NewUnitOfWork(db, srcDb), inmemory.NewFetchLocker(), nil)
 
 	// Initialize handler and execute CLI commands
 	_, err := cli.NewHandler(svc, srcSvc)
@@ -21,3 +43,82 @@ func main() {
 		panic(err)
 	}
 }
+
+// dbConfig returns the settings of the database selected by STORAGE_TYPE,
+// read from the same environment variables as the web server reads them.
+func dbConfig() storage.Config {
+	return storage.Config{
+		Host:     os.Getenv("POSTGRES_HOST"),
+		Username: os.Getenv("POSTGRES_USER"),
+		Password: os.Getenv("POSTGRES_PASSWORD"),
+		DBName:   os.Getenv("POSTGRES_DB"),
+		SSLMode:  "disable",
+		Path:     os.Getenv("SQLITE_PATH"),
+	}
+}
+
+// migrate manages the schema migrations of the database.
+func migrate(args []string) {
+	db, err := storage.Connect(dbConfig())
+	if err != nil {
+		logrus.Fatal("error occurred while connecting to the database: ", err.Error())
+	}
+	defer db.Close()
+
+	m, err := storage.Migrator(db)
+	if err != nil {
+		logrus.Fatal("error occurred while reading the migrations: ", err.Error())
+	}
+	if err = cli.Migrate(context.Background(), m, os.Args[0], args, os.Stdout); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// prune prunes the articles of the database beyond the retention policies,
+// archiving them first if ARCHIVE_DIR is set.
+func prune(args []string) {
+	db, err := storage.NewDB(dbConfig())
+	if err != nil {
+		logrus.Fatal("error occurred while connecting to the database: ", err.Error())
+	}
+	defer db.Close()
+
+	artDb, srcDb := storage.New(db)
+	rs, err := service.NewRetentionServiceFromEnv(artDb, srcDb)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if err = cli.Prune(context.Background(), rs, os.Args[0], args, os.Stdout); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// restore restores the articles and sources of the in-memory storage to a point in time
+// from the snapshots and the journal in SAVES_DIR, saving them as the latest snapshot.
+func restore(args []string) {
+	if err := cli.Restore(context.Background(), memoryRestorer{}, os.Args[0], args, os.Stdout); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// memoryRestorer restores the in-memory storage, which the web server loads from the latest snapshot.
+type memoryRestorer struct{}
+
+// Restore restores the articles and sources as of the given time, saving them as the latest snapshot
+// unless dryRun is set.
+func (memoryRestorer) Restore(ctx context.Context, at time.Time, dryRun bool) (model.RestoreReport, error) {
+	artDb, srcDb, report, err := storage.RestoreMemory(ctx, at)
+	if err != nil || dryRun {
+		return report, err
+	}
+	articles, err := artDb.GetAll(ctx)
+	if err != nil {
+		return report, err
+	}
+	sources, err := srcDb.GetAll(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.Saved, err = backuper.NewSaver(backuper.SliceIterator(articles), sources).Save()
+	return report, err
+}
@@ -17,7 +17,7 @@ const docTemplate = `{
     "paths": {
         "/articles": {
             "get": {
-                "description": "Get articles by filter parameters",
+                "description": "Get a page of articles by filter parameters",
                 "consumes": [
                     "application/json"
                 ],
@@ -32,7 +32,7 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Keywords to search for",
+                        "description": "Keyword query: words, quoted phrases, AND, OR, NOT or -, parentheses and title: or description: scoping",
                         "name": "keywords",
                         "in": "query"
                     },
@@ -42,6 +42,18 @@ const docTemplate = `{
                         "name": "sources",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated authors, matching the articles whose author contains any of them",
+                        "name": "authors",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated categories, matching the articles in any of them",
+                        "name": "categories",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Start date for search",
@@ -53,6 +65,165 @@ const docTemplate = `{
                         "description": "End date for search",
                         "name": "date_end",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pub_date",
+                            "title",
+                            "source",
+                            "relevance"
+                        ],
+                        "type": "string",
+                        "default": "pub_date",
+                        "description": "Sort articles by",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of articles on the page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page, taken from next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Collapse the articles reporting the same story into one, listing the other sources in other_sources",
+                        "name": "collapse",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ArticlePage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/articles/prune": {
+            "post": {
+                "description": "Delete the articles beyond the retention policy of their source or the global one, archiving them first if an archive directory is configured. A dry run only lists the expired articles",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "articles"
+                ],
+                "summary": "Prune expired articles",
+                "operationId": "prune-articles",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "List the expired articles without deleting them",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PruneReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/export": {
+            "get": {
+                "description": "Download all the articles as gzip-compressed NDJSON, one article per line, ordered by ID. The articles are streamed as they are read, and the X-Exported-Articles trailer holds their number",
+                "produces": [
+                    "application/gzip"
+                ],
+                "tags": [
+                    "articles"
+                ],
+                "summary": "Export articles",
+                "operationId": "export-articles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/fetch-runs": {
+            "get": {
+                "description": "Get the recorded fetch runs, newest first, with the attempt of every source: its status, HTTP status, downloaded bytes, new and updated articles and error. The runs are kept for FETCH_HISTORY_MAX_AGE, a week by default, up to FETCH_HISTORY_MAX_RUNS of them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fetch-runs"
+                ],
+                "summary": "Get fetch runs",
+                "operationId": "get-fetch-runs",
+                "parameters": [
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of runs",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -61,7 +232,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.Article"
+                                "$ref": "#/definitions/model.FetchRun"
                             }
                         }
                     },
@@ -80,9 +251,141 @@ const docTemplate = `{
                 }
             }
         },
+        "/import": {
+            "post": {
+                "description": "Import the gzip-compressed NDJSON articles of the request body, as exported, saving them in batches. The already saved articles are updated rather than repeated, and the articles keep the IDs of their sources, which must exist. The response streams an NDJSON line with the progress after every batch, and a last one with done set, or error if the import failed midway",
+                "consumes": [
+                    "application/gzip"
+                ],
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "articles"
+                ],
+                "summary": "Import articles",
+                "operationId": "import-articles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/web.importProgress"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduler": {
+            "get": {
+                "description": "Get whether the scheduler is paused, when the next source is due and the latest fetch of every scheduled source",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Get scheduler status",
+                "operationId": "get-scheduler-status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SchedulerStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduler/pause": {
+            "post": {
+                "description": "Skip the scheduled fetches until the scheduler is resumed. The fetches in progress run to completion",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Pause scheduler",
+                "operationId": "pause-scheduler",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SchedulerStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduler/resume": {
+            "post": {
+                "description": "Run the scheduled fetches again after the scheduler was paused",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Resume scheduler",
+                "operationId": "resume-scheduler",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SchedulerStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduler/run": {
+            "post": {
+                "description": "Immediately fetch all scheduled sources without waiting for the fetches to complete. The results show up in the status once they do",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Run scheduler now",
+                "operationId": "run-scheduler",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.SchedulerStatus"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/sources": {
             "get": {
-                "description": "Gets all currently available sources for fetching news",
+                "description": "Gets all currently available sources for fetching news, along with the health of their fetches",
                 "consumes": [
                     "application/json"
                 ],
@@ -119,7 +422,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new source",
+                "description": "Create a new source. HTML pages without a feed need a scrape config with the CSS selectors of their articles",
                 "consumes": [
                     "application/json"
                 ],
@@ -212,7 +515,7 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update source by ID",
+                "description": "Update source by ID, including its fetch schedule. The scheduler picks up the new schedule on its next sync. Updating a source resets its health, enabling it again if it was disabled after failing too many times",
                 "consumes": [
                     "application/json"
                 ],
@@ -306,49 +609,459 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/sources/{id}/fetch-runs": {
+            "get": {
+                "description": "Get the recorded fetch attempts of the source, newest first, telling why it failed or went quiet",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sources"
+                ],
+                "summary": "Get fetch runs of source",
+                "operationId": "get-source-fetch-runs",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Source ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of attempts",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.FetchAttempt"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
         "model.Article": {
             "type": "object",
             "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "content": {
+                    "type": "string"
+                },
                 "description": {
                     "type": "string"
                 },
+                "guid": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "image_url": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
                 "link": {
                     "type": "string"
                 },
-                "pubDate": {
+                "other_sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Source"
+                    }
+                },
+                "pub_date": {
                     "type": "string"
                 },
+                "relevance": {
+                    "type": "number"
+                },
                 "source": {
                     "$ref": "#/definitions/model.Source"
                 },
+                "story_id": {
+                    "type": "integer"
+                },
                 "title": {
                     "type": "string"
                 }
             }
         },
+        "model.ArticlePage": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Article"
+                    }
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.FetchAttempt": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "integer"
+                },
+                "bytes": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "http_status": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inserted": {
+                    "type": "integer"
+                },
+                "run_id": {
+                    "type": "integer"
+                },
+                "source_id": {
+                    "type": "integer"
+                },
+                "source_name": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.FetchReport": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "integer"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SourceFetchResult"
+                    }
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.FetchRun": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FetchAttempt"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inserted": {
+                    "type": "integer"
+                },
+                "sources": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.FetchSchedule": {
+            "type": "object",
+            "properties": {
+                "cron": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "interval": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PruneReport": {
+            "type": "object",
+            "properties": {
+                "archive": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SourcePruneResult"
+                    }
+                }
+            }
+        },
+        "model.RetentionPolicy": {
+            "type": "object",
+            "properties": {
+                "max_age_days": {
+                    "type": "integer"
+                },
+                "max_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.SaveResult": {
+            "type": "object",
+            "properties": {
+                "inserted": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.SchedulerStatus": {
+            "type": "object",
+            "properties": {
+                "last_report": {
+                    "$ref": "#/definitions/model.FetchReport"
+                },
+                "next_run": {
+                    "type": "string"
+                },
+                "paused": {
+                    "type": "boolean"
+                },
+                "sources": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ScrapeConfig": {
+            "type": "object",
+            "properties": {
+                "article_selector": {
+                    "type": "string"
+                },
+                "base_url": {
+                    "type": "string"
+                },
+                "date_attribute": {
+                    "type": "string"
+                },
+                "date_formats": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description_attribute": {
+                    "type": "string"
+                },
+                "description_selector": {
+                    "type": "string"
+                },
+                "link_selector": {
+                    "type": "string"
+                },
+                "pub_date_selector": {
+                    "type": "string"
+                },
+                "title_selector": {
+                    "type": "string"
+                }
+            }
+        },
         "model.Source": {
             "type": "object",
             "properties": {
+                "etag": {
+                    "type": "string"
+                },
+                "health": {
+                    "$ref": "#/definitions/model.SourceHealth"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "last_modified": {
+                    "type": "string"
+                },
                 "link": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
+                "retention": {
+                    "$ref": "#/definitions/model.RetentionPolicy"
+                },
+                "schedule": {
+                    "$ref": "#/definitions/model.FetchSchedule"
+                },
+                "scrape": {
+                    "$ref": "#/definitions/model.ScrapeConfig"
+                },
                 "short_name": {
                     "type": "string"
                 }
             }
         },
+        "model.SourceFetchResult": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "integer"
+                },
+                "bytes": {
+                    "type": "integer"
+                },
+                "duration": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "http_status": {
+                    "type": "integer"
+                },
+                "saved": {
+                    "$ref": "#/definitions/model.SaveResult"
+                },
+                "source": {
+                    "$ref": "#/definitions/model.Source"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.SourceHealth": {
+            "type": "object",
+            "properties": {
+                "average_items": {
+                    "type": "number"
+                },
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "fetches": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_failure": {
+                    "type": "string"
+                },
+                "last_success": {
+                    "type": "string"
+                },
+                "last_update": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "update_interval": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.SourcePruneResult": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Article"
+                    }
+                },
+                "policy": {
+                    "$ref": "#/definitions/model.RetentionPolicy"
+                },
+                "pruned": {
+                    "type": "integer"
+                },
+                "source": {
+                    "$ref": "#/definitions/model.Source"
+                }
+            }
+        },
         "web.errorResponse": {
             "type": "object",
             "properties": {
@@ -356,6 +1069,29 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "web.importProgress": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "integer"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "inserted": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -4,17 +4,22 @@ import (
 	"context"
 	"fmt"
 	_ "github.com/antonchaban/news-aggregator/cmd/news-alligator/web/docs"
+	"github.com/antonchaban/news-aggregator/pkg/backuper"
 	"github.com/antonchaban/news-aggregator/pkg/handler/web"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/scheduler"
 	"github.com/antonchaban/news-aggregator/pkg/server"
 	"github.com/antonchaban/news-aggregator/pkg/service"
 	"github.com/antonchaban/news-aggregator/pkg/storage"
-	"github.com/antonchaban/news-aggregator/pkg/storage/postgres"
+	"github.com/antonchaban/news-aggregator/pkg/storage/inmemory"
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 	_ "go.uber.org/mock/mockgen/model"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 )
 
 // @title News Alligator API
@@ -24,9 +29,18 @@ import (
 // @BasePath /articles
 
 const (
-	certFileEnvVar = "CERT_FILE"
-	keyFileEnvVar  = "KEY_FILE"
-	portEnvVar     = "PORT"
+	certFileEnvVar  = "CERT_FILE"
+	keyFileEnvVar   = "KEY_FILE"
+	portEnvVar      = "PORT"
+	schedulerEnvVar = "SCHEDULER_ENABLED"
+	backupEnvVar    = "BACKUP_INTERVAL"
+
+	// fetchHistoryPruneInterval is how often the fetch runs beyond the fetch history policy are pruned.
+	fetchHistoryPruneInterval = time.Hour
+	// shutdownTimeout is the time the server has to back up the data and finish the requests in progress.
+	shutdownTimeout = 30 * time.Second
+	// defaultMemoryBackupInterval is the interval of the backups of the in-memory storages unless BACKUP_INTERVAL is set.
+	defaultMemoryBackupInterval = time.Hour
 )
 
 var (
@@ -35,6 +49,7 @@ var (
 	dbPass  string
 	dbName  string
 	sslMode string
+	dbPath  string
 )
 
 func init() {
@@ -43,6 +58,7 @@ func init() {
 	dbPass = os.Getenv("POSTGRES_PASSWORD")
 	dbName = os.Getenv("POSTGRES_DB")
 	sslMode = "disable"
+	dbPath = os.Getenv("SQLITE_PATH")
 }
 
 func main() {
@@ -52,26 +68,80 @@ func main() {
 		logrus.Fatal(err)
 	}
 
-	// Initialize in-memory databases
+	// Connect to the database of the storage type selected by STORAGE_TYPE,
+	// or restore the in-memory storages and journal their changes
+	var (
+		artDb         service.ArticleStorage
+		srcDb         service.SourceStorage
+		runs          service.FetchRunStorage
+		sourceService web.SourceService
+		journal       *backuper.Journal
+	)
+	if storage.Type() == storage.TypeMemory {
+		artDb, srcDb, journal = restoreMemory()
+		runs = inmemory.NewFetchRunStorage()
+		sourceService = service.NewSourceService(artDb, srcDb, inmemory.NewUnitOfWork(artDb, srcDb),
+			inmemory.NewFetchLocker(), runs)
+	} else {
+		db, err := storage.NewDB(storage.Config{
+			Host:     dbHost,
+			Username: dbUser,
+			Password: dbPass,
+			DBName:   dbName,
+			SSLMode:  sslMode,
+			Path:     dbPath,
+		})
+		if err != nil {
+			logrus.Fatal("error occurred while connecting to the database: ", err.Error())
+		}
+		artDb, srcDb = storage.New(db)
+		runs = storage.NewFetchRunStorage(db)
+		sourceService = service.NewSourceService(artDb, srcDb, storage.NewUnitOfWork(db), storage.NewFetchLocker(db), runs)
+	}
+	articleService := service.New(artDb)
 
-	db, err := storage.NewDB(storage.Config{
-		Host:     dbHost,
-		Username: dbUser,
-		Password: dbPass,
-		DBName:   dbName,
-		SSLMode:  sslMode,
-	})
+	// Pruned articles are archived only if ARCHIVE_DIR is set
+	retentionService, err := service.NewRetentionServiceFromEnv(artDb, srcDb)
 	if err != nil {
-		logrus.Fatal("error occurred while connecting to the database: ", err.Error())
+		logrus.Fatal(err)
 	}
 
-	srcDb := postgres.NewSrc(db)
-	artDb := postgres.New(db)
-	articleService := service.New(artDb)
-	sourceService := service.NewSourceService(artDb, srcDb)
+	// The server fetches the sources on their schedules only if SCHEDULER_ENABLED is set,
+	// otherwise that's left to the news-fetcher job
+	var sched *scheduler.Scheduler
+	var schedulerService web.SchedulerService
+	if enabled, err := schedulerEnabled(); err != nil {
+		logrus.Fatal(err)
+	} else if enabled {
+		sched = scheduler.NewScheduler(articleService, sourceService, retentionService)
+		sched.Start()
+		schedulerService = sched
+	}
+
+	// The articles and sources are backed up on shutdown, and every BACKUP_INTERVAL if it's set.
+	// The in-memory storages are backed up hourly by default, so their journal doesn't grow for long
+	interval, err := backupInterval()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if interval == 0 && journal != nil {
+		interval = defaultMemoryBackupInterval
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if interval > 0 {
+		go runBackups(jobsCtx, interval, articleService, sourceService, journal)
+	}
+
+	// The fetch runs beyond the fetch history policy are pruned hourly
+	historyPolicy, err := service.FetchHistoryPolicyFromEnv()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	go service.NewFetchHistoryPruner(runs, historyPolicy).Run(jobsCtx, fetchHistoryPruneInterval)
 
 	// Initialize web handler
-	h := web.NewHandler(articleService, sourceService)
+	h := web.NewHandler(articleService, sourceService, retentionService, schedulerService)
 
 	// Create a new HTTPS server
 	srv := server.NewServer(os.Getenv(certFileEnvVar), os.Getenv(keyFileEnvVar))
@@ -92,21 +162,120 @@ func main() {
 
 	logrus.Print("news-alligator 🐊 shutting down")
 
-	// Retrieve all articles before shutting down
-	articles, err := articleService.GetAll()
-	if err != nil {
-		logrus.Errorf("error occurred on getting all articles: %s", err.Error())
+	// Stop fetching before the data is backed up
+	if sched != nil {
+		sched.Stop()
 	}
+	stopJobs()
 
-	sources, err := sourceService.GetAll()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Retrieve all sources before shutting down, the articles are read page by page as they are saved.
+	// The snapshot is taken before anything is read, like the periodic ones
+	at := time.Now()
+	sources, err := sourceService.GetAll(ctx)
 	if err != nil {
 		logrus.Errorf("error occurred on getting all sources: %s", err.Error())
 	}
 
 	// Shutdown the server
-	if err := srv.Shutdown(context.Background(), articles, sources); err != nil {
+	if err := srv.Shutdown(ctx, backuper.NewSaverAt(at, articleIterator(ctx, articleService), sources)); err != nil {
 		logrus.Errorf("error occurred on server shutting down: %s", err.Error())
 	}
+	if journal != nil {
+		if err := journal.Close(); err != nil {
+			logrus.Errorf("error occurred on closing the journal: %s", err.Error())
+		}
+	}
+}
+
+// restoreMemory restores the in-memory storages to the latest state of the snapshots and the journal in SAVES_DIR,
+// and journals their changes from then on.
+func restoreMemory() (service.ArticleStorage, service.SourceStorage, *backuper.Journal) {
+	artDb, srcDb, report, err := storage.RestoreMemory(context.Background(), time.Time{})
+	if err != nil {
+		logrus.Fatal("error occurred while restoring the articles and sources: ", err.Error())
+	}
+	logrus.WithFields(logrus.Fields{
+		"snapshot": report.Snapshot,
+		"changes":  report.Changes,
+		"articles": report.Articles,
+		"sources":  report.Sources,
+	}).Info("in-memory storages restored")
+
+	journal, err := backuper.NewJournal()
+	if err != nil {
+		logrus.Fatal("error occurred while opening the journal: ", err.Error())
+	}
+	if err = inmemory.SetJournal(journal, artDb, srcDb); err != nil {
+		logrus.Fatal(err)
+	}
+	return artDb, srcDb, journal
+}
+
+// schedulerEnabled reports whether SCHEDULER_ENABLED is set to true.
+func schedulerEnabled() (bool, error) {
+	value := os.Getenv(schedulerEnvVar)
+	if value == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: %w", schedulerEnvVar, value, err)
+	}
+	return enabled, nil
+}
+
+// backupInterval returns the interval of the backups set by BACKUP_INTERVAL, zero if it's not set.
+func backupInterval() (time.Duration, error) {
+	value := os.Getenv(backupEnvVar)
+	if value == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", backupEnvVar, value)
+	}
+	return interval, nil
+}
+
+// runBackups saves a snapshot of the articles and sources every interval until ctx is done,
+// starting a new segment of the journal, if any, after every snapshot.
+func runBackups(ctx context.Context, interval time.Duration, asvc web.ArticleService, ssvc web.SourceService,
+	journal *backuper.Journal) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// The snapshot is taken before anything is read, the journal from then on holds the changes it misses
+		at := time.Now()
+		sources, err := ssvc.GetAll(ctx)
+		if err != nil {
+			logrus.Errorf("error occurred on getting all sources: %s", err.Error())
+			continue
+		}
+		if _, err = backuper.NewSaverAt(at, articleIterator(ctx, asvc), sources).Save(); err != nil {
+			logrus.Errorf("error occurred on backing up: %s", err.Error())
+			continue
+		}
+		if journal != nil {
+			if err = journal.Rotate(); err != nil {
+				logrus.Errorf("error occurred on rotating the journal: %s", err.Error())
+			}
+		}
+	}
+}
+
+// articleIterator returns the iterator over all the articles of the service, read page by page.
+func articleIterator(ctx context.Context, asvc web.ArticleService) backuper.ArticleIterator {
+	return func(yield func(model.Article) error) error {
+		return asvc.Each(ctx, yield)
+	}
 }
 
 // checkEnvVars checks if the required environment variables are set and returns an error if any are missing
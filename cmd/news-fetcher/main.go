@@ -1,20 +1,24 @@
 package main
 
 import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/antonchaban/news-aggregator/pkg/service"
 	"github.com/antonchaban/news-aggregator/pkg/storage"
-	"github.com/antonchaban/news-aggregator/pkg/storage/postgres"
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 var (
-	pgHost    string
-	pgUser    string
-	pgPass    string
-	pgDBName  string
-	pgSSLMode string
+	pgHost     string
+	pgUser     string
+	pgPass     string
+	pgDBName   string
+	pgSSLMode  string
+	sqlitePath string
 )
 
 func init() {
@@ -23,6 +27,7 @@ func init() {
 	pgPass = os.Getenv("POSTGRES_PASSWORD")
 	pgDBName = os.Getenv("POSTGRES_DB")
 	pgSSLMode = "disable"
+	sqlitePath = os.Getenv("SQLITE_PATH")
 }
 
 func main() {
@@ -32,17 +37,82 @@ func main() {
 		Password: pgPass,
 		DBName:   pgDBName,
 		SSLMode:  pgSSLMode,
+		Path:     sqlitePath,
 	})
 	if err != nil {
 		logrus.Fatal("error occurred while connecting to the database: ", err.Error())
 	}
 
-	srcDb := postgres.NewSrc(db)
-	artDb := postgres.New(db)
-	sourceService := service.NewSourceService(artDb, srcDb)
-	err = sourceService.FetchFromAllSources()
+	artDb, srcDb := storage.New(db)
+	runs := storage.NewFetchRunStorage(db)
+	sourceService := service.NewSourceService(artDb, srcDb, storage.NewUnitOfWork(db), storage.NewFetchLocker(db), runs)
+	// Stop fetching when the job is interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := sourceService.FetchFromAllSources(ctx)
 	if err != nil {
 		logrus.Fatal("error occurred while fetching articles from sources: ", err.Error())
 		return
 	}
+	for _, result := range report.Sources {
+		entry := logrus.WithFields(logrus.Fields{
+			"source":   result.Source.ShortName,
+			"status":   result.Status,
+			"http":     result.HTTPStatus,
+			"bytes":    result.Bytes,
+			"articles": result.Articles,
+			"inserted": result.Saved.Inserted,
+			"updated":  result.Saved.Updated,
+			"skipped":  result.Saved.Skipped,
+			"duration": result.Duration,
+		})
+		if result.Error != "" {
+			entry.Warn("source fetch failed: ", result.Error)
+			continue
+		}
+		entry.Info("source fetched")
+	}
+	logrus.WithFields(logrus.Fields{
+		"sources":   len(report.Sources),
+		"unchanged": report.Count(model.FetchUnchanged),
+		"failed":    report.Count(model.FetchFailed),
+		"leased":    report.Count(model.FetchSkipped),
+		"disabled":  report.Count(model.FetchDisabled),
+		"articles":  report.Articles(),
+		"inserted":  report.Saved().Inserted,
+		"updated":   report.Saved().Updated,
+		"skipped":   report.Saved().Skipped,
+		"duration":  report.Duration,
+	}).Info("fetch completed")
+
+	// Prune the articles beyond the retention policies after every fetch,
+	// archiving them first if ARCHIVE_DIR is set
+	retentionService, err := service.NewRetentionServiceFromEnv(artDb, srcDb)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	pruneReport, err := retentionService.Prune(ctx, false)
+	if err != nil {
+		logrus.Error("error occurred while pruning articles: ", err.Error())
+	} else {
+		logrus.WithFields(logrus.Fields{
+			"sources": len(pruneReport.Sources),
+			"pruned":  pruneReport.Pruned(),
+			"archive": pruneReport.Archive,
+		}).Info("prune completed")
+	}
+
+	// Prune the fetch runs beyond the fetch history policy
+	historyPolicy, err := service.FetchHistoryPolicyFromEnv()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if _, err = service.NewFetchHistoryPruner(runs, historyPolicy).Prune(ctx); err != nil {
+		logrus.Error("error occurred while pruning the fetch history: ", err.Error())
+	}
+
+	if len(report.Sources) > 0 && report.Count(model.FetchFailed) == len(report.Sources) {
+		logrus.Fatal("all sources failed to fetch")
+	}
 }
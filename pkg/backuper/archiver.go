@@ -0,0 +1,65 @@
+package backuper
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveTimeFormat stamps the archive file names, so they sort in the order they were written.
+const archiveTimeFormat = "20060102T150405.000000000Z"
+
+// Archiver is an interface for archiving articles before they are deleted.
+type Archiver interface {
+	Archive(articles ArticleIterator) (string, error)
+}
+
+// newsArchiver is an implementation of the Archiver interface
+// writing gzip-compressed NDJSON files, one article per line.
+type newsArchiver struct {
+	dir string
+	now func() time.Time
+}
+
+// NewArchiver creates a new Archiver writing the archives to the given directory.
+func NewArchiver(dir string) Archiver {
+	return &newsArchiver{dir: dir, now: time.Now}
+}
+
+// Archive writes the articles to a new archive named after the current time and returns its path,
+// or an empty path if there were no articles to archive.
+// The archive is written to a temporary file first, so a failed write leaves no partial archive behind.
+func (n *newsArchiver) Archive(articles ArticleIterator) (string, error) {
+	if err := os.MkdirAll(n.dir, 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(n.dir, "articles-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	aw := NewArticleWriter(tmp)
+	if err = articles(aw.Write); err != nil {
+		return "", err
+	}
+	if aw.Count() == 0 {
+		return "", nil
+	}
+	if err = aw.Close(); err != nil {
+		return "", err
+	}
+	if err = tmp.Sync(); err != nil {
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(n.dir, "articles-"+n.now().UTC().Format(archiveTimeFormat)+".ndjson.gz")
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,61 @@
+package backuper
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchiver_Archive(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	now := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	archiver := &newsArchiver{dir: dir, now: func() time.Time { return now }}
+	articles := []model.Article{
+		{Id: 1, Title: "Trial starts", Link: "https://bbc.com/trial", PubDate: now},
+		{Id: 2, Title: "Heatstroke in Tokyo", Link: "https://abcnews.go.com/heatstroke", PubDate: now},
+	}
+
+	path, err := archiver.Archive(SliceIterator(articles))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "articles-20240805T090000.000000000Z.ndjson.gz"), path)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	var archived []model.Article
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var article model.Article
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &article))
+		archived = append(archived, article)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, articles, archived)
+
+	// The temporary file is renamed, so only the archive is left
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestArchiver_Archive_NoArticles(t *testing.T) {
+	dir := t.TempDir()
+	archiver := NewArchiver(dir)
+
+	path, err := archiver.Archive(SliceIterator(nil))
+	require.NoError(t, err)
+	assert.Empty(t, path)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no archive is written without articles")
+}
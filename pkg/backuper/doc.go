@@ -1,7 +1,12 @@
 /*
 Package backuper provides functionality for loading and saving article backups
-to and from a JSON file. The package defines interfaces and implementations for
-loading and saving operations, making it easy to extend or replace the backup
-mechanism if needed.
+as snapshots verified against the checksums of their manifest and rotated under
+a retention count, and for archiving the pruned articles. The articles are written
+and read one at a time as gzip-compressed NDJSON by ArticleWriter and ArticleReader,
+which the export and import of the articles use as well.
+Journal records the changes of the in-memory storages between the snapshots, and Restore
+replays them on top of a snapshot to restore the state as of a point in time.
+The package defines interfaces and implementations for loading and saving operations,
+making it easy to extend or replace the backup mechanism if needed.
 */
 package backuper
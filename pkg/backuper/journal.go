@@ -0,0 +1,217 @@
+package backuper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	eventJournalRotated   = "journal_rotated"
+	eventJournalPruned    = "journal_pruned"
+	eventJournalTornWrite = "journal_torn_write"
+)
+
+const (
+	// journalPrefix starts the names of the journal segments, followed by the time they were started.
+	journalPrefix = "journal-"
+	journalExt    = ".ndjson"
+)
+
+// Journal appends the changes of the in-memory storages to the journal in the directory specified
+// by the SAVES_DIR environment variable, one change per line, so the changes made since the latest
+// snapshot survive a crash. Every change is written through to the operating system as it's appended,
+// which is enough to survive the process being killed.
+// The journal is split into segments, a new one is started by Rotate once a snapshot is saved,
+// and the segments made redundant by the kept snapshots are deleted along with the old snapshots.
+type Journal struct {
+	mu  sync.Mutex
+	dir string
+	f   *os.File
+	now func() time.Time
+}
+
+// NewJournal creates a new Journal, starting a new segment.
+func NewJournal() (*Journal, error) {
+	dir, err := savesDir()
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	j := &Journal{dir: dir, now: time.Now}
+	if err = j.open(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// open starts a new segment, the caller must hold the lock.
+func (j *Journal) open() error {
+	path := filepath.Join(j.dir, journalPrefix+j.now().UTC().Format(archiveTimeFormat)+journalExt)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	j.f = f
+	return nil
+}
+
+// Append appends the changes to the current segment in a single write.
+func (j *Journal) Append(changes ...model.Change) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, change := range changes {
+		if err := enc.Encode(change); err != nil {
+			return err
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err := j.f.Write(buf.Bytes())
+	return err
+}
+
+// Rotate closes the current segment, flushed to the disk, and starts a new one.
+func (j *Journal) Rotate() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.close(); err != nil {
+		return err
+	}
+	if err := j.open(); err != nil {
+		return err
+	}
+	logrus.WithField("event_id", eventJournalRotated).Infof("Started journal segment %s", j.f.Name())
+	return nil
+}
+
+// Close closes the current segment, flushed to the disk.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.close()
+}
+
+// close closes the current segment, the caller must hold the lock.
+func (j *Journal) close() error {
+	if err := j.f.Sync(); err != nil {
+		_ = j.f.Close()
+		return err
+	}
+	return j.f.Close()
+}
+
+// listJournal returns the paths of the journal segments in dir, oldest first.
+func listJournal(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), journalPrefix) && strings.HasSuffix(entry.Name(), journalExt) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	// The names end with the time the segments were started, in a sortable format
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// readJournal calls fn with the changes of the journal in dir made from the from time to the to time,
+// in the order they were made, and returns their number. The zero to time sets no limit.
+// An incomplete last line, left by a crash in the middle of a write, is skipped.
+func readJournal(dir string, from, to time.Time, fn func(model.Change) error) (int, error) {
+	paths, err := listJournal(dir)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, path := range paths {
+		err = readSegment(path, func(change model.Change) error {
+			if change.At.Before(from) || (!to.IsZero() && change.At.After(to)) {
+				return nil
+			}
+			count++
+			return fn(change)
+		})
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// readSegment calls fn with every change of the journal segment at path.
+func readSegment(path string, fn func(model.Change) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				logrus.WithField("event_id", eventJournalTornWrite).
+					Warnf("Skipping the incomplete line %d of %s", line, path)
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var change model.Change
+		if err = json.Unmarshal(data, &change); err != nil {
+			return fmt.Errorf("invalid change on line %d of %s: %w", line, path, err)
+		}
+		if err = fn(change); err != nil {
+			return err
+		}
+	}
+}
+
+// pruneJournal deletes the journal segments in dir holding only the changes made before the since time,
+// which the snapshots taken since already hold.
+func pruneJournal(dir string, since time.Time) error {
+	paths, err := listJournal(dir)
+	if err != nil {
+		return err
+	}
+	// A segment ends when the next one starts, the current segment is never deleted
+	for i := 0; i+1 < len(paths); i++ {
+		end, err := startTime(paths[i+1], journalPrefix, journalExt)
+		if err != nil || end.After(since) {
+			return err
+		}
+		if err = os.Remove(paths[i]); err != nil {
+			return err
+		}
+		logrus.WithField("event_id", eventJournalPruned).Infof("Deleted old journal segment %s", paths[i])
+	}
+	return nil
+}
+
+// startTime returns the time in the name of the snapshot or journal segment at path,
+// between the given prefix and suffix.
+func startTime(path, prefix, suffix string) (time.Time, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), prefix), suffix)
+	return time.Parse(archiveTimeFormat, name)
+}
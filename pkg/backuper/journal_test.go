@@ -0,0 +1,149 @@
+package backuper
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testJournal creates a journal in dir whose segments are named after the times now returns.
+func testJournal(t *testing.T, dir string, now func() time.Time) *Journal {
+	t.Helper()
+	j := &Journal{dir: dir, now: now}
+	require.NoError(t, j.open())
+	t.Cleanup(func() { _ = j.Close() })
+	return j
+}
+
+func TestNewJournal(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(savesDirEnvVar, dir)
+
+	j, err := NewJournal()
+	require.NoError(t, err)
+	require.NoError(t, j.Close())
+	paths, err := listJournal(dir)
+	require.NoError(t, err)
+	assert.Len(t, paths, 1)
+
+	t.Setenv(savesDirEnvVar, "")
+	_, err = NewJournal()
+	assert.EqualError(t, err, "SAVES_DIR environment variable is not set")
+}
+
+func TestJournal_Append(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	now := start
+	j := testJournal(t, dir, func() time.Time { return now })
+	change := func(minutes, id int) model.Change {
+		return model.Change{At: start.Add(time.Duration(minutes) * time.Minute), Kind: model.ChangeDeleteArticle, Id: id}
+	}
+
+	require.NoError(t, j.Append(change(10, 1), change(20, 2)))
+	now = start.Add(30 * time.Minute)
+	require.NoError(t, j.Rotate())
+	require.NoError(t, j.Append(change(40, 3)))
+
+	paths, err := listJournal(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "journal-20240805T090000.000000000Z.ndjson"),
+		filepath.Join(dir, "journal-20240805T093000.000000000Z.ndjson"),
+	}, paths)
+
+	tests := []struct {
+		name    string
+		from    time.Time
+		to      time.Time
+		wantIDs []int
+	}{
+		{name: "all changes", wantIDs: []int{1, 2, 3}},
+		{name: "changes since", from: start.Add(20 * time.Minute), wantIDs: []int{2, 3}},
+		{name: "changes until", to: start.Add(20 * time.Minute), wantIDs: []int{1, 2}},
+		{name: "no changes", from: start.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []int
+			count, err := readJournal(dir, tt.from, tt.to, func(change model.Change) error {
+				ids = append(ids, change.Id)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIDs, ids)
+			assert.Equal(t, len(tt.wantIDs), count)
+		})
+	}
+}
+
+func TestReadJournal_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantIDs []int
+		wantErr string
+	}{
+		{
+			name:    "incomplete last line is skipped",
+			data:    `{"kind":"delete_article","id":1}` + "\n" + `{"kind":"delete_art`,
+			wantIDs: []int{1},
+		},
+		{
+			name:    "invalid line",
+			data:    `{"kind":"delete_article","id":1}` + "\n" + `invalid json` + "\n",
+			wantIDs: []int{1},
+			wantErr: "invalid change on line 2 of",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, journalPrefix+"20240805T090000.000000000Z"+journalExt)
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o644))
+
+			var ids []int
+			_, err := readJournal(dir, time.Time{}, time.Time{}, func(change model.Change) error {
+				ids = append(ids, change.Id)
+				return nil
+			})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+		})
+	}
+}
+
+func TestSaver_Save_PrunesJournal(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(savesDirEnvVar, dir)
+	t.Setenv(backupRetentionEnvVar, "2")
+	now := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	j := testJournal(t, dir, clock)
+	saver := &newsSaver{now: clock}
+
+	// A snapshot is taken every hour, starting a new journal segment half an hour later
+	var segments []string
+	for i := 0; i < 4; i++ {
+		segments = append(segments, j.f.Name())
+		now = now.Add(time.Hour)
+		_, err := saver.Save()
+		require.NoError(t, err)
+		now = now.Add(30 * time.Minute)
+		require.NoError(t, j.Rotate())
+	}
+	segments = append(segments, j.f.Name())
+
+	// The segments holding the changes made since the oldest kept snapshot are kept
+	paths, err := listJournal(dir)
+	require.NoError(t, err)
+	assert.Equal(t, segments[2:], paths)
+}
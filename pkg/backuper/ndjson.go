@@ -0,0 +1,130 @@
+package backuper
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"io"
+)
+
+// ArticleIterator calls yield with every article in turn, stopping at the first error yield returns.
+// It lets the articles be written without holding all of them in memory.
+type ArticleIterator func(yield func(model.Article) error) error
+
+// SliceIterator returns the iterator over the articles of the slice.
+func SliceIterator(articles []model.Article) ArticleIterator {
+	return func(yield func(model.Article) error) error {
+		for _, article := range articles {
+			if err := yield(article); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Batch calls fn with the articles of the iterator in batches of up to size articles.
+// The batch is reused, fn must not keep it.
+func (it ArticleIterator) Batch(size int, fn func([]model.Article) error) error {
+	batch := make([]model.Article, 0, size)
+	err := it(func(article model.Article) error {
+		batch = append(batch, article)
+		if len(batch) < size {
+			return nil
+		}
+		err := fn(batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+	return fn(batch)
+}
+
+// ArticleWriter writes articles as gzip-compressed NDJSON, one article per line.
+type ArticleWriter struct {
+	zw    *gzip.Writer
+	enc   *json.Encoder
+	count int
+}
+
+// NewArticleWriter creates a new ArticleWriter writing to w.
+func NewArticleWriter(w io.Writer) *ArticleWriter {
+	zw := gzip.NewWriter(w)
+	return &ArticleWriter{zw: zw, enc: json.NewEncoder(zw)}
+}
+
+// Write writes the article as the next line.
+func (a *ArticleWriter) Write(article model.Article) error {
+	if err := a.enc.Encode(article); err != nil {
+		return fmt.Errorf("failed to write article %d: %w", article.Id, err)
+	}
+	a.count++
+	return nil
+}
+
+// Count returns the number of articles written so far.
+func (a *ArticleWriter) Count() int {
+	return a.count
+}
+
+// Close flushes the articles written so far and ends the gzip stream, without closing the underlying writer.
+func (a *ArticleWriter) Close() error {
+	return a.zw.Close()
+}
+
+// ArticleReader reads articles from gzip-compressed NDJSON, one article per line.
+type ArticleReader struct {
+	zr    *gzip.Reader
+	dec   *json.Decoder
+	count int
+}
+
+// NewArticleReader creates a new ArticleReader reading from r, failing if r isn't gzip-compressed.
+func NewArticleReader(r io.Reader) (*ArticleReader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &ArticleReader{zr: zr, dec: json.NewDecoder(zr)}, nil
+}
+
+// Read reads the next article, returning io.EOF once all articles are read.
+func (a *ArticleReader) Read() (model.Article, error) {
+	var article model.Article
+	if err := a.dec.Decode(&article); err != nil {
+		if err == io.EOF {
+			return model.Article{}, err
+		}
+		return model.Article{}, fmt.Errorf("failed to read article %d: %w", a.count+1, err)
+	}
+	a.count++
+	return article, nil
+}
+
+// Each calls yield with every article read, stopping at the first error.
+func (a *ArticleReader) Each(yield func(model.Article) error) error {
+	for {
+		article, err := a.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = yield(article); err != nil {
+			return err
+		}
+	}
+}
+
+// Count returns the number of articles read so far.
+func (a *ArticleReader) Count() int {
+	return a.count
+}
+
+// Close closes the gzip stream, without closing the underlying reader.
+func (a *ArticleReader) Close() error {
+	return a.zr.Close()
+}
@@ -0,0 +1,91 @@
+package backuper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestArticleWriter_ArticleReader(t *testing.T) {
+	pubDate := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	articles := []model.Article{
+		{Id: 1, Title: "Test Article", PubDate: pubDate, Source: model.Source{Id: 1, Name: "Test Source"}},
+		{Id: 2, Title: "Another Article", Categories: []string{"world"}, PubDate: pubDate},
+	}
+
+	var buf bytes.Buffer
+	aw := NewArticleWriter(&buf)
+	require.NoError(t, SliceIterator(articles)(aw.Write))
+	require.NoError(t, aw.Close())
+	assert.Equal(t, 2, aw.Count())
+
+	// Every article is a line of the decompressed stream
+	zr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(data, []byte("\n")))
+
+	ar, err := NewArticleReader(&buf)
+	require.NoError(t, err)
+	var got []model.Article
+	require.NoError(t, ar.Each(func(article model.Article) error {
+		got = append(got, article)
+		return nil
+	}))
+	assert.Equal(t, articles, got)
+	assert.Equal(t, 2, ar.Count())
+	require.NoError(t, ar.Close())
+}
+
+func TestArticleReader_Errors(t *testing.T) {
+	compress := func(data string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	_, err := NewArticleReader(bytes.NewReader([]byte(`{"title":"Test Article"}`)))
+	assert.ErrorIs(t, err, gzip.ErrHeader)
+
+	ar, err := NewArticleReader(bytes.NewReader(compress("{\"title\":\"Test Article\"}\n{\"title\":")))
+	require.NoError(t, err)
+	article, err := ar.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "Test Article", article.Title)
+	_, err = ar.Read()
+	assert.ErrorContains(t, err, "failed to read article 2")
+}
+
+func TestArticleIterator_Batch(t *testing.T) {
+	articles := make([]model.Article, 5)
+	for i := range articles {
+		articles[i].Id = i + 1
+	}
+
+	var sizes []int
+	err := SliceIterator(articles).Batch(2, func(batch []model.Article) error {
+		sizes = append(sizes, len(batch))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+
+	errSave := errors.New("save failed")
+	calls := 0
+	err = SliceIterator(articles).Batch(2, func(batch []model.Article) error {
+		calls++
+		return errSave
+	})
+	assert.ErrorIs(t, err, errSave)
+	assert.Equal(t, 1, calls, "the iteration stops at the first error")
+}
@@ -2,18 +2,22 @@ package backuper
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/handler/web"
-	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
+	"time"
 )
 
-// Loader is an interface for loading articles and sources from a file.
+const (
+	eventSnapshotLoaded    = "snapshot_loaded"
+	eventSnapshotCorrupted = "snapshot_corrupted"
+)
+
+// Loader is an interface for loading the latest snapshot of the articles and sources.
 type Loader interface {
-	LoadAllFromFile() ([]model.Article, error)
-	LoadSrcsFromFile() ([]model.Source, error)
+	Load() (Snapshot, error)
 }
 
 // newsLoader is an implementation of the Loader interface.
@@ -28,74 +32,79 @@ func NewLoader(srcSvc web.SourceService) Loader {
 	}
 }
 
-// LoadAllFromFile loads all articles from a JSON file.
-// It reads the file specified by the SAVES_DIR environment variable and unmarshals
-// its contents into a slice of Article structs.
-func (n *newsLoader) LoadAllFromFile() ([]model.Article, error) {
-	// Construct the file path
-	dataDir := os.Getenv("SAVES_DIR")
-	if dataDir == "" {
-		return nil, errors.New("SAVES_DIR environment variable is not set")
-	}
-	filePath := filepath.Join(dataDir, "articles.json")
-
-	// Check if the file exists
-	fileInfo, err := os.Stat(filePath)
-	if os.IsNotExist(err) || fileInfo.Size() == 0 {
-		logrus.Warn("No articles found in the backup file")
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
-
-	// Read the file contents
-	fileData, err := os.ReadFile(filePath)
+// Load loads the latest valid snapshot from the directory specified by the SAVES_DIR environment variable.
+// A snapshot with a missing file or a file not matching its checksum is skipped in favor of the previous one,
+// an error is returned only if none of them is valid. The articles are verified, but left to be read
+// one at a time by Snapshot.Articles.
+// Without snapshots, the articles.json and sources.json files written by the earlier versions are loaded,
+// if there are any.
+func (n *newsLoader) Load() (Snapshot, error) {
+	dir, err := savesDir()
 	if err != nil {
-		return nil, err
+		return Snapshot{}, err
 	}
+	return loadAt(dir, time.Time{})
+}
 
-	// Unmarshal the JSON data
-	var articles []model.Article
-	err = json.Unmarshal(fileData, &articles)
+// loadAt loads the newest valid snapshot in dir taken at or before the given time, the zero time setting no limit.
+// Without snapshots, the legacy backup files are loaded.
+func loadAt(dir string, at time.Time) (Snapshot, error) {
+	paths, err := listSnapshots(dir)
 	if err != nil {
-		return nil, err
+		return Snapshot{}, err
 	}
-
-	return articles, nil
-}
-
-// LoadSrcsFromFile loads all sources from a JSON file.
-// It reads the file specified by the SAVES_DIR environment variable and unmarshals
-// its contents into a slice of Source structs.
-func (n *newsLoader) LoadSrcsFromFile() ([]model.Source, error) {
-	// Construct the file path
-	dataDir := os.Getenv("SAVES_DIR")
-	if dataDir == "" {
-		return nil, errors.New("SAVES_DIR environment variable is not set")
+	if len(paths) == 0 {
+		return loadLegacy(dir)
 	}
-	filePath := filepath.Join(dataDir, "sources.json")
 
-	// Check if the file exists
-	fileInfo, err := os.Stat(filePath)
-	if os.IsNotExist(err) || fileInfo.Size() == 0 {
-		logrus.Warn("No sources found in the backup file")
-		return nil, nil
-	} else if err != nil {
-		return nil, err
+	for _, path := range paths {
+		if taken, err := startTime(path, snapshotPrefix, ""); err == nil && !at.IsZero() && taken.After(at) {
+			continue
+		}
+		snapshot, err := readSnapshot(path)
+		if err != nil {
+			logrus.WithField("event_id", eventSnapshotCorrupted).
+				Warnf("Skipping corrupted snapshot %s: %v", path, err)
+			continue
+		}
+		logrus.WithField("event_id", eventSnapshotLoaded).Infof("Loaded snapshot %s with %d sources",
+			path, len(snapshot.Sources))
+		return snapshot, nil
 	}
-
-	// Read the file contents
-	fileData, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
+	if !at.IsZero() {
+		return Snapshot{}, fmt.Errorf("none of the %d snapshots in %s is valid and taken at or before %s",
+			len(paths), dir, at.Format(time.RFC3339))
 	}
+	return Snapshot{}, fmt.Errorf("none of the %d snapshots in %s is valid", len(paths), dir)
+}
 
-	// Unmarshal the JSON data
-	var sources []model.Source
-	err = json.Unmarshal(fileData, &sources)
-	if err != nil {
-		return nil, err
-	}
+// loadLegacy loads the articles and sources from the articles.json and sources.json files in dir,
+// the missing or empty ones are loaded as empty.
+func loadLegacy(dir string) (Snapshot, error) {
+	var snapshot Snapshot
+	for _, name := range []string{legacyArticlesFile, sourcesFile} {
+		filePath := filepath.Join(dir, name)
+		// Check if the file exists
+		fileInfo, err := os.Stat(filePath)
+		if os.IsNotExist(err) || (err == nil && fileInfo.Size() == 0) {
+			logrus.Warnf("No backup found in %s", filePath)
+			continue
+		} else if err != nil {
+			return Snapshot{}, err
+		}
 
-	return sources, nil
+		// The articles are read when they are iterated
+		if name == legacyArticlesFile {
+			snapshot.articlesPath = filePath
+			continue
+		}
+		fileData, err := os.ReadFile(filePath)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		if err = json.Unmarshal(fileData, &snapshot.Sources); err != nil {
+			return Snapshot{}, fmt.Errorf("invalid backup %s: %w", filePath, err)
+		}
+	}
+	return snapshot, nil
 }
@@ -1,98 +1,178 @@
 package backuper
 
-/*import (
+import (
 	"encoding/json"
 	"github.com/antonchaban/news-aggregator/pkg/model"
-	"github.com/antonchaban/news-aggregator/pkg/service/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNewLoader(t *testing.T) {
-	mockSrcService := new(mocks.MockSourceService)
-	loader := NewLoader(mockSrcService)
+	loader := NewLoader(nil)
 	assert.NotNil(t, loader)
 }
 
-func TestLoadAllFromFile(t *testing.T) {
-	mockSrcService := new(mocks.MockSourceService)
-	loader := NewLoader(mockSrcService)
-	os.Setenv("SAVES_DIR", "./testdata")
-	defer os.Unsetenv("SAVES_DIR")
-
-	t.Run("should return error when SAVES_DIR is not set", func(t *testing.T) {
-		os.Unsetenv("SAVES_DIR")
-		articles, err := loader.LoadAllFromFile()
-		assert.Nil(t, articles)
-		assert.EqualError(t, err, "SAVES_DIR environment variable is not set")
-		os.Setenv("SAVES_DIR", "./testdata")
-	})
+func TestLoader_Load(t *testing.T) {
+	start := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	// save takes a snapshot of a single article with the given title, an hour after the previous one
+	save := func(t *testing.T, title string) string {
+		saver := &newsSaver{
+			articles: SliceIterator([]model.Article{{Title: title}}),
+			sources:  []model.Source{{Name: "Test Source"}},
+			now:      func() time.Time { return start },
+		}
+		start = start.Add(time.Hour)
+		path, err := saver.Save()
+		require.NoError(t, err)
+		return path
+	}
 
-	t.Run("should return error when json is invalid", func(t *testing.T) {
-		os.Setenv("SAVES_DIR", "./testdata")
-		invalidFilePath := filepath.Join("./testdata", "articles.json")
-		os.WriteFile(invalidFilePath, []byte(`invalid json`), 0644)
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T, dir string)
+		wantTitle string
+		wantErr   string
+	}{
+		{
+			name: "latest snapshot",
+			setup: func(t *testing.T, dir string) {
+				save(t, "Old Article")
+				save(t, "New Article")
+			},
+			wantTitle: "New Article",
+		},
+		{
+			name: "corrupted file falls back to the previous snapshot",
+			setup: func(t *testing.T, dir string) {
+				save(t, "Old Article")
+				path := save(t, "New Article")
+				require.NoError(t, os.WriteFile(filepath.Join(path, articlesFile), []byte(`[{"title":"Tampered"}]`), 0o644))
+			},
+			wantTitle: "Old Article",
+		},
+		{
+			name: "missing manifest falls back to the previous snapshot",
+			setup: func(t *testing.T, dir string) {
+				save(t, "Old Article")
+				path := save(t, "New Article")
+				require.NoError(t, os.Remove(filepath.Join(path, manifestFile)))
+			},
+			wantTitle: "Old Article",
+		},
+		{
+			name: "truncated file falls back to the previous snapshot",
+			setup: func(t *testing.T, dir string) {
+				save(t, "Old Article")
+				path := save(t, "New Article")
+				require.NoError(t, os.Truncate(filepath.Join(path, sourcesFile), 3))
+			},
+			wantTitle: "Old Article",
+		},
+		{
+			name: "incomplete snapshots are ignored",
+			setup: func(t *testing.T, dir string) {
+				save(t, "Old Article")
+				require.NoError(t, os.Mkdir(filepath.Join(dir, ".backup-1.tmp"), 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, ".backup-1.tmp", articlesFile), []byte(`[`), 0o644))
+			},
+			wantTitle: "Old Article",
+		},
+		{
+			name: "no valid snapshot",
+			setup: func(t *testing.T, dir string) {
+				path := save(t, "New Article")
+				require.NoError(t, os.WriteFile(filepath.Join(path, manifestFile), []byte(`invalid json`), 0o644))
+			},
+			wantErr: "none of the 1 snapshots in",
+		},
+		{
+			name: "snapshot of an earlier version",
+			setup: func(t *testing.T, dir string) {
+				path := filepath.Join(dir, snapshotPrefix+"20240805T090000.000000000Z")
+				require.NoError(t, os.Mkdir(path, 0o755))
+				m := manifest{CreatedAt: start, Files: map[string]manifestEntry{}}
+				var err error
+				m.Files[legacyArticlesFile], err = writeFile(filepath.Join(path, legacyArticlesFile), []model.Article{{Title: "Earlier Article"}})
+				require.NoError(t, err)
+				m.Files[sourcesFile], err = writeFile(filepath.Join(path, sourcesFile), []model.Source{{Name: "Test Source"}})
+				require.NoError(t, err)
+				_, err = writeFile(filepath.Join(path, manifestFile), m)
+				require.NoError(t, err)
+			},
+			wantTitle: "Earlier Article",
+		},
+		{
+			name: "legacy backup",
+			setup: func(t *testing.T, dir string) {
+				data, err := json.Marshal([]model.Article{{Title: "Legacy Article"}})
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(dir, legacyArticlesFile), data, 0o644))
+			},
+			wantTitle: "Legacy Article",
+		},
+		{
+			name: "invalid legacy backup",
+			setup: func(t *testing.T, dir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, sourcesFile), []byte(`invalid json`), 0o644))
+			},
+			wantErr: "invalid backup",
+		},
+		{
+			name:  "no backup",
+			setup: func(t *testing.T, dir string) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv(savesDirEnvVar, dir)
+			tt.setup(t, dir)
 
-		articles, err := loader.LoadAllFromFile()
-		assert.Nil(t, articles)
-		assert.NotNil(t, err)
-	})
+			snapshot, err := NewLoader(nil).Load()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			articles := collect(t, snapshot)
+			if tt.wantTitle == "" {
+				assert.Empty(t, articles)
+				return
+			}
+			require.Len(t, articles, 1)
+			assert.Equal(t, tt.wantTitle, articles[0].Title)
+		})
+	}
 
-	t.Run("should load articles successfully", func(t *testing.T) {
-		os.Setenv("SAVES_DIR", "./testdata")
-		validArticles := []model.Article{
-			{Title: "Test Article"},
-		}
-		fileData, _ := json.Marshal(validArticles)
-		validFilePath := filepath.Join("./testdata", "articles.json")
-		os.WriteFile(validFilePath, fileData, 0644)
+	t.Run("invalid legacy articles", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv(savesDirEnvVar, dir)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, legacyArticlesFile), []byte(`[{"title":`), 0o644))
 
-		articles, err := loader.LoadAllFromFile()
-		assert.NotNil(t, articles)
-		assert.Nil(t, err)
-		assert.Equal(t, validArticles, articles)
+		snapshot, err := NewLoader(nil).Load()
+		require.NoError(t, err)
+		// The articles are only read when they are iterated
+		assert.Error(t, snapshot.Articles(func(model.Article) error { return nil }))
 	})
-}
-
-func TestLoadSrcsFromFile(t *testing.T) {
-	mockSrcService := new(mocks.MockSourceService)
-	loader := NewLoader(mockSrcService)
-	os.Setenv("SAVES_DIR", "./testdata")
-	defer os.Unsetenv("SAVES_DIR")
 
-	t.Run("should return error when SAVES_DIR is not set", func(t *testing.T) {
-		os.Unsetenv("SAVES_DIR")
-		sources, err := loader.LoadSrcsFromFile()
-		assert.Nil(t, sources)
+	t.Run("saves dir not set", func(t *testing.T) {
+		t.Setenv(savesDirEnvVar, "")
+		_, err := NewLoader(nil).Load()
 		assert.EqualError(t, err, "SAVES_DIR environment variable is not set")
-		os.Setenv("SAVES_DIR", "./testdata")
 	})
+}
 
-	t.Run("should return error when json is invalid", func(t *testing.T) {
-		os.Setenv("SAVES_DIR", "./testdata")
-		invalidFilePath := filepath.Join("./testdata", "sources.json")
-		os.WriteFile(invalidFilePath, []byte(`invalid json`), 0644)
-
-		sources, err := loader.LoadSrcsFromFile()
-		assert.Nil(t, sources)
-		assert.NotNil(t, err)
-	})
-
-	t.Run("should load sources successfully", func(t *testing.T) {
-		os.Setenv("SAVES_DIR", "./testdata")
-		validSources := []model.Source{
-			{Name: "Test Source"},
-		}
-		fileData, _ := json.Marshal(validSources)
-		validFilePath := filepath.Join("./testdata", "sources.json")
-		os.WriteFile(validFilePath, fileData, 0644)
-
-		sources, err := loader.LoadSrcsFromFile()
-		assert.NotNil(t, sources)
-		assert.Nil(t, err)
-		assert.Equal(t, validSources, sources)
-	})
+// collect reads all the articles of the snapshot.
+func collect(t *testing.T, snapshot Snapshot) []model.Article {
+	t.Helper()
+	var articles []model.Article
+	require.NoError(t, snapshot.Articles(func(article model.Article) error {
+		articles = append(articles, article)
+		return nil
+	}))
+	return articles
 }
-*/
@@ -1,81 +1,164 @@
 package backuper
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
-// Saver is an interface for saving articles to a file.
+const (
+	eventSnapshotSaved   = "snapshot_saved"
+	eventSnapshotRotated = "snapshot_rotated"
+	eventRotateError     = "snapshot_rotate_error"
+)
+
+const (
+	backupRetentionEnvVar = "BACKUP_RETENTION"
+	// defaultBackupRetention is the number of snapshots kept unless BACKUP_RETENTION is set.
+	defaultBackupRetention = 5
+)
+
+// Saver is an interface for saving snapshots of the articles and sources.
 type Saver interface {
-	SaveAllToFile() error
-	SaveSrcsToFile() error
+	Save() (string, error)
 }
 
 // newsSaver is an implementation of the Saver interface.
 type newsSaver struct {
-	articles []model.Article
+	articles ArticleIterator
 	sources  []model.Source
+	now      func() time.Time
+}
+
+// NewSaver creates a new Saver instance, saving the articles of the iterator and the sources.
+func NewSaver(articles ArticleIterator, sources []model.Source) Saver {
+	return &newsSaver{
+		articles: articles,
+		sources:  sources,
+		now:      time.Now,
+	}
 }
 
-// NewSaver creates a new Saver instance.
-func NewSaver(articles []model.Article, sources []model.Source) Saver {
+// NewSaverAt creates a new Saver instance like NewSaver, saving a snapshot taken at the given time.
+// The time must be taken before the sources and articles are read, so a change made while they are read
+// is journaled after it and replayed by the restore, even if the snapshot holds it already.
+func NewSaverAt(at time.Time, articles ArticleIterator, sources []model.Source) Saver {
 	return &newsSaver{
 		articles: articles,
 		sources:  sources,
+		now:      func() time.Time { return at },
 	}
 }
 
-// SaveAllToFile saves all articles to a JSON file.
-// It marshals the articles into JSON and writes them to the file specified by
-// the SAVES_DIR environment variable.
-func (n newsSaver) SaveAllToFile() error {
-	// Convert articles to JSON
-	data, err := json.MarshalIndent(n.articles, "", "  ")
+// Save writes the articles and sources to a new snapshot in the directory specified by
+// the SAVES_DIR environment variable and returns its path. Then the oldest snapshots
+// beyond the BACKUP_RETENTION count, 5 by default, are deleted.
+// The snapshot is written to a temporary directory renamed once it's complete,
+// so a crash mid-write leaves the previous snapshots intact. Its manifest holds
+// the SHA-256 checksums of the files, which the loader verifies.
+// The articles are written one at a time as gzip-compressed NDJSON.
+func (n *newsSaver) Save() (string, error) {
+	dir, err := savesDir()
 	if err != nil {
-		return err
+		return "", err
+	}
+	retention, err := backupRetention()
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
 	}
 
-	file, err := os.Create(filepath.Join(os.Getenv("SAVES_DIR"), "articles.json"))
+	tmp, err := os.MkdirTemp(dir, "."+snapshotPrefix+"*.tmp")
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer file.Close()
+	defer os.RemoveAll(tmp)
 
-	fmt.Println(file.Name())
-	// Write the JSON data to the file
-	_, err = file.Write(data)
+	now := n.now().UTC()
+	m := manifest{CreatedAt: now, Files: map[string]manifestEntry{}}
+	var count int
+	m.Files[articlesFile], err = createFile(filepath.Join(tmp, articlesFile), func(w io.Writer) error {
+		aw := NewArticleWriter(w)
+		if n.articles != nil {
+			if err := n.articles(aw.Write); err != nil {
+				return err
+			}
+		}
+		count = aw.Count()
+		return aw.Close()
+	})
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to write %s: %w", articlesFile, err)
+	}
+	m.Files[sourcesFile], err = writeFile(filepath.Join(tmp, sourcesFile), n.sources)
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", sourcesFile, err)
+	}
+	// The manifest is written last, a snapshot without one is never trusted
+	if _, err = writeFile(filepath.Join(tmp, manifestFile), m); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", manifestFile, err)
+	}
+	if err = syncDir(tmp); err != nil {
+		return "", err
 	}
 
-	return nil
-}
+	path := filepath.Join(dir, snapshotPrefix+now.Format(archiveTimeFormat))
+	if err = os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	if err = syncDir(dir); err != nil {
+		return "", err
+	}
+	logrus.WithField("event_id", eventSnapshotSaved).Infof("Saved %d articles and %d sources to %s",
+		count, len(n.sources), path)
 
-// SaveSrcsToFile saves all sources to a JSON file.
-// It marshals the sources into JSON and writes them to the file specified by
-// the SAVES_DIR environment variable.
-func (n newsSaver) SaveSrcsToFile() error {
-	// Convert sources to JSON
-	data, err := json.MarshalIndent(n.sources, "", "  ")
-	if err != nil {
-		return err
+	// The snapshot is saved even if the old ones can't be deleted
+	if err = rotate(dir, retention); err != nil {
+		logrus.WithField("event_id", eventRotateError).Errorf("Error deleting old snapshots: %v", err)
 	}
+	return path, nil
+}
 
-	file, err := os.Create(filepath.Join(os.Getenv("SAVES_DIR"), "sources.json"))
+// rotate deletes the oldest snapshots in dir beyond the retention count,
+// along with the journal segments older than the snapshots kept.
+func rotate(dir string, retention int) error {
+	paths, err := listSnapshots(dir)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	fmt.Println(file.Name())
-	// Write the JSON data to the file
-	_, err = file.Write(data)
+	if len(paths) == 0 {
+		return nil
+	}
+	kept := min(retention, len(paths))
+	for _, path := range paths[kept:] {
+		if err = os.RemoveAll(path); err != nil {
+			return err
+		}
+		logrus.WithField("event_id", eventSnapshotRotated).Infof("Deleted old snapshot %s", path)
+	}
+	oldest, err := startTime(paths[kept-1], snapshotPrefix, "")
 	if err != nil {
 		return err
 	}
+	return pruneJournal(dir, oldest)
+}
 
-	return nil
+// backupRetention returns the number of snapshots to keep, set by the BACKUP_RETENTION environment variable.
+func backupRetention() (int, error) {
+	value := os.Getenv(backupRetentionEnvVar)
+	if value == "" {
+		return defaultBackupRetention, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s: %s", backupRetentionEnvVar, value)
+	}
+	return n, nil
 }
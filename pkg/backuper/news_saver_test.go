@@ -2,11 +2,14 @@ package backuper
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNewSaver(t *testing.T) {
@@ -16,58 +19,112 @@ func TestNewSaver(t *testing.T) {
 	sources := []model.Source{
 		{Name: "Test Source"},
 	}
-	saver := NewSaver(articles, sources)
+	saver := NewSaver(SliceIterator(articles), sources)
 	assert.NotNil(t, saver)
 }
 
-func TestSaveAllToFile(t *testing.T) {
+func TestSaver_Save(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(savesDirEnvVar, dir)
+	now := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
 	articles := []model.Article{
-		{Title: "Test Article"},
+		{Id: 1, Title: "Test Article", PubDate: now},
+		{Id: 2, Title: "Another Article", PubDate: now},
 	}
-	sources := []model.Source{
-		{Name: "Test Source"},
+	saver := &newsSaver{
+		articles: SliceIterator(articles),
+		sources:  []model.Source{{Id: 1, Name: "Test Source"}},
+		now:      func() time.Time { return now },
 	}
-	saver := NewSaver(articles, sources)
-	os.Setenv("SAVES_DIR", "./testdata")
-	defer os.Unsetenv("SAVES_DIR")
 
-	t.Run("should save articles to file", func(t *testing.T) {
-		err := saver.SaveAllToFile()
-		assert.Nil(t, err)
+	path, err := saver.Save()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "backup-20240805T090000.000000000Z"), path)
+
+	snapshot, err := readSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, snapshot.Path)
+	assert.Equal(t, now, snapshot.CreatedAt)
+	assert.Equal(t, saver.sources, snapshot.Sources)
+	assert.Equal(t, articles, collect(t, snapshot))
 
-		filePath := filepath.Join("./testdata", "articles.json")
-		fileData, err := os.ReadFile(filePath)
-		assert.Nil(t, err)
+	data, err := os.ReadFile(filepath.Join(path, manifestFile))
+	require.NoError(t, err)
+	var m manifest
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Len(t, m.Files, 2)
+	assert.Len(t, m.Files[articlesFile].SHA256, 64)
 
-		var savedArticles []model.Article
-		err = json.Unmarshal(fileData, &savedArticles)
-		assert.Nil(t, err)
-		assert.Equal(t, articles, savedArticles)
-	})
+	// The temporary directory is renamed, so only the snapshot is left
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
 }
 
-func TestSaveSrcsToFile(t *testing.T) {
-	articles := []model.Article{
-		{Title: "Test Article"},
+func TestSaver_Save_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(savesDirEnvVar, dir)
+	t.Setenv(backupRetentionEnvVar, "2")
+	now := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	saver := &newsSaver{now: func() time.Time { return now }}
+
+	var paths []string
+	for i := 0; i < 4; i++ {
+		path, err := saver.Save()
+		require.NoError(t, err)
+		paths = append(paths, path)
+		now = now.Add(time.Hour)
 	}
-	sources := []model.Source{
-		{Name: "Test Source"},
+
+	got, err := listSnapshots(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{paths[3], paths[2]}, got, "only the newest snapshots are kept")
+}
+
+func TestSaver_Save_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		savesDir  string
+		retention string
+		wantErr   string
+	}{
+		{
+			name:    "saves dir not set",
+			wantErr: "SAVES_DIR environment variable is not set",
+		},
+		{
+			name:      "invalid retention",
+			savesDir:  t.TempDir(),
+			retention: "0",
+			wantErr:   "invalid BACKUP_RETENTION: 0",
+		},
 	}
-	saver := NewSaver(articles, sources)
-	os.Setenv("SAVES_DIR", "./testdata")
-	defer os.Unsetenv("SAVES_DIR")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(savesDirEnvVar, tt.savesDir)
+			t.Setenv(backupRetentionEnvVar, tt.retention)
+
+			_, err := NewSaver(nil, nil).Save()
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
 
-	t.Run("should save sources to file", func(t *testing.T) {
-		err := saver.SaveSrcsToFile()
-		assert.Nil(t, err)
+func TestSaver_Save_ArticlesError(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(savesDirEnvVar, dir)
+	saver := NewSaver(func(yield func(model.Article) error) error {
+		if err := yield(model.Article{Title: "Test Article"}); err != nil {
+			return err
+		}
+		return errors.New("db is down")
+	}, nil)
 
-		filePath := filepath.Join("./testdata", "sources.json")
-		fileData, err := os.ReadFile(filePath)
-		assert.Nil(t, err)
+	_, err := saver.Save()
+	assert.EqualError(t, err, "failed to write articles.ndjson.gz: db is down")
 
-		var savedSources []model.Source
-		err = json.Unmarshal(fileData, &savedSources)
-		assert.Nil(t, err)
-		assert.Equal(t, sources, savedSources)
-	})
+	// The incomplete snapshot is removed
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
 }
@@ -0,0 +1,53 @@
+package backuper
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const eventRestoreComplete = "restore_complete"
+
+// Replayer applies the records of a snapshot and the changes of the journal, such as the in-memory storages.
+// Applying a change more than once must leave the same state as applying it once.
+type Replayer interface {
+	Apply(change model.Change) error
+}
+
+// Restore restores the articles and sources as of the given time into r, the latest ones if it's zero:
+// the records of the newest valid snapshot taken at or before that time in the directory specified
+// by the SAVES_DIR environment variable, followed by the changes of the journal made since the snapshot
+// was taken. The changes made while the snapshot was being written may be in it already,
+// hence they must be idempotent.
+func Restore(at time.Time, r Replayer) (model.RestoreReport, error) {
+	report := model.RestoreReport{At: at}
+	dir, err := savesDir()
+	if err != nil {
+		return report, err
+	}
+	snapshot, err := loadAt(dir, at)
+	if err != nil {
+		return report, err
+	}
+	report.Snapshot, report.SnapshotAt = snapshot.Path, snapshot.CreatedAt
+
+	for _, src := range snapshot.Sources {
+		if err = r.Apply(model.Change{At: snapshot.CreatedAt, Kind: model.ChangeSaveSource, Id: src.Id, Source: &src}); err != nil {
+			return report, err
+		}
+	}
+	err = snapshot.Articles(func(article model.Article) error {
+		return r.Apply(model.Change{At: snapshot.CreatedAt, Kind: model.ChangeSaveArticle, Id: article.Id, Article: &article})
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.Changes, err = readJournal(dir, snapshot.CreatedAt, at, r.Apply)
+	if err != nil {
+		return report, err
+	}
+	logrus.WithField("event_id", eventRestoreComplete).Infof("Restored snapshot %s and %d changes of the journal",
+		snapshot.Path, report.Changes)
+	return report, nil
+}
@@ -0,0 +1,144 @@
+package backuper
+
+import (
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// recordingReplayer records the changes applied to it.
+type recordingReplayer struct {
+	changes []model.Change
+	err     error
+}
+
+func (r *recordingReplayer) Apply(change model.Change) error {
+	r.changes = append(r.changes, change)
+	return r.err
+}
+
+func TestRestore(t *testing.T) {
+	start := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	// setup takes snapshots at 9:00 and 10:00 and journals a change every 20 minutes until 10:40
+	setup := func(t *testing.T, dir string) {
+		j := testJournal(t, dir, func() time.Time { return start })
+		for minutes := 0; minutes <= 100; minutes += 20 {
+			require.NoError(t, j.Append(model.Change{At: at(minutes), Kind: model.ChangeDeleteArticle, Id: minutes}))
+		}
+		for i, title := range []string{"Old Article", "New Article"} {
+			saver := &newsSaver{
+				articles: SliceIterator([]model.Article{{Id: 1, Title: title}}),
+				sources:  []model.Source{{Id: 1, Name: "Test Source"}},
+				now:      func() time.Time { return at(60 * i) },
+			}
+			_, err := saver.Save()
+			require.NoError(t, err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		at           time.Time
+		wantTitle    string
+		wantSnapshot time.Time
+		wantIDs      []int
+		wantErr      string
+	}{
+		{
+			name:         "latest state",
+			wantTitle:    "New Article",
+			wantSnapshot: at(60),
+			wantIDs:      []int{60, 80, 100},
+		},
+		{
+			name:         "between the snapshots",
+			at:           at(50),
+			wantTitle:    "Old Article",
+			wantSnapshot: at(0),
+			wantIDs:      []int{0, 20, 40},
+		},
+		{
+			name:         "at a snapshot",
+			at:           at(60),
+			wantTitle:    "New Article",
+			wantSnapshot: at(60),
+			wantIDs:      []int{60},
+		},
+		{
+			name:    "before the snapshots",
+			at:      at(-10),
+			wantErr: "none of the 2 snapshots in",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv(savesDirEnvVar, dir)
+			setup(t, dir)
+
+			r := &recordingReplayer{}
+			report, err := Restore(tt.at, r)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.at, report.At)
+			assert.Equal(t, tt.wantSnapshot, report.SnapshotAt)
+			assert.Equal(t, len(tt.wantIDs), report.Changes)
+
+			// The snapshot's source and article come first, then the journal
+			require.Len(t, r.changes, 2+len(tt.wantIDs))
+			assert.Equal(t, model.ChangeSaveSource, r.changes[0].Kind)
+			assert.Equal(t, "Test Source", r.changes[0].Source.Name)
+			assert.Equal(t, model.ChangeSaveArticle, r.changes[1].Kind)
+			assert.Equal(t, tt.wantTitle, r.changes[1].Article.Title)
+			var ids []int
+			for _, change := range r.changes[2:] {
+				ids = append(ids, change.Id)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+		})
+	}
+
+	t.Run("replayer error", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv(savesDirEnvVar, dir)
+		setup(t, dir)
+
+		_, err := Restore(time.Time{}, &recordingReplayer{err: errors.New("unknown change")})
+		assert.EqualError(t, err, "unknown change")
+	})
+}
+
+func TestRestore_ChangesWhileSaving(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(savesDirEnvVar, dir)
+	start := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	j := testJournal(t, dir, func() time.Time { return start })
+
+	// The snapshot is taken at 9:00, then the source is renamed before it's read
+	// and an article is saved before the articles are read
+	renamed := model.Source{Id: 1, Name: "Renamed Source"}
+	article := model.Article{Id: 1, Title: "Test Article"}
+	require.NoError(t, j.Append(
+		model.Change{At: start.Add(time.Second), Kind: model.ChangeSaveSource, Id: 1, Source: &renamed},
+		model.Change{At: start.Add(2 * time.Second), Kind: model.ChangeSaveArticle, Id: 1, Article: &article}))
+	_, err := NewSaverAt(start, SliceIterator([]model.Article{article}), []model.Source{renamed}).Save()
+	require.NoError(t, err)
+	// A change made after the snapshot was read is journaled after it as well
+	require.NoError(t, j.Append(model.Change{At: start.Add(time.Minute), Kind: model.ChangeDeleteArticle, Id: 1}))
+
+	r := &recordingReplayer{}
+	report, err := Restore(time.Time{}, r)
+	require.NoError(t, err)
+	assert.Equal(t, start, report.SnapshotAt)
+	assert.Equal(t, 3, report.Changes, "the changes made since the snapshot was taken are replayed, even the ones it holds")
+	require.Len(t, r.changes, 5)
+	assert.Equal(t, []string{model.ChangeSaveSource, model.ChangeSaveArticle, model.ChangeDeleteArticle},
+		[]string{r.changes[2].Kind, r.changes[3].Kind, r.changes[4].Kind})
+}
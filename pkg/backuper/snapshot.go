@@ -0,0 +1,262 @@
+package backuper
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	savesDirEnvVar = "SAVES_DIR"
+
+	// snapshotPrefix starts the names of the snapshot directories, followed by the time they were taken.
+	snapshotPrefix = "backup-"
+	// articlesFile holds the articles as gzip-compressed NDJSON.
+	articlesFile = "articles.ndjson.gz"
+	// legacyArticlesFile holds the articles as a JSON array, as the earlier versions wrote them.
+	legacyArticlesFile = "articles.json"
+	sourcesFile        = "sources.json"
+	manifestFile       = "manifest.json"
+)
+
+// Snapshot is a backup of the articles and sources taken at the same time.
+// It has the following fields:
+// - Path: the directory of the snapshot, empty if nothing was backed up yet
+// - CreatedAt: the time the snapshot was taken
+// - Sources: the backed up sources
+//
+// The articles aren't loaded with the snapshot, but read from its file by Articles.
+type Snapshot struct {
+	Path      string
+	CreatedAt time.Time
+	Sources   []model.Source
+
+	// articlesPath is the file holding the articles, empty if there are none
+	articlesPath string
+}
+
+// Articles reads the articles of the snapshot one at a time, calling yield with every one of them.
+// It's an ArticleIterator, so the articles never have to be held in memory all at once.
+func (s Snapshot) Articles(yield func(model.Article) error) error {
+	if s.articlesPath == "" {
+		return nil
+	}
+	f, err := os.Open(s.articlesPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if filepath.Base(s.articlesPath) == legacyArticlesFile {
+		return eachJSONArticle(bufio.NewReader(f), yield)
+	}
+	ar, err := NewArticleReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+	return ar.Each(yield)
+}
+
+// eachJSONArticle calls yield with every article of the JSON array read from r, decoding them one at a time.
+func eachJSONArticle(r io.Reader, yield func(model.Article) error) error {
+	dec := json.NewDecoder(r)
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	// A snapshot without articles holds null
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected an array of articles, got %v", token)
+	}
+	for dec.More() {
+		var article model.Article
+		if err = dec.Decode(&article); err != nil {
+			return err
+		}
+		if err = yield(article); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// manifest describes the files of a snapshot, which are verified against their checksums before they are trusted.
+type manifest struct {
+	CreatedAt time.Time                `json:"created_at"`
+	Files     map[string]manifestEntry `json:"files"`
+}
+
+// manifestEntry is the size and SHA-256 checksum of a snapshot file.
+type manifestEntry struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// savesDir returns the directory the snapshots are kept in, set by the SAVES_DIR environment variable.
+func savesDir() (string, error) {
+	dir := os.Getenv(savesDirEnvVar)
+	if dir == "" {
+		return "", errors.New("SAVES_DIR environment variable is not set")
+	}
+	return dir, nil
+}
+
+// listSnapshots returns the paths of the snapshots in dir, newest first.
+// The temporary directories of the snapshots being written aren't listed.
+func listSnapshots(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), snapshotPrefix) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	// The names end with the time the snapshots were taken, in a sortable format
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths, nil
+}
+
+// writeFile writes v as indented JSON to a new file at path, flushed to the disk, and returns its manifest entry.
+func writeFile(path string, v interface{}) (manifestEntry, error) {
+	return createFile(path, func(w io.Writer) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+}
+
+// createFile creates a new file at path with the content written by write, flushed to the disk,
+// and returns its manifest entry. The checksum is computed as the content is written.
+func createFile(path string, write func(w io.Writer) error) (manifestEntry, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(f, h))
+	if err = write(w); err != nil {
+		return manifestEntry{}, err
+	}
+	if err = w.Flush(); err != nil {
+		return manifestEntry{}, err
+	}
+	if err = f.Sync(); err != nil {
+		return manifestEntry{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	if err = f.Close(); err != nil {
+		return manifestEntry{}, err
+	}
+	return manifestEntry{Size: info.Size(), SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// verifyFile checks the snapshot file at path against its manifest entry, reading it without holding it in memory.
+func verifyFile(path string, entry manifestEntry) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	return verify(filepath.Base(path), size, h.Sum(nil), entry)
+}
+
+// readFile reads the snapshot file at path into v, after verifying it against its manifest entry.
+func readFile(path string, entry manifestEntry, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if err = verify(filepath.Base(path), int64(len(data)), sum[:], entry); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verify checks the size and SHA-256 checksum of the named snapshot file against its manifest entry.
+func verify(name string, size int64, sum []byte, entry manifestEntry) error {
+	if size != entry.Size {
+		return fmt.Errorf("%s is %d bytes, expected %d", name, size, entry.Size)
+	}
+	if hex.EncodeToString(sum) != entry.SHA256 {
+		return fmt.Errorf("%s doesn't match its checksum", name)
+	}
+	return nil
+}
+
+// readSnapshot reads the snapshot in the directory at path, failing if any of its files is missing or corrupted.
+// The sources are read right away, while the articles file is only verified, to be read by Snapshot.Articles.
+// The snapshots of the earlier versions hold the articles as a JSON array instead.
+func readSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(path, manifestFile))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var m manifest
+	if err = json.Unmarshal(data, &m); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	articles := articlesFile
+	if _, ok := m.Files[articles]; !ok {
+		articles = legacyArticlesFile
+	}
+	for _, name := range []string{articles, sourcesFile} {
+		if _, ok := m.Files[name]; !ok {
+			return Snapshot{}, fmt.Errorf("%s is missing from the manifest", name)
+		}
+	}
+
+	snapshot := Snapshot{Path: path, CreatedAt: m.CreatedAt, articlesPath: filepath.Join(path, articles)}
+	if err = verifyFile(snapshot.articlesPath, m.Files[articles]); err != nil {
+		return Snapshot{}, err
+	}
+	if err = readFile(filepath.Join(path, sourcesFile), m.Files[sourcesFile], &snapshot.Sources); err != nil {
+		return Snapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// syncDir flushes the entries of the directory to the disk, so a renamed file survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,21 @@
+package dedup
+
+import "github.com/antonchaban/news-aggregator/pkg/model"
+
+// UniqueByLink sets the canonical links and fingerprints of the articles and drops the articles
+// linking to the same page as an earlier one in the batch. It returns the kept articles
+// and the number of dropped ones.
+func UniqueByLink(articles []model.Article) ([]model.Article, int) {
+	seen := make(map[string]bool, len(articles))
+	unique := make([]model.Article, 0, len(articles))
+	for _, article := range articles {
+		article.CanonicalLink = CanonicalURL(article.Link)
+		if seen[article.CanonicalLink] {
+			continue
+		}
+		seen[article.CanonicalLink] = true
+		article.Fingerprint = Fingerprint(article.Title, article.Description)
+		unique = append(unique, article)
+	}
+	return unique, len(articles) - len(unique)
+}
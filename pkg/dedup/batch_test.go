@@ -0,0 +1,26 @@
+package dedup
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUniqueByLink(t *testing.T) {
+	articles := []model.Article{
+		{Title: "First", Link: "https://www.bbc.com/news/1?utm_source=rss"},
+		{Title: "Second", Link: "https://bbc.com/news/2"},
+		{Title: "First again", Link: "http://bbc.com/news/1/"},
+	}
+
+	unique, dropped := UniqueByLink(articles)
+	assert.Equal(t, 1, dropped)
+	if assert.Len(t, unique, 2) {
+		assert.Equal(t, "First", unique[0].Title)
+		assert.Equal(t, "https://bbc.com/news/1", unique[0].CanonicalLink)
+		assert.Equal(t, Fingerprint("First", ""), unique[0].Fingerprint)
+		assert.Equal(t, "Second", unique[1].Title)
+		assert.Equal(t, "https://bbc.com/news/2", unique[1].CanonicalLink)
+	}
+	assert.Empty(t, articles[0].CanonicalLink, "the given articles are left intact")
+}
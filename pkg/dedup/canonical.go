@@ -0,0 +1,81 @@
+package dedup
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are the query parameters added by newsletters, social networks
+// and ad campaigns, which don't change the page they link to.
+var trackingParams = map[string]bool{
+	"at_campaign": true,
+	"at_medium":   true,
+	"cmp":         true,
+	"cmpid":       true,
+	"dclid":       true,
+	"fbclid":      true,
+	"gclid":       true,
+	"igshid":      true,
+	"mc_cid":      true,
+	"mc_eid":      true,
+	"msclkid":     true,
+	"ocid":        true,
+	"ref":         true,
+	"ref_src":     true,
+	"rss":         true,
+	"taid":        true,
+	"yptr":        true,
+}
+
+// trackingPrefixes are the prefixes of the tracking query parameter families.
+var trackingPrefixes = []string{"utm_", "ns_", "_ga"}
+
+// CanonicalURL returns the canonical form of the article link, which is the same
+// for every link to the same page: the scheme is https, the host is lowercase
+// without "www." and the default port, the fragment, the trailing slash and
+// the tracking parameters are removed and the other parameters are sorted.
+// Links that aren't absolute URLs are returned trimmed.
+func CanonicalURL(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return link
+	}
+
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	query := u.Query()
+	for param := range query {
+		if isTrackingParam(param) {
+			query.Del(param)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// isTrackingParam reports whether the query parameter is used for tracking only.
+func isTrackingParam(param string) bool {
+	param = strings.ToLower(param)
+	if trackingParams[param] {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(param, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package dedup
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{
+			name: "already canonical",
+			link: "https://example.com/world/story",
+			want: "https://example.com/world/story",
+		},
+		{
+			name: "scheme, www and host case",
+			link: "http://WWW.Example.com/world/story",
+			want: "https://example.com/world/story",
+		},
+		{
+			name: "fragment and trailing slash",
+			link: "https://example.com/world/story/#comments",
+			want: "https://example.com/world/story",
+		},
+		{
+			name: "tracking parameters",
+			link: "https://example.com/story?utm_source=rss&utm_medium=feed&fbclid=abc&ocid=msn&at_medium=RSS",
+			want: "https://example.com/story",
+		},
+		{
+			name: "other parameters are kept and sorted",
+			link: "https://example.com/story?page=2&UTM_Campaign=x&id=7",
+			want: "https://example.com/story?id=7&page=2",
+		},
+		{
+			name: "default port",
+			link: "https://example.com:443/story",
+			want: "https://example.com/story",
+		},
+		{
+			name: "other port",
+			link: "https://example.com:8080/story",
+			want: "https://example.com:8080/story",
+		},
+		{
+			name: "not an absolute URL",
+			link: " link1 ",
+			want: "link1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CanonicalURL(tt.link))
+		})
+	}
+}
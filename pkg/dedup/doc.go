@@ -0,0 +1,6 @@
+// Package dedup detects articles that report the same story.
+// CanonicalURL strips the tracking parameters, fragments and "www" off article links,
+// so the same article syndicated with different links is saved only once.
+// Fingerprint computes a SimHash of the article title and description, and articles
+// with fingerprints within MaxDistance bits of each other are clustered into one story.
+package dedup
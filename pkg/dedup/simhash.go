@@ -0,0 +1,109 @@
+package dedup
+
+import (
+	"github.com/reiver/go-porterstemmer"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxDistance is the largest number of differing fingerprint bits
+	// of two articles reporting the same story.
+	MaxDistance = 12
+
+	// shingleSize is the number of consecutive words hashed together.
+	shingleSize = 2
+	// titleWeight is how much more the title shingles count than the description ones,
+	// as outlets syndicating a story keep the headline but often cut the description.
+	titleWeight = 3
+)
+
+// stopWords are the words too common to tell stories apart.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"to": true, "was": true, "were": true, "will": true, "with": true,
+}
+
+// Fingerprint returns the 64-bit SimHash of the article title and description.
+// Articles with similar texts have fingerprints that differ in few bits, see Distance.
+// Texts without any words have the zero fingerprint, which matches no story.
+func Fingerprint(title, description string) uint64 {
+	var weights [64]int
+	add := func(text string, weight int) {
+		for _, shingle := range shingles(words(text)) {
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(shingle))
+			sum := mix(h.Sum64())
+			for bit := 0; bit < 64; bit++ {
+				if sum&(1<<bit) != 0 {
+					weights[bit] += weight
+				} else {
+					weights[bit] -= weight
+				}
+			}
+		}
+	}
+	add(title, titleWeight)
+	add(description, 1)
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// Distance returns the number of bits the two fingerprints differ in.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Similar reports whether the two fingerprints belong to the same story.
+func Similar(a, b uint64) bool {
+	return a != 0 && b != 0 && Distance(a, b) <= MaxDistance
+}
+
+// mix spreads every bit of the hash over all the others, as FNV barely changes
+// the high bits for shingles that only differ in their last characters.
+// It's the finalizer of MurmurHash3.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// words splits the text into lowercase stemmed words, dropping the stop words.
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, word := range fields {
+		if !stopWords[word] {
+			words = append(words, porterstemmer.StemString(word))
+		}
+	}
+	return words
+}
+
+// shingles returns the sequences of shingleSize consecutive words,
+// or the single words if there are too few of them.
+func shingles(words []string) []string {
+	if len(words) < shingleSize {
+		return words
+	}
+	shingles := make([]string, 0, len(words)-shingleSize+1)
+	for i := 0; i+shingleSize <= len(words); i++ {
+		shingles = append(shingles, strings.Join(words[i:i+shingleSize], " "))
+	}
+	return shingles
+}
@@ -0,0 +1,68 @@
+package dedup
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	const (
+		title       = "Trial starts for a Polish man accused of punching Danish prime minister in Copenhagen"
+		description = "A Polish man went on trial in Copenhagen on Monday accused of punching Danish Prime Minister Mette Frederiksen in June."
+	)
+	tests := []struct {
+		name        string
+		title       string
+		description string
+		similar     bool
+	}{
+		{
+			name:        "same text",
+			title:       title,
+			description: description,
+			similar:     true,
+		},
+		{
+			name:        "syndicated with a shorter description",
+			title:       title,
+			description: "A Polish man went on trial in Copenhagen accused of punching the Danish prime minister.",
+			similar:     true,
+		},
+		{
+			name:        "syndicated with different punctuation and case",
+			title:       "Trial starts for Polish man accused of punching Danish Prime Minister in Copenhagen",
+			description: description,
+			similar:     true,
+		},
+		{
+			name:        "another story",
+			title:       "More than 120 people died in Tokyo from heatstroke in July as average temperatures hit record highs",
+			description: "More than 120 people died of heatstroke in Tokyo in July, when the average temperature hit a record high.",
+			similar:     false,
+		},
+		{
+			name:        "no text",
+			title:       "",
+			description: "",
+			similar:     false,
+		},
+	}
+	fingerprint := Fingerprint(title, description)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := Fingerprint(tt.title, tt.description)
+			assert.Equal(t, tt.similar, Similar(fingerprint, other), "distance %d", Distance(fingerprint, other))
+		})
+	}
+}
+
+func TestFingerprint_Empty(t *testing.T) {
+	assert.Zero(t, Fingerprint("", " - "))
+	assert.False(t, Similar(0, 0), "articles without text don't report the same story")
+}
+
+func TestDistance(t *testing.T) {
+	assert.Equal(t, 0, Distance(0b1011, 0b1011))
+	assert.Equal(t, 2, Distance(0b1011, 0b0001))
+	assert.Equal(t, 64, Distance(0, ^uint64(0)))
+}
@@ -0,0 +1,92 @@
+package dedup
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"sort"
+	"time"
+)
+
+// StoryWindow is how far apart the publication dates of two articles
+// reporting the same story can be.
+const StoryWindow = 72 * time.Hour
+
+// Candidate is a saved article a new article can join the story of.
+type Candidate struct {
+	StoryId     int
+	Fingerprint uint64
+}
+
+// FindStory returns the story of the candidate with the fingerprint nearest to the given one,
+// reporting whether any candidate is similar enough to report the same story.
+func FindStory(fingerprint uint64, candidates []Candidate) (int, bool) {
+	storyID, best := 0, MaxDistance+1
+	for _, c := range candidates {
+		if !Similar(fingerprint, c.Fingerprint) {
+			continue
+		}
+		if d := Distance(fingerprint, c.Fingerprint); d < best {
+			storyID, best = c.StoryId, d
+		}
+	}
+	return storyID, best <= MaxDistance
+}
+
+// Collapse keeps a single representative of every story among the articles,
+// which is the earliest published one. The representatives keep the order of the articles.
+// Articles without a story are always kept.
+func Collapse(articles []model.Article) []model.Article {
+	representatives := make(map[int]model.Article)
+	for _, article := range articles {
+		if article.StoryId == 0 {
+			continue
+		}
+		if rep, ok := representatives[article.StoryId]; !ok || precedes(article, rep) {
+			representatives[article.StoryId] = article
+		}
+	}
+
+	collapsed := make([]model.Article, 0, len(articles))
+	for _, article := range articles {
+		if article.StoryId == 0 || representatives[article.StoryId].Id == article.Id {
+			collapsed = append(collapsed, article)
+		}
+	}
+	return collapsed
+}
+
+// OtherSources sets the OtherSources of every article to the sources of the other articles
+// of its story among members, ordered by publication date and without repeating a source.
+func OtherSources(articles []model.Article, members []model.Article) {
+	stories := make(map[int][]model.Article)
+	for _, member := range members {
+		stories[member.StoryId] = append(stories[member.StoryId], member)
+	}
+	for _, story := range stories {
+		sort.SliceStable(story, func(i, j int) bool { return precedes(story[i], story[j]) })
+	}
+
+	for i := range articles {
+		if articles[i].StoryId == 0 {
+			continue
+		}
+		seen := map[int]bool{articles[i].Source.Id: true}
+		var sources []model.Source
+		for _, member := range stories[articles[i].StoryId] {
+			if member.Id == articles[i].Id || seen[member.Source.Id] {
+				continue
+			}
+			seen[member.Source.Id] = true
+			sources = append(sources, member.Source)
+		}
+		articles[i].OtherSources = sources
+	}
+}
+
+// precedes reports whether the article was published before the other one,
+// breaking ties by their IDs.
+func precedes(article, other model.Article) bool {
+	if !article.PubDate.Equal(other.PubDate) {
+		return article.PubDate.Before(other.PubDate)
+	}
+	return article.Id < other.Id
+}
@@ -0,0 +1,90 @@
+package dedup
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFindStory(t *testing.T) {
+	fingerprint := uint64(0xf0f0f0f0f0f0f0f0)
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		want       int
+		wantOK     bool
+	}{
+		{
+			name:       "no candidates",
+			candidates: nil,
+			want:       0,
+			wantOK:     false,
+		},
+		{
+			name:       "no similar candidate",
+			candidates: []Candidate{{StoryId: 1, Fingerprint: ^fingerprint}},
+			want:       0,
+			wantOK:     false,
+		},
+		{
+			name: "nearest similar candidate",
+			candidates: []Candidate{
+				{StoryId: 1, Fingerprint: ^fingerprint},
+				{StoryId: 2, Fingerprint: fingerprint ^ 0xff},
+				{StoryId: 3, Fingerprint: fingerprint ^ 0x3},
+				{StoryId: 4, Fingerprint: 0},
+			},
+			want:   3,
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FindStory(fingerprint, tt.candidates)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	early := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	articles := []model.Article{
+		{Id: 1, StoryId: 1, PubDate: late},
+		{Id: 2, StoryId: 0, PubDate: late},
+		{Id: 3, StoryId: 1, PubDate: early},
+		{Id: 4, StoryId: 4, PubDate: late},
+		{Id: 5, StoryId: 1, PubDate: early},
+		{Id: 6, StoryId: 0, PubDate: early},
+	}
+
+	got := Collapse(articles)
+	ids := make([]int, len(got))
+	for i, article := range got {
+		ids[i] = article.Id
+	}
+	assert.Equal(t, []int{2, 3, 4, 6}, ids)
+}
+
+func TestOtherSources(t *testing.T) {
+	bbc := model.Source{Id: 1, Name: "BBC News"}
+	nbc := model.Source{Id: 2, Name: "NBC News"}
+	abc := model.Source{Id: 3, Name: "ABC News"}
+	early := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	members := []model.Article{
+		{Id: 1, StoryId: 1, Source: bbc, PubDate: early},
+		{Id: 2, StoryId: 1, Source: abc, PubDate: early.Add(2 * time.Hour)},
+		{Id: 3, StoryId: 1, Source: nbc, PubDate: early.Add(time.Hour)},
+		{Id: 4, StoryId: 1, Source: nbc, PubDate: early.Add(3 * time.Hour)},
+		{Id: 5, StoryId: 1, Source: bbc, PubDate: early.Add(4 * time.Hour)},
+		{Id: 6, StoryId: 6, Source: abc, PubDate: early},
+	}
+	articles := []model.Article{members[0], members[5], {Id: 7, Source: bbc}}
+
+	OtherSources(articles, members)
+	assert.Equal(t, []model.Source{nbc, abc}, articles[0].OtherSources)
+	assert.Nil(t, articles[1].OtherSources, "a story reported by a single source has no other sources")
+	assert.Nil(t, articles[2].OtherSources)
+}
@@ -10,14 +10,21 @@ type ArticleFilter interface {
 	SetNext(handler ArticleFilter) ArticleFilter
 	// Filter filters the articles based on the given filters and returns the filtered articles or an error.
 	Filter(articles []model.Article, f Filters) ([]model.Article, error)
-	BuildFilterQuery(f Filters, query string) (string, []interface{})
+	// BuildFilterQuery adds the conditions for the given filters to the query
+	// and passes it to the next handler in the chain.
+	BuildFilterQuery(f Filters, q *Query) error
 }
 
 // Filters struct contains the filtering criteria.
+// Source, Author and Category are comma-separated lists, an article matches any of their items.
+// Collapse keeps a single article of every story reported by several sources.
 type Filters struct {
 	Keyword   string
 	Source    string
+	Author    string
+	Category  string
 	StartDate string
 	EndDate   string
+	Collapse  bool
 	UseDB     bool
 }
@@ -0,0 +1,57 @@
+package filter
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"strings"
+)
+
+const (
+	eventAuthorFilterStart       = "author_filter_start"
+	eventAuthorFilteringComplete = "author_filtering_complete"
+)
+
+// likeEscaper escapes the LIKE wildcards, so the names are matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// authorField matches the articles whose authors contain the name.
+var authorField = listField{
+	name:          "AuthorFilter",
+	startEvent:    eventAuthorFilterStart,
+	completeEvent: eventAuthorFilteringComplete,
+	list:          func(f Filters) string { return f.Author },
+	matches: func(article model.Article, name string) bool {
+		return strings.Contains(strings.ToLower(article.Author), name)
+	},
+	condition: func(q *Query, names []string) (string, []interface{}) {
+		conditions := make([]string, len(names))
+		args := make([]interface{}, len(names))
+		for i, name := range names {
+			conditions[i] = `lower(a.author) LIKE ? ESCAPE '\'`
+			args[i] = "%" + likeEscaper.Replace(name) + "%"
+		}
+		return strings.Join(conditions, " OR "), args
+	},
+}
+
+// AuthorFilter filters articles based on their authors.
+// An article matches if its authors contain any of the comma-separated names, ignoring case,
+// so "smith" matches the articles written by John Smith.
+type AuthorFilter struct {
+	next ArticleFilter
+}
+
+// SetNext sets the next filter in the chain and returns the filter.
+func (h *AuthorFilter) SetNext(filter ArticleFilter) ArticleFilter {
+	h.next = filter
+	return filter
+}
+
+// Filter filters articles by their authors based on the provided Filters.
+func (h *AuthorFilter) Filter(articles []model.Article, f Filters) ([]model.Article, error) {
+	return authorField.filter(articles, f, h.next)
+}
+
+// BuildFilterQuery adds a condition matching the authors containing any of the names to the query.
+func (h *AuthorFilter) BuildFilterQuery(f Filters, q *Query) error {
+	return authorField.buildFilterQuery(f, q, h.next)
+}
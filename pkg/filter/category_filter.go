@@ -0,0 +1,57 @@
+package filter
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"strings"
+)
+
+const (
+	eventCategoryFilterStart       = "category_filter_start"
+	eventCategoryFilteringComplete = "category_filtering_complete"
+)
+
+// categoryField matches the articles having the category.
+var categoryField = listField{
+	name:          "CategoryFilter",
+	startEvent:    eventCategoryFilterStart,
+	completeEvent: eventCategoryFilteringComplete,
+	list:          func(f Filters) string { return f.Category },
+	matches: func(article model.Article, category string) bool {
+		for _, own := range article.Categories {
+			if strings.EqualFold(own, category) {
+				return true
+			}
+		}
+		return false
+	},
+	condition: func(q *Query, categories []string) (string, []interface{}) {
+		args := make([]interface{}, len(categories))
+		for i, category := range categories {
+			args[i] = category
+		}
+		return "EXISTS (SELECT 1 FROM " + q.Dialect().Elements("a.categories") +
+			" WHERE lower(value) IN (" + placeholders(len(args)) + "))", args
+	},
+}
+
+// CategoryFilter filters articles based on their categories.
+// An article matches if any of its categories is one of the comma-separated ones, ignoring case.
+type CategoryFilter struct {
+	next ArticleFilter
+}
+
+// SetNext sets the next filter in the chain and returns the filter.
+func (h *CategoryFilter) SetNext(filter ArticleFilter) ArticleFilter {
+	h.next = filter
+	return filter
+}
+
+// Filter filters articles by their categories based on the provided Filters.
+func (h *CategoryFilter) Filter(articles []model.Article, f Filters) ([]model.Article, error) {
+	return categoryField.filter(articles, f, h.next)
+}
+
+// BuildFilterQuery adds a condition matching the articles with any of the categories to the query.
+func (h *CategoryFilter) BuildFilterQuery(f Filters, q *Query) error {
+	return categoryField.buildFilterQuery(f, q, h.next)
+}
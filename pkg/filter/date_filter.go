@@ -73,27 +73,28 @@ func (h *DateRangeFilter) Filter(articles []model.Article, f Filters) ([]model.A
 	return articles, nil
 }
 
-func (h *DateRangeFilter) BuildFilterQuery(f Filters, query string) (string, []interface{}) {
+// BuildFilterQuery adds the publication date range conditions to the query.
+func (h *DateRangeFilter) BuildFilterQuery(f Filters, q *Query) error {
 	if f.StartDate != "" {
 		startDate, err := time.Parse("2006-01-02", f.StartDate)
 		if err != nil {
-			logrus.WithField("event_id", "parse_start_date_error").Errorf("Failed to parse start date: %v", err)
-			return query, nil
+			logrus.WithField("event_id", eventParseStartDateError).Errorf("Failed to parse start date: %v", err)
+			return fmt.Errorf("failed to parse start date: %v", err)
 		}
-		query += fmt.Sprintf(" AND pub_date >= '%s'", startDate.Format("2006-01-02"))
+		q.Where("a.pub_date >= ?", startDate)
 	}
 
 	if f.EndDate != "" {
 		endDate, err := time.Parse("2006-01-02", f.EndDate)
 		if err != nil {
-			logrus.WithField("event_id", "parse_end_date_error").Errorf("Failed to parse end date: %v", err)
-			return query, nil
+			logrus.WithField("event_id", eventParseEndDateError).Errorf("Failed to parse end date: %v", err)
+			return fmt.Errorf("failed to parse end date: %v", err)
 		}
-		query += fmt.Sprintf(" AND pub_date <= '%s'", endDate.Format("2006-01-02"))
+		q.Where("a.pub_date <= ?", endDate)
 	}
 
 	if h.next != nil {
-		return h.next.BuildFilterQuery(f, query)
+		return h.next.BuildFilterQuery(f, q)
 	}
-	return query, nil
+	return nil
 }
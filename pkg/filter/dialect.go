@@ -0,0 +1,126 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Dialect renders the parts of the article queries that differ between the SQL databases.
+type Dialect interface {
+	// Placeholder returns the positional placeholder of the n-th query argument, counting from 1.
+	Placeholder(n int) string
+	// Match returns the condition matching the articles containing the phrase in the field,
+	// or in any field if the field is empty, together with the values bound to its "?" placeholders.
+	Match(field, phrase string) (string, []interface{})
+	// Rank returns the expression scoring how relevant the articles are to the phrases,
+	// together with the values bound to its "?" placeholders.
+	Rank(phrases []string) (string, []interface{})
+	// Elements returns the table of the elements of the list column, with the elements in its value column.
+	Elements(column string) string
+}
+
+var (
+	// Postgres searches the articles search vector with the english text search configuration.
+	Postgres Dialect = postgresDialect{}
+	// SQLite searches the articles_fts full-text index, stemmed with the porter tokenizer.
+	SQLite Dialect = sqliteDialect{}
+)
+
+// postgresPhraseQuery converts a phrase into a full-text search query matching its words in order,
+// using the same english stemming as the articles search vector.
+const postgresPhraseQuery = "phraseto_tsquery('english', ?)"
+
+// postgresVectors maps the query fields to the part of the articles search vector they search in:
+// the title lexemes are weighted A and the description ones B.
+var postgresVectors = map[string]string{
+	"":               "a.search_vector",
+	FieldTitle:       "ts_filter(a.search_vector, '{a}')",
+	FieldDescription: "ts_filter(a.search_vector, '{b}')",
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) Match(field, phrase string) (string, []interface{}) {
+	return postgresVectors[field] + " @@ " + postgresPhraseQuery, []interface{}{phrase}
+}
+
+// Rank scores the articles with ts_rank, cast from real to double precision, so the rank read back
+// into the page cursor compares equal to the rank the next page is queried with.
+func (postgresDialect) Rank(phrases []string) (string, []interface{}) {
+	queries := make([]string, len(phrases))
+	args := make([]interface{}, len(phrases))
+	for i, phrase := range phrases {
+		queries[i] = postgresPhraseQuery
+		args[i] = phrase
+	}
+	return "ts_rank(a.search_vector, " + strings.Join(queries, " || ") + ")::float8", args
+}
+
+// Elements unnests the text array column.
+func (postgresDialect) Elements(column string) string {
+	return "unnest(" + column + ") AS value"
+}
+
+// sqliteFieldColumns maps the query fields to the articles_fts columns they search in.
+var sqliteFieldColumns = map[string]string{
+	FieldTitle:       "title",
+	FieldDescription: "description",
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?" + strconv.Itoa(n)
+}
+
+func (sqliteDialect) Match(field, phrase string) (string, []interface{}) {
+	if !hasWords(phrase) {
+		// FTS5 rejects empty phrases, while they match nothing in Postgres
+		return "FALSE", nil
+	}
+	match := ftsPhrase(phrase)
+	if column, ok := sqliteFieldColumns[field]; ok {
+		match = column + " : " + match
+	}
+	return "a.id IN (SELECT rowid FROM articles_fts WHERE articles_fts MATCH ?)", []interface{}{match}
+}
+
+// Rank scores the articles with bm25, weighting the title and description columns
+// like the in-memory relevance. bm25 is negative, the better the match the lower it is.
+func (sqliteDialect) Rank(phrases []string) (string, []interface{}) {
+	var matches []string
+	for _, phrase := range phrases {
+		if hasWords(phrase) {
+			matches = append(matches, ftsPhrase(phrase))
+		}
+	}
+	if len(matches) == 0 {
+		return "0", nil
+	}
+	return "coalesce((SELECT -bm25(articles_fts, " + strconv.FormatFloat(titleWeight, 'f', -1, 64) + ", " +
+			strconv.FormatFloat(descriptionWeight, 'f', -1, 64) + ") FROM articles_fts " +
+			"WHERE articles_fts MATCH ? AND rowid = a.id), 0)",
+		[]interface{}{strings.Join(matches, " OR ")}
+}
+
+// Elements reads the JSON array column, as SQLite has no array type.
+func (sqliteDialect) Elements(column string) string {
+	return "json_each(" + column + ")"
+}
+
+// ftsPhrase quotes the text as an FTS5 phrase, so its words are matched in order.
+func ftsPhrase(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// hasWords reports whether the text contains any letter or digit to search for.
+func hasWords(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0
+}
@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_Match(t *testing.T) {
+	tests := []struct {
+		name          string
+		field         string
+		phrase        string
+		wantCondition string
+		wantArgs      []interface{}
+	}{
+		{
+			name:          "any field",
+			phrase:        "peace talks",
+			wantCondition: "a.id IN (SELECT rowid FROM articles_fts WHERE articles_fts MATCH ?)",
+			wantArgs:      []interface{}{`"peace talks"`},
+		},
+		{
+			name:          "title only",
+			field:         FieldTitle,
+			phrase:        "ukraine",
+			wantCondition: "a.id IN (SELECT rowid FROM articles_fts WHERE articles_fts MATCH ?)",
+			wantArgs:      []interface{}{`title : "ukraine"`},
+		},
+		{
+			name:          "quotes are escaped",
+			field:         FieldDescription,
+			phrase:        `say "no" OR`,
+			wantCondition: "a.id IN (SELECT rowid FROM articles_fts WHERE articles_fts MATCH ?)",
+			wantArgs:      []interface{}{`description : "say ""no"" OR"`},
+		},
+		{
+			name:          "no words",
+			phrase:        "+-*",
+			wantCondition: "FALSE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args := SQLite.Match(tt.field, tt.phrase)
+			assert.Equal(t, tt.wantCondition, condition)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestSQLite_KeywordQuery(t *testing.T) {
+	query, err := ParseKeywordQuery(`(ukraine OR title:"peace talks") -russia`)
+	require.NoError(t, err)
+
+	condition, args := query.Condition(SQLite)
+	assert.Equal(t, "((a.id IN (SELECT rowid FROM articles_fts WHERE articles_fts MATCH ?)"+
+		" OR a.id IN (SELECT rowid FROM articles_fts WHERE articles_fts MATCH ?))"+
+		" AND NOT (a.id IN (SELECT rowid FROM articles_fts WHERE articles_fts MATCH ?)))", condition)
+	assert.Equal(t, []interface{}{`"ukraine"`, `title : "peace talks"`, `"russia"`}, args)
+
+	rank, args, ok := query.Rank(SQLite)
+	require.True(t, ok)
+	assert.Equal(t, "coalesce((SELECT -bm25(articles_fts, 1, 0.4) FROM articles_fts"+
+		" WHERE articles_fts MATCH ? AND rowid = a.id), 0)", rank)
+	assert.Equal(t, []interface{}{`"ukraine" OR "peace talks"`}, args)
+}
+
+func TestSQLite_Placeholders(t *testing.T) {
+	q := NewQuery(SQLite, testColumns, testFrom)
+	q.Where("s.short_name IN (?, ?)", "bbc", "nbc")
+	q.Where("a.id > ?", 10)
+
+	sql, args := q.Build()
+	assert.Equal(t, testBaseQuery+" WHERE (s.short_name IN (?1, ?2)) AND (a.id > ?3)", sql)
+	assert.Equal(t, []interface{}{"bbc", "nbc", 10}, args)
+}
+
+func TestSQLite_Elements(t *testing.T) {
+	q := NewQuery(SQLite, testColumns, testFrom)
+	require.NoError(t, (&CategoryFilter{}).BuildFilterQuery(Filters{Category: "World, Europe"}, q))
+
+	sql, args := q.Build()
+	assert.Equal(t, testBaseQuery+" WHERE (EXISTS (SELECT 1 FROM json_each(a.categories) WHERE lower(value) IN (?1, ?2)))", sql)
+	assert.Equal(t, []interface{}{"world", "europe"}, args)
+}
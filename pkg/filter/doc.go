@@ -1,4 +1,7 @@
 // Package filter implements the chain of responsibility pattern for filtering articles.
-// It provides multiple filters such as SourceFilter, DateRangeFilter, and KeywordFilter
+// It provides multiple filters such as SourceFilter, AuthorFilter, CategoryFilter, DateRangeFilter, and KeywordFilter
 // to apply various filtering criteria to a list of articles.
+// The same chain builds a parameterized SQL Query for filtering articles in the database.
+// KeywordFilter accepts a boolean keyword query, see KeywordQuery for its syntax.
+// StoryFilter ends the chain, collapsing the articles reporting the same story into one.
 package filter
@@ -2,9 +2,7 @@ package filter
 
 import (
 	"github.com/antonchaban/news-aggregator/pkg/model"
-	"github.com/reiver/go-porterstemmer"
 	"github.com/sirupsen/logrus"
-	"strings"
 )
 
 const (
@@ -12,6 +10,13 @@ const (
 	eventKeywordFilteringComplete = "keyword_filtering_complete"
 )
 
+// titleWeight and descriptionWeight are the in-memory relevance weights of a keyword match,
+// they mirror the default ts_rank weights of the title (A) and description (B) lexemes.
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
 // KeywordFilter filters articles based on keywords in their title or description.
 type KeywordFilter struct {
 	next ArticleFilter
@@ -23,26 +28,21 @@ func (h *KeywordFilter) SetNext(filter ArticleFilter) ArticleFilter {
 	return filter
 }
 
-// Filter filters articles by keywords in their title or description based on the provided Filters
-// using stemming algorithm.
+// Filter filters articles matching the keyword query of the provided Filters, see KeywordQuery.
+// Every matched article gets a relevance score, a keyword found in the title weighs more
+// than one found in the description.
 func (h *KeywordFilter) Filter(articles []model.Article, f Filters) ([]model.Article, error) {
 	logrus.WithField("event_id", eventKeywordFilterStart).Info("Starting KeywordFilter")
-
-	if f.Keyword != "" {
-		keywordList := strings.Split(f.Keyword, ",")
+	query, err := ParseKeywordQuery(f.Keyword)
+	if err != nil {
+		return nil, err
+	}
+	if !query.Empty() {
 		var keywordFilteredArticles []model.Article
 		for _, article := range articles {
-			for _, keyword := range keywordList {
-				normalizedTitle := strings.ToLower(article.Title)
-				normalizedDesc := strings.ToLower(article.Description)
-				stemmedKeyword := porterstemmer.StemString(keyword)
-				stemmedTitle := porterstemmer.StemString(normalizedTitle)
-				stemmedDesc := porterstemmer.StemString(normalizedDesc)
-				if strings.Contains(stemmedTitle, stemmedKeyword) ||
-					strings.Contains(stemmedDesc, stemmedKeyword) {
-					keywordFilteredArticles = append(keywordFilteredArticles, article)
-					break // Avoid adding the same article multiple times for different keywords
-				}
+			if query.Match(article) {
+				article.Relevance = query.Relevance(article)
+				keywordFilteredArticles = append(keywordFilteredArticles, article)
 			}
 		}
 		articles = keywordFilteredArticles
@@ -55,21 +55,23 @@ func (h *KeywordFilter) Filter(articles []model.Article, f Filters) ([]model.Art
 	return articles, nil
 }
 
-func (h *KeywordFilter) BuildFilterQuery(f Filters, query string) (string, []interface{}) {
-	if f.Keyword != "" {
-		keywordList := strings.Split(f.Keyword, ",")
-		var keywordConditions []string
-		for _, keyword := range keywordList {
-			normalizedKeyword := "%" + strings.ToLower(keyword) + "%"
-			condition := "(LOWER(title) ILIKE '" + normalizedKeyword + "' OR LOWER(description) ILIKE '" + normalizedKeyword + "')"
-			keywordConditions = append(keywordConditions, condition)
-		}
-		if len(keywordConditions) > 0 {
-			query += " AND (" + strings.Join(keywordConditions, " OR ") + ")"
+// BuildFilterQuery adds the full-text search condition compiled from the keyword query
+// in the dialect of the query to it and ranks the matched articles by relevance.
+func (h *KeywordFilter) BuildFilterQuery(f Filters, q *Query) error {
+	query, err := ParseKeywordQuery(f.Keyword)
+	if err != nil {
+		return err
+	}
+	if !query.Empty() {
+		condition, args := query.Condition(q.Dialect())
+		q.Where(condition, args...)
+		if rank, args, ok := query.Rank(q.Dialect()); ok {
+			q.Rank(rank, args...)
 		}
 	}
+
 	if h.next != nil {
-		return h.next.BuildFilterQuery(f, query)
+		return h.next.BuildFilterQuery(f, q)
 	}
-	return query, nil
+	return nil
 }
@@ -51,11 +51,13 @@ func TestKeywordFilter_Filter(t *testing.T) {
 					Id:          1,
 					Title:       "Golang is great",
 					Description: "Go is an open-source programming language",
+					Relevance:   1,
 				},
 				{
 					Id:          2,
 					Title:       "Python vs Golang",
 					Description: "Comparing Python and Golang for backend development",
+					Relevance:   1.4,
 				},
 			},
 			wantErr: assert.NoError,
@@ -111,16 +113,19 @@ func TestKeywordFilter_Filter(t *testing.T) {
 					Id:          1,
 					Title:       "Golang is great",
 					Description: "Go is an open-source programming language",
+					Relevance:   1,
 				},
 				{
 					Id:          2,
 					Title:       "Python vs Golang",
 					Description: "Comparing Python and Golang for backend development",
+					Relevance:   1.4,
 				},
 				{
 					Id:          3,
 					Title:       "Learning Java",
 					Description: "Java is a popular language",
+					Relevance:   1.4,
 				},
 			},
 			wantErr: assert.NoError,
@@ -0,0 +1,490 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/reiver/go-porterstemmer"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Fields a keyword query term can be scoped to.
+	FieldTitle       = "title"
+	FieldDescription = "description"
+)
+
+// maxKeywordQueryDepth is how deeply groups and negations may be nested in a keyword query.
+const maxKeywordQueryDepth = 32
+
+// fields are the fields a keyword query term can be scoped to.
+var fields = map[string]bool{
+	FieldTitle:       true,
+	FieldDescription: true,
+}
+
+// SyntaxError is returned when a keyword query can't be parsed.
+// Pos is the 1-based position of the offending character in the query.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// KeywordQuery is a parsed boolean keyword query. The grammar is:
+//
+//	query   = or
+//	or      = and { ( "OR" | "," ) and }
+//	and     = unary { [ "AND" ] unary }
+//	unary   = ( "NOT" | "-" ) unary | primary
+//	primary = "(" or ")" | [ field ":" ] ( word | '"' phrase '"' )
+//	field   = "title" | "description"
+//
+// Terms next to each other are ANDed, so "ukraine war" needs both words.
+// A word with a colon is scoped only if it starts with a field, so "re:invent" is a plain word.
+// The comma-separated keyword lists of the older API keep working as ORed terms.
+type KeywordQuery struct {
+	root keywordNode
+}
+
+// ParseKeywordQuery parses the keyword query. An empty query matches every article.
+func ParseKeywordQuery(query string) (*KeywordQuery, error) {
+	tokens, err := lexKeywordQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &keywordParser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return &KeywordQuery{}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected " + t.String()}
+	}
+	return &KeywordQuery{root: root}, nil
+}
+
+// Empty reports whether the query has no terms and so matches every article.
+func (k *KeywordQuery) Empty() bool {
+	return k.root == nil
+}
+
+// Match reports whether the article matches the query.
+func (k *KeywordQuery) Match(article model.Article) bool {
+	return k.root == nil || k.root.match(newDocument(article))
+}
+
+// Relevance scores how well the article matches the terms of the query that aren't negated.
+// A term found in the title weighs more than one found in the description.
+func (k *KeywordQuery) Relevance(article model.Article) float64 {
+	var relevance float64
+	d := newDocument(article)
+	k.positiveTerms(func(t *termNode) {
+		if t.field != FieldDescription && containsPhrase(d.title, t.stems) {
+			relevance += titleWeight
+		}
+		if t.field != FieldTitle && containsPhrase(d.description, t.stems) {
+			relevance += descriptionWeight
+		}
+	})
+	return relevance
+}
+
+// Condition compiles the query into an SQL condition on the full-text index of the dialect.
+func (k *KeywordQuery) Condition(d Dialect) (string, []interface{}) {
+	if k.root == nil {
+		return "TRUE", nil
+	}
+	var args []interface{}
+	return k.root.sql(d, &args), args
+}
+
+// Rank compiles the terms of the query that aren't negated into an SQL expression
+// ranking the articles by relevance. It reports false if there are no such terms.
+func (k *KeywordQuery) Rank(d Dialect) (string, []interface{}, bool) {
+	var phrases []string
+	k.positiveTerms(func(t *termNode) {
+		phrases = append(phrases, t.text)
+	})
+	if len(phrases) == 0 {
+		return "", nil, false
+	}
+	rank, args := d.Rank(phrases)
+	return rank, args, true
+}
+
+// positiveTerms calls fn for every term of the query that isn't negated.
+func (k *KeywordQuery) positiveTerms(fn func(t *termNode)) {
+	if k.root != nil {
+		k.root.terms(false, func(t *termNode, negated bool) {
+			if !negated {
+				fn(t)
+			}
+		})
+	}
+}
+
+// document holds the stemmed words of an article.
+type document struct {
+	title       []string
+	description []string
+}
+
+func newDocument(article model.Article) document {
+	return document{title: stemWords(article.Title), description: stemWords(article.Description)}
+}
+
+// stemWords splits the text into lowercase words and stems them.
+func stemWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = porterstemmer.StemString(word)
+	}
+	return words
+}
+
+// containsPhrase reports whether the words contain the phrase as a contiguous sequence.
+func containsPhrase(words, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		found := true
+		for j := range phrase {
+			if words[i+j] != phrase[j] {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// keywordNode is a node of the keyword query syntax tree.
+type keywordNode interface {
+	// match evaluates the node against the article.
+	match(d document) bool
+	// sql renders the node as an SQL condition of the dialect, appending the values bound to it to args.
+	sql(d Dialect, args *[]interface{}) string
+	// terms calls fn for every term under the node, telling whether it is negated.
+	terms(negated bool, fn func(t *termNode, negated bool))
+}
+
+type andNode struct {
+	left, right keywordNode
+}
+
+func (n *andNode) match(d document) bool {
+	return n.left.match(d) && n.right.match(d)
+}
+
+func (n *andNode) sql(d Dialect, args *[]interface{}) string {
+	return "(" + n.left.sql(d, args) + " AND " + n.right.sql(d, args) + ")"
+}
+
+func (n *andNode) terms(negated bool, fn func(t *termNode, negated bool)) {
+	n.left.terms(negated, fn)
+	n.right.terms(negated, fn)
+}
+
+type orNode struct {
+	left, right keywordNode
+}
+
+func (n *orNode) match(d document) bool {
+	return n.left.match(d) || n.right.match(d)
+}
+
+func (n *orNode) sql(d Dialect, args *[]interface{}) string {
+	return "(" + n.left.sql(d, args) + " OR " + n.right.sql(d, args) + ")"
+}
+
+func (n *orNode) terms(negated bool, fn func(t *termNode, negated bool)) {
+	n.left.terms(negated, fn)
+	n.right.terms(negated, fn)
+}
+
+type notNode struct {
+	operand keywordNode
+}
+
+func (n *notNode) match(d document) bool {
+	return !n.operand.match(d)
+}
+
+func (n *notNode) sql(d Dialect, args *[]interface{}) string {
+	return "NOT (" + n.operand.sql(d, args) + ")"
+}
+
+func (n *notNode) terms(negated bool, fn func(t *termNode, negated bool)) {
+	n.operand.terms(!negated, fn)
+}
+
+// termNode is a word or a quoted phrase, optionally scoped to a field.
+type termNode struct {
+	field string
+	text  string
+	stems []string
+}
+
+func newTermNode(field, text string) *termNode {
+	return &termNode{field: field, text: text, stems: stemWords(text)}
+}
+
+func (n *termNode) match(d document) bool {
+	switch n.field {
+	case FieldTitle:
+		return containsPhrase(d.title, n.stems)
+	case FieldDescription:
+		return containsPhrase(d.description, n.stems)
+	default:
+		return containsPhrase(d.title, n.stems) || containsPhrase(d.description, n.stems)
+	}
+}
+
+func (n *termNode) sql(d Dialect, args *[]interface{}) string {
+	condition, termArgs := d.Match(n.field, n.text)
+	*args = append(*args, termArgs...)
+	return condition
+}
+
+func (n *termNode) terms(negated bool, fn func(t *termNode, negated bool)) {
+	fn(n, negated)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField
+	tokLParen
+	tokRParen
+	tokComma
+	tokAnd
+	tokOr
+	tokNot
+)
+
+// keywordToken is a lexical token of a keyword query, pos is its 1-based position in the query.
+type keywordToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t keywordToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokPhrase:
+		return fmt.Sprintf("phrase %q", t.text)
+	case tokField:
+		return fmt.Sprintf("field %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// lexKeywordQuery splits the query into tokens. Commas leading, trailing or repeated
+// in the query are dropped, as the older comma-separated lists allowed them.
+func lexKeywordQuery(query string) ([]keywordToken, error) {
+	r := []rune(query)
+	var tokens []keywordToken
+	emit := func(kind tokenKind, text string, pos int) {
+		if kind == tokComma && (len(tokens) == 0 || tokens[len(tokens)-1].kind == tokComma) {
+			return
+		}
+		tokens = append(tokens, keywordToken{kind: kind, text: text, pos: pos + 1})
+	}
+
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			emit(tokLParen, "(", i)
+			i++
+		case c == ')':
+			emit(tokRParen, ")", i)
+			i++
+		case c == ',':
+			emit(tokComma, ",", i)
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(r) && r[end] != '"' {
+				end++
+			}
+			if end == len(r) {
+				return nil, &SyntaxError{Pos: i + 1, Msg: "unterminated phrase"}
+			}
+			emit(tokPhrase, string(r[i+1:end]), i)
+			i = end + 1
+		case c == '-' && i+1 < len(r) && (!isWordBoundary(r[i+1]) || r[i+1] == '(' || r[i+1] == '"'):
+			emit(tokNot, "-", i)
+			i++
+		default:
+			end := i
+			for end < len(r) && !isWordBoundary(r[end]) {
+				end++
+			}
+			word := string(r[i:end])
+			if field, rest, ok := strings.Cut(word, ":"); ok && fields[strings.ToLower(field)] {
+				field = strings.ToLower(field)
+				emit(tokField, field, i)
+				if rest != "" {
+					emit(tokWord, rest, i+len([]rune(field))+1)
+				}
+			} else {
+				switch word {
+				case "AND":
+					emit(tokAnd, word, i)
+				case "OR":
+					emit(tokOr, word, i)
+				case "NOT":
+					emit(tokNot, word, i)
+				default:
+					emit(tokWord, word, i)
+				}
+			}
+			i = end
+		}
+	}
+
+	for len(tokens) > 0 && tokens[len(tokens)-1].kind == tokComma {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return append(tokens, keywordToken{kind: tokEOF, pos: len(r) + 1}), nil
+}
+
+// isWordBoundary reports whether the character ends a word.
+func isWordBoundary(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`()",`, r)
+}
+
+// keywordParser is a recursive descent parser of keyword queries.
+// depth counts the groups and negations it is in, so deeply nested queries can't exhaust the stack.
+type keywordParser struct {
+	tokens []keywordToken
+	i      int
+	depth  int
+}
+
+func (p *keywordParser) peek() keywordToken {
+	return p.tokens[p.i]
+}
+
+func (p *keywordParser) advance() keywordToken {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *keywordParser) parseOr() (keywordNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr || p.peek().kind == tokComma {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *keywordParser) parseAnd() (keywordNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.advance()
+		case tokWord, tokPhrase, tokField, tokLParen, tokNot:
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+}
+
+// nest enters a group or negation starting with the token t, failing if it is nested too deeply.
+// The returned function leaves it.
+func (p *keywordParser) nest(t keywordToken) (func(), error) {
+	if p.depth == maxKeywordQueryDepth {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("query is nested deeper than %d levels", maxKeywordQueryDepth)}
+	}
+	p.depth++
+	return func() { p.depth-- }, nil
+}
+
+func (p *keywordParser) parseUnary() (keywordNode, error) {
+	if p.peek().kind == tokNot {
+		leave, err := p.nest(p.advance())
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *keywordParser) parsePrimary() (keywordNode, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokWord, tokPhrase:
+		return newTermNode("", t.text), nil
+	case tokField:
+		term := p.advance()
+		if term.kind != tokWord && term.kind != tokPhrase {
+			return nil, &SyntaxError{Pos: term.pos, Msg: fmt.Sprintf("expected a word or phrase after %s, got %s", t, term)}
+		}
+		return newTermNode(t.text, term.text), nil
+	case tokLParen:
+		leave, err := p.nest(t)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokRParen {
+			return nil, &SyntaxError{Pos: closing.pos, Msg: fmt.Sprintf("expected \")\", got %s", closing)}
+		}
+		return node, nil
+	default:
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected " + t.String()}
+	}
+}
@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeywordArticles() []model.Article {
+	return []model.Article{
+		{Id: 1, Title: "Ukraine war update", Description: "Heavy fighting reported in the east"},
+		{Id: 2, Title: "Elections in Ukraine", Description: "Voters head to the polls during the war"},
+		{Id: 3, Title: "Golang 1.22 released", Description: "The open source language gets range over integers"},
+		{Id: 4, Title: "Open letter", Description: "Scientists publish a source of concern"},
+	}
+}
+
+func TestKeywordQuery_Match(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{name: "empty query", query: "", want: []int{1, 2, 3, 4}},
+		{name: "word", query: "ukraine", want: []int{1, 2}},
+		{name: "stemmed word", query: "elected", want: []int{2}},
+		{name: "comma-separated list", query: "golang, elections", want: []int{2, 3}},
+		{name: "implicit AND", query: "ukraine war", want: []int{1, 2}},
+		{name: "AND", query: "ukraine AND fighting", want: []int{1}},
+		{name: "OR", query: "fighting OR golang", want: []int{1, 3}},
+		{name: "NOT", query: "ukraine NOT elections", want: []int{1}},
+		{name: "minus", query: "ukraine -elections", want: []int{1}},
+		{name: "phrase", query: `"open source"`, want: []int{3}},
+		{name: "words of a phrase", query: "open source", want: []int{3, 4}},
+		{name: "title scope", query: "title:war", want: []int{1}},
+		{name: "description scope", query: "description:war", want: []int{2}},
+		{name: "scoped phrase", query: `Title:"open letter"`, want: []int{4}},
+		{name: "grouping", query: "(golang OR open) -(letter OR fighting)", want: []int{3}},
+		{name: "negated group", query: "NOT (ukraine OR golang)", want: []int{4}},
+		{name: "unknown field", query: "re:invent", want: nil},
+		{name: "word with a colon", query: "Update: fighting", want: []int{1}},
+		{name: "link", query: "https://golang.org", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseKeywordQuery(tt.query)
+			require.NoError(t, err)
+			var got []int
+			for _, article := range testKeywordArticles() {
+				if query.Match(article) {
+					got = append(got, article.Id)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKeywordQuery_Relevance(t *testing.T) {
+	query, err := ParseKeywordQuery("ukraine war -golang")
+	require.NoError(t, err)
+	articles := testKeywordArticles()
+	assert.Equal(t, 2.0, query.Relevance(articles[0]))
+	assert.Equal(t, 1.4, query.Relevance(articles[1]))
+	assert.Equal(t, 0.0, query.Relevance(articles[2]))
+}
+
+func TestKeywordQuery_SQL(t *testing.T) {
+	query, err := ParseKeywordQuery(`(ukraine OR title:"peace talks") -russia`)
+	require.NoError(t, err)
+
+	condition, args := query.Condition(Postgres)
+	assert.Equal(t, "((a.search_vector @@ phraseto_tsquery('english', ?)"+
+		" OR ts_filter(a.search_vector, '{a}') @@ phraseto_tsquery('english', ?))"+
+		" AND NOT (a.search_vector @@ phraseto_tsquery('english', ?)))", condition)
+	assert.Equal(t, []interface{}{"ukraine", "peace talks", "russia"}, args)
+
+	rank, args, ok := query.Rank(Postgres)
+	require.True(t, ok)
+	assert.Equal(t, "ts_rank(a.search_vector, phraseto_tsquery('english', ?) || phraseto_tsquery('english', ?))::float8", rank)
+	assert.Equal(t, []interface{}{"ukraine", "peace talks"}, args)
+
+	query, err = ParseKeywordQuery("-russia")
+	require.NoError(t, err)
+	_, _, ok = query.Rank(Postgres)
+	assert.False(t, ok, "negated terms must not be ranked")
+}
+
+func TestParseKeywordQuery_SyntaxError(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantPos int
+		wantMsg string
+	}{
+		{query: "(ukraine OR war", wantPos: 16, wantMsg: `expected ")", got end of query`},
+		{query: "ukraine)", wantPos: 8, wantMsg: `unexpected ")"`},
+		{query: "ukraine AND", wantPos: 12, wantMsg: "unexpected end of query"},
+		{query: "OR war", wantPos: 1, wantMsg: `unexpected "OR"`},
+		{query: `war "peace talks`, wantPos: 5, wantMsg: "unterminated phrase"},
+		{query: "description:", wantPos: 13, wantMsg: "expected a word or phrase after field \"description\", got end of query"},
+		{query: "title: (war)", wantPos: 8, wantMsg: `expected a word or phrase after field "title", got "("`},
+		{query: "ukraine ()", wantPos: 10, wantMsg: `unexpected ")"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseKeywordQuery(tt.query)
+			var syntaxErr *SyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			assert.Equal(t, tt.wantPos, syntaxErr.Pos)
+			assert.Equal(t, tt.wantMsg, syntaxErr.Msg)
+		})
+	}
+}
+
+func TestParseKeywordQuery_Depth(t *testing.T) {
+	nested := strings.Repeat("(", maxKeywordQueryDepth) + "war" + strings.Repeat(")", maxKeywordQueryDepth)
+	_, err := ParseKeywordQuery(nested)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantPos int
+	}{
+		{name: "groups", query: "(" + nested + ")", wantPos: maxKeywordQueryDepth + 1},
+		{name: "negations", query: strings.Repeat("NOT ", maxKeywordQueryDepth) + "-war", wantPos: 4*maxKeywordQueryDepth + 1},
+		{name: "huge query", query: strings.Repeat("-(", 100000) + "war", wantPos: maxKeywordQueryDepth + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKeywordQuery(tt.query)
+			var syntaxErr *SyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			assert.Equal(t, tt.wantPos, syntaxErr.Pos)
+			assert.Equal(t, fmt.Sprintf("query is nested deeper than %d levels", maxKeywordQueryDepth), syntaxErr.Msg)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// listField is a field of the articles filtered by one of the comma-separated lists of the Filters.
+// An article matches if its field matches any of the items, ignoring case.
+type listField struct {
+	name          string
+	startEvent    string
+	completeEvent string
+	// list returns the list of the Filters the field is filtered by.
+	list func(f Filters) string
+	// matches reports whether the field of the article matches the lowercase item.
+	matches func(article model.Article, item string) bool
+	// condition returns the condition matching the articles whose field matches any of the lowercase items,
+	// and its arguments.
+	condition func(q *Query, items []string) (string, []interface{})
+}
+
+// filter keeps the articles whose field matches any of the items and passes them to the next filter.
+func (l listField) filter(articles []model.Article, f Filters, next ArticleFilter) ([]model.Article, error) {
+	logrus.WithField("event_id", l.startEvent).Info("Starting " + l.name)
+
+	if items := lowerList(l.list(f)); len(items) > 0 {
+		var filteredArticles []model.Article
+		for _, article := range articles {
+			for _, item := range items {
+				if l.matches(article, item) {
+					filteredArticles = append(filteredArticles, article)
+					break
+				}
+			}
+		}
+		articles = filteredArticles
+		logrus.WithField("filtered_count", len(filteredArticles)).Info(l.completeEvent)
+	}
+
+	if next != nil {
+		return next.Filter(articles, f)
+	}
+	return articles, nil
+}
+
+// buildFilterQuery adds the condition of the field to the query and passes it to the next filter.
+func (l listField) buildFilterQuery(f Filters, q *Query, next ArticleFilter) error {
+	if items := lowerList(l.list(f)); len(items) > 0 {
+		condition, args := l.condition(q, items)
+		q.Where(condition, args...)
+	}
+
+	if next != nil {
+		return next.BuildFilterQuery(f, q)
+	}
+	return nil
+}
+
+// lowerList splits a comma-separated list like splitList, lowering the case of the items.
+func lowerList(list string) []string {
+	items := splitList(list)
+	for i, item := range items {
+		items[i] = strings.ToLower(item)
+	}
+	return items
+}
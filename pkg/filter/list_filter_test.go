@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestListFilter_Filter(t *testing.T) {
+	articles := []model.Article{
+		{Id: 1, Title: "Article 1", Author: "John Smith", Categories: []string{"World", "Europe"}},
+		{Id: 2, Title: "Article 2", Author: "Jane Doe, Mary Roe", Categories: []string{"Technology"}},
+		{Id: 3, Title: "Article 3"},
+	}
+	tests := []struct {
+		name    string
+		h       ArticleFilter
+		f       Filters
+		want    []model.Article
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "Part of the name ignoring case",
+			h:       &AuthorFilter{},
+			f:       Filters{Author: "SMITH"},
+			want:    []model.Article{articles[0]},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "Any of the names",
+			h:       &AuthorFilter{},
+			f:       Filters{Author: "smith, roe"},
+			want:    []model.Article{articles[0], articles[1]},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "No matching authors",
+			h:       &AuthorFilter{},
+			f:       Filters{Author: "nobody"},
+			want:    nil,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "Empty authors",
+			h:       &AuthorFilter{},
+			f:       Filters{},
+			want:    articles,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "Category ignoring case",
+			h:       &CategoryFilter{},
+			f:       Filters{Category: "europe"},
+			want:    []model.Article{articles[0]},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "Any of the categories",
+			h:       &CategoryFilter{},
+			f:       Filters{Category: "world, technology"},
+			want:    []model.Article{articles[0], articles[1]},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "Whole categories only",
+			h:       &CategoryFilter{},
+			f:       Filters{Category: "tech"},
+			want:    nil,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "Empty categories",
+			h:       &CategoryFilter{},
+			f:       Filters{},
+			want:    articles,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "Both lists",
+			h:       &AuthorFilter{next: &CategoryFilter{}},
+			f:       Filters{Author: "roe", Category: "world, technology"},
+			want:    []model.Article{articles[1]},
+			wantErr: assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.Filter(articles, tt.f)
+			if !tt.wantErr(t, err, fmt.Sprintf("Filter(%v, %v)", articles, tt.f)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Filter(%v, %v)", articles, tt.f)
+		})
+	}
+}
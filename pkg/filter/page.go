@@ -0,0 +1,264 @@
+package filter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonchaban/news-aggregator/pkg/model"
+)
+
+const (
+	// Supported sort keys of the articles.
+	SortPubDate   = "pub_date"
+	SortTitle     = "title"
+	SortSource    = "source"
+	SortRelevance = "relevance"
+
+	// Supported sort orders of the articles.
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+
+	// DefaultLimit is the page size used when no limit is requested.
+	DefaultLimit = 50
+	// MaxLimit is the biggest page size that can be requested.
+	MaxLimit = 1000
+)
+
+// sortColumns maps the supported sort keys to the article query columns.
+// Sorting by relevance uses the rank expression of the query instead.
+var sortColumns = map[string]string{
+	SortPubDate:   "a.pub_date",
+	SortTitle:     "a.title",
+	SortSource:    "s.name",
+	SortRelevance: "",
+}
+
+// ErrInvalidCursor is returned when the page cursor can't be decoded
+// or was issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page contains the sorting and cursor-based pagination criteria.
+// The zero value means the first page of DefaultLimit articles sorted by publication date, newest first.
+type Page struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	Order  string
+}
+
+// cursor is the decoded form of the opaque page cursor.
+// It holds the sort key and ID of the last article on the previous page.
+type cursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Key   string `json:"k"`
+	Id    int    `json:"id"`
+}
+
+// Normalize validates the page criteria and fills in the defaults.
+func (p Page) Normalize() (Page, error) {
+	if p.Sort == "" {
+		p.Sort = SortPubDate
+	}
+	if _, ok := sortColumns[p.Sort]; !ok {
+		return p, fmt.Errorf("unsupported sort: %s", p.Sort)
+	}
+	p.Order = strings.ToLower(p.Order)
+	if p.Order == "" {
+		p.Order = OrderDesc
+	}
+	if p.Order != OrderAsc && p.Order != OrderDesc {
+		return p, fmt.Errorf("unsupported order: %s", p.Order)
+	}
+	if p.Limit == 0 {
+		p.Limit = DefaultLimit
+	}
+	if p.Limit < 0 || p.Limit > MaxLimit {
+		return p, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+	}
+	if p.Cursor != "" {
+		if _, err := p.decodeCursor(); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// Paginate sorts the articles and returns the requested page of them
+// together with the cursor of the next page, which is empty on the last page.
+func (p Page) Paginate(articles []model.Article) ([]model.Article, string, error) {
+	p, err := p.Normalize()
+	if err != nil {
+		return nil, "", err
+	}
+
+	sorted := make([]model.Article, len(articles))
+	copy(sorted, articles)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return p.less(p.key(sorted[i]), sorted[i].Id, p.key(sorted[j]), sorted[j].Id)
+	})
+
+	start := 0
+	if p.Cursor != "" {
+		c, err := p.decodeCursor()
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(sorted), func(i int) bool {
+			return p.less(c.Key, c.Id, p.key(sorted[i]), sorted[i].Id)
+		})
+	}
+
+	end := start + p.Limit
+	if end >= len(sorted) {
+		return sorted[start:], "", nil
+	}
+	page := sorted[start:end]
+	return page, p.encodeCursor(page[len(page)-1]), nil
+}
+
+// BuildPageQuery adds the cursor condition, ordering and limit to the query.
+// The query asks for one article more than the page size, so the caller
+// can tell whether there is a next page.
+func (p Page) BuildPageQuery(q *Query) error {
+	p, err := p.Normalize()
+	if err != nil {
+		return err
+	}
+
+	column, columnArgs := sortColumns[p.Sort], []interface{}(nil)
+	if p.Sort == SortRelevance {
+		column, columnArgs = q.RankExpression()
+	}
+
+	if p.Cursor != "" {
+		c, err := p.decodeCursor()
+		if err != nil {
+			return err
+		}
+		operator := ">"
+		if p.Order == OrderDesc {
+			operator = "<"
+		}
+		args := append(append([]interface{}{}, columnArgs...), p.parseKey(c.Key), c.Id)
+		q.Where(fmt.Sprintf("(%s, a.id) %s (?, ?)", column, operator), args...)
+	}
+
+	direction := strings.ToUpper(p.Order)
+	q.OrderBy(column+" "+direction, columnArgs...)
+	q.OrderBy("a.id " + direction)
+	q.Limit(p.Limit + 1)
+	return nil
+}
+
+// NextCursor trims the articles fetched with BuildPageQuery to the page size
+// and returns them together with the cursor of the next page.
+func (p Page) NextCursor(articles []model.Article) ([]model.Article, string, error) {
+	p, err := p.Normalize()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(articles) <= p.Limit {
+		return articles, "", nil
+	}
+	page := articles[:p.Limit]
+	return page, p.encodeCursor(page[len(page)-1]), nil
+}
+
+// key returns the sort key of the article in its cursor representation.
+func (p Page) key(article model.Article) string {
+	switch p.Sort {
+	case SortTitle:
+		return article.Title
+	case SortSource:
+		return article.Source.Name
+	case SortRelevance:
+		return strconv.FormatFloat(article.Relevance, 'g', -1, 64)
+	default:
+		return article.PubDate.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// parseKey converts the sort key from its cursor representation to the value of the sort column.
+func (p Page) parseKey(key string) interface{} {
+	switch p.Sort {
+	case SortPubDate:
+		t, _ := time.Parse(time.RFC3339Nano, key)
+		return t
+	case SortRelevance:
+		f, _ := strconv.ParseFloat(key, 64)
+		return f
+	default:
+		return key
+	}
+}
+
+// less reports whether the article with key a and ID idA goes before the one with key b and ID idB.
+func (p Page) less(a string, idA int, b string, idB int) bool {
+	cmp := 0
+	switch p.Sort {
+	case SortPubDate:
+		cmp = p.parseKey(a).(time.Time).Compare(p.parseKey(b).(time.Time))
+	case SortRelevance:
+		cmp = cmpFloat(p.parseKey(a).(float64), p.parseKey(b).(float64))
+	default:
+		cmp = strings.Compare(a, b)
+	}
+	if cmp == 0 {
+		cmp = idA - idB
+	}
+	if p.Order == OrderDesc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// cmpFloat compares two floats, returning -1, 0 or +1.
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// encodeCursor returns the opaque cursor pointing right after the given article.
+func (p Page) encodeCursor(last model.Article) string {
+	data, _ := json.Marshal(cursor{Sort: p.Sort, Order: p.Order, Key: p.key(last), Id: last.Id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor decodes the page cursor and checks that it belongs to the page sort order.
+func (p Page) decodeCursor() (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.Sort != p.Sort || c.Order != p.Order {
+		return c, fmt.Errorf("%w: cursor was issued for sort=%s&order=%s", ErrInvalidCursor, c.Sort, c.Order)
+	}
+	switch c.Sort {
+	case SortPubDate:
+		if _, err := time.Parse(time.RFC3339Nano, c.Key); err != nil {
+			return c, ErrInvalidCursor
+		}
+	case SortRelevance:
+		if _, err := strconv.ParseFloat(c.Key, 64); err != nil {
+			return c, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,207 @@
+package filter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPageArticles() []model.Article {
+	return []model.Article{
+		{Id: 1, Title: "Charlie", Source: model.Source{Name: "BBC News"}, PubDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Relevance: 1},
+		{Id: 2, Title: "Alpha", Source: model.Source{Name: "NBC News"}, PubDate: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Relevance: 0.4},
+		{Id: 3, Title: "Bravo", Source: model.Source{Name: "ABC News"}, PubDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Relevance: 1.4},
+		{Id: 4, Title: "Delta", Source: model.Source{Name: "BBC News"}, PubDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Relevance: 0.4},
+	}
+}
+
+func ids(articles []model.Article) []int {
+	var result []int
+	for _, article := range articles {
+		result = append(result, article.Id)
+	}
+	return result
+}
+
+func TestPage_Normalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    Page
+		want    Page
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "defaults",
+			page:    Page{},
+			want:    Page{Limit: DefaultLimit, Sort: SortPubDate, Order: OrderDesc},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "order is case insensitive",
+			page:    Page{Limit: 10, Sort: SortTitle, Order: "ASC"},
+			want:    Page{Limit: 10, Sort: SortTitle, Order: OrderAsc},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "unsupported sort",
+			page:    Page{Sort: "author"},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "unsupported order",
+			page:    Page{Order: "random"},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "limit too big",
+			page:    Page{Limit: MaxLimit + 1},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "malformed cursor",
+			page:    Page{Cursor: "!!!"},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.page.Normalize()
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPage_Paginate(t *testing.T) {
+	tests := []struct {
+		name  string
+		page  Page
+		pages [][]int
+	}{
+		{
+			name:  "by publication date, newest first",
+			page:  Page{Limit: 2},
+			pages: [][]int{{2, 4}, {3, 1}},
+		},
+		{
+			name:  "by publication date, oldest first",
+			page:  Page{Limit: 3, Order: OrderAsc},
+			pages: [][]int{{1, 3, 4}, {2}},
+		},
+		{
+			name:  "by title",
+			page:  Page{Limit: 1, Sort: SortTitle, Order: OrderAsc},
+			pages: [][]int{{2}, {3}, {1}, {4}},
+		},
+		{
+			name:  "by source",
+			page:  Page{Limit: 2, Sort: SortSource, Order: OrderDesc},
+			pages: [][]int{{2, 4}, {1, 3}},
+		},
+		{
+			name:  "everything on one page",
+			page:  Page{Limit: 10},
+			pages: [][]int{{2, 4, 3, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.page
+			for i, want := range tt.pages {
+				got, next, err := p.Paginate(testPageArticles())
+				require.NoError(t, err)
+				assert.Equal(t, want, ids(got), "page %d", i)
+				if i == len(tt.pages)-1 {
+					assert.Empty(t, next, "last page must not have a next cursor")
+				} else {
+					require.NotEmpty(t, next)
+				}
+				p.Cursor = next
+			}
+		})
+	}
+}
+
+func TestPage_Paginate_CursorForDifferentSort(t *testing.T) {
+	_, next, err := Page{Limit: 1}.Paginate(testPageArticles())
+	require.NoError(t, err)
+
+	_, _, err = Page{Limit: 1, Sort: SortTitle, Cursor: next}.Paginate(testPageArticles())
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestPage_BuildPageQuery(t *testing.T) {
+	first := NewQuery(Postgres, testColumns, testFrom)
+	require.NoError(t, Page{Limit: 2}.BuildPageQuery(first))
+	sql, args := first.Build()
+	assert.Equal(t, testBaseQuery+" ORDER BY a.pub_date DESC, a.id DESC LIMIT 3", sql)
+	assert.Empty(t, args)
+
+	articles, next, err := Page{Limit: 2}.NextCursor(testPageArticles()[:3])
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, ids(articles))
+	require.NotEmpty(t, next)
+
+	second := NewQuery(Postgres, testColumns, testFrom)
+	second.Where("s.short_name IN (?)", "bbc")
+	require.NoError(t, Page{Limit: 2, Cursor: next}.BuildPageQuery(second))
+	sql, args = second.Build()
+	assert.Equal(t, testBaseQuery+" WHERE (s.short_name IN ($1)) AND ((a.pub_date, a.id) < ($2, $3))"+
+		" ORDER BY a.pub_date DESC, a.id DESC LIMIT 3", sql)
+	assert.Equal(t, []interface{}{"bbc", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 2}, args)
+}
+
+func TestPage_BuildPageQuery_Relevance(t *testing.T) {
+	page := Page{Limit: 1, Sort: SortRelevance}
+	_, next, err := page.NextCursor([]model.Article{{Id: 7, Relevance: 0.5}, {Id: 3, Relevance: 0.25}})
+	require.NoError(t, err)
+	page.Cursor = next
+
+	q := NewQuery(Postgres, testColumns, testFrom)
+	q.Rank("ts_rank(a.search_vector, to_tsquery(?))", "go")
+	require.NoError(t, page.BuildPageQuery(q))
+	sql, args := q.Build()
+	assert.Equal(t, "SELECT "+testColumns+", ts_rank(a.search_vector, to_tsquery($1)) AS relevance FROM "+testFrom+
+		" WHERE ((ts_rank(a.search_vector, to_tsquery($2)), a.id) < ($3, $4))"+
+		" ORDER BY ts_rank(a.search_vector, to_tsquery($5)) DESC, a.id DESC LIMIT 2", sql)
+	assert.Equal(t, []interface{}{"go", "go", 0.5, 7, "go"}, args)
+}
+
+func TestPage_RelevanceTies(t *testing.T) {
+	// ts_rank scores are reals, read back as doubles they tie or differ only in their last bits
+	rank := float64(float32(0.0607927))
+	articles := []model.Article{
+		{Id: 1, Relevance: rank},
+		{Id: 2, Relevance: rank},
+		{Id: 3, Relevance: math.Nextafter(rank, 1)},
+		{Id: 4, Relevance: rank},
+		{Id: 5, Relevance: math.Nextafter(rank, 0)},
+	}
+
+	page := Page{Limit: 2, Sort: SortRelevance}
+	var got []int
+	for {
+		items, next, err := page.Paginate(articles)
+		require.NoError(t, err)
+		got = append(got, ids(items)...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+
+		// The next page is queried with the exact rank of the last article
+		q := NewQuery(Postgres, testColumns, testFrom)
+		q.Rank("ts_rank(a.search_vector, to_tsquery(?))::float8", "go")
+		require.NoError(t, page.BuildPageQuery(q))
+		_, args := q.Build()
+		last := items[len(items)-1]
+		assert.Equal(t, []interface{}{last.Relevance, last.Id}, args[2:4])
+	}
+	assert.Equal(t, []int{3, 4, 2, 1, 5}, got, "every article is listed once")
+}
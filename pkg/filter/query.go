@@ -0,0 +1,181 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// placeholder is the argument marker used in the query clauses.
+const placeholder = "?"
+
+// clause is a piece of SQL with "?" placeholders and the values bound to them.
+type clause struct {
+	sql  string
+	args []interface{}
+}
+
+// Query is a parameterized SQL query assembled by the filter chain.
+// Filters never splice user input into the SQL text: every value travels
+// in the args slice and is referenced by a placeholder in its clause.
+type Query struct {
+	dialect    Dialect
+	columns    string
+	from       string
+	rank       *clause
+	conditions []clause
+	orderBy    []clause
+	limit      int
+}
+
+// NewQuery creates a new Query in the given dialect selecting the given columns from the given tables.
+func NewQuery(dialect Dialect, columns, from string) *Query {
+	return &Query{dialect: dialect, columns: columns, from: from}
+}
+
+// Dialect returns the SQL dialect the query is written in.
+func (q *Query) Dialect() Dialect {
+	return q.dialect
+}
+
+// Where adds a condition to the query. Every "?" in the condition is bound
+// to the next value of args, so the number of placeholders must match len(args).
+func (q *Query) Where(condition string, args ...interface{}) {
+	q.conditions = append(q.conditions, clause{sql: condition, args: args})
+}
+
+// Collapse adds a condition keeping a single article of every group of articles with the same key
+// among the articles matched by the conditions added so far, which is the first one in the given order.
+// It must be called after all the filter conditions were added.
+func (q *Query) Collapse(key, order string) {
+	var sb strings.Builder
+	var args []interface{}
+	sb.WriteString("a.id IN (SELECT id FROM (SELECT a.id, ROW_NUMBER() OVER (PARTITION BY " + key +
+		" ORDER BY " + order + ") AS group_row FROM " + q.from)
+	for i, condition := range q.conditions {
+		if i == 0 {
+			sb.WriteString(" WHERE (")
+		} else {
+			sb.WriteString(" AND (")
+		}
+		sb.WriteString(condition.sql + ")")
+		args = append(args, condition.args...)
+	}
+	sb.WriteString(") AS grouped WHERE group_row = 1)")
+	q.Where(sb.String(), args...)
+}
+
+// Rank sets the expression scoring how relevant each row is to the query.
+// The score is selected as an additional "relevance" column.
+func (q *Query) Rank(expression string, args ...interface{}) {
+	q.rank = &clause{sql: expression, args: args}
+}
+
+// RankExpression returns the rank expression of the query and its args,
+// or a constant zero rank if none was set.
+func (q *Query) RankExpression() (string, []interface{}) {
+	if q.rank == nil {
+		return "0", nil
+	}
+	return q.rank.sql, q.rank.args
+}
+
+// OrderBy adds an ORDER BY term to the query.
+func (q *Query) OrderBy(term string, args ...interface{}) {
+	q.orderBy = append(q.orderBy, clause{sql: term, args: args})
+}
+
+// Limit sets the maximum number of rows returned by the query.
+func (q *Query) Limit(limit int) {
+	q.limit = limit
+}
+
+// Build returns the query text with all conditions joined by AND and
+// "?" placeholders rewritten into the positional placeholders of the dialect,
+// together with the values bound to them.
+func (q *Query) Build() (string, []interface{}) {
+	b := &queryBuilder{dialect: q.dialect}
+	b.sb.WriteString("SELECT " + q.columns)
+	if q.rank != nil {
+		b.sb.WriteString(", ")
+		b.write(*q.rank)
+		b.sb.WriteString(" AS relevance")
+	}
+	b.sb.WriteString(" FROM " + q.from)
+	b.writeConditions(q.conditions)
+	for i, term := range q.orderBy {
+		if i == 0 {
+			b.sb.WriteString(" ORDER BY ")
+		} else {
+			b.sb.WriteString(", ")
+		}
+		b.write(term)
+	}
+	if q.limit > 0 {
+		b.sb.WriteString(" LIMIT " + strconv.Itoa(q.limit))
+	}
+	return b.sb.String(), b.args
+}
+
+// Count returns the query counting all rows matched by the query conditions,
+// ignoring its ordering and limit, together with the values bound to it.
+func (q *Query) Count() (string, []interface{}) {
+	b := &queryBuilder{dialect: q.dialect}
+	b.sb.WriteString("SELECT COUNT(*) FROM " + q.from)
+	b.writeConditions(q.conditions)
+	return b.sb.String(), b.args
+}
+
+// queryBuilder renders clauses into SQL, numbering their placeholders in order.
+type queryBuilder struct {
+	dialect Dialect
+	sb      strings.Builder
+	n       int
+	args    []interface{}
+}
+
+// write writes the clause, replacing its placeholders with the next positional ones.
+func (b *queryBuilder) write(c clause) {
+	sql := c.sql
+	for {
+		idx := strings.Index(sql, placeholder)
+		if idx < 0 {
+			b.sb.WriteString(sql)
+			break
+		}
+		b.n++
+		b.sb.WriteString(sql[:idx])
+		b.sb.WriteString(b.dialect.Placeholder(b.n))
+		sql = sql[idx+len(placeholder):]
+	}
+	b.args = append(b.args, c.args...)
+}
+
+// writeConditions writes the WHERE clause made of the given conditions.
+func (b *queryBuilder) writeConditions(conditions []clause) {
+	for i, condition := range conditions {
+		if i == 0 {
+			b.sb.WriteString(" WHERE (")
+		} else {
+			b.sb.WriteString(" AND (")
+		}
+		b.write(condition)
+		b.sb.WriteString(")")
+	}
+}
+
+// placeholders returns n comma-separated "?" placeholders, e.g. "?, ?, ?".
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat(placeholder+", ", n), ", ")
+}
+
+// splitList splits a comma-separated list, trimming spaces and dropping empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,291 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testColumns   = "a.id"
+	testFrom      = "articles a JOIN sources s ON a.source_id = s.id"
+	testBaseQuery = "SELECT " + testColumns + " FROM " + testFrom
+)
+
+// newTestChain creates the same filter chain as the article service uses.
+func newTestChain() ArticleFilter {
+	sourceFilter := &SourceFilter{}
+	authorFilter := &AuthorFilter{}
+	categoryFilter := &CategoryFilter{}
+	keywordFilter := &KeywordFilter{}
+	dateRangeFilter := &DateRangeFilter{}
+	storyFilter := &StoryFilter{}
+	sourceFilter.SetNext(authorFilter).SetNext(categoryFilter).SetNext(keywordFilter).SetNext(dateRangeFilter).SetNext(storyFilter)
+	return sourceFilter
+}
+
+// buildTestQuery runs the filter chain for f and returns the resulting query.
+func buildTestQuery(t testing.TB, f Filters) *Query {
+	q := NewQuery(Postgres, testColumns, testFrom)
+	require.NoError(t, newTestChain().BuildFilterQuery(f, q))
+	return q
+}
+
+func TestQuery_SQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(q *Query)
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "no conditions",
+			build:    func(q *Query) {},
+			wantSQL:  testBaseQuery,
+			wantArgs: nil,
+		},
+		{
+			name: "conditions are joined and placeholders numbered",
+			build: func(q *Query) {
+				q.Where("s.short_name IN (?, ?)", "bbc", "nbc")
+				q.Where("a.title ILIKE ?", "%go%")
+			},
+			wantSQL:  testBaseQuery + " WHERE (s.short_name IN ($1, $2)) AND (a.title ILIKE $3)",
+			wantArgs: []interface{}{"bbc", "nbc", "%go%"},
+		},
+		{
+			name: "rank, ordering and limit",
+			build: func(q *Query) {
+				q.Where("a.search_vector @@ to_tsquery(?)", "go")
+				q.Rank("ts_rank(a.search_vector, to_tsquery(?))", "go")
+				rank, args := q.RankExpression()
+				q.OrderBy(rank+" DESC", args...)
+				q.OrderBy("a.id DESC")
+				q.Limit(10)
+			},
+			wantSQL: "SELECT " + testColumns + ", ts_rank(a.search_vector, to_tsquery($1)) AS relevance FROM " + testFrom +
+				" WHERE (a.search_vector @@ to_tsquery($2))" +
+				" ORDER BY ts_rank(a.search_vector, to_tsquery($3)) DESC, a.id DESC LIMIT 10",
+			wantArgs: []interface{}{"go", "go", "go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuery(Postgres, testColumns, testFrom)
+			tt.build(q)
+			sql, args := q.Build()
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestQuery_Count(t *testing.T) {
+	q := NewQuery(Postgres, testColumns, testFrom)
+	q.Where("s.short_name IN (?)", "bbc")
+	q.Rank("ts_rank(a.search_vector, to_tsquery(?))", "go")
+	q.OrderBy("a.id DESC")
+	q.Limit(10)
+
+	sql, args := q.Count()
+	assert.Equal(t, "SELECT COUNT(*) FROM "+testFrom+" WHERE (s.short_name IN ($1))", sql)
+	assert.Equal(t, []interface{}{"bbc"}, args)
+}
+
+func TestArticleFilter_BuildFilterQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		f        Filters
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "empty filters",
+			f:        Filters{},
+			wantSQL:  testBaseQuery,
+			wantArgs: nil,
+			wantErr:  assert.NoError,
+		},
+		{
+			name: "all filters",
+			f: Filters{
+				Source:    "bbc, nbc",
+				Author:    "Smith, 100%_sure",
+				Category:  "World",
+				Keyword:   "golang, \"open source\"",
+				StartDate: "2024-01-02",
+				EndDate:   "2024-01-03",
+			},
+			wantSQL: "SELECT " + testColumns + ", ts_rank(a.search_vector, phraseto_tsquery('english', $1) || phraseto_tsquery('english', $2))::float8 AS relevance" +
+				" FROM " + testFrom + " WHERE (s.short_name IN ($3, $4))" +
+				` AND (lower(a.author) LIKE $5 ESCAPE '\' OR lower(a.author) LIKE $6 ESCAPE '\')` +
+				" AND (EXISTS (SELECT 1 FROM unnest(a.categories) AS value WHERE lower(value) IN ($7)))" +
+				" AND ((a.search_vector @@ phraseto_tsquery('english', $8) OR a.search_vector @@ phraseto_tsquery('english', $9)))" +
+				" AND (a.pub_date >= $10) AND (a.pub_date <= $11)",
+			wantArgs: []interface{}{
+				"golang", "open source",
+				"bbc", "nbc",
+				"%smith%", `%100\%\_sure%`,
+				"world",
+				"golang", "open source",
+				time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "collapsed stories",
+			f:    Filters{Source: "bbc", StartDate: "2024-01-02", Collapse: true},
+			wantSQL: testBaseQuery + " WHERE (s.short_name IN ($1)) AND (a.pub_date >= $2)" +
+				" AND (a.id IN (SELECT id FROM (SELECT a.id, ROW_NUMBER() OVER (PARTITION BY a.story_id" +
+				" ORDER BY a.pub_date, a.id) AS group_row FROM " + testFrom +
+				" WHERE (s.short_name IN ($3)) AND (a.pub_date >= $4)) AS grouped WHERE group_row = 1))",
+			wantArgs: []interface{}{
+				"bbc", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				"bbc", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "invalid keywords",
+			f:       Filters{Keyword: "ukraine AND"},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "invalid start date",
+			f:       Filters{StartDate: "02-01-2024"},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "invalid end date",
+			f:       Filters{EndDate: "yesterday"},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuery(Postgres, testColumns, testFrom)
+			err := newTestChain().BuildFilterQuery(tt.f, q)
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+			sql, args := q.Build()
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+// assertSameStructure checks that the query built for hostile filters has exactly the same
+// SQL text as the query built for benign filters of the same shape, and that the hostile
+// values only travel in the args slice.
+func assertSameStructure(t *testing.T, hostile, benign Filters) {
+	gotSQL, gotArgs := buildTestQuery(t, hostile).Build()
+	wantSQL, _ := buildTestQuery(t, benign).Build()
+	if gotSQL != wantSQL {
+		t.Fatalf("query structure depends on input %+v:\n got: %s\nwant: %s", hostile, gotSQL, wantSQL)
+	}
+	if n := strings.Count(gotSQL, "$"); n != len(gotArgs) {
+		t.Fatalf("query has %d placeholders but %d args", n, len(gotArgs))
+	}
+}
+
+// benignList replaces every item of a comma-separated list with a harmless value.
+func benignList(list string) string {
+	items := splitList(list)
+	for i := range items {
+		items[i] = "x"
+	}
+	return strings.Join(items, ",")
+}
+
+// benignKeywords replaces every term of a keyword query with a harmless value,
+// keeping the operators, fields and grouping. It reports false if the query is invalid.
+func benignKeywords(keywords string) (string, bool) {
+	query, err := ParseKeywordQuery(keywords)
+	if err != nil {
+		return "", false
+	}
+	if query.Empty() {
+		return "", true
+	}
+	var render func(n keywordNode) string
+	render = func(n keywordNode) string {
+		switch n := n.(type) {
+		case *andNode:
+			return "(" + render(n.left) + " AND " + render(n.right) + ")"
+		case *orNode:
+			return "(" + render(n.left) + " OR " + render(n.right) + ")"
+		case *notNode:
+			return "NOT " + render(n.operand)
+		case *termNode:
+			if n.field != "" {
+				return n.field + ":x"
+			}
+			return "x"
+		}
+		panic("unknown keyword node")
+	}
+	return render(query.root), true
+}
+
+func FuzzKeywordFilter_BuildFilterQuery(f *testing.F) {
+	seeds := []string{
+		"golang",
+		"ukraine, war",
+		"'; DROP TABLE articles; --",
+		"%' OR '1'='1",
+		"$1) OR (1=1",
+		"? OR 1=1 --",
+		`\'; SELECT pg_sleep(10); --`,
+		`title:"'); DROP TABLE sources; --" OR -(a AND b)`,
+		"",
+		",,,",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, keywords string) {
+		benign, ok := benignKeywords(keywords)
+		if !ok {
+			t.Skip("invalid keyword query")
+		}
+		assertSameStructure(t, Filters{Keyword: keywords}, Filters{Keyword: benign})
+	})
+}
+
+func FuzzSourceFilter_BuildFilterQuery(f *testing.F) {
+	seeds := []string{
+		"bbc",
+		"bbc,nbc,abcnews",
+		"bbc' OR '1'='1",
+		"x') UNION SELECT id, password FROM users --",
+		"$2",
+		"?",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, sources string) {
+		assertSameStructure(t, Filters{Source: sources}, Filters{Source: benignList(sources)})
+	})
+}
+
+func FuzzArticleFilter_BuildFilterQuery(f *testing.F) {
+	f.Add("golang", "bbc")
+	f.Add("'; DELETE FROM sources; --", "nbc') OR 1=1 --")
+	f.Add("$1", "?")
+	f.Fuzz(func(t *testing.T, keywords, sources string) {
+		benignQuery, ok := benignKeywords(keywords)
+		if !ok {
+			t.Skip("invalid keyword query")
+		}
+		hostile := Filters{Keyword: keywords, Source: sources, StartDate: "2024-01-01"}
+		benign := Filters{Keyword: benignQuery, Source: benignList(sources), StartDate: "2024-01-01"}
+		assertSameStructure(t, hostile, benign)
+	})
+}
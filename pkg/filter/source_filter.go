@@ -76,22 +76,21 @@ func (h *SourceFilter) Filter(articles []model.Article, f Filters) ([]model.Arti
 	return articles, nil
 }
 
-func (h *SourceFilter) BuildFilterQuery(f Filters, query string) (string, []interface{}) {
-	if f.Source != "" {
-		sourceList := strings.Split(f.Source, ",")
-		var sourceConditions []string
+// BuildFilterQuery adds a condition matching any of the source short names to the query.
+func (h *SourceFilter) BuildFilterQuery(f Filters, q *Query) error {
+	sourceList := splitList(f.Source)
+	if len(sourceList) > 0 {
+		args := make([]interface{}, 0, len(sourceList))
 		for _, source := range sourceList {
-			condition := fmt.Sprintf("s.short_name = '%s'", source)
-			sourceConditions = append(sourceConditions, condition)
-		}
-		if len(sourceConditions) > 0 {
-			query += " AND (" + strings.Join(sourceConditions, " OR ") + ")"
+			args = append(args, source)
 		}
+		q.Where("s.short_name IN ("+placeholders(len(args))+")", args...)
 	}
+
 	if h.next != nil {
-		return h.next.BuildFilterQuery(f, query)
+		return h.next.BuildFilterQuery(f, q)
 	}
-	return query, nil
+	return nil
 }
 
 // findOther checks if the source name is not one of the predefined sources
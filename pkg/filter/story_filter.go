@@ -0,0 +1,51 @@
+package filter
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventStoryFilterStart       = "story_filter_start"
+	eventStoryFilteringComplete = "story_filtering_complete"
+)
+
+// StoryFilter collapses the articles reporting the same story into the earliest published one.
+// It must be the last filter in the chain, as it collapses the articles matched by the other filters.
+type StoryFilter struct {
+	next ArticleFilter
+}
+
+// SetNext sets the next filter in the chain and returns the filter.
+func (h *StoryFilter) SetNext(filter ArticleFilter) ArticleFilter {
+	h.next = filter
+	return filter
+}
+
+// Filter keeps a single article of every story if the Filters ask to collapse them.
+func (h *StoryFilter) Filter(articles []model.Article, f Filters) ([]model.Article, error) {
+	logrus.WithField("event_id", eventStoryFilterStart).Info("Starting StoryFilter")
+
+	if f.Collapse {
+		articles = dedup.Collapse(articles)
+		logrus.WithField("filtered_count", len(articles)).Info(eventStoryFilteringComplete)
+	}
+
+	if h.next != nil {
+		return h.next.Filter(articles, f)
+	}
+	return articles, nil
+}
+
+// BuildFilterQuery adds a condition keeping the earliest published article of every story to the query.
+func (h *StoryFilter) BuildFilterQuery(f Filters, q *Query) error {
+	if f.Collapse {
+		q.Collapse("a.story_id", "a.pub_date, a.id")
+	}
+
+	if h.next != nil {
+		return h.next.BuildFilterQuery(f, q)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package filter
+
+import (
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStoryFilter_Filter(t *testing.T) {
+	early := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	articles := []model.Article{
+		{Id: 1, Title: "Story 1 by BBC", StoryId: 1, PubDate: late, Source: model.Source{Name: bbcNewsSource}},
+		{Id: 2, Title: "Story 1 by NBC", StoryId: 1, PubDate: early, Source: model.Source{Name: nbcNewsSource}},
+		{Id: 3, Title: "Story 2", StoryId: 3, PubDate: early, Source: model.Source{Name: bbcNewsSource}},
+		{Id: 4, Title: "Story 1 by ABC", StoryId: 1, PubDate: early, Source: model.Source{Name: abcNewsSource}},
+	}
+
+	tests := []struct {
+		name string
+		f    Filters
+		want []model.Article
+	}{
+		{
+			name: "Stories are kept by default",
+			f:    Filters{},
+			want: articles,
+		},
+		{
+			name: "Stories are collapsed into the earliest article",
+			f:    Filters{Collapse: true},
+			want: []model.Article{articles[1], articles[2]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &StoryFilter{}
+			got, err := h.Filter(articles, tt.f)
+			assert.NoError(t, err)
+			assert.Equalf(t, tt.want, got, "Filter(%v, %v)", articles, tt.f)
+		})
+	}
+}
+
+func TestStoryFilter_Filter_Chain(t *testing.T) {
+	articles := []model.Article{
+		{Id: 1, Title: "Story 1 by NBC", StoryId: 1, Source: model.Source{Name: nbcNewsSource}},
+		{Id: 2, Title: "Story 1 by BBC", StoryId: 1, Source: model.Source{Name: bbcNewsSource}},
+	}
+
+	got, err := newTestChain().Filter(articles, Filters{Source: "bbc", Collapse: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Article{articles[1]}, got, "the story is represented by the article matching the other filters")
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/Masterminds/sprig"
@@ -18,8 +19,8 @@ const articleTemplate = "article.tmpl"
 
 // filterArticles filters the provided articles based on the provided sources, keywords, and date range.
 // It returns the filtered articles.
-func (h *cliHandler) filterArticles(f filter.Filters) ([]model.Article, error) {
-	articles, err := h.artService.GetByFilter(f)
+func (h *cliHandler) filterArticles(ctx context.Context, f filter.Filters) ([]model.Article, error) {
+	articles, err := h.artService.GetByFilter(ctx, f)
 	if err != nil {
 		log.Fatalf("Error filtering articles: %v", err)
 		return nil, err
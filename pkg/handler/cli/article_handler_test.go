@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"github.com/antonchaban/news-aggregator/pkg/filter"
 	"github.com/antonchaban/news-aggregator/pkg/handler/web/mocks"
 	"github.com/antonchaban/news-aggregator/pkg/model"
@@ -24,13 +25,13 @@ func TestHandler_filterArticles(t *testing.T) {
 		{Id: 2, Source: model.Source{Name: "bbc"}, Title: "Title 2", Description: "Description 2", Link: "http://link2.com", PubDate: pubDate2},
 	}
 
-	mockArticleService.EXPECT().GetByFilter(filter.Filters{Source: "abcnews"}).Return([]model.Article{articles[0]}, nil).Times(1)
+	mockArticleService.EXPECT().GetByFilter(gomock.Any(), filter.Filters{Source: "abcnews"}).Return([]model.Article{articles[0]}, nil).Times(1)
 
 	f := filter.Filters{
 		Source: "abcnews",
 	}
 
-	filtered, err := handler.filterArticles(f)
+	filtered, err := handler.filterArticles(context.Background(), f)
 	if err != nil {
 		t.Errorf("Expected to filter articles, but got an error")
 	}
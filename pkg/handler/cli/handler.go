@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/filter"
@@ -34,6 +35,8 @@ func (h *cliHandler) initCommands() error {
 	helpDesc := "Show all available arguments and their descriptions."
 	sourcesDesc := "Select the desired news sources to get the news from. Supported sources: abcnews, bbc, washingtontimes, nbc, usatoday"
 	keywordsDesc := "Specify the keywords to filter the news by."
+	authorsDesc := "Specify the comma-separated authors to filter the news by."
+	categoriesDesc := "Specify the comma-separated categories to filter the news by."
 	dateStartDesc := "Specify the start date to filter the news by (format: YYYY-MM-DD)."
 	dateEndDesc := "Specify the end date to filter the news by (format: YYYY-MM-DD)."
 	sortOrderDesc := "Specify the sort order for the news by date (ASC or DESC)."
@@ -41,6 +44,8 @@ func (h *cliHandler) initCommands() error {
 	help := flag.Bool("help", false, helpDesc)
 	sources := flag.String("sources", "", sourcesDesc)
 	keywords := flag.String("keywords", "", keywordsDesc)
+	authors := flag.String("authors", "", authorsDesc)
+	categories := flag.String("categories", "", categoriesDesc)
 	dateStart := flag.String("date-start", "", dateStartDesc)
 	dateEnd := flag.String("date-end", "", dateEndDesc)
 	sortOrder := flag.String("sort-order", "DESC", sortOrderDesc)
@@ -50,6 +55,8 @@ func (h *cliHandler) initCommands() error {
 		fmt.Printf("  -help\n\t%s\n", helpDesc)
 		fmt.Printf("  -sources string\n\t%s\n", sourcesDesc)
 		fmt.Printf("  -keywords string\n\t%s\n", keywordsDesc)
+		fmt.Printf("  -authors string\n\t%s\n", authorsDesc)
+		fmt.Printf("  -categories string\n\t%s\n", categoriesDesc)
 		fmt.Printf("  -date-start string\n\t%s\n", dateStartDesc)
 		fmt.Printf("  -date-end string\n\t%s\n", dateEndDesc)
 		fmt.Printf("  -sort-order string\n\t%s\n", sortOrderDesc)
@@ -65,6 +72,8 @@ func (h *cliHandler) initCommands() error {
 	err := h.execute(filter.Filters{
 		Source:    *sources,
 		Keyword:   *keywords,
+		Author:    *authors,
+		Category:  *categories,
 		StartDate: *dateStart,
 		EndDate:   *dateEnd,
 	}, *sortOrder)
@@ -77,16 +86,17 @@ func (h *cliHandler) initCommands() error {
 // execute loads the articles, filters them based on the provided sources, keywords, and date range,
 // and then prints the filtered articles.
 func (h *cliHandler) execute(f filter.Filters, sortOrder string) error {
+	ctx := context.Background()
 	articles, err := h.srcService.LoadDataFromFiles()
 	if err != nil {
 		return err
 	}
-	err = h.artService.SaveAll(articles)
+	_, err = h.artService.SaveAll(ctx, articles)
 	if err != nil {
 		return err
 	}
 
-	filteredArticles, err := h.filterArticles(f)
+	filteredArticles, err := h.filterArticles(ctx, f)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/storage/migrate"
+	"io"
+	"strconv"
+)
+
+// MigrateCommand is the name of the subcommand managing the schema migrations of the database.
+const MigrateCommand = "migrate"
+
+const migrateUsage = `Usage: %s migrate <command>
+  up
+	Apply all the pending migrations.
+  down [N]
+	Revert the latest N applied migrations, 1 by default.
+  status
+	Show the schema version of the database and the pending migrations.
+`
+
+//go:generate mockgen -destination=mocks/mock_migrator.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/cli Migrator
+
+// Migrator applies and reverts the schema migrations of the database.
+type Migrator interface {
+	Up(ctx context.Context) error
+	Down(ctx context.Context, steps int) error
+	Status(ctx context.Context) (migrate.Status, error)
+}
+
+// Migrate runs the migrate subcommand with the given arguments, printing its output to out.
+func Migrate(ctx context.Context, m Migrator, name string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintf(out, migrateUsage, name)
+		return errors.New("missing migrate command")
+	}
+
+	switch args[0] {
+	case "up":
+		if err := m.Up(ctx); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations to revert: %s", args[1])
+			}
+			steps = n
+		}
+		if err := m.Down(ctx, steps); err != nil {
+			return err
+		}
+	case "status":
+	case "-help", "--help", "help":
+		fmt.Fprintf(out, migrateUsage, name)
+		return nil
+	default:
+		fmt.Fprintf(out, migrateUsage, name)
+		return fmt.Errorf("unknown migrate command: %s", args[0])
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	printStatus(out, status)
+	return nil
+}
+
+// printStatus prints the schema version of the database and the pending migrations.
+func printStatus(out io.Writer, status migrate.Status) {
+	dirty := ""
+	if status.Dirty {
+		dirty = " (dirty)"
+	}
+	fmt.Fprintf(out, "Version: %d%s\nLatest: %d\n", status.Version, dirty, status.Latest)
+	if len(status.Pending) == 0 {
+		fmt.Fprintln(out, "No pending migrations")
+		return
+	}
+	fmt.Fprintln(out, "Pending migrations:")
+	for _, m := range status.Pending {
+		fmt.Fprintf(out, "  %s\n", m)
+	}
+}
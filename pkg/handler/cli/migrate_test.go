@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/handler/cli/mocks"
+	"github.com/antonchaban/news-aggregator/pkg/storage/migrate"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	pending := migrate.Status{Version: 5, Latest: 6, Pending: []migrate.Migration{{Version: 6, Name: "articles_stories"}}}
+	current := migrate.Status{Version: 6, Latest: 6}
+
+	tests := []struct {
+		name       string
+		args       []string
+		mock       func(m *mocks.MockMigrator)
+		wantOutput string
+		wantErr    string
+	}{
+		{
+			name: "up",
+			args: []string{"up"},
+			mock: func(m *mocks.MockMigrator) {
+				m.EXPECT().Up(gomock.Any()).Return(nil)
+				m.EXPECT().Status(gomock.Any()).Return(current, nil)
+			},
+			wantOutput: "Version: 6\nLatest: 6\nNo pending migrations\n",
+		},
+		{
+			name: "down by default reverts one migration",
+			args: []string{"down"},
+			mock: func(m *mocks.MockMigrator) {
+				m.EXPECT().Down(gomock.Any(), 1).Return(nil)
+				m.EXPECT().Status(gomock.Any()).Return(pending, nil)
+			},
+			wantOutput: "Version: 5\nLatest: 6\nPending migrations:\n  000006_articles_stories\n",
+		},
+		{
+			name: "down several",
+			args: []string{"down", "3"},
+			mock: func(m *mocks.MockMigrator) {
+				m.EXPECT().Down(gomock.Any(), 3).Return(errors.New("no down script"))
+			},
+			wantErr: "no down script",
+		},
+		{
+			name:    "invalid down steps",
+			args:    []string{"down", "all"},
+			mock:    func(m *mocks.MockMigrator) {},
+			wantErr: "invalid number of migrations to revert: all",
+		},
+		{
+			name: "dirty status",
+			args: []string{"status"},
+			mock: func(m *mocks.MockMigrator) {
+				m.EXPECT().Status(gomock.Any()).Return(migrate.Status{Version: 6, Dirty: true, Latest: 6}, nil)
+			},
+			wantOutput: "Version: 6 (dirty)\nLatest: 6\nNo pending migrations\n",
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"force"},
+			mock:    func(m *mocks.MockMigrator) {},
+			wantErr: "unknown migrate command: force",
+		},
+		{
+			name:    "missing command",
+			mock:    func(m *mocks.MockMigrator) {},
+			wantErr: "missing migrate command",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			m := mocks.NewMockMigrator(ctrl)
+			tt.mock(m)
+
+			var out bytes.Buffer
+			err := Migrate(context.Background(), m, "cli", tt.args, &out)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOutput, out.String())
+		})
+	}
+}
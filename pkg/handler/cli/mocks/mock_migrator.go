@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/antonchaban/news-aggregator/pkg/handler/cli (interfaces: Migrator)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/mock_migrator.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/cli Migrator
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	migrate "github.com/antonchaban/news-aggregator/pkg/storage/migrate"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMigrator is a mock of Migrator interface.
+type MockMigrator struct {
+	ctrl     *gomock.Controller
+	recorder *MockMigratorMockRecorder
+}
+
+// MockMigratorMockRecorder is the mock recorder for MockMigrator.
+type MockMigratorMockRecorder struct {
+	mock *MockMigrator
+}
+
+// NewMockMigrator creates a new mock instance.
+func NewMockMigrator(ctrl *gomock.Controller) *MockMigrator {
+	mock := &MockMigrator{ctrl: ctrl}
+	mock.recorder = &MockMigratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMigrator) EXPECT() *MockMigratorMockRecorder {
+	return m.recorder
+}
+
+// Down mocks base method.
+func (m *MockMigrator) Down(arg0 context.Context, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Down", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Down indicates an expected call of Down.
+func (mr *MockMigratorMockRecorder) Down(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Down", reflect.TypeOf((*MockMigrator)(nil).Down), arg0, arg1)
+}
+
+// Status mocks base method.
+func (m *MockMigrator) Status(arg0 context.Context) (migrate.Status, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status", arg0)
+	ret0, _ := ret[0].(migrate.Status)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockMigratorMockRecorder) Status(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockMigrator)(nil).Status), arg0)
+}
+
+// Up mocks base method.
+func (m *MockMigrator) Up(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Up", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Up indicates an expected call of Up.
+func (mr *MockMigratorMockRecorder) Up(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Up", reflect.TypeOf((*MockMigrator)(nil).Up), arg0)
+}
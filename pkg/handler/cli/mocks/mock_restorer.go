@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/antonchaban/news-aggregator/pkg/handler/cli (interfaces: Restorer)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/mock_restorer.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/cli Restorer
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/antonchaban/news-aggregator/pkg/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRestorer is a mock of Restorer interface.
+type MockRestorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockRestorerMockRecorder
+}
+
+// MockRestorerMockRecorder is the mock recorder for MockRestorer.
+type MockRestorerMockRecorder struct {
+	mock *MockRestorer
+}
+
+// NewMockRestorer creates a new mock instance.
+func NewMockRestorer(ctrl *gomock.Controller) *MockRestorer {
+	mock := &MockRestorer{ctrl: ctrl}
+	mock.recorder = &MockRestorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRestorer) EXPECT() *MockRestorerMockRecorder {
+	return m.recorder
+}
+
+// Restore mocks base method.
+func (m *MockRestorer) Restore(arg0 context.Context, arg1 time.Time, arg2 bool) (model.RestoreReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.RestoreReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockRestorerMockRecorder) Restore(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockRestorer)(nil).Restore), arg0, arg1, arg2)
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/handler/web"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"io"
+)
+
+// PruneCommand is the name of the subcommand pruning the articles beyond the retention policies.
+const PruneCommand = "prune"
+
+// Prune runs the prune subcommand with the given arguments, printing what was pruned to out.
+// With the -dry-run flag the expired articles are only listed.
+func Prune(ctx context.Context, rs web.RetentionService, name string, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(name+" "+PruneCommand, flag.ContinueOnError)
+	flags.SetOutput(out)
+	dryRun := flags.Bool("dry-run", false, "List the expired articles without deleting them.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := rs.Prune(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+	printPruneReport(out, report)
+	return nil
+}
+
+// printPruneReport prints the expired articles of every source, listing them on dry runs.
+func printPruneReport(out io.Writer, report model.PruneReport) {
+	for _, result := range report.Sources {
+		fmt.Fprintf(out, "%s: %d expired articles (max age: %d days, max count: %d)\n",
+			result.Source.ShortName, result.Pruned, result.Policy.MaxAgeDays, result.Policy.MaxCount)
+		for _, article := range result.Articles {
+			fmt.Fprintf(out, "  %s %s %s\n", article.PubDate.Format("2006-01-02"), article.Title, article.Link)
+		}
+	}
+	switch {
+	case report.DryRun:
+		fmt.Fprintf(out, "Would prune %d articles\n", report.Pruned())
+	case report.Archive != "":
+		fmt.Fprintf(out, "Pruned %d articles, archived to %s\n", report.Pruned(), report.Archive)
+	default:
+		fmt.Fprintf(out, "Pruned %d articles\n", report.Pruned())
+	}
+}
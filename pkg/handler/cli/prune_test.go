@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	web_mocks "github.com/antonchaban/news-aggregator/pkg/handler/web/mocks"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	bbc := model.Source{Id: 1, ShortName: "bbc"}
+	pubDate := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	result := model.SourcePruneResult{Source: bbc, Policy: model.RetentionPolicy{MaxAgeDays: 30}, Pruned: 1}
+
+	tests := []struct {
+		name       string
+		args       []string
+		mock       func(rs *web_mocks.MockRetentionService)
+		wantOutput string
+		wantErr    string
+	}{
+		{
+			name: "dry run",
+			args: []string{"-dry-run"},
+			mock: func(rs *web_mocks.MockRetentionService) {
+				dryRun := result
+				dryRun.Articles = []model.Article{{Title: "Trial starts", Link: "https://bbc.com/trial", PubDate: pubDate}}
+				rs.EXPECT().Prune(gomock.Any(), true).Return(model.PruneReport{DryRun: true,
+					Sources: []model.SourcePruneResult{dryRun}}, nil)
+			},
+			wantOutput: "bbc: 1 expired articles (max age: 30 days, max count: 0)\n" +
+				"  2024-08-05 Trial starts https://bbc.com/trial\nWould prune 1 articles\n",
+		},
+		{
+			name: "archived",
+			mock: func(rs *web_mocks.MockRetentionService) {
+				rs.EXPECT().Prune(gomock.Any(), false).Return(model.PruneReport{Archive: "archive/articles.ndjson.gz",
+					Sources: []model.SourcePruneResult{result}}, nil)
+			},
+			wantOutput: "bbc: 1 expired articles (max age: 30 days, max count: 0)\n" +
+				"Pruned 1 articles, archived to archive/articles.ndjson.gz\n",
+		},
+		{
+			name: "nothing expired",
+			mock: func(rs *web_mocks.MockRetentionService) {
+				rs.EXPECT().Prune(gomock.Any(), false).Return(model.PruneReport{}, nil)
+			},
+			wantOutput: "Pruned 0 articles\n",
+		},
+		{
+			name: "error",
+			mock: func(rs *web_mocks.MockRetentionService) {
+				rs.EXPECT().Prune(gomock.Any(), false).Return(model.PruneReport{}, errors.New("connection reset"))
+			},
+			wantErr: "connection reset",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			rs := web_mocks.NewMockRetentionService(ctrl)
+			tt.mock(rs)
+
+			var out bytes.Buffer
+			err := Prune(context.Background(), rs, "cli", tt.args, &out)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOutput, out.String())
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"io"
+	"time"
+)
+
+// RestoreCommand is the name of the subcommand restoring the articles and sources to a point in time.
+const RestoreCommand = "restore"
+
+//go:generate mockgen -destination=mocks/mock_restorer.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/cli Restorer
+
+// Restorer restores the articles and sources as of the given time, the latest ones if it's zero,
+// saving them as the latest snapshot unless dryRun is set.
+type Restorer interface {
+	Restore(ctx context.Context, at time.Time, dryRun bool) (model.RestoreReport, error)
+}
+
+// Restore runs the restore subcommand with the given arguments, printing what was restored to out.
+// The -at flag takes the RFC 3339 time to restore, the latest state is restored without it.
+// With the -dry-run flag the restored state isn't saved.
+func Restore(ctx context.Context, r Restorer, name string, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(name+" "+RestoreCommand, flag.ContinueOnError)
+	flags.SetOutput(out)
+	atFlag := flags.String("at", "", "The RFC 3339 time to restore the articles and sources to, such as 2024-08-05T09:00:00Z.")
+	dryRun := flags.Bool("dry-run", false, "Report what would be restored without saving it.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var at time.Time
+	if *atFlag != "" {
+		var err error
+		at, err = time.Parse(time.RFC3339, *atFlag)
+		if err != nil {
+			return fmt.Errorf("invalid -at time %q, expected RFC 3339", *atFlag)
+		}
+	}
+
+	report, err := r.Restore(ctx, at, *dryRun)
+	if err != nil {
+		return err
+	}
+	printRestoreReport(out, report)
+	return nil
+}
+
+// printRestoreReport prints where the articles and sources were restored from and where they were saved.
+func printRestoreReport(out io.Writer, report model.RestoreReport) {
+	if report.Snapshot != "" {
+		fmt.Fprintf(out, "Snapshot %s taken at %s\n", report.Snapshot, report.SnapshotAt.Format(time.RFC3339))
+	} else {
+		fmt.Fprintln(out, "No snapshot")
+	}
+	fmt.Fprintf(out, "Replayed %d changes of the journal\n", report.Changes)
+	state := "the latest state"
+	if !report.At.IsZero() {
+		state = report.At.Format(time.RFC3339)
+	}
+	if report.Saved == "" {
+		fmt.Fprintf(out, "Would restore %d articles and %d sources as of %s\n", report.Articles, report.Sources, state)
+		return
+	}
+	fmt.Fprintf(out, "Restored %d articles and %d sources as of %s, saved to %s\n",
+		report.Articles, report.Sources, state, report.Saved)
+}
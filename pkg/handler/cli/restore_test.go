@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/handler/cli/mocks"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestRestore(t *testing.T) {
+	at := time.Date(2024, 8, 5, 9, 30, 0, 0, time.UTC)
+	report := model.RestoreReport{At: at, Snapshot: "saves/backup-20240805T090000.000000000Z",
+		SnapshotAt: time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC), Changes: 3, Articles: 10, Sources: 2}
+
+	tests := []struct {
+		name       string
+		args       []string
+		mock       func(r *mocks.MockRestorer)
+		wantOutput string
+		wantErr    string
+	}{
+		{
+			name: "point in time",
+			args: []string{"-at", "2024-08-05T09:30:00Z"},
+			mock: func(r *mocks.MockRestorer) {
+				saved := report
+				saved.Saved = "saves/backup-20240805T120000.000000000Z"
+				r.EXPECT().Restore(gomock.Any(), at, false).Return(saved, nil)
+			},
+			wantOutput: "Snapshot saves/backup-20240805T090000.000000000Z taken at 2024-08-05T09:00:00Z\n" +
+				"Replayed 3 changes of the journal\n" +
+				"Restored 10 articles and 2 sources as of 2024-08-05T09:30:00Z, saved to saves/backup-20240805T120000.000000000Z\n",
+		},
+		{
+			name: "dry run of the latest state",
+			args: []string{"-dry-run"},
+			mock: func(r *mocks.MockRestorer) {
+				latest := report
+				latest.At = time.Time{}
+				r.EXPECT().Restore(gomock.Any(), time.Time{}, true).Return(latest, nil)
+			},
+			wantOutput: "Snapshot saves/backup-20240805T090000.000000000Z taken at 2024-08-05T09:00:00Z\n" +
+				"Replayed 3 changes of the journal\n" +
+				"Would restore 10 articles and 2 sources as of the latest state\n",
+		},
+		{
+			name:    "invalid time",
+			args:    []string{"-at", "yesterday"},
+			mock:    func(r *mocks.MockRestorer) {},
+			wantErr: `invalid -at time "yesterday", expected RFC 3339`,
+		},
+		{
+			name: "error",
+			args: []string{"-at", "2024-08-05T09:30:00Z"},
+			mock: func(r *mocks.MockRestorer) {
+				r.EXPECT().Restore(gomock.Any(), at, false).Return(model.RestoreReport{}, errors.New("no snapshot"))
+			},
+			wantErr: "no snapshot",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mocks.NewMockRestorer(ctrl)
+			tt.mock(r)
+
+			var out bytes.Buffer
+			err := Restore(context.Background(), r, "cli", tt.args, &out)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOutput, out.String())
+		})
+	}
+}
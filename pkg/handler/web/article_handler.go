@@ -1,35 +1,52 @@
 package web
 
 import (
+	"context"
 	"github.com/antonchaban/news-aggregator/pkg/filter"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	_ "github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
+	"strconv"
 )
 
 //go:generate mockgen -destination=mocks/mock_article_service.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/web ArticleService
 
 // ArticleService is an interface that defines the methods for interacting with the article storage.
 type ArticleService interface {
-	GetAll() ([]model.Article, error)
-	Create(article model.Article) (model.Article, error)
-	Delete(id int) error
-	SaveAll(articles []model.Article) error
-	GetByFilter(f filter.Filters) ([]model.Article, error)
+	GetAll(ctx context.Context) ([]model.Article, error)
+	Create(ctx context.Context, article model.Article) (model.Article, error)
+	Delete(ctx context.Context, id int) error
+	SaveAll(ctx context.Context, articles []model.Article) (model.SaveResult, error)
+	GetByFilter(ctx context.Context, f filter.Filters) ([]model.Article, error)
+	GetPage(ctx context.Context, f filter.Filters, p filter.Page) (model.ArticlePage, error)
+	// Each calls fn with every article, ordered by ID, without loading all of them at once.
+	Each(ctx context.Context, fn func(model.Article) error) error
+	// Export writes all the articles to w as gzip-compressed NDJSON and returns their number.
+	Export(ctx context.Context, w io.Writer, progress func(int)) (int, error)
+	// Import saves the gzip-compressed NDJSON articles read from r, reporting the progress after every batch.
+	Import(ctx context.Context, r io.Reader, progress func(model.ImportReport)) (model.ImportReport, error)
 }
 
 // @Summary Get articles by filter
-// @Description Get articles by filter parameters
+// @Description Get a page of articles by filter parameters
 // @Tags articles
 // @ID get-articles-by-filter
 // @Accept json
 // @Produce json
-// @Param keywords query string false "Keywords to search for"
+// @Param keywords query string false "Keyword query: words, quoted phrases, AND, OR, NOT or -, parentheses and title: or description: scoping"
 // @Param sources query string false "Sources to search for"
+// @Param authors query string false "Comma-separated authors, matching the articles whose author contains any of them"
+// @Param categories query string false "Comma-separated categories, matching the articles in any of them"
 // @Param date_start query string false "Start date for search"
 // @Param date_end query string false "End date for search"
-// @Success 200 {object} []model.Article
+// @Param sort query string false "Sort articles by" Enums(pub_date, title, source, relevance) default(pub_date)
+// @Param order query string false "Sort order" Enums(asc, desc) default(desc)
+// @Param limit query int false "Maximum number of articles on the page" minimum(1) maximum(1000) default(50)
+// @Param cursor query string false "Cursor of the page, taken from next_cursor of the previous page"
+// @Param collapse query bool false "Collapse the articles reporting the same story into one, listing the other sources in other_sources" default(false)
+// @Success 200 {object} model.ArticlePage
 // @Failure 400 {object} errorResponse
 // @Failure 500 {object} errorResponse
 // @Router /articles [get]
@@ -37,14 +54,52 @@ func (h *Handler) getArticlesByFilter(c *gin.Context) {
 	f := filter.Filters{
 		Keyword:   c.Query("keywords"),
 		Source:    c.Query("sources"),
+		Author:    c.Query("authors"),
+		Category:  c.Query("categories"),
 		StartDate: c.Query("date_start"),
 		EndDate:   c.Query("date_end"),
 	}
 
-	articles, err := h.articleService.GetByFilter(f)
+	if _, err := filter.ParseKeywordQuery(f.Keyword); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if collapse := c.Query("collapse"); collapse != "" {
+		var err error
+		f.Collapse, err = strconv.ParseBool(collapse)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	p := filter.Page{
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+	}
+	// Keyword searches are sorted by relevance unless another sort is requested
+	if p.Sort == "" && f.Keyword != "" {
+		p.Sort = filter.SortRelevance
+	}
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		p.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	p, err := p.Normalize()
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.articleService.GetPage(c.Request.Context(), f, p)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, articles)
+	c.JSON(http.StatusOK, page)
 }
@@ -12,35 +12,139 @@ import (
 )
 
 func TestHandler_getArticlesByFilter(t *testing.T) {
-	type mockBehavior func(r *service_mocks.MockArticleService, filters filter.Filters)
+	type mockBehavior func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page)
+
 	tests := []struct {
 		name                 string
 		mockBehavior         mockBehavior
 		expectedCode         int
 		expectedResponseBody string
-		inputQuery           map[string]string
+		inputQuery           string
+		expectedFilters      filter.Filters
+		expectedPage         filter.Page
 	}{
 		{
 			name: "OK",
-			mockBehavior: func(r *service_mocks.MockArticleService, filters filter.Filters) {
-				r.EXPECT().GetByFilter(filters).Return([]model.Article{
-					{
-						Id:          1,
-						Title:       "Title",
-						Link:        "Link",
-						Description: "Description",
-						Source: model.Source{
-							Name:      "CNN",
-							ShortName: "cnn",
+			mockBehavior: func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {
+				r.EXPECT().GetPage(gomock.Any(), filters, page).Return(model.ArticlePage{
+					Articles: []model.Article{
+						{
+							Id:          1,
+							Title:       "Title",
+							Link:        "Link",
+							Description: "Description",
+							Source: model.Source{
+								Name:      "CNN",
+								ShortName: "cnn",
+							},
 						},
 					},
+					Count: 1,
+					Total: 1,
+				}, nil)
+			},
+			expectedCode:         200,
+			expectedResponseBody: `{"articles":[{"id":1,"title":"Title","description":"Description","link":"Link","source":{"id":0,"name":"CNN","link":"","short_name":"cnn"},"pub_date":"0001-01-01T00:00:00Z"}],"count":1,"total":1}`,
+			inputQuery:           "sources=cnn",
+			expectedFilters:      filter.Filters{Source: "cnn"},
+			expectedPage:         filter.Page{Limit: filter.DefaultLimit, Sort: filter.SortPubDate, Order: filter.OrderDesc},
+		},
+		{
+			name: "Authors and categories",
+			mockBehavior: func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {
+				r.EXPECT().GetPage(gomock.Any(), filters, page).Return(model.ArticlePage{
+					Articles: []model.Article{{Id: 5, Title: "Trial", Author: "Jane Doe", Categories: []string{"World", "Europe"},
+						ImageURL: "https://example.com/5.jpg", Language: "en", GUID: "5"}},
+					Count: 1,
+					Total: 1,
+				}, nil)
+			},
+			expectedCode:         200,
+			expectedResponseBody: `{"articles":[{"id":5,"title":"Trial","description":"","link":"","source":{"id":0,"name":"","link":"","short_name":""},"pub_date":"0001-01-01T00:00:00Z","author":"Jane Doe","categories":["World","Europe"],"image_url":"https://example.com/5.jpg","language":"en","guid":"5"}],"count":1,"total":1}`,
+			inputQuery:           "authors=jane,john&categories=world",
+			expectedFilters:      filter.Filters{Author: "jane,john", Category: "world"},
+			expectedPage:         filter.Page{Limit: filter.DefaultLimit, Sort: filter.SortPubDate, Order: filter.OrderDesc},
+		},
+		{
+			name: "OK with pagination",
+			mockBehavior: func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {
+				r.EXPECT().GetPage(gomock.Any(), filters, page).Return(model.ArticlePage{
+					Articles:   []model.Article{{Id: 2, Title: "B"}},
+					Count:      1,
+					Total:      3,
+					NextCursor: "next",
+				}, nil)
+			},
+			expectedCode:         200,
+			expectedResponseBody: `{"articles":[{"id":2,"title":"B","description":"","link":"","source":{"id":0,"name":"","link":"","short_name":""},"pub_date":"0001-01-01T00:00:00Z"}],"count":1,"total":3,"next_cursor":"next"}`,
+			inputQuery:           "keywords=go&sort=title&order=ASC&limit=1",
+			expectedFilters:      filter.Filters{Keyword: "go"},
+			expectedPage:         filter.Page{Limit: 1, Sort: filter.SortTitle, Order: filter.OrderAsc},
+		},
+		{
+			name: "Keywords are sorted by relevance",
+			mockBehavior: func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {
+				r.EXPECT().GetPage(gomock.Any(), filters, page).Return(model.ArticlePage{
+					Articles: []model.Article{{Id: 3, Title: "Go", Relevance: 0.5}},
+					Count:    1,
+					Total:    1,
 				}, nil)
 			},
 			expectedCode:         200,
-			expectedResponseBody: `[{"Id":1,"Title":"Title","Description":"Description","Link":"Link","Source":{"id":0,"name":"CNN","link":"","short_name":"cnn"},"PubDate":"0001-01-01T00:00:00Z"}]`,
-			inputQuery: map[string]string{
-				"sources": "cnn",
+			expectedResponseBody: `{"articles":[{"id":3,"title":"Go","description":"","link":"","source":{"id":0,"name":"","link":"","short_name":""},"pub_date":"0001-01-01T00:00:00Z","relevance":0.5}],"count":1,"total":1}`,
+			inputQuery:           "keywords=go",
+			expectedFilters:      filter.Filters{Keyword: "go"},
+			expectedPage:         filter.Page{Limit: filter.DefaultLimit, Sort: filter.SortRelevance, Order: filter.OrderDesc},
+		},
+		{
+			name: "Collapsed stories",
+			mockBehavior: func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {
+				r.EXPECT().GetPage(gomock.Any(), filters, page).Return(model.ArticlePage{
+					Articles: []model.Article{{Id: 4, Title: "Story", StoryId: 4, OtherSources: []model.Source{{Id: 2, Name: "BBC News", ShortName: "bbc"}}}},
+					Count:    1,
+					Total:    1,
+				}, nil)
 			},
+			expectedCode:         200,
+			expectedResponseBody: `{"articles":[{"id":4,"title":"Story","description":"","link":"","source":{"id":0,"name":"","link":"","short_name":""},"pub_date":"0001-01-01T00:00:00Z","story_id":4,"other_sources":[{"id":2,"name":"BBC News","link":"","short_name":"bbc"}]}],"count":1,"total":1}`,
+			inputQuery:           "collapse=true",
+			expectedFilters:      filter.Filters{Collapse: true},
+			expectedPage:         filter.Page{Limit: filter.DefaultLimit, Sort: filter.SortPubDate, Order: filter.OrderDesc},
+		},
+		{
+			name:                 "Invalid collapse",
+			mockBehavior:         func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"strconv.ParseBool: parsing \"maybe\": invalid syntax"}`,
+			inputQuery:           "collapse=maybe",
+		},
+		{
+			name:                 "Invalid keywords",
+			mockBehavior:         func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"syntax error at position 16: expected \")\", got end of query"}`,
+			inputQuery:           "keywords=%28ukraine+OR+war",
+		},
+		{
+			name:                 "Invalid limit",
+			mockBehavior:         func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"strconv.Atoi: parsing \"ten\": invalid syntax"}`,
+			inputQuery:           "limit=ten",
+		},
+		{
+			name:                 "Invalid sort",
+			mockBehavior:         func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"unsupported sort: author"}`,
+			inputQuery:           "sort=author",
+		},
+		{
+			name:                 "Invalid cursor",
+			mockBehavior:         func(r *service_mocks.MockArticleService, filters filter.Filters, page filter.Page) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"invalid cursor"}`,
+			inputQuery:           "cursor=%21%21%21",
 		},
 	}
 	for _, test := range tests {
@@ -51,30 +155,22 @@ func TestHandler_getArticlesByFilter(t *testing.T) {
 
 			artSvc := service_mocks.NewMockArticleService(c)
 			sSvc := service_mocks.NewMockSourceService(c)
-			for range test.inputQuery {
-				f := filter.Filters{
-					Keyword:   test.inputQuery["keywords"],
-					Source:    test.inputQuery["sources"],
-					StartDate: test.inputQuery["date_start"],
-					EndDate:   test.inputQuery["date_end"],
-				}
-				test.mockBehavior(artSvc, f)
-			}
+			test.mockBehavior(artSvc, test.expectedFilters, test.expectedPage)
 
 			// Init Endpoint
 			r := gin.New()
-			r.POST("/articles", NewHandler(artSvc, sSvc).getArticlesByFilter)
+			r.GET("/articles", NewHandler(artSvc, sSvc, nil, nil).getArticlesByFilter)
 
 			// Create Request
 			w := httptest.NewRecorder()
-			req := httptest.NewRequest("POST", "/articles?sources=cnn", nil)
+			req := httptest.NewRequest("GET", "/articles?"+test.inputQuery, nil)
 
 			// Make Request
 			r.ServeHTTP(w, req)
 
 			// Assert
-			assert.Equal(t, w.Code, test.expectedCode)
-			assert.Equal(t, w.Body.String(), test.expectedResponseBody)
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
 		})
 	}
 }
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	eventExportProgress = "export_progress"
+	eventExportComplete = "export_complete"
+	eventExportError    = "export_error"
+	eventImportComplete = "import_complete"
+	eventImportError    = "import_error"
+)
+
+const (
+	// exportFileName is the name the exported articles are downloaded as.
+	exportFileName = "articles.ndjson.gz"
+	// exportedArticlesTrailer is the trailer holding the number of exported articles.
+	exportedArticlesTrailer = "X-Exported-Articles"
+)
+
+// ErrInvalidImport is returned when the imported articles aren't gzip-compressed NDJSON.
+var ErrInvalidImport = errors.New("invalid import")
+
+// importProgress is a line of the import response, sent after every saved batch and once the import is over.
+// It has the following fields:
+// - ImportReport: the articles read and saved so far
+// - Done: whether the import is over
+// - Error: the error that stopped the import, if any
+type importProgress struct {
+	model.ImportReport
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+}
+
+// @Summary Export articles
+// @Description Download all the articles as gzip-compressed NDJSON, one article per line, ordered by ID. The articles are streamed as they are read, and the X-Exported-Articles trailer holds their number
+// @Tags articles
+// @ID export-articles
+// @Produce application/gzip
+// @Success 200 {file} file
+// @Failure 500 {object} errorResponse
+// @Router /export [get]
+func (h *Handler) exportArticles(c *gin.Context) {
+	clearDeadlines(c)
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", `attachment; filename="`+exportFileName+`"`)
+	c.Header("Trailer", exportedArticlesTrailer)
+
+	count, err := h.articleService.Export(c.Request.Context(), c.Writer, func(count int) {
+		logrus.WithField("event_id", eventExportProgress).Infof("Exported %d articles", count)
+	})
+	if err != nil {
+		logrus.WithField("event_id", eventExportError).Errorf("Export failed after %d articles: %v", count, err)
+		if !c.Writer.Written() {
+			newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		// Otherwise the status is already sent, the truncated download lacks the trailer
+		return
+	}
+	c.Writer.Header().Set(exportedArticlesTrailer, strconv.Itoa(count))
+	logrus.WithField("event_id", eventExportComplete).Infof("Exported %d articles", count)
+}
+
+// @Summary Import articles
+// @Description Import the gzip-compressed NDJSON articles of the request body, as exported, saving them in batches. The already saved articles are updated rather than repeated, and the articles keep the IDs of their sources, which must exist. The response streams an NDJSON line with the progress after every batch, and a last one with done set, or error if the import failed midway
+// @Tags articles
+// @ID import-articles
+// @Accept application/gzip
+// @Produce application/x-ndjson
+// @Success 200 {object} importProgress
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /import [post]
+func (h *Handler) importArticles(c *gin.Context) {
+	clearDeadlines(c)
+	enc := json.NewEncoder(c.Writer)
+	send := func(p importProgress) {
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+		}
+		if err := enc.Encode(p); err != nil {
+			logrus.WithField("event_id", eventImportError).Errorf("Error sending the import progress: %v", err)
+			return
+		}
+		c.Writer.Flush()
+	}
+
+	report, err := h.articleService.Import(c.Request.Context(), c.Request.Body, func(report model.ImportReport) {
+		send(importProgress{ImportReport: report})
+	})
+	if err != nil {
+		logrus.WithField("event_id", eventImportError).Errorf("Import failed after %d articles: %v", report.Articles, err)
+		if c.Writer.Written() {
+			send(importProgress{ImportReport: report, Done: true, Error: err.Error()})
+			return
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidImport) {
+			status = http.StatusBadRequest
+		}
+		newErrorResponse(c, status, err.Error())
+		return
+	}
+	logrus.WithField("event_id", eventImportComplete).Infof("Imported %d articles", report.Articles)
+	send(importProgress{ImportReport: report, Done: true})
+}
+
+// clearDeadlines lifts the read and write timeouts of the server for the request,
+// so streaming all the articles isn't cut off midway.
+func clearDeadlines(c *gin.Context) {
+	rc := http.NewResponseController(c.Writer)
+	// Not every writer supports deadlines, the test recorder doesn't, so the errors are ignored
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,164 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	service_mocks "github.com/antonchaban/news-aggregator/pkg/handler/web/mocks"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_exportArticles(t *testing.T) {
+	type mockBehavior func(r *service_mocks.MockArticleService)
+	tests := []struct {
+		name                 string
+		mockBehavior         mockBehavior
+		expectedCode         int
+		expectedResponseBody string
+		expectedTrailer      string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(r *service_mocks.MockArticleService) {
+				r.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, w io.Writer, progress func(int)) (int, error) {
+						_, err := io.WriteString(w, "articles")
+						progress(2)
+						return 2, err
+					})
+			},
+			expectedCode:         200,
+			expectedResponseBody: "articles",
+			expectedTrailer:      "2",
+		},
+		{
+			name: "InternalServerError",
+			mockBehavior: func(r *service_mocks.MockArticleService) {
+				r.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any()).Return(0, errors.New("connection reset"))
+			},
+			expectedCode:         500,
+			expectedResponseBody: `{"message":"connection reset"}`,
+		},
+		{
+			name: "ErrorMidway",
+			mockBehavior: func(r *service_mocks.MockArticleService) {
+				r.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, w io.Writer, progress func(int)) (int, error) {
+						_, _ = io.WriteString(w, "arti")
+						return 1, errors.New("connection reset")
+					})
+			},
+			expectedCode:         200,
+			expectedResponseBody: "arti",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			articleSvc := service_mocks.NewMockArticleService(c)
+			test.mockBehavior(articleSvc)
+
+			r := gin.New()
+			r.GET("/export", NewHandler(articleSvc, nil, nil, nil).exportArticles)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/export", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+			assert.Equal(t, test.expectedTrailer, w.Result().Trailer.Get(exportedArticlesTrailer))
+			if test.expectedCode == 200 {
+				assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="articles.ndjson.gz"`, w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
+
+func TestHandler_importArticles(t *testing.T) {
+	type mockBehavior func(r *service_mocks.MockArticleService)
+	tests := []struct {
+		name                 string
+		mockBehavior         mockBehavior
+		expectedCode         int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(r *service_mocks.MockArticleService) {
+				r.EXPECT().Import(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, r io.Reader, progress func(model.ImportReport)) (model.ImportReport, error) {
+						progress(model.ImportReport{Articles: 1000, SaveResult: model.SaveResult{Inserted: 1000}})
+						return model.ImportReport{Articles: 1001, SaveResult: model.SaveResult{Inserted: 1000, Updated: 1}}, nil
+					})
+			},
+			expectedCode: 200,
+			expectedResponseBody: `{"articles":1000,"inserted":1000,"updated":0,"skipped":0,"done":false}` + "\n" +
+				`{"articles":1001,"inserted":1000,"updated":1,"skipped":0,"done":true}` + "\n",
+		},
+		{
+			name: "BadRequest",
+			mockBehavior: func(r *service_mocks.MockArticleService) {
+				r.EXPECT().Import(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(model.ImportReport{}, fmt.Errorf("%w: gzip: invalid header", ErrInvalidImport))
+			},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"invalid import: gzip: invalid header"}`,
+		},
+		{
+			name: "InternalServerError",
+			mockBehavior: func(r *service_mocks.MockArticleService) {
+				r.EXPECT().Import(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(model.ImportReport{}, errors.New("failed to save articles"))
+			},
+			expectedCode:         500,
+			expectedResponseBody: `{"message":"failed to save articles"}`,
+		},
+		{
+			name: "ErrorMidway",
+			mockBehavior: func(r *service_mocks.MockArticleService) {
+				r.EXPECT().Import(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, r io.Reader, progress func(model.ImportReport)) (model.ImportReport, error) {
+						report := model.ImportReport{Articles: 1000, SaveResult: model.SaveResult{Skipped: 1000}}
+						progress(report)
+						return report, fmt.Errorf("%w: failed to read article 1001", ErrInvalidImport)
+					})
+			},
+			expectedCode: 200,
+			expectedResponseBody: `{"articles":1000,"inserted":0,"updated":0,"skipped":1000,"done":false}` + "\n" +
+				`{"articles":1000,"inserted":0,"updated":0,"skipped":1000,"done":true,` +
+				`"error":"invalid import: failed to read article 1001"}` + "\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			articleSvc := service_mocks.NewMockArticleService(c)
+			test.mockBehavior(articleSvc)
+
+			r := gin.New()
+			r.POST("/import", NewHandler(articleSvc, nil, nil, nil).importArticles)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/import", strings.NewReader("articles"))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultFetchRunLimit is the number of fetch runs listed unless the limit is given.
+	defaultFetchRunLimit = 50
+	// maxFetchRunLimit is the most fetch runs that can be listed at once.
+	maxFetchRunLimit = 1000
+)
+
+// @Summary Get fetch runs
+// @Description Get the recorded fetch runs, newest first, with the attempt of every source: its status, HTTP status, downloaded bytes, new and updated articles and error. The runs are kept for FETCH_HISTORY_MAX_AGE, a week by default, up to FETCH_HISTORY_MAX_RUNS of them
+// @Tags fetch-runs
+// @ID get-fetch-runs
+// @Produce json
+// @Param limit query int false "Maximum number of runs" minimum(1) maximum(1000) default(50)
+// @Success 200 {object} []model.FetchRun
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /fetch-runs [get]
+func (h *Handler) getFetchRuns(c *gin.Context) {
+	limit, err := fetchRunLimit(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	runs, err := h.srcService.GetFetchRuns(c.Request.Context(), limit)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// @Summary Get fetch runs of source
+// @Description Get the recorded fetch attempts of the source, newest first, telling why it failed or went quiet
+// @Tags sources
+// @ID get-source-fetch-runs
+// @Produce json
+// @Param id path int true "Source ID"
+// @Param limit query int false "Maximum number of attempts" minimum(1) maximum(1000) default(50)
+// @Success 200 {object} []model.FetchAttempt
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /sources/{id}/fetch-runs [get]
+func (h *Handler) getSourceFetchRuns(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := fetchRunLimit(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	attempts, err := h.srcService.GetSourceFetchRuns(c.Request.Context(), id, limit)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, attempts)
+}
+
+// fetchRunLimit returns the limit query parameter, or the default one if it's not given.
+func fetchRunLimit(c *gin.Context) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return defaultFetchRunLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 1 || limit > maxFetchRunLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxFetchRunLimit)
+	}
+	return limit, nil
+}
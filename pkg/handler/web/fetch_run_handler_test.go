@@ -0,0 +1,152 @@
+package web
+
+import (
+	"errors"
+	service_mocks "github.com/antonchaban/news-aggregator/pkg/handler/web/mocks"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_getFetchRuns(t *testing.T) {
+	type mockBehavior func(r *service_mocks.MockSourceService)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedCode         int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(r *service_mocks.MockSourceService) {
+				r.EXPECT().GetFetchRuns(gomock.Any(), defaultFetchRunLimit).Return([]model.FetchRun{{Id: 1, StartedAt: start,
+					FinishedAt: start.Add(time.Second), Sources: 1, Failed: 1, Attempts: []model.FetchAttempt{{Id: 2, RunId: 1,
+						SourceId: 3, SourceName: "bbc", StartedAt: start, FinishedAt: start.Add(time.Second),
+						Status: model.FetchFailed, HTTPStatus: 503, Error: "503 Service Unavailable"}}}}, nil)
+			},
+			expectedCode: 200,
+			expectedResponseBody: `[{"id":1,"started_at":"2024-06-01T12:00:00Z","finished_at":"2024-06-01T12:00:01Z","sources":1,"failed":1,` +
+				`"inserted":0,"updated":0,"attempts":[{"id":2,"run_id":1,"source_id":3,"source_name":"bbc",` +
+				`"started_at":"2024-06-01T12:00:00Z","finished_at":"2024-06-01T12:00:01Z","status":"failed","http_status":503,` +
+				`"bytes":0,"articles":0,"inserted":0,"updated":0,"error":"503 Service Unavailable"}]}]`,
+		},
+		{
+			name:  "Limit",
+			query: "?limit=5",
+			mockBehavior: func(r *service_mocks.MockSourceService) {
+				r.EXPECT().GetFetchRuns(gomock.Any(), 5).Return([]model.FetchRun{}, nil)
+			},
+			expectedCode:         200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:                 "BadRequest",
+			query:                "?limit=0",
+			mockBehavior:         func(r *service_mocks.MockSourceService) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"limit must be between 1 and 1000"}`,
+		},
+		{
+			name: "InternalServerError",
+			mockBehavior: func(r *service_mocks.MockSourceService) {
+				r.EXPECT().GetFetchRuns(gomock.Any(), defaultFetchRunLimit).Return(nil, errors.New("connection reset"))
+			},
+			expectedCode:         500,
+			expectedResponseBody: `{"message":"connection reset"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			srcSvc := service_mocks.NewMockSourceService(c)
+			test.mockBehavior(srcSvc)
+
+			r := gin.New()
+			r.GET("/fetch-runs", NewHandler(nil, srcSvc, nil, nil).getFetchRuns)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/fetch-runs"+test.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getSourceFetchRuns(t *testing.T) {
+	type mockBehavior func(r *service_mocks.MockSourceService)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                 string
+		path                 string
+		mockBehavior         mockBehavior
+		expectedCode         int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			path: "/sources/3/fetch-runs?limit=10",
+			mockBehavior: func(r *service_mocks.MockSourceService) {
+				r.EXPECT().GetSourceFetchRuns(gomock.Any(), 3, 10).Return([]model.FetchAttempt{{Id: 2, RunId: 1, SourceId: 3,
+					SourceName: "bbc", StartedAt: start, FinishedAt: start.Add(time.Second), Status: model.FetchOK,
+					HTTPStatus: 200, Bytes: 2048, Articles: 10, Inserted: 2}}, nil)
+			},
+			expectedCode: 200,
+			expectedResponseBody: `[{"id":2,"run_id":1,"source_id":3,"source_name":"bbc","started_at":"2024-06-01T12:00:00Z",` +
+				`"finished_at":"2024-06-01T12:00:01Z","status":"ok","http_status":200,"bytes":2048,"articles":10,"inserted":2,"updated":0}]`,
+		},
+		{
+			name:                 "Invalid ID",
+			path:                 "/sources/abc/fetch-runs",
+			mockBehavior:         func(r *service_mocks.MockSourceService) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"strconv.Atoi: parsing \"abc\": invalid syntax"}`,
+		},
+		{
+			name:                 "Invalid limit",
+			path:                 "/sources/3/fetch-runs?limit=many",
+			mockBehavior:         func(r *service_mocks.MockSourceService) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"strconv.Atoi: parsing \"many\": invalid syntax"}`,
+		},
+		{
+			name: "InternalServerError",
+			path: "/sources/3/fetch-runs",
+			mockBehavior: func(r *service_mocks.MockSourceService) {
+				r.EXPECT().GetSourceFetchRuns(gomock.Any(), 3, defaultFetchRunLimit).Return(nil, errors.New("connection reset"))
+			},
+			expectedCode:         500,
+			expectedResponseBody: `{"message":"connection reset"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			srcSvc := service_mocks.NewMockSourceService(c)
+			test.mockBehavior(srcSvc)
+
+			r := gin.New()
+			r.GET("/sources/:id/fetch-runs", NewHandler(nil, srcSvc, nil, nil).getSourceFetchRuns)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.path, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -6,10 +6,12 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-// Handler represents the handler with article and source services.
+// Handler represents the handler with article, source and retention services and the scheduler.
 type Handler struct {
-	articleService ArticleService
-	srcService     SourceService
+	articleService   ArticleService
+	srcService       SourceService
+	retentionService RetentionService
+	schedulerService SchedulerService
 }
 
 // SrcService returns the source service.
@@ -23,9 +25,12 @@ func (h *Handler) ArticleService() ArticleService {
 }
 
 // NewHandler creates a new Handler instance.
-func NewHandler(asvc ArticleService, ss SourceService) *Handler {
+// A nil scheduler means the server doesn't run one, so its routes aren't registered.
+func NewHandler(asvc ArticleService, ss SourceService, rs RetentionService, sched SchedulerService) *Handler {
 	h := &Handler{articleService: asvc,
-		srcService: ss}
+		srcService:       ss,
+		retentionService: rs,
+		schedulerService: sched}
 	return h
 }
 
@@ -38,7 +43,10 @@ func (h *Handler) InitRoutes() *gin.Engine {
 	articles := router.Group("/articles")
 	{
 		articles.GET("", h.getArticlesByFilter)
+		articles.POST("/prune", h.pruneArticles)
 	}
+	router.GET("/export", h.exportArticles)
+	router.POST("/import", h.importArticles)
 	sources := router.Group("/sources")
 	{
 		sources.GET("/:id", h.fetchSrcById)
@@ -46,6 +54,17 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		sources.DELETE("/:id", h.deleteSource)
 		sources.PUT("/:id", h.updateSource)
 		sources.GET("", h.getAllSources)
+		sources.GET("/:id/fetch-runs", h.getSourceFetchRuns)
+	}
+	router.GET("/fetch-runs", h.getFetchRuns)
+	if h.schedulerService != nil {
+		scheduler := router.Group("/scheduler")
+		{
+			scheduler.GET("", h.getSchedulerStatus)
+			scheduler.POST("/pause", h.pauseScheduler)
+			scheduler.POST("/resume", h.resumeScheduler)
+			scheduler.POST("/run", h.runScheduler)
+		}
 	}
 	return router
 }
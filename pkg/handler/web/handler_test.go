@@ -2,6 +2,7 @@ package web
 
 import (
 	service_mocks "github.com/antonchaban/news-aggregator/pkg/handler/web/mocks"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -10,7 +11,7 @@ func TestHandler_ArticleService(t *testing.T) {
 	mockArticleService := new(service_mocks.MockArticleService)
 	mockSourceService := new(service_mocks.MockSourceService)
 
-	h := NewHandler(mockArticleService, mockSourceService)
+	h := NewHandler(mockArticleService, mockSourceService, nil, nil)
 
 	assert.Equal(t, mockArticleService, h.ArticleService())
 }
@@ -19,14 +20,14 @@ func TestHandler_InitRoutes(t *testing.T) {
 	mockArticleService := new(service_mocks.MockArticleService)
 	mockSourceService := new(service_mocks.MockSourceService)
 
-	h := NewHandler(mockArticleService, mockSourceService)
+	h := NewHandler(mockArticleService, mockSourceService, nil, nil)
 	router := h.InitRoutes()
 
 	assert.NotNil(t, router)
 
 	// Check if the routes are properly set up
 	routes := router.Routes()
-	expectedRoutes := []string{"/swagger/*any", "/articles", "/sources/:id", "/sources", "/sources/:id", "/sources/:id"}
+	expectedRoutes := []string{"/swagger/*any", "/articles", "/articles/prune", "/sources/:id", "/sources", "/sources/:id", "/sources/:id"}
 
 	for _, route := range expectedRoutes {
 		found := false
@@ -40,11 +41,33 @@ func TestHandler_InitRoutes(t *testing.T) {
 	}
 }
 
+func TestHandler_InitRoutes_Scheduler(t *testing.T) {
+	schedulerRoutes := []string{"/scheduler", "/scheduler/pause", "/scheduler/resume", "/scheduler/run"}
+
+	// The scheduler routes are registered only if the server runs a scheduler
+	withoutScheduler := NewHandler(nil, nil, nil, nil).InitRoutes()
+	withScheduler := NewHandler(nil, nil, nil, new(service_mocks.MockSchedulerService)).InitRoutes()
+
+	for _, route := range schedulerRoutes {
+		assert.False(t, hasRoute(withoutScheduler.Routes(), route), "Route %s found", route)
+		assert.True(t, hasRoute(withScheduler.Routes(), route), "Route %s not found", route)
+	}
+}
+
+func hasRoute(routes gin.RoutesInfo, path string) bool {
+	for _, r := range routes {
+		if r.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
 func TestHandler_SrcService(t *testing.T) {
 	mockArticleService := new(service_mocks.MockArticleService)
 	mockSourceService := new(service_mocks.MockSourceService)
 
-	h := NewHandler(mockArticleService, mockSourceService)
+	h := NewHandler(mockArticleService, mockSourceService, nil, nil)
 
 	assert.Equal(t, mockSourceService, h.SrcService())
 }
@@ -53,7 +76,7 @@ func TestNewHandler(t *testing.T) {
 	mockArticleService := new(service_mocks.MockArticleService)
 	mockSourceService := new(service_mocks.MockSourceService)
 
-	h := NewHandler(mockArticleService, mockSourceService)
+	h := NewHandler(mockArticleService, mockSourceService, nil, nil)
 
 	assert.Equal(t, mockArticleService, h.ArticleService())
 	assert.Equal(t, mockSourceService, h.SrcService())
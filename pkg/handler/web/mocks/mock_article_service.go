@@ -10,6 +10,8 @@
 package mocks
 
 import (
+	context "context"
+	io "io"
 	reflect "reflect"
 
 	filter "github.com/antonchaban/news-aggregator/pkg/filter"
@@ -41,74 +43,134 @@ func (m *MockArticleService) EXPECT() *MockArticleServiceMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockArticleService) Create(arg0 model.Article) (model.Article, error) {
+func (m *MockArticleService) Create(arg0 context.Context, arg1 model.Article) (model.Article, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0)
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
 	ret0, _ := ret[0].(model.Article)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockArticleServiceMockRecorder) Create(arg0 any) *gomock.Call {
+func (mr *MockArticleServiceMockRecorder) Create(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockArticleService)(nil).Create), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockArticleService)(nil).Create), arg0, arg1)
 }
 
 // Delete mocks base method.
-func (m *MockArticleService) Delete(arg0 int) error {
+func (m *MockArticleService) Delete(arg0 context.Context, arg1 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockArticleServiceMockRecorder) Delete(arg0 any) *gomock.Call {
+func (mr *MockArticleServiceMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockArticleService)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockArticleService)(nil).Delete), arg0, arg1)
+}
+
+// Each mocks base method.
+func (m *MockArticleService) Each(arg0 context.Context, arg1 func(model.Article) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Each", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Each indicates an expected call of Each.
+func (mr *MockArticleServiceMockRecorder) Each(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Each", reflect.TypeOf((*MockArticleService)(nil).Each), arg0, arg1)
+}
+
+// Export mocks base method.
+func (m *MockArticleService) Export(arg0 context.Context, arg1 io.Writer, arg2 func(int)) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockArticleServiceMockRecorder) Export(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockArticleService)(nil).Export), arg0, arg1, arg2)
 }
 
 // GetAll mocks base method.
-func (m *MockArticleService) GetAll() ([]model.Article, error) {
+func (m *MockArticleService) GetAll(arg0 context.Context) ([]model.Article, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAll", arg0)
 	ret0, _ := ret[0].([]model.Article)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockArticleServiceMockRecorder) GetAll() *gomock.Call {
+func (mr *MockArticleServiceMockRecorder) GetAll(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockArticleService)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockArticleService)(nil).GetAll), arg0)
 }
 
 // GetByFilter mocks base method.
-func (m *MockArticleService) GetByFilter(arg0 filter.Filters) ([]model.Article, error) {
+func (m *MockArticleService) GetByFilter(arg0 context.Context, arg1 filter.Filters) ([]model.Article, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByFilter", arg0)
+	ret := m.ctrl.Call(m, "GetByFilter", arg0, arg1)
 	ret0, _ := ret[0].([]model.Article)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByFilter indicates an expected call of GetByFilter.
-func (mr *MockArticleServiceMockRecorder) GetByFilter(arg0 any) *gomock.Call {
+func (mr *MockArticleServiceMockRecorder) GetByFilter(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByFilter", reflect.TypeOf((*MockArticleService)(nil).GetByFilter), arg0, arg1)
+}
+
+// GetPage mocks base method.
+func (m *MockArticleService) GetPage(arg0 context.Context, arg1 filter.Filters, arg2 filter.Page) (model.ArticlePage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.ArticlePage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPage indicates an expected call of GetPage.
+func (mr *MockArticleServiceMockRecorder) GetPage(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockArticleService)(nil).GetPage), arg0, arg1, arg2)
+}
+
+// Import mocks base method.
+func (m *MockArticleService) Import(arg0 context.Context, arg1 io.Reader, arg2 func(model.ImportReport)) (model.ImportReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.ImportReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockArticleServiceMockRecorder) Import(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByFilter", reflect.TypeOf((*MockArticleService)(nil).GetByFilter), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockArticleService)(nil).Import), arg0, arg1, arg2)
 }
 
 // SaveAll mocks base method.
-func (m *MockArticleService) SaveAll(arg0 []model.Article) error {
+func (m *MockArticleService) SaveAll(arg0 context.Context, arg1 []model.Article) (model.SaveResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveAll", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "SaveAll", arg0, arg1)
+	ret0, _ := ret[0].(model.SaveResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SaveAll indicates an expected call of SaveAll.
-func (mr *MockArticleServiceMockRecorder) SaveAll(arg0 any) *gomock.Call {
+func (mr *MockArticleServiceMockRecorder) SaveAll(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockArticleService)(nil).SaveAll), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockArticleService)(nil).SaveAll), arg0, arg1)
 }
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/antonchaban/news-aggregator/pkg/handler/web (interfaces: RetentionService)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/mock_retention_service.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/web RetentionService
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/antonchaban/news-aggregator/pkg/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRetentionService is a mock of RetentionService interface.
+type MockRetentionService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRetentionServiceMockRecorder
+}
+
+// MockRetentionServiceMockRecorder is the mock recorder for MockRetentionService.
+type MockRetentionServiceMockRecorder struct {
+	mock *MockRetentionService
+}
+
+// NewMockRetentionService creates a new mock instance.
+func NewMockRetentionService(ctrl *gomock.Controller) *MockRetentionService {
+	mock := &MockRetentionService{ctrl: ctrl}
+	mock.recorder = &MockRetentionServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRetentionService) EXPECT() *MockRetentionServiceMockRecorder {
+	return m.recorder
+}
+
+// Prune mocks base method.
+func (m *MockRetentionService) Prune(arg0 context.Context, arg1 bool) (model.PruneReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Prune", arg0, arg1)
+	ret0, _ := ret[0].(model.PruneReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Prune indicates an expected call of Prune.
+func (mr *MockRetentionServiceMockRecorder) Prune(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Prune", reflect.TypeOf((*MockRetentionService)(nil).Prune), arg0, arg1)
+}
@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/antonchaban/news-aggregator/pkg/handler/web (interfaces: SchedulerService)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/mock_scheduler_service.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/web SchedulerService
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/antonchaban/news-aggregator/pkg/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSchedulerService is a mock of SchedulerService interface.
+type MockSchedulerService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSchedulerServiceMockRecorder
+}
+
+// MockSchedulerServiceMockRecorder is the mock recorder for MockSchedulerService.
+type MockSchedulerServiceMockRecorder struct {
+	mock *MockSchedulerService
+}
+
+// NewMockSchedulerService creates a new mock instance.
+func NewMockSchedulerService(ctrl *gomock.Controller) *MockSchedulerService {
+	mock := &MockSchedulerService{ctrl: ctrl}
+	mock.recorder = &MockSchedulerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSchedulerService) EXPECT() *MockSchedulerServiceMockRecorder {
+	return m.recorder
+}
+
+// Pause mocks base method.
+func (m *MockSchedulerService) Pause() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Pause")
+}
+
+// Pause indicates an expected call of Pause.
+func (mr *MockSchedulerServiceMockRecorder) Pause() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pause", reflect.TypeOf((*MockSchedulerService)(nil).Pause))
+}
+
+// Resume mocks base method.
+func (m *MockSchedulerService) Resume() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Resume")
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockSchedulerServiceMockRecorder) Resume() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockSchedulerService)(nil).Resume))
+}
+
+// RunNow mocks base method.
+func (m *MockSchedulerService) RunNow(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunNow", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunNow indicates an expected call of RunNow.
+func (mr *MockSchedulerServiceMockRecorder) RunNow(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunNow", reflect.TypeOf((*MockSchedulerService)(nil).RunNow), arg0)
+}
+
+// Status mocks base method.
+func (m *MockSchedulerService) Status() model.SchedulerStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status")
+	ret0, _ := ret[0].(model.SchedulerStatus)
+	return ret0
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockSchedulerServiceMockRecorder) Status() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockSchedulerService)(nil).Status))
+}
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	model "github.com/antonchaban/news-aggregator/pkg/model"
@@ -40,76 +41,122 @@ func (m *MockSourceService) EXPECT() *MockSourceServiceMockRecorder {
 }
 
 // AddSource mocks base method.
-func (m *MockSourceService) AddSource(arg0 model.Source) (model.Source, error) {
+func (m *MockSourceService) AddSource(arg0 context.Context, arg1 model.Source) (model.Source, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddSource", arg0)
+	ret := m.ctrl.Call(m, "AddSource", arg0, arg1)
 	ret0, _ := ret[0].(model.Source)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AddSource indicates an expected call of AddSource.
-func (mr *MockSourceServiceMockRecorder) AddSource(arg0 any) *gomock.Call {
+func (mr *MockSourceServiceMockRecorder) AddSource(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSource", reflect.TypeOf((*MockSourceService)(nil).AddSource), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSource", reflect.TypeOf((*MockSourceService)(nil).AddSource), arg0, arg1)
 }
 
 // DeleteSource mocks base method.
-func (m *MockSourceService) DeleteSource(arg0 int) error {
+func (m *MockSourceService) DeleteSource(arg0 context.Context, arg1 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSource", arg0)
+	ret := m.ctrl.Call(m, "DeleteSource", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteSource indicates an expected call of DeleteSource.
-func (mr *MockSourceServiceMockRecorder) DeleteSource(arg0 any) *gomock.Call {
+func (mr *MockSourceServiceMockRecorder) DeleteSource(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSource", reflect.TypeOf((*MockSourceService)(nil).DeleteSource), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSource", reflect.TypeOf((*MockSourceService)(nil).DeleteSource), arg0, arg1)
 }
 
 // FetchFromAllSources mocks base method.
-func (m *MockSourceService) FetchFromAllSources() error {
+func (m *MockSourceService) FetchFromAllSources(arg0 context.Context) (model.FetchReport, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FetchFromAllSources")
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "FetchFromAllSources", arg0)
+	ret0, _ := ret[0].(model.FetchReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // FetchFromAllSources indicates an expected call of FetchFromAllSources.
-func (mr *MockSourceServiceMockRecorder) FetchFromAllSources() *gomock.Call {
+func (mr *MockSourceServiceMockRecorder) FetchFromAllSources(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchFromAllSources", reflect.TypeOf((*MockSourceService)(nil).FetchFromAllSources), arg0)
+}
+
+// FetchSource mocks base method.
+func (m *MockSourceService) FetchSource(arg0 context.Context, arg1 int) (model.SourceFetchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchSource", arg0, arg1)
+	ret0, _ := ret[0].(model.SourceFetchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchSource indicates an expected call of FetchSource.
+func (mr *MockSourceServiceMockRecorder) FetchSource(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchFromAllSources", reflect.TypeOf((*MockSourceService)(nil).FetchFromAllSources))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSource", reflect.TypeOf((*MockSourceService)(nil).FetchSource), arg0, arg1)
 }
 
 // FetchSourceByID mocks base method.
-func (m *MockSourceService) FetchSourceByID(arg0 int) ([]model.Article, error) {
+func (m *MockSourceService) FetchSourceByID(arg0 context.Context, arg1 int) ([]model.Article, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FetchSourceByID", arg0)
+	ret := m.ctrl.Call(m, "FetchSourceByID", arg0, arg1)
 	ret0, _ := ret[0].([]model.Article)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FetchSourceByID indicates an expected call of FetchSourceByID.
-func (mr *MockSourceServiceMockRecorder) FetchSourceByID(arg0 any) *gomock.Call {
+func (mr *MockSourceServiceMockRecorder) FetchSourceByID(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSourceByID", reflect.TypeOf((*MockSourceService)(nil).FetchSourceByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSourceByID", reflect.TypeOf((*MockSourceService)(nil).FetchSourceByID), arg0, arg1)
 }
 
 // GetAll mocks base method.
-func (m *MockSourceService) GetAll() ([]model.Source, error) {
+func (m *MockSourceService) GetAll(arg0 context.Context) ([]model.Source, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAll", arg0)
 	ret0, _ := ret[0].([]model.Source)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockSourceServiceMockRecorder) GetAll() *gomock.Call {
+func (mr *MockSourceServiceMockRecorder) GetAll(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockSourceService)(nil).GetAll), arg0)
+}
+
+// GetFetchRuns mocks base method.
+func (m *MockSourceService) GetFetchRuns(arg0 context.Context, arg1 int) ([]model.FetchRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFetchRuns", arg0, arg1)
+	ret0, _ := ret[0].([]model.FetchRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFetchRuns indicates an expected call of GetFetchRuns.
+func (mr *MockSourceServiceMockRecorder) GetFetchRuns(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFetchRuns", reflect.TypeOf((*MockSourceService)(nil).GetFetchRuns), arg0, arg1)
+}
+
+// GetSourceFetchRuns mocks base method.
+func (m *MockSourceService) GetSourceFetchRuns(arg0 context.Context, arg1, arg2 int) ([]model.FetchAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSourceFetchRuns", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.FetchAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSourceFetchRuns indicates an expected call of GetSourceFetchRuns.
+func (mr *MockSourceServiceMockRecorder) GetSourceFetchRuns(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockSourceService)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSourceFetchRuns", reflect.TypeOf((*MockSourceService)(nil).GetSourceFetchRuns), arg0, arg1, arg2)
 }
 
 // LoadDataFromFiles mocks base method.
@@ -128,16 +175,16 @@ func (mr *MockSourceServiceMockRecorder) LoadDataFromFiles() *gomock.Call {
 }
 
 // UpdateSource mocks base method.
-func (m *MockSourceService) UpdateSource(arg0 int, arg1 model.Source) (model.Source, error) {
+func (m *MockSourceService) UpdateSource(arg0 context.Context, arg1 int, arg2 model.Source) (model.Source, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateSource", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateSource", arg0, arg1, arg2)
 	ret0, _ := ret[0].(model.Source)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateSource indicates an expected call of UpdateSource.
-func (mr *MockSourceServiceMockRecorder) UpdateSource(arg0, arg1 any) *gomock.Call {
+func (mr *MockSourceServiceMockRecorder) UpdateSource(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSource", reflect.TypeOf((*MockSourceService)(nil).UpdateSource), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSource", reflect.TypeOf((*MockSourceService)(nil).UpdateSource), arg0, arg1, arg2)
 }
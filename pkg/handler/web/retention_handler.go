@@ -0,0 +1,44 @@
+package web
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+//go:generate mockgen -destination=mocks/mock_retention_service.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/web RetentionService
+
+// RetentionService represents the service pruning the articles beyond the retention policies.
+type RetentionService interface {
+	Prune(ctx context.Context, dryRun bool) (model.PruneReport, error)
+}
+
+// @Summary Prune expired articles
+// @Description Delete the articles beyond the retention policy of their source or the global one, archiving them first if an archive directory is configured. A dry run only lists the expired articles
+// @Tags articles
+// @ID prune-articles
+// @Produce json
+// @Param dry_run query bool false "List the expired articles without deleting them" default(false)
+// @Success 200 {object} model.PruneReport
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /articles/prune [post]
+func (h *Handler) pruneArticles(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	report, err := h.retentionService.Prune(c.Request.Context(), dryRun)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
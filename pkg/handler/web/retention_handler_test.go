@@ -0,0 +1,79 @@
+package web
+
+import (
+	"errors"
+	service_mocks "github.com/antonchaban/news-aggregator/pkg/handler/web/mocks"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_pruneArticles(t *testing.T) {
+	type mockBehavior func(r *service_mocks.MockRetentionService)
+	tests := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedCode         int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(r *service_mocks.MockRetentionService) {
+				r.EXPECT().Prune(gomock.Any(), false).Return(model.PruneReport{Archive: "articles.ndjson.gz",
+					Sources: []model.SourcePruneResult{{Source: model.Source{Id: 1, ShortName: "bbc"},
+						Policy: model.RetentionPolicy{MaxCount: 100}, Pruned: 2}}}, nil)
+			},
+			expectedCode: 200,
+			expectedResponseBody: `{"dry_run":false,"archive":"articles.ndjson.gz","sources":[{"source":{"id":1,"name":"","link":"","short_name":"bbc"},` +
+				`"policy":{"max_count":100},"pruned":2}]}`,
+		},
+		{
+			name:  "Dry run",
+			query: "?dry_run=true",
+			mockBehavior: func(r *service_mocks.MockRetentionService) {
+				r.EXPECT().Prune(gomock.Any(), true).Return(model.PruneReport{DryRun: true}, nil)
+			},
+			expectedCode:         200,
+			expectedResponseBody: `{"dry_run":true,"sources":null}`,
+		},
+		{
+			name:                 "BadRequest",
+			query:                "?dry_run=maybe",
+			mockBehavior:         func(r *service_mocks.MockRetentionService) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"strconv.ParseBool: parsing \"maybe\": invalid syntax"}`,
+		},
+		{
+			name: "InternalServerError",
+			mockBehavior: func(r *service_mocks.MockRetentionService) {
+				r.EXPECT().Prune(gomock.Any(), false).Return(model.PruneReport{}, errors.New("read-only file system"))
+			},
+			expectedCode:         500,
+			expectedResponseBody: `{"message":"read-only file system"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			retentionSvc := service_mocks.NewMockRetentionService(c)
+			test.mockBehavior(retentionSvc)
+
+			r := gin.New()
+			r.POST("/articles/prune", NewHandler(nil, nil, retentionSvc, nil).pruneArticles)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/articles/prune"+test.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+//go:generate mockgen -destination=mocks/mock_scheduler_service.go -package=mocks github.com/antonchaban/news-aggregator/pkg/handler/web SchedulerService
+
+// SchedulerService represents the scheduler fetching the sources on their schedules.
+type SchedulerService interface {
+	Pause()
+	Resume()
+	RunNow(ctx context.Context) error
+	Status() model.SchedulerStatus
+}
+
+// @Summary Get scheduler status
+// @Description Get whether the scheduler is paused, when the next source is due and the latest fetch of every scheduled source
+// @Tags scheduler
+// @ID get-scheduler-status
+// @Produce json
+// @Success 200 {object} model.SchedulerStatus
+// @Router /scheduler [get]
+func (h *Handler) getSchedulerStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.schedulerService.Status())
+}
+
+// @Summary Pause scheduler
+// @Description Skip the scheduled fetches until the scheduler is resumed. The fetches in progress run to completion
+// @Tags scheduler
+// @ID pause-scheduler
+// @Produce json
+// @Success 200 {object} model.SchedulerStatus
+// @Router /scheduler/pause [post]
+func (h *Handler) pauseScheduler(c *gin.Context) {
+	h.schedulerService.Pause()
+	c.JSON(http.StatusOK, h.schedulerService.Status())
+}
+
+// @Summary Resume scheduler
+// @Description Run the scheduled fetches again after the scheduler was paused
+// @Tags scheduler
+// @ID resume-scheduler
+// @Produce json
+// @Success 200 {object} model.SchedulerStatus
+// @Router /scheduler/resume [post]
+func (h *Handler) resumeScheduler(c *gin.Context) {
+	h.schedulerService.Resume()
+	c.JSON(http.StatusOK, h.schedulerService.Status())
+}
+
+// @Summary Run scheduler now
+// @Description Immediately fetch all scheduled sources without waiting for the fetches to complete. The results show up in the status once they do
+// @Tags scheduler
+// @ID run-scheduler
+// @Produce json
+// @Success 202 {object} model.SchedulerStatus
+// @Failure 409 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /scheduler/run [post]
+func (h *Handler) runScheduler(c *gin.Context) {
+	if h.schedulerService.Status().Paused {
+		newErrorResponse(c, http.StatusConflict, "scheduler is paused")
+		return
+	}
+	if err := h.schedulerService.RunNow(c.Request.Context()); err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusAccepted, h.schedulerService.Status())
+}
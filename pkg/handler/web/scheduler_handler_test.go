@@ -0,0 +1,120 @@
+package web
+
+import (
+	"errors"
+	service_mocks "github.com/antonchaban/news-aggregator/pkg/handler/web/mocks"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_scheduler(t *testing.T) {
+	nextRun := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	status := model.SchedulerStatus{Sources: 2, NextRun: &nextRun}
+	statusBody := `{"paused":false,"sources":2,"next_run":"2024-05-01T12:00:00Z",` +
+		`"last_report":{"started_at":"0001-01-01T00:00:00Z","duration":0,"sources":null}}`
+
+	type mockBehavior func(r *service_mocks.MockSchedulerService)
+	tests := []struct {
+		name                 string
+		method               string
+		path                 string
+		mockBehavior         mockBehavior
+		expectedCode         int
+		expectedResponseBody string
+	}{
+		{
+			name:   "Status",
+			method: "GET",
+			path:   "/scheduler",
+			mockBehavior: func(r *service_mocks.MockSchedulerService) {
+				r.EXPECT().Status().Return(status)
+			},
+			expectedCode:         200,
+			expectedResponseBody: statusBody,
+		},
+		{
+			name:   "Pause",
+			method: "POST",
+			path:   "/scheduler/pause",
+			mockBehavior: func(r *service_mocks.MockSchedulerService) {
+				r.EXPECT().Pause()
+				r.EXPECT().Status().Return(model.SchedulerStatus{Paused: true})
+			},
+			expectedCode: 200,
+			expectedResponseBody: `{"paused":true,"sources":0,` +
+				`"last_report":{"started_at":"0001-01-01T00:00:00Z","duration":0,"sources":null}}`,
+		},
+		{
+			name:   "Resume",
+			method: "POST",
+			path:   "/scheduler/resume",
+			mockBehavior: func(r *service_mocks.MockSchedulerService) {
+				r.EXPECT().Resume()
+				r.EXPECT().Status().Return(status)
+			},
+			expectedCode:         200,
+			expectedResponseBody: statusBody,
+		},
+		{
+			name:   "Run",
+			method: "POST",
+			path:   "/scheduler/run",
+			mockBehavior: func(r *service_mocks.MockSchedulerService) {
+				r.EXPECT().Status().Return(status).Times(2)
+				r.EXPECT().RunNow(gomock.Any()).Return(nil)
+			},
+			expectedCode:         202,
+			expectedResponseBody: statusBody,
+		},
+		{
+			name:   "Run paused",
+			method: "POST",
+			path:   "/scheduler/run",
+			mockBehavior: func(r *service_mocks.MockSchedulerService) {
+				r.EXPECT().Status().Return(model.SchedulerStatus{Paused: true})
+			},
+			expectedCode:         409,
+			expectedResponseBody: `{"message":"scheduler is paused"}`,
+		},
+		{
+			name:   "Run error",
+			method: "POST",
+			path:   "/scheduler/run",
+			mockBehavior: func(r *service_mocks.MockSchedulerService) {
+				r.EXPECT().Status().Return(status)
+				r.EXPECT().RunNow(gomock.Any()).Return(errors.New("connection refused"))
+			},
+			expectedCode:         500,
+			expectedResponseBody: `{"message":"connection refused"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			schedulerSvc := service_mocks.NewMockSchedulerService(c)
+			test.mockBehavior(schedulerSvc)
+
+			h := NewHandler(nil, nil, nil, schedulerSvc)
+			r := gin.New()
+			r.GET("/scheduler", h.getSchedulerStatus)
+			r.POST("/scheduler/pause", h.pauseScheduler)
+			r.POST("/scheduler/resume", h.resumeScheduler)
+			r.POST("/scheduler/run", h.runScheduler)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.path, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package web
 
 import (
+	"context"
+	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	_ "github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/parser"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
@@ -12,13 +15,16 @@ import (
 
 // SourceService represents the service for sources.
 type SourceService interface {
-	FetchFromAllSources() error
-	FetchSourceByID(id int) ([]model.Article, error)
+	FetchFromAllSources(ctx context.Context) (model.FetchReport, error)
+	FetchSource(ctx context.Context, id int) (model.SourceFetchResult, error)
+	FetchSourceByID(ctx context.Context, id int) ([]model.Article, error)
 	LoadDataFromFiles() ([]model.Article, error)
-	AddSource(source model.Source) (model.Source, error)
-	DeleteSource(id int) error
-	UpdateSource(id int, source model.Source) (model.Source, error)
-	GetAll() ([]model.Source, error)
+	AddSource(ctx context.Context, source model.Source) (model.Source, error)
+	DeleteSource(ctx context.Context, id int) error
+	UpdateSource(ctx context.Context, id int, source model.Source) (model.Source, error)
+	GetAll(ctx context.Context) ([]model.Source, error)
+	GetFetchRuns(ctx context.Context, limit int) ([]model.FetchRun, error)
+	GetSourceFetchRuns(ctx context.Context, sourceID int, limit int) ([]model.FetchAttempt, error)
 }
 
 // @Summary Fetch source by ID
@@ -38,7 +44,7 @@ func (h *Handler) fetchSrcById(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	fetchedArticles, err := h.SrcService().FetchSourceByID(id)
+	fetchedArticles, err := h.SrcService().FetchSourceByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -47,7 +53,7 @@ func (h *Handler) fetchSrcById(c *gin.Context) {
 }
 
 // @Summary Create a new source
-// @Description Create a new source
+// @Description Create a new source. HTML pages without a feed need a scrape config with the CSS selectors of their articles
 // @Tags sources
 // @ID create-source
 // @Accept json
@@ -63,7 +69,13 @@ func (h *Handler) createSource(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	sources, err := h.SrcService().AddSource(input)
+	if err := validateSource(input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	// The health is maintained by the fetches
+	input.Health = nil
+	sources, err := h.SrcService().AddSource(c.Request.Context(), input)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -88,7 +100,7 @@ func (h *Handler) deleteSource(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	err = h.SrcService().DeleteSource(id)
+	err = h.SrcService().DeleteSource(c.Request.Context(), id)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -97,7 +109,7 @@ func (h *Handler) deleteSource(c *gin.Context) {
 }
 
 // @Summary Update source by ID
-// @Description Update source by ID
+// @Description Update source by ID, including its fetch schedule. The scheduler picks up the new schedule on its next sync. Updating a source resets its health, enabling it again if it was disabled after failing too many times
 // @Tags sources
 // @ID update-source-by-id
 // @Accept json
@@ -114,12 +126,16 @@ func (h *Handler) updateSource(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := validateSource(input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	sources, err := h.srcService.UpdateSource(id, input)
+	sources, err := h.srcService.UpdateSource(c.Request.Context(), id, input)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -128,7 +144,7 @@ func (h *Handler) updateSource(c *gin.Context) {
 }
 
 // @Summary Get all sources
-// @Description Gets all currently available sources for fetching news
+// @Description Gets all currently available sources for fetching news, along with the health of their fetches
 // @Tags sources
 // @ID get-all-sources
 // @Accept json
@@ -138,10 +154,25 @@ func (h *Handler) updateSource(c *gin.Context) {
 // @Failure 500 {object} errorResponse
 // @Router /sources [get]
 func (h *Handler) getAllSources(c *gin.Context) {
-	sources, err := h.SrcService().GetAll()
+	sources, err := h.SrcService().GetAll(c.Request.Context())
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, sources)
 }
+
+// validateSource checks the scraping config and the fetch schedule of the source, if it has them.
+func validateSource(src model.Source) error {
+	if src.Scrape != nil {
+		if err := parser.ValidateScrapeConfig(*src.Scrape); err != nil {
+			return fmt.Errorf("invalid scrape config: %w", err)
+		}
+	}
+	if src.Schedule != nil {
+		if err := src.Schedule.Validate(); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+	return nil
+}
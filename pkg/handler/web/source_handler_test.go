@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHandler_fetchSrcById(t *testing.T) {
@@ -26,7 +27,7 @@ func TestHandler_fetchSrcById(t *testing.T) {
 		{
 			name: "OK",
 			mockBehavior: func(r *service_mocks.MockSourceService, id int) {
-				r.EXPECT().FetchSourceByID(id).Return([]model.Article{
+				r.EXPECT().FetchSourceByID(gomock.Any(), id).Return([]model.Article{
 					{
 						Id:          1,
 						Title:       "Title1",
@@ -48,7 +49,7 @@ func TestHandler_fetchSrcById(t *testing.T) {
 				}, nil)
 			},
 			expectedCode:         200,
-			expectedResponseBody: `[{"Id":1,"Title":"Title1","Description":"Description1","Link":"Link1","Source":{"id":0,"name":"CNN","link":"","short_name":""},"PubDate":"0001-01-01T00:00:00Z"},{"Id":2,"Title":"Title2","Description":"Description2","Link":"Link2","Source":{"id":0,"name":"CNN","link":"","short_name":""},"PubDate":"0001-01-01T00:00:00Z"}]`,
+			expectedResponseBody: `[{"id":1,"title":"Title1","description":"Description1","link":"Link1","source":{"id":0,"name":"CNN","link":"","short_name":""},"pub_date":"0001-01-01T00:00:00Z"},{"id":2,"title":"Title2","description":"Description2","link":"Link2","source":{"id":0,"name":"CNN","link":"","short_name":""},"pub_date":"0001-01-01T00:00:00Z"}]`,
 			inputID:              "1",
 		},
 		{
@@ -73,7 +74,7 @@ func TestHandler_fetchSrcById(t *testing.T) {
 
 			// Init Endpoint
 			r := gin.New()
-			r.GET("/source/:id", NewHandler(nil, srcSvc).fetchSrcById)
+			r.GET("/source/:id", NewHandler(nil, srcSvc, nil, nil).fetchSrcById)
 
 			// Create Request
 			w := httptest.NewRecorder()
@@ -101,12 +102,22 @@ func TestHandler_createSource(t *testing.T) {
 		{
 			name: "OK",
 			mockBehavior: func(r *service_mocks.MockSourceService, src model.Source) {
-				r.EXPECT().AddSource(src).Return(src, nil)
+				r.EXPECT().AddSource(gomock.Any(), src).Return(src, nil)
 			},
 			expectedCode:         200,
 			expectedResponseBody: `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn"}`,
 			inputBody:            `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn"}`,
 		},
+		{
+			name: "OK with scrape config",
+			mockBehavior: func(r *service_mocks.MockSourceService, src model.Source) {
+				src.Scrape = &model.ScrapeConfig{ArticleSelector: "div.story", DateFormats: []string{"Jan 02, 2006"}}
+				r.EXPECT().AddSource(gomock.Any(), src).Return(src, nil)
+			},
+			expectedCode:         200,
+			expectedResponseBody: `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn","scrape":{"article_selector":"div.story","date_formats":["Jan 02, 2006"]}}`,
+			inputBody:            `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn","scrape":{"article_selector":"div.story","date_formats":["Jan 02, 2006"]}}`,
+		},
 		{
 			name:                 "BadRequest",
 			mockBehavior:         func(r *service_mocks.MockSourceService, src model.Source) {},
@@ -114,6 +125,13 @@ func TestHandler_createSource(t *testing.T) {
 			expectedResponseBody: `{"message":"EOF"}`,
 			inputBody:            ``,
 		},
+		{
+			name:                 "Invalid scrape config",
+			mockBehavior:         func(r *service_mocks.MockSourceService, src model.Source) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"invalid scrape config: article selector is required"}`,
+			inputBody:            `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn","scrape":{"title_selector":"h2"}}`,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -130,7 +148,7 @@ func TestHandler_createSource(t *testing.T) {
 
 			// Init Endpoint
 			r := gin.New()
-			r.POST("/source", NewHandler(nil, srcSvc).createSource)
+			r.POST("/source", NewHandler(nil, srcSvc, nil, nil).createSource)
 
 			// Create Request
 			w := httptest.NewRecorder()
@@ -159,7 +177,7 @@ func TestHandler_deleteSource(t *testing.T) {
 		{
 			name: "OK",
 			mockBehavior: func(r *service_mocks.MockSourceService, id int) {
-				r.EXPECT().DeleteSource(id).Return(nil)
+				r.EXPECT().DeleteSource(gomock.Any(), id).Return(nil)
 			},
 			expectedCode:         200,
 			expectedResponseBody: `{"message":"source deleted"}`,
@@ -187,7 +205,7 @@ func TestHandler_deleteSource(t *testing.T) {
 
 			// Init Endpoint
 			r := gin.New()
-			r.DELETE("/source/:id", NewHandler(nil, srcSvc).deleteSource)
+			r.DELETE("/source/:id", NewHandler(nil, srcSvc, nil, nil).deleteSource)
 
 			// Create Request
 			w := httptest.NewRecorder()
@@ -216,13 +234,32 @@ func TestHandler_updateSource(t *testing.T) {
 		{
 			name: "OK",
 			mockBehavior: func(r *service_mocks.MockSourceService, id int, src model.Source) {
-				r.EXPECT().UpdateSource(id, src).Return(src, nil)
+				r.EXPECT().UpdateSource(gomock.Any(), id, src).Return(src, nil)
 			},
 			expectedCode:         200,
 			expectedResponseBody: `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn"}`,
 			inputBody:            `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn"}`,
 			inputID:              "1",
 		},
+		{
+			name: "Schedule",
+			mockBehavior: func(r *service_mocks.MockSourceService, id int, src model.Source) {
+				src.Schedule = &model.FetchSchedule{Cron: "*/30 6-22 * * *", Enabled: true}
+				r.EXPECT().UpdateSource(gomock.Any(), id, src).Return(src, nil)
+			},
+			expectedCode:         200,
+			expectedResponseBody: `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn","schedule":{"cron":"*/30 6-22 * * *","enabled":true}}`,
+			inputBody:            `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn","schedule":{"cron":"*/30 6-22 * * *"}}`,
+			inputID:              "1",
+		},
+		{
+			name:                 "Invalid schedule",
+			mockBehavior:         func(r *service_mocks.MockSourceService, id int, src model.Source) {},
+			expectedCode:         400,
+			expectedResponseBody: `{"message":"invalid schedule: interval 10s is shorter than 1m0s"}`,
+			inputBody:            `{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn","schedule":{"interval":"10s"}}`,
+			inputID:              "1",
+		},
 		{
 			name:                 "BadRequest",
 			mockBehavior:         func(r *service_mocks.MockSourceService, id int, src model.Source) {},
@@ -248,7 +285,7 @@ func TestHandler_updateSource(t *testing.T) {
 
 			// Init Endpoint
 			r := gin.New()
-			r.PUT("/source/:id", NewHandler(nil, srcSvc).updateSource)
+			r.PUT("/source/:id", NewHandler(nil, srcSvc, nil, nil).updateSource)
 
 			// Create Request
 			w := httptest.NewRecorder()
@@ -276,7 +313,7 @@ func TestHandler_getAllSources(t *testing.T) {
 		{
 			name: "OK",
 			mockBehavior: func(r *service_mocks.MockSourceService) {
-				r.EXPECT().GetAll().Return([]model.Source{
+				r.EXPECT().GetAll(gomock.Any()).Return([]model.Source{
 					{
 						Id:        1,
 						Name:      "CNN",
@@ -294,10 +331,29 @@ func TestHandler_getAllSources(t *testing.T) {
 			expectedCode:         200,
 			expectedResponseBody: `[{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn"},{"id":2,"name":"BBC","link":"http://bbc.com","short_name":"bbc"}]`,
 		},
+		{
+			name: "Health",
+			mockBehavior: func(r *service_mocks.MockSourceService) {
+				lastFailure := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+				r.EXPECT().GetAll(gomock.Any()).Return([]model.Source{
+					{
+						Id:        1,
+						Name:      "CNN",
+						Link:      "http://cnn.com",
+						ShortName: "cnn",
+						Health: &model.SourceHealth{Status: model.SourceDisabled, ConsecutiveFailures: 10,
+							LastFailure: &lastFailure, LastError: "404 Not Found", Fetches: 4, AverageItems: 12.5},
+					},
+				}, nil)
+			},
+			expectedCode: 200,
+			expectedResponseBody: `[{"id":1,"name":"CNN","link":"http://cnn.com","short_name":"cnn","health":{"status":"disabled",` +
+				`"consecutive_failures":10,"last_failure":"2024-05-01T12:00:00Z","last_error":"404 Not Found","fetches":4,"average_items":12.5}}]`,
+		},
 		{
 			name: "Service Error",
 			mockBehavior: func(r *service_mocks.MockSourceService) {
-				r.EXPECT().GetAll().Return(nil, errors.New("internal server error"))
+				r.EXPECT().GetAll(gomock.Any()).Return(nil, errors.New("internal server error"))
 			},
 			expectedCode:         http.StatusInternalServerError,
 			expectedResponseBody: `{"message":"internal server error"}`,
@@ -311,7 +367,7 @@ func TestHandler_getAllSources(t *testing.T) {
 			srcSvc := service_mocks.NewMockSourceService(c)
 			test.mockBehavior(srcSvc)
 			r := gin.New()
-			r.GET("/source", NewHandler(nil, srcSvc).getAllSources)
+			r.GET("/source", NewHandler(nil, srcSvc, nil, nil).getAllSources)
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest("GET", "/source", nil)
 
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -13,13 +14,62 @@ import (
 // - Link: a string that represents the link to the original article
 // - Source: a string that represents the source of the article
 // - PubDate: a time.Time that represents the publication date of the article
+// - Author: a string that represents the comma-separated authors of the article
+// - Categories: a slice of strings that represents the categories or tags of the article
+// - ImageURL: a string that represents the link to the image of the article
+// - Content: a string that represents the full content of the article, if the feed provides it
+// - Language: a string that represents the language code of the article, taken from the feed if the article has none
+// - GUID: a string that represents the identifier of the article in its feed
+// - Relevance: a float64 that represents how well the article matches the searched keywords
+// - CanonicalLink: a string that represents the link without tracking parameters, the articles are unique by it
+// - Fingerprint: a uint64 that represents the SimHash of the title and description, similar for the same story
+// - StoryId: an integer that represents the story the article reports, shared by its near-duplicates from other sources
+// - OtherSources: a slice of the other sources reporting the story when the stories are collapsed
 type Article struct {
-	Id          int       `db:"id"`
-	Title       string    `db:"title"`
-	Description string    `db:"description"`
-	Link        string    `db:"link"`
-	Source      Source    `db:"source_id"`
-	PubDate     time.Time `db:"pub_date"`
+	Id            int       `db:"id" json:"id"`
+	Title         string    `db:"title" json:"title"`
+	Description   string    `db:"description" json:"description"`
+	Link          string    `db:"link" json:"link"`
+	Source        Source    `db:"source_id" json:"source"`
+	PubDate       time.Time `db:"pub_date" json:"pub_date"`
+	Author        string    `db:"author" json:"author,omitempty"`
+	Categories    []string  `db:"categories" json:"categories,omitempty"`
+	ImageURL      string    `db:"image_url" json:"image_url,omitempty"`
+	Content       string    `db:"content" json:"content,omitempty"`
+	Language      string    `db:"language" json:"language,omitempty"`
+	GUID          string    `db:"guid" json:"guid,omitempty"`
+	Relevance     float64   `db:"relevance" json:"relevance,omitempty"`
+	CanonicalLink string    `db:"canonical_link" json:"-"`
+	Fingerprint   uint64    `db:"fingerprint" json:"-"`
+	StoryId       int       `db:"story_id" json:"story_id,omitempty"`
+	OtherSources  []Source  `db:"-" json:"other_sources,omitempty"`
+}
+
+// UnmarshalJSON decodes the article, accepting the keys of the files saved before
+// the article had JSON tags as well. The keys were the field names then.
+func (a *Article) UnmarshalJSON(data []byte) error {
+	type article Article
+	var decoded struct {
+		article
+		LegacyPubDate      *time.Time `json:"PubDate"`
+		LegacyStoryId      *int       `json:"StoryId"`
+		LegacyOtherSources []Source   `json:"OtherSources"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*a = Article(decoded.article)
+	if decoded.LegacyPubDate != nil && a.PubDate.IsZero() {
+		a.PubDate = *decoded.LegacyPubDate
+	}
+	if decoded.LegacyStoryId != nil && a.StoryId == 0 {
+		a.StoryId = *decoded.LegacyStoryId
+	}
+	if a.OtherSources == nil {
+		a.OtherSources = decoded.LegacyOtherSources
+	}
+	return nil
 }
 
 // String method returns a string representation of the Article struct
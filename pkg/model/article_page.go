@@ -0,0 +1,14 @@
+package model
+
+// ArticlePage is a single page of articles returned by a paginated query.
+// It has the following fields:
+// - Articles: the articles on the page
+// - Count: the number of articles on the page
+// - Total: the number of articles matching the query on all pages
+// - NextCursor: an opaque cursor to request the next page with, empty on the last page
+type ArticlePage struct {
+	Articles   []Article `json:"articles"`
+	Count      int       `json:"count"`
+	Total      int       `json:"total"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	// ChangeSaveArticle means an article was added or updated.
+	ChangeSaveArticle = "save_article"
+	// ChangeDeleteArticle means an article was deleted.
+	ChangeDeleteArticle = "delete_article"
+	// ChangeSaveSource means a source was added or updated.
+	ChangeSaveSource = "save_source"
+	// ChangeDeleteSource means a source was deleted.
+	ChangeDeleteSource = "delete_source"
+)
+
+// Change is a change of the stored articles or sources, recorded in the journal
+// so the changes made since the latest snapshot can be replayed over it.
+// It has the following fields:
+// - At: the time of the change
+// - Kind: what changed, one of the Change constants
+// - Id: the ID of the changed article or source
+// - Article: the article as it was saved, for ChangeSaveArticle
+// - Source: the source as it was saved, for ChangeSaveSource
+type Change struct {
+	At      time.Time `json:"at"`
+	Kind    string    `json:"kind"`
+	Id      int       `json:"id"`
+	Article *Article  `json:"article,omitempty"`
+	Source  *Source   `json:"source,omitempty"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+const (
+	// FetchOK means the source feed was fetched and its articles were saved.
+	FetchOK = "ok"
+	// FetchUnchanged means the source feed wasn't modified since the previous fetch.
+	FetchUnchanged = "unchanged"
+	// FetchFailed means the source feed couldn't be fetched or its articles couldn't be saved.
+	FetchFailed = "failed"
+	// FetchSkipped means another instance holds the lease on the source, having fetched it this period.
+	FetchSkipped = "skipped"
+	// FetchDisabled means the source was disabled after failing too many times in a row, so it wasn't fetched.
+	FetchDisabled = "disabled"
+)
+
+// FetchReport is the outcome of fetching articles from all sources.
+// It has the following fields:
+// - StartedAt: the time the fetch started
+// - Duration: how long fetching all sources took
+// - Sources: the outcome of every source, in the order the sources were listed
+type FetchReport struct {
+	StartedAt time.Time           `json:"started_at"`
+	Duration  time.Duration       `json:"duration" swaggertype:"integer"`
+	Sources   []SourceFetchResult `json:"sources"`
+}
+
+// SourceFetchResult is the outcome of fetching articles from a single source.
+// It has the following fields:
+// - Source: the fetched source
+// - Status: FetchOK, FetchUnchanged, FetchFailed, FetchSkipped or FetchDisabled
+// - Articles: the number of fetched articles
+// - Saved: what saving the fetched articles changed in the storage
+// - StartedAt: the time the fetch of the source started
+// - Duration: how long fetching the source took
+// - HTTPStatus: the status code the feed was served with, zero if no response was received
+// - Bytes: the size of the downloaded feed
+// - Error: the reason the fetch failed, empty on success
+type SourceFetchResult struct {
+	Source     Source        `json:"source"`
+	Status     string        `json:"status"`
+	Articles   int           `json:"articles"`
+	Saved      SaveResult    `json:"saved"`
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration" swaggertype:"integer"`
+	HTTPStatus int           `json:"http_status,omitempty"`
+	Bytes      int64         `json:"bytes"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Count returns the number of sources with the given status.
+func (r FetchReport) Count(status string) int {
+	count := 0
+	for _, result := range r.Sources {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Articles returns the number of articles fetched from all sources.
+func (r FetchReport) Articles() int {
+	count := 0
+	for _, result := range r.Sources {
+		count += result.Articles
+	}
+	return count
+}
+
+// Saved returns what saving the articles fetched from all sources changed in the storage.
+func (r FetchReport) Saved() SaveResult {
+	var saved SaveResult
+	for _, result := range r.Sources {
+		saved.Add(result.Saved)
+	}
+	return saved
+}
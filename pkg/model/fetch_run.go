@@ -0,0 +1,90 @@
+package model
+
+import "time"
+
+// FetchRun is the recorded outcome of fetching articles from one or more sources.
+// It has the following fields:
+// - Id: the ID of the run
+// - StartedAt: the time the run started
+// - FinishedAt: the time the run finished
+// - Sources: the number of sources attempted
+// - Failed: the number of sources that failed to fetch
+// - Inserted: the number of new articles
+// - Updated: the number of updated articles
+// - Attempts: the attempt of every source, in the order the sources were fetched
+type FetchRun struct {
+	Id         int            `json:"id" db:"id"`
+	StartedAt  time.Time      `json:"started_at" db:"started_at"`
+	FinishedAt time.Time      `json:"finished_at" db:"finished_at"`
+	Sources    int            `json:"sources" db:"sources"`
+	Failed     int            `json:"failed" db:"failed"`
+	Inserted   int            `json:"inserted" db:"inserted"`
+	Updated    int            `json:"updated" db:"updated"`
+	Attempts   []FetchAttempt `json:"attempts" db:"-"`
+}
+
+// FetchAttempt is the recorded outcome of fetching articles from a single source within a run.
+// It has the following fields:
+// - Id: the ID of the attempt
+// - RunId: the ID of the run the attempt belongs to
+// - SourceId: the ID of the fetched source
+// - SourceName: the short name the source had at the time of the attempt
+// - StartedAt: the time the attempt started
+// - FinishedAt: the time the attempt finished
+// - Status: FetchOK, FetchUnchanged, FetchFailed, FetchSkipped or FetchDisabled
+// - HTTPStatus: the status code the feed was served with, zero if no response was received
+// - Bytes: the size of the downloaded feed
+// - Articles: the number of fetched articles
+// - Inserted: the number of new articles
+// - Updated: the number of updated articles
+// - Error: the reason the attempt failed, empty on success
+type FetchAttempt struct {
+	Id         int       `json:"id" db:"id"`
+	RunId      int       `json:"run_id" db:"run_id"`
+	SourceId   int       `json:"source_id" db:"source_id"`
+	SourceName string    `json:"source_name" db:"source_name"`
+	StartedAt  time.Time `json:"started_at" db:"started_at"`
+	FinishedAt time.Time `json:"finished_at" db:"finished_at"`
+	Status     string    `json:"status" db:"status"`
+	HTTPStatus int       `json:"http_status,omitempty" db:"http_status"`
+	Bytes      int64     `json:"bytes" db:"bytes"`
+	Articles   int       `json:"articles" db:"articles"`
+	Inserted   int       `json:"inserted" db:"inserted"`
+	Updated    int       `json:"updated" db:"updated"`
+	Error      string    `json:"error,omitempty" db:"error"`
+}
+
+// Run returns the record of the fetch, to be kept in the fetch history.
+func (r FetchReport) Run() FetchRun {
+	saved := r.Saved()
+	run := FetchRun{
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.StartedAt.Add(r.Duration),
+		Sources:    len(r.Sources),
+		Failed:     r.Count(FetchFailed),
+		Inserted:   saved.Inserted,
+		Updated:    saved.Updated,
+		Attempts:   make([]FetchAttempt, len(r.Sources)),
+	}
+	for i, result := range r.Sources {
+		run.Attempts[i] = result.Attempt()
+	}
+	return run
+}
+
+// Attempt returns the record of the fetch of the source, to be kept in the fetch history.
+func (r SourceFetchResult) Attempt() FetchAttempt {
+	return FetchAttempt{
+		SourceId:   r.Source.Id,
+		SourceName: r.Source.ShortName,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.StartedAt.Add(r.Duration),
+		Status:     r.Status,
+		HTTPStatus: r.HTTPStatus,
+		Bytes:      r.Bytes,
+		Articles:   r.Articles,
+		Inserted:   r.Saved.Inserted,
+		Updated:    r.Saved.Updated,
+		Error:      r.Error,
+	}
+}
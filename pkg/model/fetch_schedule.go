@@ -0,0 +1,106 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+const (
+	// DefaultFetchInterval is how often the sources without their own schedule are fetched.
+	DefaultFetchInterval = time.Minute
+	// MinFetchInterval is the shortest interval a source may be fetched at.
+	MinFetchInterval = time.Minute
+)
+
+// FetchSchedule is when the articles of a source are fetched.
+// It has the following fields:
+// - Interval: the time between the fetches, such as "15m" or "2h"
+// - Cron: a standard five-field cron expression, such as "*/30 6-22 * * *", evaluated in UTC
+// - Enabled: whether the source is fetched on schedule at all, true unless set otherwise
+//
+// A schedule has either an interval or a cron expression, neither means the default interval.
+type FetchSchedule struct {
+	Interval string `json:"interval,omitempty"`
+	Cron     string `json:"cron,omitempty"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// DefaultFetchSchedule returns the schedule of the sources without their own.
+func DefaultFetchSchedule() FetchSchedule {
+	return FetchSchedule{Interval: DefaultFetchInterval.String(), Enabled: true}
+}
+
+// UnmarshalJSON decodes the schedule, enabling it if the enabled field is missing.
+func (s *FetchSchedule) UnmarshalJSON(data []byte) error {
+	type schedule FetchSchedule
+	decoded := schedule{Enabled: true}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*s = FetchSchedule(decoded)
+	return nil
+}
+
+// Validate checks that the schedule has at most one of a valid interval and cron expression.
+func (s FetchSchedule) Validate() error {
+	if s.Interval != "" && s.Cron != "" {
+		return errors.New("schedule can't have both an interval and a cron expression")
+	}
+	if s.Cron != "" {
+		if _, err := cron.ParseStandard(s.Cron); err != nil {
+			return fmt.Errorf("invalid cron expression %q: %w", s.Cron, err)
+		}
+		return nil
+	}
+	_, err := s.Every()
+	return err
+}
+
+// Every returns the interval of the schedule, the default one if it has neither an interval nor a cron expression.
+func (s FetchSchedule) Every() (time.Duration, error) {
+	if s.Interval == "" {
+		return DefaultFetchInterval, nil
+	}
+	interval, err := time.ParseDuration(s.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q: %w", s.Interval, err)
+	}
+	if interval < MinFetchInterval {
+		return 0, fmt.Errorf("interval %s is shorter than %s", interval, MinFetchInterval)
+	}
+	return interval, nil
+}
+
+// Period returns the time from now until the next fetch is due, at least MinFetchInterval.
+// It varies over the day for cron expressions such as "0 9,17 * * *".
+func (s FetchSchedule) Period(now time.Time) (time.Duration, error) {
+	if s.Cron == "" {
+		return s.Every()
+	}
+	schedule, err := cron.ParseStandard(s.Cron)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cron expression %q: %w", s.Cron, err)
+	}
+	return max(schedule.Next(now.UTC()).Sub(now), MinFetchInterval), nil
+}
+
+// Value stores the schedule as JSON, so it fits in a single column.
+func (s FetchSchedule) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan reads the schedule from its JSON column.
+func (s *FetchSchedule) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, s)
+	case string:
+		return json.Unmarshal([]byte(src), s)
+	default:
+		return fmt.Errorf("can't scan %T into FetchSchedule", src)
+	}
+}
@@ -0,0 +1,10 @@
+package model
+
+// ImportReport counts the articles imported so far.
+// It has the following fields:
+// - Articles: the number of articles read
+// - SaveResult: what saving them changed
+type ImportReport struct {
+	Articles int `json:"articles"`
+	SaveResult
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// RestoreReport is the outcome of restoring the articles and sources to a point in time.
+// It has the following fields:
+// - At: the point in time restored, the zero time for the latest state
+// - Snapshot: the path of the snapshot restored, empty if there was none
+// - SnapshotAt: the time the snapshot was taken
+// - Changes: the number of journal changes replayed over the snapshot
+// - Articles: the number of restored articles
+// - Sources: the number of restored sources
+// - Saved: the path of the snapshot the restored state was saved to, empty if it wasn't saved
+type RestoreReport struct {
+	At         time.Time
+	Snapshot   string
+	SnapshotAt time.Time
+	Changes    int
+	Articles   int
+	Sources    int
+	Saved      string
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy limits the articles kept of a source.
+// It has the following fields:
+// - MaxAgeDays: the articles published more days ago are pruned
+// - MaxCount: only the newest articles up to this number are kept
+//
+// Zero fields set no limit.
+type RetentionPolicy struct {
+	MaxAgeDays int `json:"max_age_days,omitempty"`
+	MaxCount   int `json:"max_count,omitempty"`
+}
+
+// Or returns the policy with its unset limits taken from the defaults.
+func (p RetentionPolicy) Or(defaults RetentionPolicy) RetentionPolicy {
+	if p.MaxAgeDays == 0 {
+		p.MaxAgeDays = defaults.MaxAgeDays
+	}
+	if p.MaxCount == 0 {
+		p.MaxCount = defaults.MaxCount
+	}
+	return p
+}
+
+// IsZero reports whether the policy sets no limit.
+func (p RetentionPolicy) IsZero() bool {
+	return p.MaxAgeDays == 0 && p.MaxCount == 0
+}
+
+// Cutoff returns the time the articles published before are expired, the zero time if there is no age limit.
+func (p RetentionPolicy) Cutoff(now time.Time) time.Time {
+	if p.MaxAgeDays == 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -p.MaxAgeDays)
+}
+
+// Value stores the policy as JSON, so it fits in a single column.
+func (p RetentionPolicy) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan reads the policy from its JSON column.
+func (p *RetentionPolicy) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, p)
+	case string:
+		return json.Unmarshal([]byte(src), p)
+	default:
+		return fmt.Errorf("can't scan %T into RetentionPolicy", src)
+	}
+}
+
+// PruneReport is the outcome of pruning the articles beyond the retention policies.
+// It has the following fields:
+// - DryRun: whether the articles were only listed and kept
+// - Archive: the file the pruned articles were archived to, empty if they weren't archived
+// - Sources: the outcome of every source with expired articles
+type PruneReport struct {
+	DryRun  bool                `json:"dry_run"`
+	Archive string              `json:"archive,omitempty"`
+	Sources []SourcePruneResult `json:"sources"`
+}
+
+// SourcePruneResult is the outcome of pruning the articles of a single source.
+// It has the following fields:
+// - Source: the pruned source
+// - Policy: the retention policy applied to the source
+// - Pruned: the number of expired articles
+// - Articles: the expired articles, listed on dry runs only
+type SourcePruneResult struct {
+	Source   Source          `json:"source"`
+	Policy   RetentionPolicy `json:"policy"`
+	Pruned   int             `json:"pruned"`
+	Articles []Article       `json:"articles,omitempty"`
+}
+
+// Pruned returns the number of expired articles of all sources.
+func (r PruneReport) Pruned() int {
+	count := 0
+	for _, result := range r.Sources {
+		count += result.Pruned
+	}
+	return count
+}
@@ -0,0 +1,19 @@
+package model
+
+// SaveResult counts what saving a batch of articles changed.
+// It has the following fields:
+// - Inserted: the number of new articles
+// - Updated: the number of saved articles whose content or metadata changed
+// - Skipped: the number of articles saved before without changes or repeated in the batch
+type SaveResult struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+}
+
+// Add adds the counts of the other result to the result.
+func (r *SaveResult) Add(other SaveResult) {
+	r.Inserted += other.Inserted
+	r.Updated += other.Updated
+	r.Skipped += other.Skipped
+}
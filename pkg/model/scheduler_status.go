@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// SchedulerStatus is the state of the scheduler fetching the sources.
+// It has the following fields:
+// - Paused: whether the scheduled jobs are skipped until the scheduler is resumed
+// - Sources: the number of scheduled sources
+// - NextRun: the time the next source is due, omitted if no source is scheduled
+// - LastReport: the latest fetch of every scheduled source
+type SchedulerStatus struct {
+	Paused     bool        `json:"paused"`
+	Sources    int         `json:"sources"`
+	NextRun    *time.Time  `json:"next_run,omitempty"`
+	LastReport FetchReport `json:"last_report"`
+}
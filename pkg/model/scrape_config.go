@@ -0,0 +1,50 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// ScrapeConfig holds the rules to scrape articles from the HTML page of a source without a feed.
+// It has the following fields:
+// - ArticleSelector: the CSS selector of the elements holding a single article each
+// - TitleSelector: the CSS selector of the article title, the article element itself if empty
+// - LinkSelector: the CSS selector of the element whose href links to the article, the article element itself if empty
+// - DescriptionSelector: the CSS selector of the article description, the article element itself if empty
+// - DescriptionAttribute: the attribute holding the description, the element text if empty
+// - PubDateSelector: the CSS selector of the article publication date, the article element itself if empty
+// - DateAttribute: the attribute holding the publication date, the element text if empty
+// - DateFormats: the layouts the publication date is parsed with, in order
+// - BaseURL: the URL relative article links are resolved against, the page URL if empty
+//
+// The selectors other than ArticleSelector are relative to the article element.
+// The description and the publication date are only scraped if their selector or attribute is set.
+type ScrapeConfig struct {
+	ArticleSelector      string   `json:"article_selector"`
+	TitleSelector        string   `json:"title_selector,omitempty"`
+	LinkSelector         string   `json:"link_selector,omitempty"`
+	DescriptionSelector  string   `json:"description_selector,omitempty"`
+	DescriptionAttribute string   `json:"description_attribute,omitempty"`
+	PubDateSelector      string   `json:"pub_date_selector,omitempty"`
+	DateAttribute        string   `json:"date_attribute,omitempty"`
+	DateFormats          []string `json:"date_formats,omitempty"`
+	BaseURL              string   `json:"base_url,omitempty"`
+}
+
+// Value stores the config as JSON, so it fits in a single column.
+func (c ScrapeConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan reads the config from its JSON column.
+func (c *ScrapeConfig) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, c)
+	case string:
+		return json.Unmarshal([]byte(src), c)
+	default:
+		return fmt.Errorf("can't scan %T into ScrapeConfig", src)
+	}
+}
@@ -1,12 +1,73 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
+// Source is a news feed articles are fetched from.
+// ETag and LastModified are the HTTP validators of the latest fetch of the feed,
+// they are sent back on the next fetch to skip downloading an unchanged feed.
+// Scrape holds the rules to scrape the articles if the source is an HTML page rather than a feed.
+// Retention limits the articles kept of the source, overriding the global retention policy.
+// Schedule is when the source is fetched, the default schedule applies to the sources without one.
+// Health tracks the fetches of the source, it's maintained by the fetches and reset when the source is updated.
 type Source struct {
-	Id        int    `json:"id" db:"id"`
-	Name      string `json:"name" db:"name"`
-	Link      string `json:"link" db:"link"`
-	ShortName string `json:"short_name" db:"short_name"`
+	Id           int              `json:"id" db:"id"`
+	Name         string           `json:"name" db:"name"`
+	Link         string           `json:"link" db:"link"`
+	ShortName    string           `json:"short_name" db:"short_name"`
+	ETag         string           `json:"etag,omitempty" db:"etag"`
+	LastModified string           `json:"last_modified,omitempty" db:"last_modified"`
+	Scrape       *ScrapeConfig    `json:"scrape,omitempty" db:"scrape_config"`
+	Retention    *RetentionPolicy `json:"retention,omitempty" db:"retention"`
+	Schedule     *FetchSchedule   `json:"schedule,omitempty" db:"schedule"`
+	Health       *SourceHealth    `json:"health,omitempty" db:"health"`
+}
+
+// FetchSchedule returns the schedule of the source, the default one if the source has none.
+func (s Source) FetchSchedule() FetchSchedule {
+	if s.Schedule == nil {
+		return DefaultFetchSchedule()
+	}
+	return *s.Schedule
+}
+
+// EffectiveSchedule returns the schedule the source is fetched on, adapted to its health:
+// - a source disabled after too many failures isn't fetched at all
+// - a source without its own schedule is polled at half the observed interval between its updates,
+// from MinFetchInterval to MaxAdaptiveInterval, or at the default interval until it updated twice
+// - the interval of a failing source doubles with every failure in a row, up to MaxBackoffInterval
+//
+// Cron schedules are kept as they are, as well as the schedules with an invalid interval.
+func (s Source) EffectiveSchedule() FetchSchedule {
+	schedule := s.FetchSchedule()
+	if s.Health == nil {
+		return schedule
+	}
+	if s.Health.Status == SourceDisabled {
+		schedule.Enabled = false
+		return schedule
+	}
+	if schedule.Cron != "" {
+		return schedule
+	}
+	every, err := schedule.Every()
+	if err != nil {
+		return schedule
+	}
+	interval := every
+	if s.Schedule == nil && s.Health.UpdateInterval > 0 {
+		interval = min(max(s.Health.UpdateInterval/2, MinFetchInterval), MaxAdaptiveInterval).Truncate(time.Minute)
+	}
+	limit := max(interval, MaxBackoffInterval)
+	for i := 0; i < s.Health.ConsecutiveFailures && interval < limit; i++ {
+		interval = min(2*interval, limit)
+	}
+	if interval != every {
+		schedule.Interval = interval.String()
+	}
+	return schedule
 }
 
 func (s Source) String() string {
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// SourceHealthy means the latest fetch of the source succeeded.
+	SourceHealthy = "healthy"
+	// SourceFailing means the latest fetches of the source failed, so it's polled less often.
+	SourceFailing = "failing"
+	// SourceDisabled means the source failed MaxConsecutiveFailures times in a row and isn't fetched anymore.
+	SourceDisabled = "disabled"
+)
+
+const (
+	// MaxConsecutiveFailures is the number of failed fetches in a row after which a source is disabled.
+	MaxConsecutiveFailures = 10
+	// MaxAdaptiveInterval is the longest interval a source without its own schedule is polled at, however rarely it updates.
+	MaxAdaptiveInterval = time.Hour
+	// MaxBackoffInterval is the longest interval a failing source backs off to, unless its own interval is longer.
+	MaxBackoffInterval = 6 * time.Hour
+)
+
+// SourceHealth tracks how the fetches of a source went.
+// It has the following fields:
+// - Status: SourceHealthy, SourceFailing or SourceDisabled
+// - ConsecutiveFailures: the number of failed fetches since the latest successful one
+// - LastSuccess: the time of the latest successful fetch
+// - LastFailure: the time of the latest failed fetch
+// - LastError: the reason the latest fetch failed, empty once a fetch succeeds
+// - Fetches: the number of successful fetches
+// - AverageItems: the average number of articles per successful fetch
+// - LastUpdate: the time new articles were last fetched from the source
+// - UpdateInterval: the observed time between the updates of the source, averaged over the recent ones
+type SourceHealth struct {
+	Status              string        `json:"status"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	LastSuccess         *time.Time    `json:"last_success,omitempty"`
+	LastFailure         *time.Time    `json:"last_failure,omitempty"`
+	LastError           string        `json:"last_error,omitempty"`
+	Fetches             int           `json:"fetches"`
+	AverageItems        float64       `json:"average_items"`
+	LastUpdate          *time.Time    `json:"last_update,omitempty"`
+	UpdateInterval      time.Duration `json:"update_interval,omitempty" swaggertype:"integer"`
+}
+
+// Record returns the health after a fetch with the given result completed at now.
+// A skipped or disabled fetch doesn't change the health.
+func (h SourceHealth) Record(result SourceFetchResult, now time.Time) SourceHealth {
+	switch result.Status {
+	case FetchFailed:
+		h.ConsecutiveFailures++
+		h.LastFailure = &now
+		h.LastError = result.Error
+		h.Status = SourceFailing
+		if h.ConsecutiveFailures >= MaxConsecutiveFailures {
+			h.Status = SourceDisabled
+		}
+	case FetchOK, FetchUnchanged:
+		h.Status = SourceHealthy
+		h.ConsecutiveFailures = 0
+		h.LastError = ""
+		h.LastSuccess = &now
+		h.Fetches++
+		h.AverageItems += (float64(result.Articles) - h.AverageItems) / float64(h.Fetches)
+		if result.Saved.Inserted > 0 {
+			if h.LastUpdate != nil {
+				observed := now.Sub(*h.LastUpdate)
+				if h.UpdateInterval == 0 {
+					h.UpdateInterval = observed
+				} else {
+					h.UpdateInterval = (h.UpdateInterval + observed) / 2
+				}
+			}
+			h.LastUpdate = &now
+		}
+	}
+	return h
+}
+
+// Equal reports whether the health is the same as the other, either of which may be nil for no health.
+func (h *SourceHealth) Equal(other *SourceHealth) bool {
+	if h == nil || other == nil {
+		return h == other
+	}
+	return h.Status == other.Status && h.ConsecutiveFailures == other.ConsecutiveFailures &&
+		equalTime(h.LastSuccess, other.LastSuccess) && equalTime(h.LastFailure, other.LastFailure) &&
+		h.LastError == other.LastError && h.Fetches == other.Fetches && h.AverageItems == other.AverageItems &&
+		equalTime(h.LastUpdate, other.LastUpdate) && h.UpdateInterval == other.UpdateInterval
+}
+
+// equalTime reports whether the optional times are the same instant, or both unset.
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// Value stores the health as JSON, so it fits in a single column.
+func (h SourceHealth) Value() (driver.Value, error) {
+	return json.Marshal(h)
+}
+
+// Scan reads the health from its JSON column.
+func (h *SourceHealth) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, h)
+	case string:
+		return json.Unmarshal([]byte(src), h)
+	default:
+		return fmt.Errorf("can't scan %T into SourceHealth", src)
+	}
+}
@@ -0,0 +1,163 @@
+package atom
+
+import (
+	"context"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	gofeedatom "github.com/mmcdole/gofeed/atom"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	eventParseAtomFeedStart   = "parse_atom_feed_start"
+	eventParseAtomUrlError    = "parse_atom_url_error"
+	eventParseAtomFeedSuccess = "parse_atom_feed_success"
+	eventParseAtomFileStart   = "parse_atom_file_start"
+	eventParseAtomFileSuccess = "parse_atom_file_success"
+	eventParseAtomError       = "parse_atom_error"
+)
+
+// Parser is a struct that implements the Parser interface for Atom feeds (RFC 4287).
+type Parser struct{}
+
+// ParseFeed fetches the Atom feed at the given URL and returns a slice of articles.
+// The feed request is aborted when ctx is done.
+func (a *Parser) ParseFeed(ctx context.Context, url url.URL) ([]model.Article, error) {
+	logrus.WithField("event_id", eventParseAtomFeedStart).Infof("Starting to parse feed from URL: %s", url.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logrus.WithField("event_id", eventParseAtomUrlError).Errorf("Error fetching URL: %s", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL %s: %s", url.String(), resp.Status)
+	}
+
+	articles, err := a.Parse(resp.Body, url)
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithField("event_id", eventParseAtomFeedSuccess).Infof("Successfully parsed feed from URL: %s", url.String())
+	return articles, nil
+}
+
+// ParseFile parses the given Atom file and returns a slice of articles.
+func (a *Parser) ParseFile(f *os.File) ([]model.Article, error) {
+	logrus.WithField("event_id", eventParseAtomFileStart).Infof("Starting to parse file: %s", f.Name())
+
+	articles, err := a.Parse(f, url.URL{})
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithField("event_id", eventParseAtomFileSuccess).Infof("Successfully parsed file: %s", f.Name())
+	return articles, nil
+}
+
+// Parse parses the Atom feed read from body and returns a slice of articles.
+// feedURL is the link of the feed the body was fetched from.
+func (a *Parser) Parse(body io.Reader, feedURL url.URL) ([]model.Article, error) {
+	parser := gofeedatom.Parser{}
+	feed, err := parser.Parse(body)
+	if err != nil {
+		logrus.WithField("event_id", eventParseAtomError).Errorf("Error parsing feed: %s", err.Error())
+		return nil, err
+	}
+
+	articles := make([]model.Article, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		article := model.Article{
+			Title:       strings.TrimSpace(entry.Title),
+			Link:        entryLink(entry.Links),
+			Description: strings.TrimSpace(entry.Summary),
+			Author:      authors(entry.Authors, feed.Authors),
+			Categories:  categories(entry.Categories),
+			ImageURL:    imageLink(entry.Links),
+			Language:    feed.Language,
+			GUID:        strings.TrimSpace(entry.ID),
+			Source: model.Source{
+				Name: feed.Title,
+				Link: feedURL.String(),
+			},
+		}
+		if entry.Content != nil {
+			article.Content = strings.TrimSpace(entry.Content.Value)
+		}
+		if article.Description == "" && (entry.Content == nil || isText(entry.Content.Type)) {
+			article.Description = article.Content
+		}
+		if entry.PublishedParsed != nil {
+			article.PubDate = *entry.PublishedParsed
+		} else if entry.UpdatedParsed != nil {
+			article.PubDate = *entry.UpdatedParsed
+		}
+		articles = append(articles, article)
+	}
+	return articles, nil
+}
+
+// entryLink returns the alternate link of an entry, which is the link
+// without a rel attribute or with rel="alternate", or the first link if there's none.
+func entryLink(links []*gofeedatom.Link) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+// imageLink returns the first enclosure link of an entry to an image.
+func imageLink(links []*gofeedatom.Link) string {
+	for _, link := range links {
+		if link.Rel == "enclosure" && strings.HasPrefix(link.Type, "image/") {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// categories returns the terms of the entry categories.
+func categories(entryCategories []*gofeedatom.Category) []string {
+	var terms []string
+	for _, category := range entryCategories {
+		if term := strings.TrimSpace(category.Term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// authors returns the comma-separated names of the entry authors,
+// or of the feed authors if the entry doesn't have its own.
+func authors(entryAuthors, feedAuthors []*gofeedatom.Person) string {
+	people := entryAuthors
+	if len(people) == 0 {
+		people = feedAuthors
+	}
+	names := make([]string, 0, len(people))
+	for _, person := range people {
+		if name := strings.TrimSpace(person.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// isText reports whether the Atom content type is plain text, which is the default.
+func isText(contentType string) bool {
+	return contentType == "" || contentType == "text"
+}
@@ -0,0 +1,137 @@
+package atom
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func validArticles(feedLink string) []model.Article {
+	source := model.Source{Name: "Sample Atom Feed", Link: feedLink}
+	return []model.Article{
+		{
+			Title:       "Article 1",
+			Link:        "http://example.com/article1",
+			Description: "This is the first article.",
+			Content:     "<p>The full first article.</p>",
+			Author:      "Jane Doe, John Doe",
+			Categories:  []string{"World", "Europe"},
+			ImageURL:    "http://example.com/article1.jpg",
+			Language:    "en",
+			GUID:        "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a",
+			Source:      source,
+			PubDate:     time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			Title:       "Article 2",
+			Link:        "http://example.com/article2",
+			Description: "This is the second article.",
+			Content:     "This is the second article.",
+			Author:      "Feed Author",
+			Language:    "en",
+			GUID:        "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6b",
+			Source:      source,
+			PubDate:     time.Date(2006, time.January, 3, 15, 4, 5, 0, time.UTC),
+		},
+	}
+}
+
+func TestParser_ParseFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		want     []model.Article
+		wantErr  bool
+	}{
+		{
+			name:     "Valid Atom",
+			fileName: "valid_atom.xml",
+			want:     validArticles(""),
+			wantErr:  false,
+		},
+		{
+			name:     "Invalid Atom",
+			fileName: "invalid_atom.xml",
+			want:     nil,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Parser{}
+			f := loadTestData(t, tt.fileName)
+			defer f.Close()
+			got, err := a.ParseFile(f)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFile() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseFeed(t *testing.T) {
+	feed, err := os.ReadFile("testdata/valid_atom.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/atom+xml")
+		_, _ = w.Write(feed)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []model.Article
+		wantErr bool
+	}{
+		{
+			name: "Valid Atom",
+			path: "/feed",
+			want: validArticles(srv.URL + "/feed"),
+		},
+		{
+			name:    "Not Found",
+			path:    "/missing",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feedURL, _ := url.Parse(srv.URL + tt.path)
+			a := &Parser{}
+			got, err := a.ParseFeed(context.Background(), *feedURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFeed() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFeed() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func loadTestData(t *testing.T, filename string) *os.File {
+	file, err := os.Open("testdata/" + filename)
+	if err != nil {
+		t.Fatalf("failed to open test data file %s: %v", filename, err)
+	}
+	return file
+}
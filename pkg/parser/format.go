@@ -1,18 +1,26 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/antonchaban/news-aggregator/pkg/parser/jsonfeed"
 	"github.com/sirupsen/logrus"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"unicode"
 )
 
 const (
-	rssFormat     = "rss"
-	jsonFormat    = "json"
-	htmlFormat    = "html"
-	unknownFormat = "unknown"
+	rssFormat      = "rss"
+	atomFormat     = "atom"
+	jsonFormat     = "json"
+	jsonFeedFormat = "jsonfeed"
+	htmlFormat     = "html"
+	unknownFormat  = "unknown"
 )
 
 // DetermineFileFormat determines the file format based on its extension
@@ -27,8 +35,13 @@ func DetermineFileFormat(filename string) (format string) {
 	return unknownFormat
 }
 
-func DetermineFeedFormat(urlPath url.URL) (format string, err error) {
-	resp, err := http.Head(urlPath.String())
+// DetermineFeedFormat determines the feed format based on the Content-Type of the feed URL.
+func DetermineFeedFormat(ctx context.Context, urlPath url.URL) (format string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, urlPath.String(), nil)
+	if err != nil {
+		return unknownFormat, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		logrus.Errorf("error occurred while sending HEAD request: %s", err.Error())
 		return unknownFormat, err
@@ -40,15 +53,86 @@ func DetermineFeedFormat(urlPath url.URL) (format string, err error) {
 		}
 	}(resp.Body)
 
-	contentType := resp.Header.Get("Content-Type")
+	return feedFormat(resp.Header.Get("Content-Type")), nil
+}
+
+// feedFormat determines the feed format based on the given Content-Type.
+func feedFormat(contentType string) string {
 	switch {
+	case strings.Contains(contentType, "atom+xml"):
+		return atomFormat
+	case strings.Contains(contentType, "feed+json"):
+		return jsonFeedFormat
 	case strings.Contains(contentType, "xml"):
-		return rssFormat, nil
+		return rssFormat
 	case strings.Contains(contentType, "json"):
-		return jsonFormat, nil
+		return jsonFormat
 	case strings.Contains(contentType, "html"):
-		return htmlFormat, nil
+		return htmlFormat
+	default:
+		return unknownFormat
+	}
+}
+
+// SniffFormat determines the feed format based on the content of the feed.
+// XML feeds are told apart by their root element: <rss> or <rdf:RDF> for RSS and <feed> for Atom.
+// JSON feeds are told apart by their top-level keys: a JSON Feed declares its jsonfeed.org version,
+// anything else is taken as a NewsAPI-style feed with its articles.
+// It returns unknownFormat if the content looks like none of the supported formats.
+func SniffFormat(content []byte) string {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	content = bytes.TrimLeftFunc(content, unicode.IsSpace)
+	switch {
+	case bytes.HasPrefix(content, []byte("{")):
+		return sniffJSON(content)
+	case bytes.HasPrefix(content, []byte("<")):
+		return sniffXML(content)
+	default:
+		return unknownFormat
+	}
+}
+
+// sniffXML determines the format of an XML or HTML document by its root element.
+func sniffXML(content []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return unknownFormat
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(element.Name.Local) {
+		case "rss", "rdf":
+			return rssFormat
+		case "feed":
+			return atomFormat
+		case "html":
+			return htmlFormat
+		default:
+			return unknownFormat
+		}
+	}
+}
+
+// sniffJSON determines the format of a JSON document by its top-level keys.
+func sniffJSON(content []byte) string {
+	var probe struct {
+		Version  string          `json:"version"`
+		Articles json.RawMessage `json:"articles"`
+	}
+	if err := json.Unmarshal(content, &probe); err != nil {
+		return unknownFormat
+	}
+	switch {
+	case strings.HasPrefix(probe.Version, jsonfeed.VersionPrefix):
+		return jsonFeedFormat
+	case probe.Articles != nil:
+		return jsonFormat
 	default:
-		return unknownFormat, nil
+		return unknownFormat
 	}
 }
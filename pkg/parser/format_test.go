@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"context"
 	"net/url"
 	"testing"
 )
@@ -85,7 +86,7 @@ func TestDetermineFeedFormat(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotFormat, err := DetermineFeedFormat(tt.args.urlPath)
+			gotFormat, err := DetermineFeedFormat(context.Background(), tt.args.urlPath)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DetermineFeedFormat() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -96,3 +97,69 @@ func TestDetermineFeedFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestSniffFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantFormat string
+	}{
+		{
+			name:       "rss",
+			content:    `<?xml version="1.0"?><rss version="2.0"><channel></channel></rss>`,
+			wantFormat: rssFormat,
+		},
+		{
+			name:       "rss 1.0",
+			content:    `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"></rdf:RDF>`,
+			wantFormat: rssFormat,
+		},
+		{
+			name:       "atom",
+			content:    "\xef\xbb\xbf\n  <?xml version=\"1.0\"?>\n<!-- comment --><feed xmlns=\"http://www.w3.org/2005/Atom\"></feed>",
+			wantFormat: atomFormat,
+		},
+		{
+			name:       "html",
+			content:    "<!DOCTYPE html><html><head><title>News</title></head><body><br></body></html>",
+			wantFormat: htmlFormat,
+		},
+		{
+			name:       "json feed",
+			content:    `{"version": "https://jsonfeed.org/version/1.1", "title": "Feed", "items": []}`,
+			wantFormat: jsonFeedFormat,
+		},
+		{
+			name:       "newsapi json",
+			content:    `{"status": "ok", "totalResults": 0, "articles": []}`,
+			wantFormat: jsonFormat,
+		},
+		{
+			name:       "other json",
+			content:    `{"items": []}`,
+			wantFormat: unknownFormat,
+		},
+		{
+			name:       "other xml",
+			content:    `<sitemap></sitemap>`,
+			wantFormat: unknownFormat,
+		},
+		{
+			name:       "plain text",
+			content:    "just some text",
+			wantFormat: unknownFormat,
+		},
+		{
+			name:       "empty",
+			content:    "",
+			wantFormat: unknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotFormat := SniffFormat([]byte(tt.content)); gotFormat != tt.wantFormat {
+				t.Errorf("SniffFormat() = %v, want %v", gotFormat, tt.wantFormat)
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package html
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/sirupsen/logrus"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -31,15 +35,53 @@ type Parser struct {
 }
 
 // FeedConfig is a struct that contains the configuration for parsing HTML feeds.
+// The selectors other than ArticleSelector are relative to the article element,
+// an empty one selects the article element itself. The link is taken from the href
+// attribute of the selected element, the other fields from their attribute if it's set
+// or from the element text otherwise. The description and the publication date
+// are only parsed if their selector or attribute is set.
+// Relative links are resolved against BaseURL, or against the page URL if it's empty.
 type FeedConfig struct {
-	ArticleSelector     string
-	TitleSelector       string
-	LinkSelector        string
-	DescriptionSelector string
-	PubDateSelector     string
-	Source              string
-	DateAttribute       string
-	TimeFormat          []string
+	ArticleSelector      string
+	TitleSelector        string
+	LinkSelector         string
+	DescriptionSelector  string
+	DescriptionAttribute string
+	PubDateSelector      string
+	Source               string
+	DateAttribute        string
+	TimeFormat           []string
+	BaseURL              string
+}
+
+// Validate checks that the article selector is set, that all the selectors are valid CSS
+// and that the base URL, if set, is absolute.
+func (c FeedConfig) Validate() error {
+	if c.ArticleSelector == "" {
+		return errors.New("article selector is required")
+	}
+	selectors := []struct{ name, selector string }{
+		{"article", c.ArticleSelector},
+		{"title", c.TitleSelector},
+		{"link", c.LinkSelector},
+		{"description", c.DescriptionSelector},
+		{"pub date", c.PubDateSelector},
+	}
+	for _, s := range selectors {
+		if s.selector == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(s.selector); err != nil {
+			return fmt.Errorf("invalid %s selector %q: %w", s.name, s.selector, err)
+		}
+	}
+	if c.BaseURL != "" {
+		base, err := url.Parse(c.BaseURL)
+		if err != nil || !base.IsAbs() {
+			return fmt.Errorf("base URL %q must be an absolute URL", c.BaseURL)
+		}
+	}
+	return nil
 }
 
 // NewHtmlParser creates a new HtmlParser with the given configuration.
@@ -47,10 +89,16 @@ func NewHtmlParser(config FeedConfig) *Parser {
 	return &Parser{config: config}
 }
 
-func (h *Parser) ParseFeed(url url.URL) ([]model.Article, error) {
+// ParseFeed fetches the page at the given URL and returns a slice of articles.
+// The page request is aborted when ctx is done.
+func (h *Parser) ParseFeed(ctx context.Context, url url.URL) ([]model.Article, error) {
 	logrus.WithField("event_id", eventParseHtmlFeedStart).Infof("Starting to parse feed from URL: %s", url.String())
 
-	resp, err := http.Get(url.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		logrus.WithField("event_id", eventHttpGetError).Errorf("Error fetching URL: %s", err.Error())
 		return nil, err
@@ -69,7 +117,7 @@ func (h *Parser) ParseFeed(url url.URL) ([]model.Article, error) {
 	}
 
 	logrus.WithField("event_id", eventParseHtmlFeedSuccess).Info("Successfully fetched and parsed feed")
-	return h.parseDocument(doc), nil
+	return h.parseDocument(doc, url), nil
 }
 
 // ParseFile parses the given file and returns a slice of articles.
@@ -83,25 +131,35 @@ func (h *Parser) ParseFile(f *os.File) ([]model.Article, error) {
 	}
 
 	logrus.WithField("event_id", eventParseHtmlFileSuccess).Infof("Successfully parsed file: %s", f.Name())
-	return h.parseDocument(doc), nil
+	return h.parseDocument(doc, url.URL{}), nil
 }
 
-// parseDocument parses the goquery document and returns a slice of articles.
-func (h *Parser) parseDocument(doc *goquery.Document) []model.Article {
+// Parse parses the page read from body and returns a slice of articles.
+func (h *Parser) Parse(body io.Reader, feedURL url.URL) ([]model.Article, error) {
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		logrus.WithField("event_id", eventParseHtmlDocumentError).Errorf("Error parsing document from URL: %s", err.Error())
+		return nil, err
+	}
+	return h.parseDocument(doc, feedURL), nil
+}
+
+// parseDocument parses the goquery document of the page at pageURL and returns a slice of articles.
+func (h *Parser) parseDocument(doc *goquery.Document, pageURL url.URL) []model.Article {
 	logrus.WithField("event_id", eventParseHtmlDocumentStart).Info("Starting to parse document")
+	base := h.baseURL(pageURL)
 	var articles []model.Article
 	doc.Find(h.config.ArticleSelector).Each(func(i int, s *goquery.Selection) {
-		title := strings.TrimSpace(s.Contents().Not("svg").Text())
-		link, _ := s.Attr("href")
-		description := strings.TrimSpace(s.AttrOr(h.config.DescriptionSelector, ""))
-		date := strings.TrimSpace(s.Find(h.config.PubDateSelector).AttrOr(h.config.DateAttribute, ""))
-		parsedDate, _ := parseDate(date, h.config.TimeFormat)
 		article := model.Article{
-			Title:       title,
-			Link:        resolveLink(link, "https://www.usatoday.com"),
-			PubDate:     parsedDate,
-			Source:      model.Source{Name: h.config.Source},
-			Description: description,
+			Title:  extract(s, h.config.TitleSelector, ""),
+			Link:   resolveLink(extract(s, h.config.LinkSelector, "href"), base),
+			Source: model.Source{Name: h.config.Source},
+		}
+		if h.config.DescriptionSelector != "" || h.config.DescriptionAttribute != "" {
+			article.Description = extract(s, h.config.DescriptionSelector, h.config.DescriptionAttribute)
+		}
+		if h.config.PubDateSelector != "" || h.config.DateAttribute != "" {
+			article.PubDate, _ = parseDate(extract(s, h.config.PubDateSelector, h.config.DateAttribute), h.config.TimeFormat)
 		}
 		if article.Title != "" || article.Description != "" {
 			articles = append(articles, article)
@@ -112,6 +170,36 @@ func (h *Parser) parseDocument(doc *goquery.Document) []model.Article {
 	return articles
 }
 
+// baseURL returns the URL relative links are resolved against,
+// or nil if neither the config nor the page URL is absolute.
+func (h *Parser) baseURL(pageURL url.URL) *url.URL {
+	if h.config.BaseURL != "" {
+		if base, err := url.Parse(h.config.BaseURL); err == nil && base.IsAbs() {
+			return base
+		}
+	}
+	if pageURL.IsAbs() {
+		return &pageURL
+	}
+	return nil
+}
+
+// extract returns the attribute of the first element matching selector within the article,
+// or its text without embedded icons if attribute is empty.
+// An empty selector means the article element itself.
+func extract(article *goquery.Selection, selector, attribute string) string {
+	s := article
+	if selector != "" {
+		s = article.Find(selector).First()
+	}
+	if attribute != "" {
+		return strings.TrimSpace(s.AttrOr(attribute, ""))
+	}
+	text := s.Clone()
+	text.Find("svg").Remove()
+	return strings.TrimSpace(text.Text())
+}
+
 // parseDate parses the given date string using the provided time formats.
 func parseDate(date string, timeFormats []string) (parsedDate time.Time, err error) {
 	for _, format := range timeFormats {
@@ -123,10 +211,11 @@ func parseDate(date string, timeFormats []string) (parsedDate time.Time, err err
 	return time.Now().UTC(), fmt.Errorf("failed to parse date: %s", date)
 }
 
-// resolveLink resolves relative links to absolute using the base URL.
-func resolveLink(link, baseURL string) string {
-	if strings.HasPrefix(link, "http") {
+// resolveLink resolves relative links to absolute using the base URL, if there's one.
+func resolveLink(link string, base *url.URL) string {
+	ref, err := url.Parse(link)
+	if err != nil || base == nil || link == "" {
 		return link
 	}
-	return baseURL + link
+	return base.ResolveReference(ref).String()
 }
@@ -1,10 +1,15 @@
 package html
 
 import (
+	"context"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -67,12 +72,13 @@ func TestParser_ParseFile(t *testing.T) {
 			name: "Test USA TODAY HTML file",
 			fields: fields{
 				config: FeedConfig{
-					ArticleSelector:     "a.gnt_m_flm_a",
-					LinkSelector:        "",
-					DescriptionSelector: "data-c-br",
-					PubDateSelector:     "div.gnt_m_flm_sbt",
-					Source:              "USA TODAY",
-					DateAttribute:       "data-c-dt",
+					ArticleSelector:      "a.gnt_m_flm_a",
+					LinkSelector:         "",
+					DescriptionAttribute: "data-c-br",
+					PubDateSelector:      "div.gnt_m_flm_sbt",
+					Source:               "USA TODAY",
+					DateAttribute:        "data-c-dt",
+					BaseURL:              "https://www.usatoday.com",
 					TimeFormat: []string{
 						"2006-01-02 15:04",
 						"Jan 02, 2006",
@@ -115,6 +121,130 @@ func TestParser_ParseFile(t *testing.T) {
 	}
 }
 
+func TestParser_Parse(t *testing.T) {
+	const page = `<html><body>
+		<div class="story">
+			<h2><a href="/news/one">First <svg>icon</svg>story</a></h2>
+			<p class="summary">The first story.</p>
+			<time datetime="2024-05-19T10:42:00Z">May 19</time>
+		</div>
+		<div class="story">
+			<h2><a href="https://other.example.com/two">Second story</a></h2>
+			<time datetime="not a date">May 20</time>
+		</div>
+		<div class="story"><a href="/empty"></a></div>
+	</body></html>`
+	pageURL, _ := url.Parse("https://example.com/world/")
+
+	tests := []struct {
+		name    string
+		config  FeedConfig
+		pageURL url.URL
+		want    []model.Article
+	}{
+		{
+			name: "selectors relative to the article",
+			config: FeedConfig{
+				ArticleSelector:     "div.story",
+				TitleSelector:       "h2",
+				LinkSelector:        "h2 a",
+				DescriptionSelector: "p.summary",
+				PubDateSelector:     "time",
+				DateAttribute:       "datetime",
+				TimeFormat:          []string{time.RFC3339},
+				Source:              "Example",
+			},
+			pageURL: *pageURL,
+			want: []model.Article{
+				{
+					Title:       "First story",
+					Link:        "https://example.com/news/one",
+					Description: "The first story.",
+					PubDate:     time.Date(2024, 5, 19, 10, 42, 0, 0, time.UTC),
+					Source:      model.Source{Name: "Example"},
+				},
+				{
+					Title:  "Second story",
+					Link:   "https://other.example.com/two",
+					Source: model.Source{Name: "Example"},
+				},
+			},
+		},
+		{
+			name: "links resolved against the base URL",
+			config: FeedConfig{
+				ArticleSelector: "div.story h2 a",
+				BaseURL:         "https://cdn.example.com",
+			},
+			pageURL: *pageURL,
+			want: []model.Article{
+				{Title: "First story", Link: "https://cdn.example.com/news/one"},
+				{Title: "Second story", Link: "https://other.example.com/two"},
+			},
+		},
+		{
+			name:   "relative links kept without a base",
+			config: FeedConfig{ArticleSelector: "div.story h2 a"},
+			want: []model.Article{
+				{Title: "First story", Link: "/news/one"},
+				{Title: "Second story", Link: "https://other.example.com/two"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewHtmlParser(tt.config).Parse(strings.NewReader(page), tt.pageURL)
+			assert.NoError(t, err)
+			// the publication date falls back to the current time if it can't be parsed
+			for i := range got {
+				if i < len(tt.want) && tt.want[i].PubDate.IsZero() && !got[i].PubDate.IsZero() {
+					assert.WithinDuration(t, time.Now(), got[i].PubDate, time.Minute)
+					got[i].PubDate = time.Time{}
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFeedConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  FeedConfig
+		wantErr string
+	}{
+		{
+			name:   "valid config",
+			config: FeedConfig{ArticleSelector: "div.story", TitleSelector: "h2 > a", BaseURL: "https://example.com"},
+		},
+		{
+			name:    "missing article selector",
+			config:  FeedConfig{TitleSelector: "h2"},
+			wantErr: "article selector is required",
+		},
+		{
+			name:    "invalid selector",
+			config:  FeedConfig{ArticleSelector: "div.story", PubDateSelector: "time["},
+			wantErr: `invalid pub date selector "time["`,
+		},
+		{
+			name:    "relative base URL",
+			config:  FeedConfig{ArticleSelector: "div.story", BaseURL: "/news"},
+			wantErr: `base URL "/news" must be an absolute URL`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
 func Test_parseDate(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -150,3 +280,21 @@ func Test_parseDate(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ParseFeed_Canceled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	pageURL, _ := url.Parse(srv.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := NewHtmlParser(FeedConfig{}).ParseFeed(ctx, *pageURL)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -1,11 +1,13 @@
 package json
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/sirupsen/logrus"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"time"
@@ -16,13 +18,39 @@ const (
 	eventReadFileError        = "read_file_error"
 	eventJsonUnmarshalError   = "json_unmarshal_error"
 	eventParseJsonFileSuccess = "parse_json_file_success"
+	eventParseJsonFeedStart   = "parse_json_feed_start"
+	eventHttpGetError         = "http_get_error"
+	eventParseJsonFeedSuccess = "parse_json_feed_success"
 )
 
 // Parser is a struct that implements the Parser interface
 type Parser struct{}
 
-func (j *Parser) ParseFeed(url url.URL) ([]model.Article, error) {
-	return nil, errors.New("not implemented")
+// ParseFeed fetches the NewsAPI-style feed at the given URL and returns a slice of articles.
+// The feed request is aborted when ctx is done.
+func (j *Parser) ParseFeed(ctx context.Context, url url.URL) ([]model.Article, error) {
+	logrus.WithField("event_id", eventParseJsonFeedStart).Infof("Starting to parse feed from URL: %s", url.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logrus.WithField("event_id", eventHttpGetError).Errorf("Error fetching URL: %s", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL %s: %s", url.String(), resp.Status)
+	}
+
+	articles, err := j.Parse(resp.Body, url)
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithField("event_id", eventParseJsonFeedSuccess).Infof("Successfully parsed feed from URL: %s", url.String())
+	return articles, nil
 }
 
 // Feed is a struct that represents the JSON feed
@@ -37,7 +65,9 @@ type Feed struct {
 		Title       string    `json:"title"`
 		Description string    `json:"description"`
 		URL         string    `json:"url"`
+		URLToImage  string    `json:"urlToImage"`
 		PublishedAt time.Time `json:"publishedAt"`
+		Content     string    `json:"content"`
 	} `json:"articles"`
 }
 
@@ -45,7 +75,18 @@ type Feed struct {
 func (j *Parser) ParseFile(f *os.File) ([]model.Article, error) {
 	logrus.WithField("event_id", eventParseJsonFileStart).Infof("Starting to parse file: %s", f.Name())
 
-	bytes, err := io.ReadAll(f)
+	articles, err := j.Parse(f, url.URL{})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithField("event_id", eventParseJsonFileSuccess).Infof("File parsing completed, found %d articles", len(articles))
+	return articles, nil
+}
+
+// Parse parses the feed read from body and returns a slice of articles.
+func (j *Parser) Parse(body io.Reader, feedURL url.URL) ([]model.Article, error) {
+	bytes, err := io.ReadAll(body)
 	if err != nil {
 		logrus.WithField("event_id", eventReadFileError).Errorf("Error reading file: %s", err.Error())
 		return nil, err
@@ -64,12 +105,13 @@ func (j *Parser) ParseFile(f *os.File) ([]model.Article, error) {
 			Title:       item.Title,
 			Link:        item.URL,
 			Description: item.Description,
+			Author:      item.Author,
+			ImageURL:    item.URLToImage,
+			Content:     item.Content,
 			Source:      model.Source{Name: item.Source.Name},
 			PubDate:     item.PublishedAt,
 		}
 		articles = append(articles, article)
 	}
-
-	logrus.WithField("event_id", eventParseJsonFileSuccess).Infof("File parsing completed, found %d articles", len(articles))
 	return articles, nil
 }
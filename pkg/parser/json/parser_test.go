@@ -1,7 +1,10 @@
 package json
 
 import (
+	"context"
 	"github.com/antonchaban/news-aggregator/pkg/model"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"reflect"
@@ -25,6 +28,9 @@ func TestParser_ParseFile(t *testing.T) {
 					Title:       "Test Title",
 					Link:        "http://testurl.com",
 					Description: "Test Description",
+					Author:      "Test Author",
+					ImageURL:    "http://testurl.com/image.jpg",
+					Content:     "Test Content",
 					Source:      model.Source{Name: "Test Source"},
 					PubDate:     time.Date(2023, 6, 4, 12, 0, 0, 0, time.UTC),
 				},
@@ -91,6 +97,20 @@ func TestParser_ParseFile(t *testing.T) {
 }
 
 func TestParser_ParseFeed(t *testing.T) {
+	feed, err := os.ReadFile("testdata/valid.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(feed)
+	}))
+	defer srv.Close()
+
 	type args struct {
 		url url.URL
 	}
@@ -101,7 +121,30 @@ func TestParser_ParseFeed(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name:    "Not Implemented",
+			name: "Valid JSON Feed",
+			args: args{url: url.URL{Scheme: "http", Host: srv.Listener.Addr().String(), Path: "/feed"}},
+			want: []model.Article{
+				{
+					Title:       "Test Title",
+					Link:        "http://testurl.com",
+					Description: "Test Description",
+					Author:      "Test Author",
+					ImageURL:    "http://testurl.com/image.jpg",
+					Content:     "Test Content",
+					Source:      model.Source{Name: "Test Source"},
+					PubDate:     time.Date(2023, 6, 4, 12, 0, 0, 0, time.UTC),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Not Found",
+			args:    args{url: url.URL{Scheme: "http", Host: srv.Listener.Addr().String(), Path: "/missing"}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid URL",
 			args:    args{},
 			want:    nil,
 			wantErr: true,
@@ -110,7 +153,7 @@ func TestParser_ParseFeed(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			j := &Parser{}
-			got, err := j.ParseFeed(tt.args.url)
+			got, err := j.ParseFeed(context.Background(), tt.args.url)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseFeed() error = %v, wantErr %v", err, tt.wantErr)
 				return
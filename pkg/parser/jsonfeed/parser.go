@@ -0,0 +1,207 @@
+package jsonfeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	eventParseJsonFeedStart     = "parse_jsonfeed_start"
+	eventParseJsonFeedUrlError  = "parse_jsonfeed_url_error"
+	eventParseJsonFeedSuccess   = "parse_jsonfeed_success"
+	eventParseJsonFeedFileStart = "parse_jsonfeed_file_start"
+	eventParseJsonFeedFileEnd   = "parse_jsonfeed_file_success"
+	eventParseJsonFeedError     = "parse_jsonfeed_error"
+)
+
+// VersionPrefix is the prefix of the version URL every JSON Feed declares.
+const VersionPrefix = "https://jsonfeed.org/version/"
+
+// Parser is a struct that implements the Parser interface for JSON Feed 1.0 and 1.1 (https://jsonfeed.org).
+type Parser struct{}
+
+// Feed is a struct that represents the JSON Feed top-level object.
+// Author is the JSON Feed 1.0 field, deprecated by Authors in 1.1.
+type Feed struct {
+	Version     string  `json:"version"`
+	Title       string  `json:"title"`
+	HomePageURL string  `json:"home_page_url"`
+	FeedURL     string  `json:"feed_url"`
+	Description string  `json:"description"`
+	Language    string  `json:"language"`
+	Authors     Authors `json:"authors"`
+	Author      *Author `json:"author"`
+	Items       []Item  `json:"items"`
+}
+
+// Item is a struct that represents a single item of a JSON Feed.
+type Item struct {
+	ID            json.RawMessage `json:"id"`
+	URL           string          `json:"url"`
+	ExternalURL   string          `json:"external_url"`
+	Title         string          `json:"title"`
+	ContentHTML   string          `json:"content_html"`
+	ContentText   string          `json:"content_text"`
+	Summary       string          `json:"summary"`
+	Image         string          `json:"image"`
+	DatePublished string          `json:"date_published"`
+	DateModified  string          `json:"date_modified"`
+	Authors       Authors         `json:"authors"`
+	Author        *Author         `json:"author"`
+	Tags          []string        `json:"tags"`
+	Language      string          `json:"language"`
+}
+
+// Author is a struct that represents an author of a JSON Feed or of one of its items.
+type Author struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Avatar string `json:"avatar"`
+}
+
+// Authors is a list of authors of a JSON Feed or of one of its items.
+type Authors []Author
+
+// ParseFeed fetches the JSON Feed at the given URL and returns a slice of articles.
+// The feed request is aborted when ctx is done.
+func (j *Parser) ParseFeed(ctx context.Context, url url.URL) ([]model.Article, error) {
+	logrus.WithField("event_id", eventParseJsonFeedStart).Infof("Starting to parse feed from URL: %s", url.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logrus.WithField("event_id", eventParseJsonFeedUrlError).Errorf("Error fetching URL: %s", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL %s: %s", url.String(), resp.Status)
+	}
+
+	articles, err := j.Parse(resp.Body, url)
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithField("event_id", eventParseJsonFeedSuccess).Infof("Successfully parsed feed from URL: %s", url.String())
+	return articles, nil
+}
+
+// ParseFile parses the given JSON Feed file and returns a slice of articles.
+func (j *Parser) ParseFile(f *os.File) ([]model.Article, error) {
+	logrus.WithField("event_id", eventParseJsonFeedFileStart).Infof("Starting to parse file: %s", f.Name())
+
+	articles, err := j.Parse(f, url.URL{})
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithField("event_id", eventParseJsonFeedFileEnd).Infof("Successfully parsed file: %s", f.Name())
+	return articles, nil
+}
+
+// Parse parses the JSON Feed read from body and returns a slice of articles.
+// feedURL is the link of the feed the body was fetched from.
+func (j *Parser) Parse(body io.Reader, feedURL url.URL) ([]model.Article, error) {
+	var feed Feed
+	err := json.NewDecoder(body).Decode(&feed)
+	if err != nil {
+		logrus.WithField("event_id", eventParseJsonFeedError).Errorf("Error decoding JSON Feed: %s", err.Error())
+		return nil, err
+	}
+	if !strings.HasPrefix(feed.Version, VersionPrefix) {
+		return nil, fmt.Errorf("unsupported JSON Feed version: %q", feed.Version)
+	}
+
+	articles := make([]model.Article, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		article := model.Article{
+			Title:       strings.TrimSpace(item.Title),
+			Link:        item.URL,
+			Description: strings.TrimSpace(item.Summary),
+			Content:     item.ContentHTML,
+			Author:      item.authors(feed).String(),
+			Categories:  item.Tags,
+			ImageURL:    item.Image,
+			Language:    item.Language,
+			GUID:        item.id(),
+			Source: model.Source{
+				Name: feed.Title,
+				Link: feedURL.String(),
+			},
+		}
+		if article.Link == "" {
+			article.Link = item.ExternalURL
+		}
+		if article.Language == "" {
+			article.Language = feed.Language
+		}
+		if article.Content == "" {
+			article.Content = item.ContentText
+		}
+		if article.Description == "" {
+			article.Description = strings.TrimSpace(item.ContentText)
+		}
+		if date, ok := parseDate(item.DatePublished); ok {
+			article.PubDate = date
+		} else if date, ok := parseDate(item.DateModified); ok {
+			article.PubDate = date
+		}
+		articles = append(articles, article)
+	}
+	return articles, nil
+}
+
+// parseDate parses an RFC 3339 date of an item, which is required by the spec,
+// reporting whether it's a valid one.
+func parseDate(date string) (time.Time, bool) {
+	parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(date))
+	return parsed, err == nil
+}
+
+// id returns the ID of the item, which should be a string but is a number in some feeds.
+func (i Item) id() string {
+	var id string
+	if err := json.Unmarshal(i.ID, &id); err == nil {
+		return id
+	}
+	return string(i.ID)
+}
+
+// authors returns the authors of the item, falling back to the authors of the feed.
+// The JSON Feed 1.0 author field is used when authors is missing.
+func (i Item) authors(feed Feed) Authors {
+	switch {
+	case len(i.Authors) > 0:
+		return i.Authors
+	case i.Author != nil:
+		return Authors{*i.Author}
+	case len(feed.Authors) > 0:
+		return feed.Authors
+	case feed.Author != nil:
+		return Authors{*feed.Author}
+	default:
+		return nil
+	}
+}
+
+// String returns the comma-separated names of the authors.
+func (a Authors) String() string {
+	names := make([]string, 0, len(a))
+	for _, author := range a {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,160 @@
+package jsonfeed
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func validArticles(feedLink string) []model.Article {
+	source := model.Source{Name: "Sample JSON Feed", Link: feedLink}
+	return []model.Article{
+		{
+			Title:       "Article 1",
+			Link:        "http://example.com/article1",
+			Description: "This is the first article.",
+			Content:     "<p>The full first article.</p>",
+			Author:      "Jane Doe, John Doe",
+			Categories:  []string{"World", "Europe"},
+			ImageURL:    "http://example.com/article1.png",
+			Language:    "en-gb",
+			GUID:        "1",
+			Source:      source,
+			PubDate:     time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			Title:       "Article 2",
+			Link:        "http://example.org/article2",
+			Description: "This is the second article.",
+			Content:     "This is the second article.",
+			Author:      "Feed Author",
+			Language:    "en",
+			GUID:        "2",
+			Source:      source,
+			PubDate:     time.Date(2006, time.January, 3, 15, 4, 5, 0, time.UTC),
+		},
+	}
+}
+
+func TestParser_ParseFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		want     []model.Article
+		wantErr  bool
+	}{
+		{
+			name:     "JSON Feed 1.1",
+			fileName: "valid_1_1.json",
+			want:     validArticles(""),
+			wantErr:  false,
+		},
+		{
+			name:     "JSON Feed 1.0",
+			fileName: "valid_1_0.json",
+			want: []model.Article{
+				{
+					Link:        "http://example.com/article1",
+					Description: "This is the first article.",
+					Content:     "This is the first article.",
+					Author:      "Jane Doe",
+					GUID:        "1",
+					Source:      model.Source{Name: "Legacy JSON Feed"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:     "Unsupported Version",
+			fileName: "unsupported_version.json",
+			want:     nil,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Parser{}
+			f := loadTestData(t, tt.fileName)
+			defer f.Close()
+			got, err := j.ParseFile(f)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFile() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseFeed(t *testing.T) {
+	feed, err := os.ReadFile("testdata/valid_1_1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.json":
+			w.Header().Set("Content-Type", "application/feed+json")
+			_, _ = w.Write(feed)
+		case "/invalid.json":
+			_, _ = w.Write([]byte("{"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []model.Article
+		wantErr bool
+	}{
+		{
+			name: "Valid JSON Feed",
+			path: "/feed.json",
+			want: validArticles(srv.URL + "/feed.json"),
+		},
+		{
+			name:    "Invalid JSON",
+			path:    "/invalid.json",
+			wantErr: true,
+		},
+		{
+			name:    "Not Found",
+			path:    "/missing.json",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feedURL, _ := url.Parse(srv.URL + tt.path)
+			j := &Parser{}
+			got, err := j.ParseFeed(context.Background(), *feedURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFeed() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFeed() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func loadTestData(t *testing.T, filename string) *os.File {
+	file, err := os.Open("testdata/" + filename)
+	if err != nil {
+		t.Fatalf("failed to open test data file %s: %v", filename, err)
+	}
+	return file
+}
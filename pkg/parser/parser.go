@@ -1,38 +1,141 @@
 package parser
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/parser/atom"
 	"github.com/antonchaban/news-aggregator/pkg/parser/html"
 	"github.com/antonchaban/news-aggregator/pkg/parser/json"
+	"github.com/antonchaban/news-aggregator/pkg/parser/jsonfeed"
 	"github.com/antonchaban/news-aggregator/pkg/parser/rss"
 	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
 )
 
+const (
+	headerETag            = "ETag"
+	headerLastModified    = "Last-Modified"
+	headerIfNoneMatch     = "If-None-Match"
+	headerIfModifiedSince = "If-Modified-Since"
+)
+
 // Parser is an interface that defines parsing strategy
 type Parser interface {
 	ParseFile(f *os.File) ([]model.Article, error)
-	ParseFeed(urlPath url.URL) ([]model.Article, error)
+	ParseFeed(ctx context.Context, urlPath url.URL) ([]model.Article, error)
+	Parse(body io.Reader, feedURL url.URL) ([]model.Article, error)
+}
+
+// FeedResponse is the result of a conditional fetch of a feed.
+// It has the following fields:
+// - Articles: the articles of the feed, empty if the feed wasn't modified
+// - NotModified: whether the server answered 304 Not Modified
+// - ETag: the ETag validator of the feed
+// - LastModified: the Last-Modified validator of the feed
+// - StatusCode: the status code of the response, zero if no response was received
+// - Bytes: the size of the downloaded feed
+type FeedResponse struct {
+	Articles     []model.Article
+	NotModified  bool
+	ETag         string
+	LastModified string
+	StatusCode   int
+	Bytes        int64
+}
+
+// ParseArticlesFromFeed fetches the feed of the source and returns its articles.
+// The feed request is aborted when ctx is done.
+func ParseArticlesFromFeed(ctx context.Context, src model.Source) ([]model.Article, error) {
+	src.ETag, src.LastModified = "", ""
+	resp, err := FetchFeed(ctx, src)
+	return resp.Articles, err
 }
 
-func ParseArticlesFromFeed(urlPath url.URL) ([]model.Article, error) {
-	format, err := DetermineFeedFormat(urlPath)
+// FetchFeed fetches the feed of the source with a single GET request and parses it
+// with the parser matching the sniffed content of the response, or its Content-Type
+// if the content doesn't tell. HTML pages are scraped with the config of the source.
+// The ETag and Last-Modified validators of the previous fetch, if any, are sent
+// as If-None-Match and If-Modified-Since, so an unchanged feed isn't downloaded again.
+// The status code and size of the response are reported even if the fetch fails.
+// The feed request is aborted when ctx is done.
+func FetchFeed(ctx context.Context, src model.Source) (FeedResponse, error) {
+	urlPath, err := url.Parse(src.Link)
 	if err != nil {
-		logrus.Errorf("error occurred while determining feed format: %s", err.Error())
-		return nil, err
+		return FeedResponse{}, err
+	}
+	etag, lastModified := src.ETag, src.LastModified
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlPath.String(), nil)
+	if err != nil {
+		return FeedResponse{}, err
+	}
+	if etag != "" {
+		req.Header.Set(headerIfNoneMatch, etag)
+	}
+	if lastModified != "" {
+		req.Header.Set(headerIfModifiedSince, lastModified)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logrus.Errorf("error occurred while fetching feed: %s", err.Error())
+		return FeedResponse{}, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logrus.Errorf("error occurred while closing response body: %s", err.Error())
+		}
+	}(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return FeedResponse{
+			NotModified:  true,
+			ETag:         headerOr(resp.Header, headerETag, etag),
+			LastModified: headerOr(resp.Header, headerLastModified, lastModified),
+			StatusCode:   resp.StatusCode,
+		}, nil
+	case http.StatusOK:
+	default:
+		return FeedResponse{StatusCode: resp.StatusCode},
+			fmt.Errorf("unexpected status fetching feed %s: %s", urlPath.String(), resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	fetched := FeedResponse{StatusCode: resp.StatusCode, Bytes: int64(len(body))}
+	if err != nil {
+		return fetched, err
+	}
+	format := SniffFormat(body)
+	if format == unknownFormat {
+		format = feedFormat(resp.Header.Get("Content-Type"))
 	}
-	parser, err := createParser(format)
+	parser, err := createParser(format, src)
 	if err != nil {
 		logrus.Errorf("error occurred while creating parser: %s", err.Error())
-		return nil, err
+		return fetched, err
 	}
-	feed, err := parser.ParseFeed(urlPath)
+	articles, err := parser.Parse(bytes.NewReader(body), *urlPath)
 	if err != nil {
 		logrus.Errorf("error occurred while parsing feed: %s", err.Error())
+		return fetched, err
+	}
+	fetched.Articles = articles
+	fetched.ETag = resp.Header.Get(headerETag)
+	fetched.LastModified = resp.Header.Get(headerLastModified)
+	return fetched, nil
+}
+
+// headerOr returns the value of the header key, or def if the header isn't set.
+func headerOr(header http.Header, key, def string) string {
+	if value := header.Get(key); value != "" {
+		return value
 	}
-	return feed, err
+	return def
 }
 
 // ParseArticlesFromFile Parse function takes a file and returns a slice of parsed articles
@@ -49,8 +152,11 @@ func ParseArticlesFromFile(file string) ([]model.Article, error) {
 		}
 	}(f)
 
-	format := DetermineFileFormat(file)
-	parser, err := createParser(format)
+	format, err := determineFileFormat(f)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := createParser(format, model.Source{})
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -65,29 +171,61 @@ func ParseArticlesFromFile(file string) ([]model.Article, error) {
 	return articles, nil
 }
 
-func createParser(format string) (Parser, error) {
+// determineFileFormat sniffs the format of the file content, falling back to its extension
+// if the content doesn't tell. The file is rewound for the parser afterward.
+func determineFileFormat(f *os.File) (string, error) {
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return unknownFormat, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return unknownFormat, err
+	}
+	if format := SniffFormat(content); format != unknownFormat {
+		return format, nil
+	}
+	return DetermineFileFormat(f.Name()), nil
+}
+
+// createParser creates the parser of the given format for the source.
+// HTML pages can only be parsed with the scraping config of their source.
+func createParser(format string, src model.Source) (Parser, error) {
 	switch format {
 	case rssFormat:
 		return &rss.Parser{}, nil
+	case atomFormat:
+		return &atom.Parser{}, nil
 	case jsonFormat:
 		return &json.Parser{}, nil
+	case jsonFeedFormat:
+		return &jsonfeed.Parser{}, nil
 	case htmlFormat:
-		config := html.FeedConfig{
-			ArticleSelector:     "div.gnt_m.gnt_m_flm > a.gnt_m_flm_a",
-			TitleSelector:       "",
-			LinkSelector:        "",
-			DescriptionSelector: "data-c-br",
-			PubDateSelector:     "div.gnt_m_flm_sbt",
-			Source:              "USA TODAY",
-			DateAttribute:       "data-c-dt",
-			TimeFormat: []string{
-				"3:04 p.m. ET January 2, 2006",
-				"2006-01-02 15:04",
-				"Jan 02, 2006",
-			},
+		if src.Scrape == nil {
+			return nil, fmt.Errorf("no scraping config for HTML source %q", src.Link)
 		}
-		return html.NewHtmlParser(config), nil
+		return html.NewHtmlParser(htmlConfig(src.Name, *src.Scrape)), nil
 	default:
 		return nil, fmt.Errorf("unsupported file format: %s", format)
 	}
 }
+
+// ValidateScrapeConfig checks that the scraping config can be used to parse HTML pages.
+func ValidateScrapeConfig(config model.ScrapeConfig) error {
+	return htmlConfig("", config).Validate()
+}
+
+// htmlConfig returns the HTML parser config for the scraping config of the source with the given name.
+func htmlConfig(name string, config model.ScrapeConfig) html.FeedConfig {
+	return html.FeedConfig{
+		ArticleSelector:      config.ArticleSelector,
+		TitleSelector:        config.TitleSelector,
+		LinkSelector:         config.LinkSelector,
+		DescriptionSelector:  config.DescriptionSelector,
+		DescriptionAttribute: config.DescriptionAttribute,
+		PubDateSelector:      config.PubDateSelector,
+		Source:               name,
+		DateAttribute:        config.DateAttribute,
+		TimeFormat:           config.DateFormats,
+		BaseURL:              config.BaseURL,
+	}
+}
@@ -1,10 +1,17 @@
 package parser
 
 import (
+	"context"
 	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/parser/atom"
+	"github.com/antonchaban/news-aggregator/pkg/parser/html"
 	"github.com/antonchaban/news-aggregator/pkg/parser/json"
+	"github.com/antonchaban/news-aggregator/pkg/parser/jsonfeed"
 	"github.com/antonchaban/news-aggregator/pkg/parser/rss"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -43,7 +50,7 @@ func TestParseArticlesFromFeed(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseArticlesFromFeed(tt.args.urlPath)
+			got, err := ParseArticlesFromFeed(context.Background(), model.Source{Link: tt.args.urlPath.String()})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseArticlesFromFeed() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -56,6 +63,132 @@ func TestParseArticlesFromFeed(t *testing.T) {
 	}
 }
 
+func TestFetchFeed(t *testing.T) {
+	feed, err := os.ReadFile("testdata/rss.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	atomFeed, err := os.ReadFile("testdata/atom.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonFeed, err := os.ReadFile("testdata/jsonfeed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected %s request, the feed must be fetched with a single GET", r.Method)
+		}
+		switch r.URL.Path {
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/atom":
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = w.Write(atomFeed)
+			return
+		case "/jsonfeed":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(jsonFeed)
+			return
+		case "/last-modified":
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write(feed)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		etag         string
+		lastModified string
+		want         FeedResponse
+		wantArticles int
+		wantErr      bool
+	}{
+		{
+			name:         "first fetch with ETag",
+			path:         "/etag",
+			want:         FeedResponse{ETag: `"v1"`, StatusCode: http.StatusOK, Bytes: int64(len(feed))},
+			wantArticles: 2,
+		},
+		{
+			name: "unchanged ETag",
+			path: "/etag",
+			etag: `"v1"`,
+			want: FeedResponse{NotModified: true, ETag: `"v1"`, StatusCode: http.StatusNotModified},
+		},
+		{
+			name:         "changed ETag",
+			path:         "/etag",
+			etag:         `"v0"`,
+			want:         FeedResponse{ETag: `"v1"`, StatusCode: http.StatusOK, Bytes: int64(len(feed))},
+			wantArticles: 2,
+		},
+		{
+			name:         "first fetch with Last-Modified",
+			path:         "/last-modified",
+			want:         FeedResponse{LastModified: lastModified, StatusCode: http.StatusOK, Bytes: int64(len(feed))},
+			wantArticles: 2,
+		},
+		{
+			name:         "unchanged Last-Modified",
+			path:         "/last-modified",
+			lastModified: lastModified,
+			want:         FeedResponse{NotModified: true, LastModified: lastModified, StatusCode: http.StatusNotModified},
+		},
+		{
+			name:         "atom sniffed from the content",
+			path:         "/atom",
+			want:         FeedResponse{StatusCode: http.StatusOK, Bytes: int64(len(atomFeed))},
+			wantArticles: 2,
+		},
+		{
+			name:         "json feed sniffed from the content",
+			path:         "/jsonfeed",
+			want:         FeedResponse{StatusCode: http.StatusOK, Bytes: int64(len(jsonFeed))},
+			wantArticles: 2,
+		},
+		{
+			name:    "unexpected status",
+			path:    "/missing",
+			want:    FeedResponse{StatusCode: http.StatusNotFound},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := model.Source{Link: srv.URL + tt.path, ETag: tt.etag, LastModified: tt.lastModified}
+			got, err := FetchFeed(context.Background(), src)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FetchFeed() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got.Articles) != tt.wantArticles {
+				t.Errorf("FetchFeed() got %d articles, want %d", len(got.Articles), tt.wantArticles)
+			}
+			got.Articles = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FetchFeed() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseArticlesFromFile(t *testing.T) {
 	type args struct {
 		file string
@@ -89,6 +222,64 @@ func TestParseArticlesFromFile(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "atom",
+			args: args{
+				file: "testdata/atom.xml",
+			},
+			want: []model.Article{
+				{
+					Title:       "Article 1",
+					Link:        "http://example.com/article1",
+					Description: "This is the first article.",
+					Content:     "<p>The full first article.</p>",
+					Author:      "Jane Doe, John Doe",
+					GUID:        "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a",
+					Source:      model.Source{Name: "Sample Atom Feed"},
+					PubDate:     time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+				},
+				{
+					Title:       "Article 2",
+					Link:        "http://example.com/article2",
+					Description: "This is the second article.",
+					Content:     "This is the second article.",
+					Author:      "Feed Author",
+					GUID:        "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6b",
+					Source:      model.Source{Name: "Sample Atom Feed"},
+					PubDate:     time.Date(2006, time.January, 3, 15, 4, 5, 0, time.UTC),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "json feed",
+			args: args{
+				file: "testdata/jsonfeed.json",
+			},
+			want: []model.Article{
+				{
+					Title:       "Article 1",
+					Link:        "http://example.com/article1",
+					Description: "This is the first article.",
+					Content:     "<p>The full first article.</p>",
+					Author:      "Jane Doe, John Doe",
+					GUID:        "1",
+					Source:      model.Source{Name: "Sample JSON Feed"},
+					PubDate:     time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC),
+				},
+				{
+					Title:       "Article 2",
+					Link:        "http://example.org/article2",
+					Description: "This is the second article.",
+					Content:     "This is the second article.",
+					Author:      "Feed Author",
+					GUID:        "2",
+					Source:      model.Source{Name: "Sample JSON Feed"},
+					PubDate:     time.Date(2006, time.January, 3, 15, 4, 5, 0, time.UTC),
+				},
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -107,6 +298,7 @@ func TestParseArticlesFromFile(t *testing.T) {
 func Test_createParser(t *testing.T) {
 	type args struct {
 		format string
+		src    model.Source
 	}
 	tests := []struct {
 		name    string
@@ -130,10 +322,59 @@ func Test_createParser(t *testing.T) {
 			want:    &json.Parser{},
 			wantErr: false,
 		},
+		{
+			name: "atom",
+			args: args{
+				format: atomFormat,
+			},
+			want:    &atom.Parser{},
+			wantErr: false,
+		},
+		{
+			name: "json feed",
+			args: args{
+				format: jsonFeedFormat,
+			},
+			want:    &jsonfeed.Parser{},
+			wantErr: false,
+		},
+		{
+			name: "html",
+			args: args{
+				format: htmlFormat,
+				src: model.Source{
+					Name:   "Example",
+					Scrape: &model.ScrapeConfig{ArticleSelector: "article", DateFormats: []string{time.RFC3339}},
+				},
+			},
+			want: html.NewHtmlParser(html.FeedConfig{
+				ArticleSelector: "article",
+				Source:          "Example",
+				TimeFormat:      []string{time.RFC3339},
+			}),
+			wantErr: false,
+		},
+		{
+			name: "html without scraping config",
+			args: args{
+				format: htmlFormat,
+				src:    model.Source{Link: "http://example.com"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unknown",
+			args: args{
+				format: unknownFormat,
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := createParser(tt.args.format)
+			got, err := createParser(tt.args.format, tt.args.src)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("createParser() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1,11 +1,14 @@
 package rss
 
 import (
+	"context"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/mmcdole/gofeed"
 	"github.com/sirupsen/logrus"
+	"io"
 	"net/url"
 	"os"
+	"strings"
 )
 
 const (
@@ -15,6 +18,7 @@ const (
 	eventParseRssFileStart         = "parse_rss_file_start"
 	eventParseRssFileError         = "parse_rss_file_error"
 	eventParseRssFileSuccess       = "parse_rss_file_success"
+	eventParseRssBodyError         = "parse_rss_body_error"
 	eventParseRssFeedItems         = "parse_rss_feed_items"
 	eventParseRssFeedItemsComplete = "parse_rss_feed_items_complete"
 )
@@ -23,11 +27,12 @@ const (
 type Parser struct{}
 
 // ParseFeed parses the given URL and returns a slice of articles.
-func (r *Parser) ParseFeed(url url.URL) ([]model.Article, error) {
+// The feed request is aborted when ctx is done.
+func (r *Parser) ParseFeed(ctx context.Context, url url.URL) ([]model.Article, error) {
 	logrus.WithField("event_id", eventParseRssFeedStart).Infof("Starting to parse feed from URL: %s", url.String())
 
 	parser := gofeed.NewParser()
-	feed, err := parser.ParseURL(url.String())
+	feed, err := parser.ParseURLWithContext(url.String(), ctx)
 	if err != nil {
 		logrus.WithField("event_id", eventParseRssUrlError).Errorf("Error parsing URL: %s", err.Error())
 		return nil, err
@@ -52,6 +57,18 @@ func (r *Parser) ParseFile(f *os.File) ([]model.Article, error) {
 	return r.parseFeed(feed, url.URL{}), nil
 }
 
+// Parse parses the feed read from body and returns a slice of articles.
+// feedURL is the link of the feed the body was fetched from.
+func (r *Parser) Parse(body io.Reader, feedURL url.URL) ([]model.Article, error) {
+	parser := gofeed.NewParser()
+	feed, err := parser.Parse(body)
+	if err != nil {
+		logrus.WithField("event_id", eventParseRssBodyError).Errorf("Error parsing feed: %s", err.Error())
+		return nil, err
+	}
+	return r.parseFeed(feed, feedURL), nil
+}
+
 // parseFeed is a helper method that processes the parsed feed and returns articles.
 func (r *Parser) parseFeed(feed *gofeed.Feed, feedUrl url.URL) []model.Article {
 	logrus.WithField("event_id", eventParseRssFeedItems).Info("Processing feed items")
@@ -61,6 +78,12 @@ func (r *Parser) parseFeed(feed *gofeed.Feed, feedUrl url.URL) []model.Article {
 			Title:       item.Title,
 			Link:        item.Link,
 			Description: item.Description,
+			Author:      authors(item),
+			Categories:  item.Categories,
+			ImageURL:    imageURL(item),
+			Content:     strings.TrimSpace(item.Content),
+			Language:    feed.Language,
+			GUID:        item.GUID,
 			Source: model.Source{
 				Name: feed.Title,
 				Link: feedUrl.String(),
@@ -74,3 +97,36 @@ func (r *Parser) parseFeed(feed *gofeed.Feed, feedUrl url.URL) []model.Article {
 	logrus.WithField("event_id", eventParseRssFeedItemsComplete).Infof("Completed processing feed items, found %d articles", len(articles))
 	return articles
 }
+
+// authors returns the comma-separated names of the item authors,
+// falling back to their emails for the authors without a name.
+func authors(item *gofeed.Item) string {
+	people := item.Authors
+	if len(people) == 0 && item.Author != nil {
+		people = []*gofeed.Person{item.Author}
+	}
+	names := make([]string, 0, len(people))
+	for _, person := range people {
+		name := strings.TrimSpace(person.Name)
+		if name == "" {
+			name = strings.TrimSpace(person.Email)
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// imageURL returns the link to the item image, or to its first image enclosure if it has no image.
+func imageURL(item *gofeed.Item) string {
+	if item.Image != nil && item.Image.URL != "" {
+		return item.Image.URL
+	}
+	for _, enclosure := range item.Enclosures {
+		if strings.HasPrefix(enclosure.Type, "image/") {
+			return enclosure.URL
+		}
+	}
+	return ""
+}
@@ -1,8 +1,12 @@
 package rss
 
 import (
+	"context"
+	"errors"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/mmcdole/gofeed"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"reflect"
@@ -38,6 +42,25 @@ func TestParser_ParseFile(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:     "Metadata RSS",
+			fileName: "metadata_rss.xml",
+			want: []model.Article{
+				{
+					Title:       "Article 1",
+					Link:        "http://example.com/article1",
+					Description: "This is the first article.",
+					Author:      "Jane Doe",
+					Categories:  []string{"World", "Europe"},
+					ImageURL:    "http://example.com/article1.jpg",
+					Content:     "<p>The full first article.</p>",
+					Language:    "en-gb",
+					GUID:        "article-1",
+					Source:      model.Source{Name: "Sample Feed"},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:     "Invalid RSS",
 			fileName: "invalid_rss.xml",
@@ -140,6 +163,52 @@ func TestParser_parseFeed(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Authors and image",
+			args: args{
+				feed: &gofeed.Feed{
+					Title: "Sample Feed",
+					Items: []*gofeed.Item{
+						{
+							Title:   "Article 1",
+							Link:    "http://example.com/article1",
+							Authors: []*gofeed.Person{{Name: "Jane Doe"}, {Email: "john@example.com"}},
+							Image:   &gofeed.Image{URL: "http://example.com/article1.png"},
+							Enclosures: []*gofeed.Enclosure{
+								{URL: "http://example.com/article1.mp3", Type: "audio/mpeg"},
+								{URL: "http://example.com/article1.jpg", Type: "image/jpeg"},
+							},
+						},
+						{
+							Title:  "Article 2",
+							Link:   "http://example.com/article2",
+							Author: &gofeed.Person{Name: "Mary Roe"},
+							Enclosures: []*gofeed.Enclosure{
+								{URL: "http://example.com/article2.mp3", Type: "audio/mpeg"},
+								{URL: "http://example.com/article2.jpg", Type: "image/jpeg"},
+							},
+						},
+					},
+				},
+				feedUrl: url.URL{},
+			},
+			want: []model.Article{
+				{
+					Title:    "Article 1",
+					Link:     "http://example.com/article1",
+					Author:   "Jane Doe, john@example.com",
+					ImageURL: "http://example.com/article1.png",
+					Source:   model.Source{Name: "Sample Feed"},
+				},
+				{
+					Title:    "Article 2",
+					Link:     "http://example.com/article2",
+					Author:   "Mary Roe",
+					ImageURL: "http://example.com/article2.jpg",
+					Source:   model.Source{Name: "Sample Feed"},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -235,3 +304,25 @@ func TestParser_ParseFile1(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ParseFeed_Canceled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	feedURL, _ := url.Parse(srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := (&Parser{}).ParseFeed(ctx, *feedURL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ParseFeed() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ParseFeed() took %v after the context was done", elapsed)
+	}
+}
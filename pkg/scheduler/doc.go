@@ -9,5 +9,8 @@
 //
 // 3. Stop the scheduler when it is no longer needed.
 //
-// 4. Periodically update articles from all sources.
+// 4. Fetch every enabled source on its own interval or cron schedule, keeping one job per source
+// in sync as sources are added, changed or deleted.
+//
+// 5. Periodically prune the articles beyond the retention policies.
 package scheduler
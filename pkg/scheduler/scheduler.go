@@ -1,9 +1,15 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"github.com/antonchaban/news-aggregator/pkg/handler/web"
+	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/go-co-op/gocron"
 	"github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,54 +20,330 @@ const (
 	eventSchedulerStarted      = "scheduler_started"
 	eventSchedulerStop         = "scheduler_stop"
 	eventSchedulerStopped      = "scheduler_stopped"
-	eventUpdateArticlesStart   = "update_articles_start"
+	eventSchedulerPaused       = "scheduler_paused"
+	eventSchedulerResumed      = "scheduler_resumed"
+	eventSchedulerRunNow       = "scheduler_run_now"
+	eventSyncSourcesError      = "sync_sources_error"
+	eventSourceScheduled       = "source_scheduled"
+	eventSourceUnscheduled     = "source_unscheduled"
 	eventUpdateArticlesError   = "update_articles_error"
 	eventUpdateArticlesSuccess = "update_articles_success"
+	eventSourceFetchFailed     = "source_fetch_failed"
+	eventPruneArticlesError    = "prune_articles_error"
+	eventPruneArticlesSuccess  = "prune_articles_success"
 )
 
-// Scheduler is a struct that holds the gocron.Scheduler instance and the services required for the scheduler to work
+const (
+	// pruneInterval is how often the articles beyond the retention policies are pruned.
+	pruneInterval = time.Hour
+	// syncInterval is how often the fetch jobs are reconciled with the sources,
+	// picking up the sources added, changed or deleted since.
+	syncInterval = 30 * time.Second
+	// sourceJobTag tags the jobs fetching the sources, so they can be run at once.
+	sourceJobTag = "source"
+)
+
+// ErrPaused is returned when running the fetches of a paused scheduler.
+var ErrPaused = errors.New("scheduler is paused")
+
+// sourceJob is the job fetching a single source on its schedule.
+type sourceJob struct {
+	job      *gocron.Job
+	schedule model.FetchSchedule
+}
+
+// sourceFetch is the latest fetch of a single source.
+type sourceFetch struct {
+	result    model.SourceFetchResult
+	startedAt time.Time
+}
+
+// Scheduler is a struct that holds the gocron.Scheduler instance and the services required for the scheduler to work.
+// Every enabled source is fetched by its own job on its own schedule.
+// A nil retention service means the articles are never pruned.
 type Scheduler struct {
 	scheduler *gocron.Scheduler
 	asvc      web.ArticleService
 	ssvc      web.SourceService
+	rsvc      web.RetentionService
+
+	// ctx is canceled on Stop to abort the fetches in progress
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	jobsMu sync.Mutex
+	jobs   map[int]sourceJob
+	paused atomic.Bool
+
+	mu      sync.RWMutex
+	fetches map[int]sourceFetch
 }
 
-// NewScheduler initializes a new Scheduler instance with the provided article and source services.
-func NewScheduler(asvc web.ArticleService, ssvc web.SourceService) *Scheduler {
+// NewScheduler initializes a new Scheduler instance with the provided article, source and retention services.
+func NewScheduler(asvc web.ArticleService, ssvc web.SourceService, rsvc web.RetentionService) *Scheduler {
 	logrus.WithField("event_id", eventSchedulerInitialized).Info("Initializing Scheduler")
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		scheduler: gocron.NewScheduler(time.UTC),
 		asvc:      asvc,
 		ssvc:      ssvc,
+		rsvc:      rsvc,
+		ctx:       ctx,
+		cancel:    cancel,
+		jobs:      make(map[int]sourceJob),
+		fetches:   make(map[int]sourceFetch),
 	}
 }
 
-// Start schedules the updateArticles task to run every minute and starts the scheduler asynchronously.
+// Start schedules the syncSources task to run every syncInterval and the pruneArticles task to run
+// every pruneInterval, then starts the scheduler asynchronously.
+// The first sync schedules the fetch jobs of the sources.
 func (s *Scheduler) Start() {
 	logrus.WithField("event_id", eventSchedulerStart).Info("Starting scheduler")
-	_, err := s.scheduler.Every(1).Minute().Do(s.updateArticles)
+	_, err := s.scheduler.Every(syncInterval).Do(s.syncSources)
 	if err != nil {
-		logrus.WithField("event_id", eventScheduleError).Errorf("Error scheduling updateArticles job: %s", err.Error())
+		logrus.WithField("event_id", eventScheduleError).Errorf("Error scheduling syncSources job: %s", err.Error())
 		return
 	}
+	if s.rsvc != nil {
+		_, err = s.scheduler.Every(pruneInterval).Do(s.pruneArticles)
+		if err != nil {
+			logrus.WithField("event_id", eventScheduleError).Errorf("Error scheduling pruneArticles job: %s", err.Error())
+			return
+		}
+	}
 	s.scheduler.StartAsync()
 	logrus.WithField("event_id", eventSchedulerStarted).Info("Scheduler started successfully")
 }
 
-// Stop stops the scheduler and logs the stop event.
+// Stop stops the scheduler, aborting the fetches in progress, and logs the stop event.
 func (s *Scheduler) Stop() {
 	logrus.WithField("event_id", eventSchedulerStop).Info("Stopping scheduler")
+	s.cancel()
 	s.scheduler.Stop()
 	logrus.WithField("event_id", eventSchedulerStopped).Info("Scheduler stopped successfully")
 }
 
-// updateArticles fetches articles from all sources using the source service.
-func (s *Scheduler) updateArticles() {
-	logrus.WithField("event_id", eventUpdateArticlesStart).Info("Updating articles...")
-	err := s.ssvc.FetchFromAllSources()
+// Pause skips the scheduled jobs until Resume is called. The jobs in progress run to completion.
+func (s *Scheduler) Pause() {
+	s.scheduler.PauseJobExecution(true)
+	s.paused.Store(true)
+	logrus.WithField("event_id", eventSchedulerPaused).Info("Scheduler paused")
+}
+
+// Resume runs the scheduled jobs again after Pause.
+func (s *Scheduler) Resume() {
+	s.scheduler.PauseJobExecution(false)
+	s.paused.Store(false)
+	logrus.WithField("event_id", eventSchedulerResumed).Info("Scheduler resumed")
+}
+
+// RunNow syncs the fetch jobs with the sources and runs all of them immediately,
+// without waiting for the fetches to complete. A job still running from its schedule is not run twice.
+func (s *Scheduler) RunNow(ctx context.Context) error {
+	if s.paused.Load() {
+		return ErrPaused
+	}
+	if err := s.Sync(ctx); err != nil {
+		return err
+	}
+
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"event_id": eventSchedulerRunNow,
+		"sources":  len(s.jobs),
+	}).Info("Running all sources")
+	if len(s.jobs) == 0 {
+		return nil
+	}
+	return s.scheduler.RunByTag(sourceJobTag)
+}
+
+// Status returns whether the scheduler is paused, when the next source is due and the latest fetches.
+func (s *Scheduler) Status() model.SchedulerStatus {
+	status := model.SchedulerStatus{
+		Paused:     s.paused.Load(),
+		LastReport: s.LastReport(),
+	}
+
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	status.Sources = len(s.jobs)
+	for _, job := range s.jobs {
+		next := job.job.NextRun()
+		if next.IsZero() {
+			continue
+		}
+		if status.NextRun == nil || next.Before(*status.NextRun) {
+			status.NextRun = &next
+		}
+	}
+	return status
+}
+
+// LastReport returns the latest fetch of every scheduled source, ordered by source ID.
+// The report starts with the oldest of these fetches and lasts until the newest one ended.
+func (s *Scheduler) LastReport() model.FetchReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]int, 0, len(s.fetches))
+	for id := range s.fetches {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var report model.FetchReport
+	var endedAt time.Time
+	for _, id := range ids {
+		fetch := s.fetches[id]
+		report.Sources = append(report.Sources, fetch.result)
+		if report.StartedAt.IsZero() || fetch.startedAt.Before(report.StartedAt) {
+			report.StartedAt = fetch.startedAt
+		}
+		if end := fetch.startedAt.Add(fetch.result.Duration); end.After(endedAt) {
+			endedAt = end
+		}
+	}
+	report.Duration = endedAt.Sub(report.StartedAt)
+	return report
+}
+
+// Sync reconciles the fetch jobs with the effective schedules of the sources: it schedules the enabled sources
+// without a job, reschedules the ones whose schedule changed, such as a failing source backing off,
+// and unschedules the deleted and disabled ones.
+// A source with an invalid schedule is logged and left unscheduled.
+func (s *Scheduler) Sync(ctx context.Context) error {
+	sources, err := s.ssvc.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	scheduled := make(map[int]bool, len(sources))
+	for _, src := range sources {
+		schedule := src.EffectiveSchedule()
+		if !schedule.Enabled {
+			continue
+		}
+		current, rescheduled := s.jobs[src.Id]
+		if rescheduled {
+			if current.schedule == schedule {
+				scheduled[src.Id] = true
+				continue
+			}
+			s.scheduler.RemoveByReference(current.job)
+			delete(s.jobs, src.Id)
+		}
+		job, err := s.schedule(src.Id, schedule, rescheduled)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"event_id": eventScheduleError,
+				"source":   src.ShortName,
+			}).Errorf("Error scheduling source: %s", err.Error())
+			continue
+		}
+		s.jobs[src.Id] = sourceJob{job: job, schedule: schedule}
+		scheduled[src.Id] = true
+		logrus.WithFields(logrus.Fields{
+			"event_id": eventSourceScheduled,
+			"source":   src.ShortName,
+			"interval": schedule.Interval,
+			"cron":     schedule.Cron,
+		}).Info("Source scheduled")
+	}
+	for id := range s.jobs {
+		if !scheduled[id] {
+			s.unschedule(id)
+			logrus.WithFields(logrus.Fields{
+				"event_id": eventSourceUnscheduled,
+				"source":   id,
+			}).Info("Source unscheduled")
+		}
+	}
+	return nil
+}
+
+// schedule adds the job fetching the source on the schedule.
+// The job never overlaps with its own previous run. A new source is fetched right away,
+// a rescheduled one waits for its new interval instead.
+func (s *Scheduler) schedule(id int, schedule model.FetchSchedule, rescheduled bool) (*gocron.Job, error) {
+	if schedule.Cron != "" {
+		return s.scheduler.Cron(schedule.Cron).Tag(sourceJobTag).SingletonMode().Do(s.fetchSource, id)
+	}
+	every, err := schedule.Every()
+	if err != nil {
+		return nil, err
+	}
+	if rescheduled {
+		return s.scheduler.Every(every).WaitForSchedule().Tag(sourceJobTag).SingletonMode().Do(s.fetchSource, id)
+	}
+	return s.scheduler.Every(every).Tag(sourceJobTag).SingletonMode().Do(s.fetchSource, id)
+}
+
+// unschedule removes the job fetching the source along with its latest fetch.
+func (s *Scheduler) unschedule(id int) {
+	s.scheduler.RemoveByReference(s.jobs[id].job)
+	delete(s.jobs, id)
+	s.mu.Lock()
+	delete(s.fetches, id)
+	s.mu.Unlock()
+}
+
+// syncSources runs Sync, logging the error if the sources can't be read.
+func (s *Scheduler) syncSources() {
+	if err := s.Sync(s.ctx); err != nil {
+		logrus.WithField("event_id", eventSyncSourcesError).Errorf("Error occurred while syncing sources: %s", err.Error())
+	}
+}
+
+// fetchSource fetches articles from a single source using the source service.
+func (s *Scheduler) fetchSource(id int) {
+	startedAt := time.Now()
+	result, err := s.ssvc.FetchSource(s.ctx, id)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"event_id": eventUpdateArticlesError,
+			"source":   id,
+		}).Errorf("Error occurred while fetching articles from source: %s", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.fetches[id] = sourceFetch{result: result, startedAt: startedAt}
+	s.mu.Unlock()
+
+	if result.Status == model.FetchFailed {
+		logrus.WithFields(logrus.Fields{
+			"event_id": eventSourceFetchFailed,
+			"source":   result.Source.ShortName,
+			"duration": result.Duration,
+		}).Warnf("Source failed to update: %s", result.Error)
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"event_id": eventUpdateArticlesSuccess,
+		"source":   result.Source.ShortName,
+		"status":   result.Status,
+		"articles": result.Articles,
+		"inserted": result.Saved.Inserted,
+		"updated":  result.Saved.Updated,
+		"duration": result.Duration,
+	}).Info("Articles updated successfully")
+}
+
+// pruneArticles deletes the articles beyond the retention policies using the retention service.
+func (s *Scheduler) pruneArticles() {
+	report, err := s.rsvc.Prune(s.ctx, false)
 	if err != nil {
-		logrus.WithField("event_id", eventUpdateArticlesError).Errorf("Error occurred while fetching articles from sources: %s", err.Error())
+		logrus.WithField("event_id", eventPruneArticlesError).Errorf("Error occurred while pruning articles: %s", err.Error())
 		return
 	}
-	logrus.WithField("event_id", eventUpdateArticlesSuccess).Info("Articles updated successfully")
+	logrus.WithFields(logrus.Fields{
+		"event_id": eventPruneArticlesSuccess,
+		"sources":  len(report.Sources),
+		"pruned":   report.Pruned(),
+		"archive":  report.Archive,
+	}).Info("Articles pruned successfully")
 }
@@ -1,8 +1,10 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	service_mocks "github.com/antonchaban/news-aggregator/pkg/handler/web/mocks"
+	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/mock/gomock"
 	"testing"
@@ -15,7 +17,7 @@ func TestNewScheduler(t *testing.T) {
 	asvc := new(service_mocks.MockArticleService)
 	ssvc := new(service_mocks.MockSourceService)
 
-	scheduler := NewScheduler(asvc, ssvc)
+	scheduler := NewScheduler(asvc, ssvc, nil)
 
 	assert.NotNil(t, scheduler)
 	assert.NotNil(t, scheduler.scheduler)
@@ -31,9 +33,11 @@ func TestScheduler_Start(t *testing.T) {
 	defer ctrl.Finish()
 	mockArticleService := service_mocks.NewMockArticleService(ctrl)
 	mockSourceService := service_mocks.NewMockSourceService(ctrl)
-	s := NewScheduler(mockArticleService, mockSourceService)
+	s := NewScheduler(mockArticleService, mockSourceService, nil)
 
-	mockSourceService.EXPECT().FetchFromAllSources().Return(nil).AnyTimes()
+	mockSourceService.EXPECT().GetAll(gomock.Any()).Return([]model.Source{{Id: 1, ShortName: "bbc"}}, nil).AnyTimes()
+	mockSourceService.EXPECT().FetchSource(gomock.Any(), 1).
+		Return(model.SourceFetchResult{Source: model.Source{Id: 1, ShortName: "bbc"}, Status: model.FetchOK}, nil).AnyTimes()
 	s.Start()
 
 	time.Sleep(2 * time.Second)
@@ -49,53 +53,356 @@ func TestScheduler_Start(t *testing.T) {
 		}
 	}
 	assert.True(t, found, "Expected log message 'Scheduler started successfully' not found")
+	assert.Len(t, s.LastReport().Sources, 1)
+}
+
+func TestScheduler_Sync(t *testing.T) {
+	disabled := model.FetchSchedule{Enabled: false}
+	hourly := model.FetchSchedule{Cron: "0 * * * *", Enabled: true}
+	daily := model.FetchSchedule{Interval: "24h", Enabled: true}
+	invalid := model.FetchSchedule{Interval: "1s", Enabled: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSourceService := service_mocks.NewMockSourceService(ctrl)
+	s := NewScheduler(nil, mockSourceService, nil)
+
+	mockSourceService.EXPECT().GetAll(gomock.Any()).Return([]model.Source{
+		{Id: 1, ShortName: "bbc"},
+		{Id: 2, ShortName: "nbc", Schedule: &hourly},
+		{Id: 3, ShortName: "cnn", Schedule: &disabled},
+		{Id: 4, ShortName: "abc", Schedule: &invalid},
+	}, nil)
+	assert.NoError(t, s.Sync(context.Background()))
+	assert.Len(t, s.jobs, 2)
+	assert.Equal(t, model.DefaultFetchSchedule(), s.jobs[1].schedule)
+	assert.Equal(t, hourly, s.jobs[2].schedule)
+	assert.Equal(t, 2, s.scheduler.Len())
+	bbc := s.jobs[1].job
 
-	mockSourceService.EXPECT().FetchFromAllSources().Return(nil).AnyTimes()
+	// nbc is rescheduled daily, bbc is deleted and cnn is enabled
+	mockSourceService.EXPECT().GetAll(gomock.Any()).Return([]model.Source{
+		{Id: 2, ShortName: "nbc", Schedule: &daily},
+		{Id: 3, ShortName: "cnn"},
+	}, nil)
+	assert.NoError(t, s.Sync(context.Background()))
+	assert.Len(t, s.jobs, 2)
+	assert.Equal(t, daily, s.jobs[2].schedule)
+	assert.Equal(t, model.DefaultFetchSchedule(), s.jobs[3].schedule)
+	assert.Equal(t, 2, s.scheduler.Len())
+	assert.NotContains(t, s.scheduler.Jobs(), bbc)
+
+	// unchanged schedules keep their jobs
+	nbc := s.jobs[2].job
+	mockSourceService.EXPECT().GetAll(gomock.Any()).Return([]model.Source{
+		{Id: 2, ShortName: "nbc", Schedule: &daily},
+	}, nil)
+	assert.NoError(t, s.Sync(context.Background()))
+	assert.Len(t, s.jobs, 1)
+	assert.Same(t, nbc, s.jobs[2].job)
+
+	mockSourceService.EXPECT().GetAll(gomock.Any()).Return(nil, fmt.Errorf("connection refused"))
+	assert.EqualError(t, s.Sync(context.Background()), "connection refused")
+	assert.Len(t, s.jobs, 1)
 }
 
-func TestScheduler_updateArticles_Success(t *testing.T) {
-	recorder := &LogRecorder{}
-	logrus.AddHook(recorder)
+func TestScheduler_Sync_EffectiveSchedule(t *testing.T) {
+	tests := []struct {
+		name   string
+		source model.Source
+		want   *model.FetchSchedule
+	}{
+		{
+			name:   "frequently updated source is polled at the minimum interval",
+			source: model.Source{Health: &model.SourceHealth{Status: model.SourceHealthy, UpdateInterval: 90 * time.Second}},
+			want:   &model.FetchSchedule{Interval: "1m0s", Enabled: true},
+		},
+		{
+			name:   "source is polled at half its update interval",
+			source: model.Source{Health: &model.SourceHealth{Status: model.SourceHealthy, UpdateInterval: 25 * time.Minute}},
+			want:   &model.FetchSchedule{Interval: "12m0s", Enabled: true},
+		},
+		{
+			name:   "rarely updated source is polled at the maximum adaptive interval",
+			source: model.Source{Health: &model.SourceHealth{Status: model.SourceHealthy, UpdateInterval: 12 * time.Hour}},
+			want:   &model.FetchSchedule{Interval: "1h0m0s", Enabled: true},
+		},
+		{
+			name:   "own interval isn't adapted",
+			source: model.Source{Schedule: &model.FetchSchedule{Interval: "15m", Enabled: true}, Health: &model.SourceHealth{Status: model.SourceHealthy, UpdateInterval: 12 * time.Hour}},
+			want:   &model.FetchSchedule{Interval: "15m", Enabled: true},
+		},
+		{
+			name:   "failing source backs off exponentially",
+			source: model.Source{Health: &model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 3}},
+			want:   &model.FetchSchedule{Interval: "8m0s", Enabled: true},
+		},
+		{
+			name:   "backoff is bounded",
+			source: model.Source{Schedule: &model.FetchSchedule{Interval: "2h", Enabled: true}, Health: &model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 9}},
+			want:   &model.FetchSchedule{Interval: "6h0m0s", Enabled: true},
+		},
+		{
+			name:   "cron schedule is kept",
+			source: model.Source{Schedule: &model.FetchSchedule{Cron: "0 * * * *", Enabled: true}, Health: &model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 3}},
+			want:   &model.FetchSchedule{Cron: "0 * * * *", Enabled: true},
+		},
+		{
+			name:   "disabled source isn't scheduled",
+			source: model.Source{Health: &model.SourceHealth{Status: model.SourceDisabled, ConsecutiveFailures: model.MaxConsecutiveFailures}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockSourceService := service_mocks.NewMockSourceService(ctrl)
+			s := NewScheduler(nil, mockSourceService, nil)
+
+			tt.source.Id, tt.source.ShortName = 1, "bbc"
+			mockSourceService.EXPECT().GetAll(gomock.Any()).Return([]model.Source{tt.source}, nil)
+			assert.NoError(t, s.Sync(context.Background()))
+
+			if tt.want == nil {
+				assert.Empty(t, s.jobs)
+				return
+			}
+			assert.Equal(t, *tt.want, s.jobs[1].schedule)
+		})
+	}
+}
 
+func TestScheduler_Sync_Backoff(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
+	mockSourceService := service_mocks.NewMockSourceService(ctrl)
+	s := NewScheduler(nil, mockSourceService, nil)
+	s.scheduler.StartAsync()
+	defer s.Stop()
 
-	mockArticleService := service_mocks.NewMockArticleService(ctrl)
+	// The failing source is rescheduled to back off without being fetched again right away
+	mockSourceService.EXPECT().GetAll(gomock.Any()).Return([]model.Source{{Id: 1, ShortName: "bbc",
+		Schedule: &model.FetchSchedule{Interval: "1h", Enabled: true}}}, nil)
+	mockSourceService.EXPECT().FetchSource(gomock.Any(), 1).
+		Return(model.SourceFetchResult{Source: model.Source{Id: 1, ShortName: "bbc"}, Status: model.FetchFailed}, nil)
+	assert.NoError(t, s.Sync(context.Background()))
+	time.Sleep(100 * time.Millisecond)
+
+	mockSourceService.EXPECT().GetAll(gomock.Any()).Return([]model.Source{{Id: 1, ShortName: "bbc",
+		Schedule: &model.FetchSchedule{Interval: "1h", Enabled: true},
+		Health:   &model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 1}}}, nil)
+	assert.NoError(t, s.Sync(context.Background()))
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, "2h0m0s", s.jobs[1].schedule.Interval)
+	assert.Equal(t, 1, s.scheduler.Len())
+	assert.Len(t, s.LastReport().Sources, 1, "the latest fetch is kept when rescheduling")
+}
+
+func TestScheduler_RunNow(t *testing.T) {
+	midnight := model.FetchSchedule{Cron: "0 0 * * *", Enabled: true}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 	mockSourceService := service_mocks.NewMockSourceService(ctrl)
+	s := NewScheduler(nil, mockSourceService, nil)
+	s.scheduler.StartAsync()
+	defer s.Stop()
 
-	s := NewScheduler(mockArticleService, mockSourceService)
-	mockSourceService.EXPECT().FetchFromAllSources().Return(nil)
-	recorder.Entries = nil
+	fetched := make(chan int, 2)
+	mockSourceService.EXPECT().GetAll(gomock.Any()).
+		Return([]model.Source{{Id: 1, ShortName: "bbc", Schedule: &midnight}}, nil).Times(2)
+	mockSourceService.EXPECT().FetchSource(gomock.Any(), 1).
+		DoAndReturn(func(ctx context.Context, id int) (model.SourceFetchResult, error) {
+			fetched <- id
+			return model.SourceFetchResult{Source: model.Source{Id: id, ShortName: "bbc"}, Status: model.FetchOK}, nil
+		}).Times(2)
 
-	// Call updateArticles directly
-	s.updateArticles()
+	assert.NoError(t, s.RunNow(context.Background()))
+	select {
+	case id := <-fetched:
+		assert.Equal(t, 1, id)
+	case <-time.After(time.Second):
+		t.Fatal("RunNow() didn't fetch the source")
+	}
 
-	// Assertions
-	assert.Len(t, recorder.Entries, 2)
-	assert.Equal(t, "Updating articles...", recorder.Entries[0].Message)
-	assert.Equal(t, "Articles updated successfully", recorder.Entries[1].Message)
+	s.Pause()
+	assert.ErrorIs(t, s.RunNow(context.Background()), ErrPaused)
+	s.Resume()
+	assert.NoError(t, s.RunNow(context.Background()))
+	select {
+	case <-fetched:
+	case <-time.After(time.Second):
+		t.Fatal("RunNow() didn't fetch the source after Resume()")
+	}
 }
 
-func TestScheduler_updateArticles_Error(t *testing.T) {
-	recorder := &LogRecorder{}
-	logrus.AddHook(recorder)
+func TestScheduler_Status(t *testing.T) {
+	midnight := model.FetchSchedule{Cron: "0 0 * * *", Enabled: true}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSourceService := service_mocks.NewMockSourceService(ctrl)
+	s := NewScheduler(nil, mockSourceService, nil)
+
+	status := s.Status()
+	assert.False(t, status.Paused)
+	assert.Zero(t, status.Sources)
+	assert.Nil(t, status.NextRun)
+
+	mockSourceService.EXPECT().GetAll(gomock.Any()).Return([]model.Source{
+		{Id: 1, ShortName: "bbc", Schedule: &midnight},
+		{Id: 2, ShortName: "nbc", Schedule: &model.FetchSchedule{Interval: "48h", Enabled: true}},
+	}, nil)
+	s.scheduler.StartAsync()
+	defer s.Stop()
+	assert.NoError(t, s.Sync(context.Background()))
+	s.Pause()
+
+	status = s.Status()
+	assert.True(t, status.Paused)
+	assert.Equal(t, 2, status.Sources)
+	if assert.NotNil(t, status.NextRun) {
+		// the daily job is due before the one running every other day
+		assert.Equal(t, s.jobs[1].job.NextRun(), *status.NextRun)
+	}
+}
+
+func TestScheduler_fetchSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		result      model.SourceFetchResult
+		err         error
+		wantMessage string
+		wantReport  bool
+	}{
+		{
+			name:        "updated",
+			result:      model.SourceFetchResult{Source: model.Source{Id: 1, ShortName: "bbc"}, Status: model.FetchOK, Articles: 10},
+			wantMessage: "Articles updated successfully",
+			wantReport:  true,
+		},
+		{
+			name:        "failed",
+			result:      model.SourceFetchResult{Source: model.Source{Id: 1, ShortName: "bbc"}, Status: model.FetchFailed, Error: "context deadline exceeded"},
+			wantMessage: "Source failed to update: context deadline exceeded",
+			wantReport:  true,
+		},
+		{
+			name:        "error",
+			err:         fmt.Errorf("source not found"),
+			wantMessage: "Error occurred while fetching articles from source: source not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &LogRecorder{}
+			logrus.AddHook(recorder)
+			ctrl := gomock.NewController(t)
+			mockSourceService := service_mocks.NewMockSourceService(ctrl)
+			s := NewScheduler(nil, mockSourceService, nil)
+
+			mockSourceService.EXPECT().FetchSource(gomock.Any(), 1).Return(tt.result, tt.err)
+			recorder.Entries = nil
+			s.fetchSource(1)
+
+			assert.Len(t, recorder.Entries, 1)
+			assert.Equal(t, tt.wantMessage, recorder.Entries[0].Message)
+			if tt.wantReport {
+				assert.Equal(t, []model.SourceFetchResult{tt.result}, s.LastReport().Sources)
+			} else {
+				assert.Empty(t, s.LastReport().Sources)
+			}
+		})
+	}
+}
 
+func TestScheduler_LastReport(t *testing.T) {
+	s := NewScheduler(nil, nil, nil)
+	startedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	nbc := model.SourceFetchResult{Source: model.Source{Id: 2, ShortName: "nbc"}, Duration: 3 * time.Second}
+	bbc := model.SourceFetchResult{Source: model.Source{Id: 1, ShortName: "bbc"}, Duration: time.Second}
+	s.fetches[2] = sourceFetch{result: nbc, startedAt: startedAt.Add(time.Minute)}
+	s.fetches[1] = sourceFetch{result: bbc, startedAt: startedAt}
+
+	report := s.LastReport()
+
+	assert.Equal(t, startedAt, report.StartedAt)
+	assert.Equal(t, time.Minute+3*time.Second, report.Duration)
+	assert.Equal(t, []model.SourceFetchResult{bbc, nbc}, report.Sources)
+}
+
+func TestScheduler_Stop_CancelsFetch(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockArticleService := service_mocks.NewMockArticleService(ctrl)
 	mockSourceService := service_mocks.NewMockSourceService(ctrl)
 
-	s := NewScheduler(mockArticleService, mockSourceService)
+	s := NewScheduler(mockArticleService, mockSourceService, nil)
+
+	started := make(chan struct{})
+	mockSourceService.EXPECT().FetchSource(gomock.Any(), 1).
+		DoAndReturn(func(ctx context.Context, id int) (model.SourceFetchResult, error) {
+			close(started)
+			<-ctx.Done()
+			return model.SourceFetchResult{}, ctx.Err()
+		})
+
+	done := make(chan struct{})
+	go func() {
+		s.fetchSource(1)
+		close(done)
+	}()
+
+	<-started
+	s.Stop()
 
-	mockSourceService.EXPECT().FetchFromAllSources().Return(fmt.Errorf("some error"))
-	recorder.Entries = nil
-	s.updateArticles()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() didn't abort the fetch in progress")
+	}
+}
 
-	// Assertions
-	assert.Len(t, recorder.Entries, 2)
-	assert.Equal(t, "Updating articles...", recorder.Entries[0].Message)
-	assert.Contains(t, recorder.Entries[1].Message, "Error occurred while fetching articles from sources: some error")
+func TestScheduler_pruneArticles(t *testing.T) {
+	tests := []struct {
+		name        string
+		report      model.PruneReport
+		err         error
+		wantMessage string
+	}{
+		{
+			name: "pruned",
+			report: model.PruneReport{Archive: "/archive/articles.ndjson.gz", Sources: []model.SourcePruneResult{
+				{Source: model.Source{ShortName: "bbc"}, Pruned: 3},
+				{Source: model.Source{ShortName: "nbc"}, Pruned: 2},
+			}},
+			wantMessage: "Articles pruned successfully",
+		},
+		{
+			name:        "error",
+			err:         fmt.Errorf("archive dir is read-only"),
+			wantMessage: "Error occurred while pruning articles: archive dir is read-only",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &LogRecorder{}
+			logrus.AddHook(recorder)
+			ctrl := gomock.NewController(t)
+			mockRetentionService := service_mocks.NewMockRetentionService(ctrl)
+			s := NewScheduler(nil, nil, mockRetentionService)
+
+			mockRetentionService.EXPECT().Prune(gomock.Any(), false).Return(tt.report, tt.err)
+			recorder.Entries = nil
+			s.pruneArticles()
+
+			assert.Len(t, recorder.Entries, 1)
+			assert.Equal(t, tt.wantMessage, recorder.Entries[0].Message)
+			if tt.err == nil {
+				assert.Equal(t, 5, recorder.Entries[0].Data["pruned"])
+				assert.Equal(t, tt.report.Archive, recorder.Entries[0].Data["archive"])
+			}
+		})
+	}
 }
 
 type LogRecorder struct {
@@ -12,7 +12,7 @@
 //	}
 //
 //	// On shutdown:
-//	err = server.Shutdown(context.Background(), articles)
+//	err = server.Shutdown(context.Background(), backuper.NewSaver(backuper.SliceIterator(articles), sources))
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
@@ -14,18 +14,15 @@ import (
 
 const (
 	eventServerStart               = "server_start"
-	eventLoadSourcesStart          = "load_sources_start"
-	eventLoadSourcesError          = "load_sources_error"
-	eventLoadSourcesComplete       = "load_sources_complete"
+	eventLoadSnapshotStart         = "load_snapshot_start"
+	eventLoadSnapshotError         = "load_snapshot_error"
+	eventLoadSnapshotComplete      = "load_snapshot_complete"
 	eventInitializeSourcesStart    = "initialize_sources_start"
 	eventInitializeSourcesComplete = "initialize_sources_complete"
 	eventAddSourcesStart           = "add_sources_start"
 	eventAddSourceError            = "add_source_error"
 	eventAddSourceSuccess          = "add_source_success"
 	eventAddSourcesComplete        = "add_sources_complete"
-	eventLoadArticlesStart         = "load_articles_start"
-	eventLoadArticlesError         = "load_articles_error"
-	eventLoadArticlesComplete      = "load_articles_complete"
 	eventSaveArticlesStart         = "save_articles_start"
 	eventSaveArticlesError         = "save_articles_error"
 	eventSaveArticlesComplete      = "save_articles_complete"
@@ -34,6 +31,9 @@ const (
 	eventServerStarted             = "server_started"
 )
 
+// saveBatchSize is the number of articles of the snapshot saved at once.
+const saveBatchSize = 1000
+
 // Server represents a web server with TLS support.
 type Server struct {
 	httpServer *http.Server
@@ -59,8 +59,8 @@ func (s *Server) Run(port string, handler http.Handler) error {
 }
 
 // RunWithFiles starts the HTTPs server on the specified port and initializes the sources.
-// It also loads articles from a backup file and saves them using the provided
-// article handler.
+// It also loads the sources and articles from the latest valid snapshot and saves them using the provided
+// article handler, the articles in batches as they are read.
 // The server listens for HTTPS requests using the specified
 // certificate and key files.
 //
@@ -80,25 +80,27 @@ func (s *Server) RunWithFiles(port string, handler http.Handler, artHandler web.
 	}
 
 	logrus.WithField("event_id", eventServerStart).Info("Starting server on port ", port)
+	ctx := context.Background()
 
-	// Load sources from file
-	logrus.WithField("event_id", eventLoadSourcesStart).Info("Loading sources from file")
-	srcs, err := backuper.NewLoader(artHandler.SrcService()).LoadSrcsFromFile()
+	// Load the sources and articles from the latest snapshot
+	logrus.WithField("event_id", eventLoadSnapshotStart).Info("Loading the latest snapshot")
+	snapshot, err := backuper.NewLoader(artHandler.SrcService()).Load()
 	if err != nil {
-		logrus.WithField("event_id", eventLoadSourcesError).Error("Failed to load sources from file", err)
+		logrus.WithField("event_id", eventLoadSnapshotError).Error("Failed to load the latest snapshot", err)
 		return err
 	}
-	logrus.WithField("event_id", eventLoadSourcesComplete).Info("Sources loaded from file")
+	srcs := snapshot.Sources
+	logrus.WithField("event_id", eventLoadSnapshotComplete).Info("Snapshot loaded")
 
 	// Initialize sources if none are found
 	if len(srcs) == 0 {
 		logrus.WithField("event_id", eventInitializeSourcesStart).Info("No sources found in file, initializing default sources")
-		initializeSources(artHandler.SrcService())
+		initializeSources(ctx, artHandler.SrcService())
 		logrus.WithField("event_id", eventInitializeSourcesComplete).Info("Default sources initialized")
 	} else {
 		logrus.WithField("event_id", eventAddSourcesStart).Info("Adding sources from file")
 		for _, src := range srcs {
-			_, err := artHandler.SrcService().AddSource(src)
+			_, err := artHandler.SrcService().AddSource(ctx, src)
 			if err != nil {
 				logrus.WithField("event_id", eventAddSourceError).Errorf("Error occurred while adding source %s: %s", src.Name, err.Error())
 			} else {
@@ -108,23 +110,24 @@ func (s *Server) RunWithFiles(port string, handler http.Handler, artHandler web.
 		logrus.WithField("event_id", eventAddSourcesComplete).Info("All sources added from file")
 	}
 
-	// Load articles from file
-	logrus.WithField("event_id", eventLoadArticlesStart).Info("Loading articles from file")
-	articles, err := backuper.NewLoader(artHandler.SrcService()).LoadAllFromFile()
-	if err != nil {
-		logrus.WithField("event_id", eventLoadArticlesError).Error("Failed to load articles from file", err)
-		return err
-	}
-	logrus.WithField("event_id", eventLoadArticlesComplete).Info("Articles loaded from file")
-
-	// Save all articles
+	// Save all articles of the snapshot
 	logrus.WithField("event_id", eventSaveArticlesStart).Info("Saving all articles")
-	err = artHandler.ArticleService().SaveAll(articles)
+	var saved model.SaveResult
+	err = backuper.ArticleIterator(snapshot.Articles).Batch(saveBatchSize, func(batch []model.Article) error {
+		result, err := artHandler.ArticleService().SaveAll(ctx, batch)
+		saved.Add(result)
+		return err
+	})
 	if err != nil {
 		logrus.WithField("event_id", eventSaveArticlesError).Error("Failed to save articles", err)
 		return err
 	}
-	logrus.WithField("event_id", eventSaveArticlesComplete).Info("All articles saved")
+	logrus.WithFields(logrus.Fields{
+		"event_id": eventSaveArticlesComplete,
+		"inserted": saved.Inserted,
+		"updated":  saved.Updated,
+		"skipped":  saved.Skipped,
+	}).Info("All articles saved")
 
 	logrus.WithField("event_id", eventServerListenStart).Info("Starting HTTPS server")
 	if err := s.httpServer.ListenAndServeTLS(s.certFile, s.keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -136,21 +139,17 @@ func (s *Server) RunWithFiles(port string, handler http.Handler, artHandler web.
 	return nil
 }
 
-// Shutdown gracefully shuts down the server and saves articles to a backup file.
+// Shutdown gracefully shuts down the server and saves the articles and sources to a new snapshot.
 // It also logs the shutdown process.
 //
 // Parameters:
 // - ctx: The context to use for the shutdown process.
-// - articles: The list of articles to save to the backup file.
+// - saver: The saver of the snapshot of the articles and sources.
 //
 // Returns an error if saving the articles or shutting down the server fails.
-func (s *Server) Shutdown(ctx context.Context, articles []model.Article, sources []model.Source) error {
+func (s *Server) Shutdown(ctx context.Context, saver backuper.Saver) error {
 	fmt.Println("Shutting down the server...")
-	err := backuper.NewSaver(articles, sources).SaveAllToFile()
-	if err != nil {
-		return err
-	}
-	err = backuper.NewSaver(articles, sources).SaveSrcsToFile()
+	_, err := saver.Save()
 	if err != nil {
 		return err
 	}
@@ -175,8 +174,9 @@ func NewServer(certFile, keyFile string) *Server {
 // It adds a predefined list of sources to the service.
 //
 // Parameters:
+// - ctx: The context to use for adding sources.
 // - ssvc: The SourceService to use for adding sources.
-func initializeSources(ssvc web.SourceService) {
+func initializeSources(ctx context.Context, ssvc web.SourceService) {
 	sources := []model.Source{
 		{Name: "BBC News", Link: "https://feeds.bbci.co.uk/news/rss.xml"},
 		{Name: "ABC News: International", Link: "https://abcnews.go.com/abcnews/internationalheadlines"},
@@ -185,7 +185,7 @@ func initializeSources(ssvc web.SourceService) {
 	}
 
 	for _, source := range sources {
-		_, err := ssvc.AddSource(source)
+		_, err := ssvc.AddSource(ctx, source)
 		if err != nil {
 			logrus.Errorf("error occurred while adding source %s: %s", source.Name, err.Error())
 		}
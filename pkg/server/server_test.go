@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"github.com/antonchaban/news-aggregator/pkg/backuper"
 	"github.com/antonchaban/news-aggregator/pkg/handler/web"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/antonchaban/news-aggregator/pkg/service"
@@ -61,14 +62,14 @@ func TestServer_RunWithFiles(t *testing.T) {
 	db := inmemory.New()
 	srcDb := inmemory.NewSrc()
 	articleService := service.New(db)
-	sourceService := service.NewSourceService(db, srcDb)
-	handler := web.NewHandler(articleService, sourceService)
+	sourceService := service.NewSourceService(db, srcDb, inmemory.NewUnitOfWork(db, srcDb), inmemory.NewFetchLocker(), nil)
+	handler := web.NewHandler(articleService, sourceService, nil, nil)
 
 	// Set up environment variables
 	os.Setenv("CERT_FILE", "server.crt")
 	os.Setenv("KEY_FILE", "server.key")
 	os.Setenv("PORT", "8443")
-	os.Setenv("SAVES_DIR", "testdata") // Assuming a temporary directory for testing
+	t.Setenv("SAVES_DIR", t.TempDir())
 
 	tests := []struct {
 		name     string
@@ -120,7 +121,7 @@ func TestServer_RunWithFiles(t *testing.T) {
 				}
 			}
 
-			err = s.Shutdown(context.Background(), []model.Article{}, []model.Source{})
+			err = s.Shutdown(context.Background(), backuper.NewSaver(backuper.SliceIterator(nil), []model.Source{}))
 			if err != nil {
 				t.Fatalf("Failed to shutdown server: %v", err)
 			}
@@ -137,8 +138,8 @@ func TestServer_Shutdown(t *testing.T) {
 	db := inmemory.New()
 	srcDb := inmemory.NewSrc()
 	articleService := service.New(db)
-	sourceService := service.NewSourceService(db, srcDb)
-	handler := web.NewHandler(articleService, sourceService)
+	sourceService := service.NewSourceService(db, srcDb, inmemory.NewUnitOfWork(db, srcDb), inmemory.NewFetchLocker(), nil)
+	handler := web.NewHandler(articleService, sourceService, nil, nil)
 	type args struct {
 		ctx      context.Context
 		articles []model.Article
@@ -148,7 +149,7 @@ func TestServer_Shutdown(t *testing.T) {
 	os.Setenv("CERT_FILE", "server.crt")
 	os.Setenv("KEY_FILE", "server.key")
 	os.Setenv("PORT", "8443")
-	os.Setenv("SAVES_DIR", "testdata")
+	t.Setenv("SAVES_DIR", t.TempDir())
 
 	server := &http.Server{
 		Addr:           ":" + os.Getenv("PORT"),
@@ -199,7 +200,8 @@ func TestServer_Shutdown(t *testing.T) {
 			// Allow some time for the server to start
 			time.Sleep(2 * time.Second)
 
-			if err := s.Shutdown(tt.args.ctx, tt.args.articles, tt.args.sources); (err != nil) != tt.wantErr {
+			saver := backuper.NewSaver(backuper.SliceIterator(tt.args.articles), tt.args.sources)
+			if err := s.Shutdown(tt.args.ctx, saver); (err != nil) != tt.wantErr {
 				t.Errorf("Shutdown() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
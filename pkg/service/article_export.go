@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/backuper"
+	"github.com/antonchaban/news-aggregator/pkg/handler/web"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"io"
+)
+
+const (
+	// exportPageSize is the number of articles read from the storage at once while exporting them.
+	exportPageSize = 1000
+	// importBatchSize is the number of articles saved at once while importing them.
+	importBatchSize = 1000
+)
+
+// Each calls fn with every article, ordered by ID, reading them from the storage page by page.
+func (a *articleService) Each(ctx context.Context, fn func(model.Article) error) error {
+	afterID := 0
+	for {
+		articles, err := a.articleStorage.GetAfter(ctx, afterID, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, article := range articles {
+			if err = fn(article); err != nil {
+				return err
+			}
+		}
+		if len(articles) < exportPageSize {
+			return nil
+		}
+		afterID = articles[len(articles)-1].Id
+	}
+}
+
+// Export writes all the articles to w as gzip-compressed NDJSON and returns their number.
+// The progress function, if any, is called with the number of articles written after every page.
+func (a *articleService) Export(ctx context.Context, w io.Writer, progress func(int)) (int, error) {
+	aw := backuper.NewArticleWriter(w)
+	err := a.Each(ctx, func(article model.Article) error {
+		if err := aw.Write(article); err != nil {
+			return err
+		}
+		if progress != nil && aw.Count()%exportPageSize == 0 {
+			progress(aw.Count())
+		}
+		return nil
+	})
+	if err != nil {
+		return aw.Count(), err
+	}
+	return aw.Count(), aw.Close()
+}
+
+// Import reads gzip-compressed NDJSON articles from r and saves them in batches, the same way
+// the fetched ones are saved, so the already saved articles are updated rather than repeated.
+// The articles keep the IDs of their sources, which must exist.
+// The progress function, if any, is called with the report after every batch.
+// An error wrapping web.ErrInvalidImport is returned if r isn't valid.
+func (a *articleService) Import(ctx context.Context, r io.Reader, progress func(model.ImportReport)) (model.ImportReport, error) {
+	var report model.ImportReport
+	ar, err := backuper.NewArticleReader(r)
+	if err != nil {
+		return report, fmt.Errorf("%w: %v", web.ErrInvalidImport, err)
+	}
+	defer ar.Close()
+
+	// The read errors are told apart from the save ones, which aren't the client's fault
+	articles := func(yield func(model.Article) error) error {
+		for {
+			article, err := ar.Read()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("%w: %v", web.ErrInvalidImport, err)
+			}
+			if err = yield(article); err != nil {
+				return err
+			}
+		}
+	}
+	err = backuper.ArticleIterator(articles).Batch(importBatchSize, func(batch []model.Article) error {
+		saved, err := a.SaveAll(ctx, batch)
+		if err != nil {
+			return err
+		}
+		report.Articles += len(batch)
+		report.Add(saved)
+		if progress != nil {
+			progress(report)
+		}
+		return nil
+	})
+	return report, err
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/backuper"
+	"github.com/antonchaban/news-aggregator/pkg/handler/web"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+// testArticles returns n articles with IDs from 1 to n.
+func testArticles(n int) []model.Article {
+	articles := make([]model.Article, n)
+	for i := range articles {
+		articles[i] = model.Article{Id: i + 1, Title: "Test Article", Source: model.Source{Id: 1}}
+	}
+	return articles
+}
+
+func Test_articleService_Export(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := mocks.NewMockArticleStorage(ctrl)
+	articles := testArticles(exportPageSize + 1)
+	gomock.InOrder(
+		mockStorage.EXPECT().GetAfter(gomock.Any(), 0, exportPageSize).Return(articles[:exportPageSize], nil),
+		mockStorage.EXPECT().GetAfter(gomock.Any(), exportPageSize, exportPageSize).Return(articles[exportPageSize:], nil),
+	)
+
+	var buf bytes.Buffer
+	var progress []int
+	count, err := New(mockStorage).Export(context.Background(), &buf, func(count int) {
+		progress = append(progress, count)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, exportPageSize+1, count)
+	assert.Equal(t, []int{exportPageSize}, progress)
+
+	ar, err := backuper.NewArticleReader(&buf)
+	require.NoError(t, err)
+	var got []model.Article
+	require.NoError(t, ar.Each(func(article model.Article) error {
+		got = append(got, article)
+		return nil
+	}))
+	assert.Equal(t, articles, got)
+}
+
+func Test_articleService_Export_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := mocks.NewMockArticleStorage(ctrl)
+	mockStorage.EXPECT().GetAfter(gomock.Any(), 0, exportPageSize).Return(nil, errors.New("db is down"))
+
+	_, err := New(mockStorage).Export(context.Background(), &bytes.Buffer{}, nil)
+	assert.EqualError(t, err, "db is down")
+}
+
+func Test_articleService_Import(t *testing.T) {
+	// export writes the articles as they are exported
+	export := func(t *testing.T, articles []model.Article) []byte {
+		var buf bytes.Buffer
+		aw := backuper.NewArticleWriter(&buf)
+		require.NoError(t, backuper.SliceIterator(articles)(aw.Write))
+		require.NoError(t, aw.Close())
+		return buf.Bytes()
+	}
+	compress := func(t *testing.T, data string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+	articles := testArticles(importBatchSize + 1)
+
+	tests := []struct {
+		name         string
+		body         []byte
+		prepare      func(m *mocks.MockArticleStorage)
+		wantReport   model.ImportReport
+		wantProgress []model.ImportReport
+		wantErr      string
+		wantInvalid  bool
+	}{
+		{
+			name: "saved in batches",
+			body: export(t, articles),
+			prepare: func(m *mocks.MockArticleStorage) {
+				gomock.InOrder(
+					m.EXPECT().SaveAll(gomock.Any(), articles[:importBatchSize]).Return(model.SaveResult{Inserted: importBatchSize}, nil),
+					m.EXPECT().SaveAll(gomock.Any(), articles[importBatchSize:]).Return(model.SaveResult{Skipped: 1}, nil),
+				)
+			},
+			wantReport: model.ImportReport{Articles: importBatchSize + 1, SaveResult: model.SaveResult{Inserted: importBatchSize, Skipped: 1}},
+			wantProgress: []model.ImportReport{
+				{Articles: importBatchSize, SaveResult: model.SaveResult{Inserted: importBatchSize}},
+				{Articles: importBatchSize + 1, SaveResult: model.SaveResult{Inserted: importBatchSize, Skipped: 1}},
+			},
+		},
+		{
+			name:        "not gzip-compressed",
+			body:        []byte(`{"title":"Test Article"}`),
+			wantErr:     "invalid import: gzip: invalid header",
+			wantInvalid: true,
+		},
+		{
+			name:        "invalid article",
+			body:        compress(t, `{"title":`),
+			wantErr:     "invalid import",
+			wantInvalid: true,
+		},
+		{
+			name: "save error",
+			body: export(t, articles[:1]),
+			prepare: func(m *mocks.MockArticleStorage) {
+				m.EXPECT().SaveAll(gomock.Any(), articles[:1]).Return(model.SaveResult{}, errors.New("db is down"))
+			},
+			wantErr: "failed to save articles",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockStorage := mocks.NewMockArticleStorage(ctrl)
+			if tt.prepare != nil {
+				tt.prepare(mockStorage)
+			}
+
+			var progress []model.ImportReport
+			report, err := New(mockStorage).Import(context.Background(), bytes.NewReader(tt.body), func(report model.ImportReport) {
+				progress = append(progress, report)
+			})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Equal(t, tt.wantInvalid, errors.Is(err, web.ErrInvalidImport))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReport, report)
+			assert.Equal(t, tt.wantProgress, progress)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/filter"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func Test_articleService_GetPage_CollapseInMemory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := mocks.NewMockArticleStorage(ctrl)
+	now := time.Now()
+	articles := []model.Article{
+		{Id: 1, Title: "Story", PubDate: now, StoryId: 1, Source: model.Source{Id: 1, ShortName: "one"}},
+		{Id: 2, Title: "Story", PubDate: now.Add(-time.Minute), StoryId: 1, Source: model.Source{Id: 2, ShortName: "two"}},
+		{Id: 3, Title: "Other story", PubDate: now.Add(-time.Hour), StoryId: 3, Source: model.Source{Id: 1, ShortName: "one"}},
+	}
+	mockStorage.EXPECT().Dialect().Return(nil)
+	// The articles are loaded once, both for filtering and for the other sources of the page
+	mockStorage.EXPECT().GetAll(gomock.Any()).Return(articles, nil)
+
+	a := New(mockStorage)
+	page, err := a.GetPage(context.Background(), filter.Filters{Collapse: true}, filter.Page{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	require.Len(t, page.Articles, 1)
+	assert.Equal(t, 2, page.Articles[0].Id)
+	assert.Equal(t, []model.Source{{Id: 1, ShortName: "one"}}, page.Articles[0].OtherSources)
+}
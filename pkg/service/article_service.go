@@ -1,12 +1,15 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
 	"github.com/antonchaban/news-aggregator/pkg/filter"
 	"github.com/antonchaban/news-aggregator/pkg/handler/web"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/sirupsen/logrus"
-	"os"
+	"strings"
+	"time"
 )
 
 const (
@@ -17,18 +20,44 @@ const (
 	eventFiltersChained      = "filters_chained"
 	eventFilteringError      = "filtering_error"
 	eventFilteringComplete   = "filtering_complete"
+	eventGetPageStart        = "get_page_start"
+	eventCountArticlesError  = "count_articles_error"
+	eventPaginationError     = "pagination_error"
+	eventStoryMembersError   = "story_members_error"
+)
+
+// articlesColumns and articlesFrom select articles joined with their sources,
+// the filter chain adds the conditions to them.
+const (
+	articlesColumns = "a.id, a.title, a.description, a.link, a.pub_date, a.story_id, " +
+		"a.author, a.categories, a.image_url, a.content, a.language, a.guid, s.id, s.name, s.link, s.short_name"
+	articlesFrom = "articles a JOIN sources s ON a.source_id = s.id"
 )
 
 //go:generate mockgen -destination=mocks/mock_article.go -package=mocks github.com/antonchaban/news-aggregator/pkg/service ArticleStorage
 
 // ArticleStorage is an interface that defines the methods for interacting with the article storage.
 type ArticleStorage interface {
-	GetAll() ([]model.Article, error)
-	Save(article model.Article) (model.Article, error)
-	SaveAll(articles []model.Article) error
-	Delete(id int) error
-	DeleteBySourceID(id int) error
-	GetByFilter(query string, args []interface{}) ([]model.Article, error)
+	GetAll(ctx context.Context) ([]model.Article, error)
+	// GetAfter returns up to limit articles with IDs greater than afterID, ordered by ID,
+	// so all the articles can be read page by page.
+	GetAfter(ctx context.Context, afterID, limit int) ([]model.Article, error)
+	Save(ctx context.Context, article model.Article) (model.Article, error)
+	// SaveAll saves the articles in a single transaction, updating the already saved ones
+	// if they changed, and counts what changed.
+	SaveAll(ctx context.Context, articles []model.Article) (model.SaveResult, error)
+	Delete(ctx context.Context, id int) error
+	DeleteBySourceID(ctx context.Context, id int) error
+	// GetExpired returns the articles of the source published before the cutoff or past
+	// the newest maxCount ones, oldest first. The zero cutoff and maxCount set no limit.
+	GetExpired(ctx context.Context, sourceID int, cutoff time.Time, maxCount int) ([]model.Article, error)
+	// DeleteByIDs deletes the articles with the given IDs and returns the number of deleted ones.
+	DeleteByIDs(ctx context.Context, ids []int) (int, error)
+	GetByFilter(ctx context.Context, query string, args []interface{}) ([]model.Article, error)
+	CountByFilter(ctx context.Context, query string, args []interface{}) (int, error)
+	// Dialect returns the SQL dialect of the GetByFilter and CountByFilter queries,
+	// or nil if the storage doesn't support them and the articles are filtered in memory.
+	Dialect() filter.Dialect
 }
 
 type articleService struct {
@@ -39,102 +68,218 @@ func New(articleRepo ArticleStorage) web.ArticleService {
 	return &articleService{articleStorage: articleRepo}
 }
 
-// SaveAll saves multiple articles to the database.
-func (a *articleService) SaveAll(articles []model.Article) error {
-	err := a.articleStorage.SaveAll(articles)
+// SaveAll saves multiple articles to the database and counts what changed.
+func (a *articleService) SaveAll(ctx context.Context, articles []model.Article) (model.SaveResult, error) {
+	result, err := a.articleStorage.SaveAll(ctx, articles)
 	if err != nil {
-		return errors.New("failed to save articles")
+		return model.SaveResult{}, errors.New("failed to save articles")
 	}
-	return err
+	return result, nil
 }
 
 // GetAll returns all articles in the database.
-func (a *articleService) GetAll() ([]model.Article, error) {
-	return a.articleStorage.GetAll()
+func (a *articleService) GetAll(ctx context.Context) ([]model.Article, error) {
+	return a.articleStorage.GetAll(ctx)
 }
 
 // Create adds a new article to the database.
-func (a *articleService) Create(article model.Article) (model.Article, error) {
-	return a.articleStorage.Save(article)
+func (a *articleService) Create(ctx context.Context, article model.Article) (model.Article, error) {
+	return a.articleStorage.Save(ctx, article)
 }
 
 // Delete removes the article with the given ID from the database.
-func (a *articleService) Delete(id int) error {
-	return a.articleStorage.Delete(id)
+func (a *articleService) Delete(ctx context.Context, id int) error {
+	return a.articleStorage.Delete(ctx, id)
 }
 
 // GetByFilter returns all articles that match the given filters.
-func (a *articleService) GetByFilter(f filter.Filters) ([]model.Article, error) {
+func (a *articleService) GetByFilter(ctx context.Context, f filter.Filters) ([]model.Article, error) {
 	logrus.WithField("event_id", eventGetByFilterStart).Info("Fetching articles with filter")
 
-	if os.Getenv("STORAGE_TYPE") == "postgres" {
-		articles, err := a.getByFilterDB(f)
-		if err != nil {
-			if err.Error() == "GetByFilter operation is not supported in in-memory storage" {
-				logrus.WithField("event_id", "fallback_to_inmemory").Warn("Falling back to in-memory filtering")
-				return a.getByFilterInMemory(f)
-			}
-			return nil, err
-		}
-		return articles, nil
+	if d := a.articleStorage.Dialect(); d != nil {
+		return a.getByFilterDB(ctx, d, f)
+	}
+	return a.getByFilterInMemory(ctx, f)
+}
+
+// GetPage returns one page of articles that match the given filters,
+// sorted and paginated according to the given page criteria.
+func (a *articleService) GetPage(ctx context.Context, f filter.Filters, p filter.Page) (model.ArticlePage, error) {
+	logrus.WithField("event_id", eventGetPageStart).Info("Fetching page of articles with filter")
+
+	if d := a.articleStorage.Dialect(); d != nil {
+		return a.getPageDB(ctx, d, f, p)
 	}
-	return a.getByFilterInMemory(f)
+	return a.getPageInMemory(ctx, f, p)
 }
 
-func (a *articleService) getByFilterInMemory(f filter.Filters) ([]model.Article, error) {
-	articles, err := a.articleStorage.GetAll()
+func (a *articleService) getPageInMemory(ctx context.Context, f filter.Filters, p filter.Page) (model.ArticlePage, error) {
+	articles, all, err := a.filterInMemory(ctx, f)
 	if err != nil {
-		logrus.WithField("event_id", eventGetAllArticlesError).Error("Error fetching all articles", err)
-		return nil, err
+		return model.ArticlePage{}, err
 	}
-	logrus.WithField("event_id", eventAllArticlesFetched).Info("All articles fetched successfully")
+
+	pageArticles, nextCursor, err := p.Paginate(articles)
+	if err != nil {
+		logrus.WithField("event_id", eventPaginationError).Error("Error during pagination", err)
+		return model.ArticlePage{}, err
+	}
+	if f.Collapse {
+		dedup.OtherSources(pageArticles, all)
+	}
+
+	return model.ArticlePage{
+		Articles:   pageArticles,
+		Count:      len(pageArticles),
+		Total:      len(articles),
+		NextCursor: nextCursor,
+	}, nil
+}
+
+func (a *articleService) getPageDB(ctx context.Context, d filter.Dialect, f filter.Filters, p filter.Page) (model.ArticlePage, error) {
+	query := filter.NewQuery(d, articlesColumns, articlesFrom)
+	err := newFilterChain().BuildFilterQuery(f, query)
+	if err != nil {
+		logrus.WithField("event_id", eventFilteringError).Error("Error building filter query", err)
+		return model.ArticlePage{}, err
+	}
+
+	countSQL, countArgs := query.Count()
+	total, err := a.articleStorage.CountByFilter(ctx, countSQL, countArgs)
+	if err != nil {
+		logrus.WithField("event_id", eventCountArticlesError).Error("Error counting articles", err)
+		return model.ArticlePage{}, err
+	}
+
+	err = p.BuildPageQuery(query)
+	if err != nil {
+		logrus.WithField("event_id", eventPaginationError).Error("Error building page query", err)
+		return model.ArticlePage{}, err
+	}
+
+	sql, args := query.Build()
+	articles, err := a.articleStorage.GetByFilter(ctx, sql, args)
+	if err != nil {
+		logrus.WithField("event_id", eventFilteringError).Error("Error executing query", err)
+		return model.ArticlePage{}, err
+	}
+
+	pageArticles, nextCursor, err := p.NextCursor(articles)
+	if err != nil {
+		return model.ArticlePage{}, err
+	}
+	if f.Collapse {
+		err = a.listOtherSources(ctx, d, pageArticles)
+		if err != nil {
+			return model.ArticlePage{}, err
+		}
+	}
+
+	logrus.WithField("event_id", eventFilteringComplete).Info("Filtering completed successfully")
+	return model.ArticlePage{
+		Articles:   pageArticles,
+		Count:      len(pageArticles),
+		Total:      total,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// newFilterChain creates the chain of source, author, category, keyword, date range and story filters.
+// The story filter is the last one, as it collapses the articles matched by the others.
+func newFilterChain() filter.ArticleFilter {
 	sourceFilter := &filter.SourceFilter{}
+	authorFilter := &filter.AuthorFilter{}
+	categoryFilter := &filter.CategoryFilter{}
 	keywordFilter := &filter.KeywordFilter{}
 	dateRangeFilter := &filter.DateRangeFilter{}
+	storyFilter := &filter.StoryFilter{}
 
 	logrus.WithField("event_id", eventFiltersCreated).Info("Filter handlers created")
 
-	// Create the chain
-	sourceFilter.SetNext(keywordFilter).SetNext(dateRangeFilter)
+	sourceFilter.SetNext(authorFilter).SetNext(categoryFilter).SetNext(keywordFilter).
+		SetNext(dateRangeFilter).SetNext(storyFilter)
 	logrus.WithField("event_id", eventFiltersChained).Info("Filters chained together")
 
-	// Start filtering
-	filteredArticles, err := sourceFilter.Filter(articles, f)
+	return sourceFilter
+}
+
+func (a *articleService) getByFilterInMemory(ctx context.Context, f filter.Filters) ([]model.Article, error) {
+	filteredArticles, articles, err := a.filterInMemory(ctx, f)
 	if err != nil {
-		logrus.WithField("event_id", eventFilteringError).Error("Error during filtering", err)
 		return nil, err
 	}
-	logrus.WithField("event_id", eventFilteringComplete).Info("Filtering completed successfully")
-
+	if f.Collapse {
+		dedup.OtherSources(filteredArticles, articles)
+	}
 	return filteredArticles, nil
 }
 
-func (a *articleService) getByFilterDB(f filter.Filters) ([]model.Article, error) {
-	baseQuery := `
-		SELECT a.id, a.title, a.description, a.link, a.pub_date,
-		       s.id, s.name, s.link, s.short_name
-		FROM articles a
-		JOIN sources s ON a.source_id = s.id
-		WHERE 1=1
-	`
-
-	sourceFilter := &filter.SourceFilter{}
-	keywordFilter := &filter.KeywordFilter{}
-	dateRangeFilter := &filter.DateRangeFilter{}
+// filterInMemory returns the stored articles that match the given filters, along with all the stored articles
+// the other sources of the collapsed stories are looked up among.
+func (a *articleService) filterInMemory(ctx context.Context, f filter.Filters) (filtered, all []model.Article, err error) {
+	all, err = a.articleStorage.GetAll(ctx)
+	if err != nil {
+		logrus.WithField("event_id", eventGetAllArticlesError).Error("Error fetching all articles", err)
+		return nil, nil, err
+	}
+	logrus.WithField("event_id", eventAllArticlesFetched).Info("All articles fetched successfully")
 
-	logrus.WithField("event_id", eventFiltersCreated).Info("Filter handlers created")
+	// Start filtering
+	filtered, err = newFilterChain().Filter(all, f)
+	if err != nil {
+		logrus.WithField("event_id", eventFilteringError).Error("Error during filtering", err)
+		return nil, nil, err
+	}
+	logrus.WithField("event_id", eventFilteringComplete).Info("Filtering completed successfully")
 
-	sourceFilter.SetNext(keywordFilter).SetNext(dateRangeFilter)
-	logrus.WithField("event_id", eventFiltersChained).Info("Filters chained together")
+	return filtered, all, nil
+}
 
-	query, args := sourceFilter.BuildFilterQuery(f, baseQuery)
+func (a *articleService) getByFilterDB(ctx context.Context, d filter.Dialect, f filter.Filters) ([]model.Article, error) {
+	query := filter.NewQuery(d, articlesColumns, articlesFrom)
+	err := newFilterChain().BuildFilterQuery(f, query)
+	if err != nil {
+		logrus.WithField("event_id", eventFilteringError).Error("Error building filter query", err)
+		return nil, err
+	}
 
-	articles, err := a.articleStorage.GetByFilter(query, args)
+	sql, args := query.Build()
+	articles, err := a.articleStorage.GetByFilter(ctx, sql, args)
 	if err != nil {
 		logrus.WithField("event_id", eventFilteringError).Error("Error executing query", err)
 		return nil, err
 	}
+	if f.Collapse {
+		err = a.listOtherSources(ctx, d, articles)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	logrus.WithField("event_id", eventFilteringComplete).Info("Filtering completed successfully")
 	return articles, nil
 }
+
+// listOtherSources sets the other sources reporting the stories of the collapsed articles,
+// looking up the articles of only those stories in the database.
+func (a *articleService) listOtherSources(ctx context.Context, d filter.Dialect, articles []model.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	storyIDs := make([]interface{}, len(articles))
+	for i, article := range articles {
+		storyIDs[i] = article.StoryId
+	}
+	query := filter.NewQuery(d, articlesColumns, articlesFrom)
+	query.Where("a.story_id IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(storyIDs)), ", ")+")", storyIDs...)
+	sql, args := query.Build()
+	members, err := a.articleStorage.GetByFilter(ctx, sql, args)
+	if err != nil {
+		logrus.WithField("event_id", eventStoryMembersError).Error("Error fetching the articles of the stories", err)
+		return err
+	}
+
+	dedup.OtherSources(articles, members)
+	return nil
+}
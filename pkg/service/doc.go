@@ -3,5 +3,8 @@
 // It interacts with the model layer to perform operations on the database.
 // It provides an abstraction over the database and parsing operations to the handler layer.
 //
+// The operations mutating both the article and the source storage run in a UnitOfWork,
+// so they are applied entirely or not at all.
+//
 // The service layer should be used by the handler layer to perform business logic operations.
 package service
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+//go:generate mockgen -destination=mocks/mock_fetch_locker.go -package=mocks github.com/antonchaban/news-aggregator/pkg/service FetchLocker
+
+// FetchLocker coordinates the instances fetching the same sources, such as the replicas of the web server
+// running a scheduler and the overlapping runs of the fetcher, so that a source is fetched once per period.
+type FetchLocker interface {
+	// Acquire takes the lease on fetching the source for ttl, reporting false if another instance holds it.
+	// The instance holding the lease may acquire it again before it expires, extending it.
+	Acquire(ctx context.Context, sourceID int, ttl time.Duration) (bool, error)
+}
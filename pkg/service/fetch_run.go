@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"time"
+)
+
+//go:generate mockgen -destination=mocks/mock_fetch_run.go -package=mocks github.com/antonchaban/news-aggregator/pkg/service FetchRunStorage
+
+const (
+	eventErrorSavingFetchRun  = "error_saving_fetch_run"
+	eventErrorPruningFetchRun = "error_pruning_fetch_runs"
+	eventFetchRunsPruned      = "fetch_runs_pruned"
+)
+
+// Environment variables holding the fetch history policy.
+const (
+	fetchHistoryMaxAgeEnvVar  = "FETCH_HISTORY_MAX_AGE"
+	fetchHistoryMaxRunsEnvVar = "FETCH_HISTORY_MAX_RUNS"
+)
+
+const (
+	// defaultFetchHistoryMaxAge is how long the fetch runs are kept unless FETCH_HISTORY_MAX_AGE is set.
+	defaultFetchHistoryMaxAge = 7 * 24 * time.Hour
+	// defaultFetchHistoryMaxRuns is the number of the newest fetch runs kept unless FETCH_HISTORY_MAX_RUNS is set.
+	defaultFetchHistoryMaxRuns = 10000
+)
+
+// errNoFetchHistory is returned when the fetch history is listed but the fetches aren't recorded.
+var errNoFetchHistory = errors.New("fetch history isn't recorded")
+
+// FetchRunStorage keeps the history of the fetches.
+type FetchRunStorage interface {
+	// Save stores the run along with its attempts and returns it with the IDs assigned.
+	Save(ctx context.Context, run model.FetchRun) (model.FetchRun, error)
+	// GetAll returns up to limit runs with their attempts, newest first.
+	GetAll(ctx context.Context, limit int) ([]model.FetchRun, error)
+	// GetAttemptsBySourceID returns up to limit attempts of the source, newest first.
+	GetAttemptsBySourceID(ctx context.Context, sourceID int, limit int) ([]model.FetchAttempt, error)
+	// DeleteBefore deletes the runs started before the cutoff along with their attempts
+	// and returns the number of deleted runs.
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int, error)
+	// DeleteBeyond deletes all but the newest maxRuns runs along with their attempts
+	// and returns the number of deleted runs.
+	DeleteBeyond(ctx context.Context, maxRuns int) (int, error)
+}
+
+// GetFetchRuns returns up to limit recorded fetch runs with the attempt of every source, newest first.
+func (s *sourceService) GetFetchRuns(ctx context.Context, limit int) ([]model.FetchRun, error) {
+	if s.runs == nil {
+		return nil, errNoFetchHistory
+	}
+	return s.runs.GetAll(ctx, limit)
+}
+
+// GetSourceFetchRuns returns up to limit recorded fetch attempts of the source with the given ID, newest first.
+func (s *sourceService) GetSourceFetchRuns(ctx context.Context, sourceID int, limit int) ([]model.FetchAttempt, error) {
+	if s.runs == nil {
+		return nil, errNoFetchHistory
+	}
+	return s.runs.GetAttemptsBySourceID(ctx, sourceID, limit)
+}
+
+// recordRun stores the fetch in the history, leaving out the sources skipped as another instance
+// held their lease, as that instance records them. The run is stored even if ctx is canceled,
+// so the interrupted fetches are recorded as well.
+// Failing to record a fetch doesn't fail it, the error is logged instead.
+func (s *sourceService) recordRun(ctx context.Context, report model.FetchReport) {
+	if s.runs == nil {
+		return
+	}
+	attempted := make([]model.SourceFetchResult, 0, len(report.Sources))
+	for _, result := range report.Sources {
+		if result.Status != model.FetchSkipped {
+			attempted = append(attempted, result)
+		}
+	}
+	if len(report.Sources) > 0 && len(attempted) == 0 {
+		return
+	}
+	report.Sources = attempted
+
+	ctx = context.WithoutCancel(ctx)
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if _, err := s.runs.Save(ctx, report.Run()); err != nil {
+		logrus.WithField("event_id", eventErrorSavingFetchRun).Errorf("Error saving fetch run: %v", err)
+	}
+}
+
+// FetchHistoryPolicy limits the fetch history: the runs older than MaxAge are deleted,
+// and so are the runs beyond the newest MaxRuns ones.
+type FetchHistoryPolicy struct {
+	MaxAge  time.Duration
+	MaxRuns int
+}
+
+// FetchHistoryPolicyFromEnv reads the fetch history policy from the FETCH_HISTORY_MAX_AGE environment variable,
+// a duration such as 168h, and FETCH_HISTORY_MAX_RUNS. Unset ones keep the runs of the past 7 days,
+// up to 10000 of them.
+func FetchHistoryPolicyFromEnv() (FetchHistoryPolicy, error) {
+	policy := FetchHistoryPolicy{MaxAge: defaultFetchHistoryMaxAge, MaxRuns: defaultFetchHistoryMaxRuns}
+	if value := os.Getenv(fetchHistoryMaxAgeEnvVar); value != "" {
+		age, err := time.ParseDuration(value)
+		if err != nil || age <= 0 {
+			return FetchHistoryPolicy{}, fmt.Errorf("invalid %s: %s", fetchHistoryMaxAgeEnvVar, value)
+		}
+		policy.MaxAge = age
+	}
+	if value := os.Getenv(fetchHistoryMaxRunsEnvVar); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return FetchHistoryPolicy{}, fmt.Errorf("invalid %s: %s", fetchHistoryMaxRunsEnvVar, value)
+		}
+		policy.MaxRuns = n
+	}
+	return policy, nil
+}
+
+// FetchHistoryPruner deletes the fetch runs beyond the fetch history policy.
+type FetchHistoryPruner struct {
+	runs   FetchRunStorage
+	policy FetchHistoryPolicy
+	now    func() time.Time
+}
+
+// NewFetchHistoryPruner creates a new FetchHistoryPruner deleting the runs of the storage beyond the policy.
+func NewFetchHistoryPruner(runs FetchRunStorage, policy FetchHistoryPolicy) *FetchHistoryPruner {
+	return &FetchHistoryPruner{runs: runs, policy: policy, now: time.Now}
+}
+
+// Prune deletes the runs older than the maximum age, then the runs beyond the maximum count,
+// and returns the number of deleted runs.
+func (p *FetchHistoryPruner) Prune(ctx context.Context) (int, error) {
+	expired, err := p.runs.DeleteBefore(ctx, p.now().Add(-p.policy.MaxAge))
+	if err != nil {
+		return 0, err
+	}
+	excess, err := p.runs.DeleteBeyond(ctx, p.policy.MaxRuns)
+	if err != nil {
+		return expired, err
+	}
+	if deleted := expired + excess; deleted > 0 {
+		logrus.WithField("event_id", eventFetchRunsPruned).Infof("Pruned %d fetch runs", deleted)
+	}
+	return expired + excess, nil
+}
+
+// Run prunes the fetch history every interval until ctx is done.
+// Failing to prune doesn't stop the pruning, the error is logged instead.
+func (p *FetchHistoryPruner) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := p.Prune(ctx); err != nil {
+			logrus.WithField("event_id", eventErrorPruningFetchRun).Errorf("Error pruning fetch runs: %v", err)
+		}
+	}
+}
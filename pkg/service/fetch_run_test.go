@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_sourceService_FetchFromAllSources_History(t *testing.T) {
+	srv := newTestFeedServer(t)
+	ctrl := gomock.NewController(t)
+	mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+	mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+	mockRuns := mocks.NewMockFetchRunStorage(ctrl)
+	s := &sourceService{
+		articleStorage: mockArticleStorage,
+		srcStorage:     mockSourceStorage,
+		uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
+		locker:         newTestLeaseTable().locker("replica"),
+		runs:           mockRuns,
+	}
+
+	sources := []model.Source{
+		{Id: 1, ShortName: "ok", Link: srv.URL + "/rss"},
+		{Id: 2, ShortName: "broken", Link: srv.URL + "/broken"},
+	}
+	mockSourceStorage.EXPECT().GetAll(gomock.Any()).Return(sources, nil)
+	mockSourceStorage.EXPECT().UpdateHealth(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).Times(2)
+	mockArticleStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 1, Updated: 1}, nil)
+	var saved model.FetchRun
+	mockRuns.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, run model.FetchRun) (model.FetchRun, error) {
+		saved = run
+		return run, nil
+	})
+
+	report, err := s.FetchFromAllSources(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, report.StartedAt, saved.StartedAt)
+	assert.Equal(t, report.StartedAt.Add(report.Duration), saved.FinishedAt)
+	assert.Equal(t, 2, saved.Sources)
+	assert.Equal(t, 1, saved.Failed)
+	assert.Equal(t, 1, saved.Inserted)
+	assert.Equal(t, 1, saved.Updated)
+	require.Len(t, saved.Attempts, 2)
+
+	ok, broken := saved.Attempts[0], saved.Attempts[1]
+	assert.Equal(t, 1, ok.SourceId)
+	assert.Equal(t, "ok", ok.SourceName)
+	assert.Equal(t, model.FetchOK, ok.Status)
+	assert.Equal(t, http.StatusOK, ok.HTTPStatus)
+	assert.Positive(t, ok.Bytes)
+	assert.Equal(t, 2, ok.Articles)
+	assert.Equal(t, 1, ok.Inserted)
+	assert.Equal(t, 1, ok.Updated)
+	assert.Empty(t, ok.Error)
+	assert.False(t, ok.FinishedAt.Before(ok.StartedAt))
+
+	assert.Equal(t, 2, broken.SourceId)
+	assert.Equal(t, model.FetchFailed, broken.Status)
+	assert.Equal(t, http.StatusInternalServerError, broken.HTTPStatus)
+	assert.Contains(t, broken.Error, "500 Internal Server Error")
+}
+
+func Test_sourceService_FetchSource_History(t *testing.T) {
+	srv := newTestFeedServer(t)
+	tests := []struct {
+		name  string
+		src   model.Source
+		setup func(runs *mocks.MockFetchRunStorage)
+		want  model.FetchAttempt
+	}{
+		{
+			name: "recorded as a run of its own",
+			src:  model.Source{Id: 1, ShortName: "one", Link: srv.URL + "/rss"},
+			setup: func(runs *mocks.MockFetchRunStorage) {
+				runs.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, run model.FetchRun) (model.FetchRun, error) {
+					assert.Equal(t, 1, run.Sources)
+					assert.Equal(t, 2, run.Inserted)
+					require.Len(t, run.Attempts, 1)
+					assert.Equal(t, run.StartedAt, run.Attempts[0].StartedAt)
+					assert.Equal(t, run.FinishedAt, run.Attempts[0].FinishedAt)
+					return run, nil
+				})
+			},
+			want: model.FetchAttempt{SourceId: 1, SourceName: "one", Status: model.FetchOK, HTTPStatus: http.StatusOK,
+				Articles: 2, Inserted: 2},
+		},
+		{
+			name: "disabled",
+			src: model.Source{Id: 1, ShortName: "one", Link: srv.URL + "/rss",
+				Health: &model.SourceHealth{Status: model.SourceDisabled}},
+			setup: func(runs *mocks.MockFetchRunStorage) {
+				runs.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, run model.FetchRun) (model.FetchRun, error) {
+					require.Len(t, run.Attempts, 1)
+					assert.Equal(t, model.FetchDisabled, run.Attempts[0].Status)
+					return run, nil
+				})
+			},
+			want: model.FetchAttempt{SourceId: 1, SourceName: "one", Status: model.FetchDisabled},
+		},
+		{
+			name: "failing to record doesn't fail the fetch",
+			src:  model.Source{Id: 1, ShortName: "one", Link: srv.URL + "/rss"},
+			setup: func(runs *mocks.MockFetchRunStorage) {
+				runs.EXPECT().Save(gomock.Any(), gomock.Any()).Return(model.FetchRun{}, errors.New("disk full"))
+			},
+			want: model.FetchAttempt{SourceId: 1, SourceName: "one", Status: model.FetchOK, HTTPStatus: http.StatusOK,
+				Articles: 2, Inserted: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+			mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+			mockRuns := mocks.NewMockFetchRunStorage(ctrl)
+			s := &sourceService{
+				articleStorage: mockArticleStorage,
+				srcStorage:     mockSourceStorage,
+				uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
+				locker:         newTestLeaseTable().locker("replica"),
+				runs:           mockRuns,
+			}
+
+			mockSourceStorage.EXPECT().GetByID(gomock.Any(), 1).Return(tt.src, nil)
+			mockSourceStorage.EXPECT().UpdateHealth(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(true, nil).MaxTimes(1)
+			mockArticleStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 2}, nil).MaxTimes(1)
+			tt.setup(mockRuns)
+
+			result, err := s.FetchSource(context.Background(), 1)
+			require.NoError(t, err)
+
+			got := result.Attempt()
+			assert.False(t, got.StartedAt.IsZero())
+			got.StartedAt, got.FinishedAt, got.Bytes = time.Time{}, time.Time{}, 0
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_sourceService_FetchSource_SkippedIsNotRecorded(t *testing.T) {
+	srv := newTestFeedServer(t)
+	ctrl := gomock.NewController(t)
+	mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+	leases := newTestLeaseTable()
+	acquired, err := leases.locker("other").Acquire(context.Background(), 1, time.Hour)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	// The run storage expects no calls, the instance holding the lease records the fetch
+	s := &sourceService{
+		srcStorage: mockSourceStorage,
+		locker:     leases.locker("replica"),
+		runs:       mocks.NewMockFetchRunStorage(ctrl),
+	}
+	mockSourceStorage.EXPECT().GetByID(gomock.Any(), 1).Return(model.Source{Id: 1, ShortName: "one", Link: srv.URL + "/rss"}, nil)
+
+	result, err := s.FetchSource(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.FetchSkipped, result.Status)
+}
+
+func TestFetchHistoryPolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxAge  string
+		maxRuns string
+		want    FetchHistoryPolicy
+		wantErr string
+	}{
+		{name: "unset", want: FetchHistoryPolicy{MaxAge: defaultFetchHistoryMaxAge, MaxRuns: defaultFetchHistoryMaxRuns}},
+		{name: "both", maxAge: "24h", maxRuns: "500", want: FetchHistoryPolicy{MaxAge: 24 * time.Hour, MaxRuns: 500}},
+		{name: "invalid age", maxAge: "7", wantErr: "invalid FETCH_HISTORY_MAX_AGE: 7"},
+		{name: "zero runs", maxRuns: "0", wantErr: "invalid FETCH_HISTORY_MAX_RUNS: 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(fetchHistoryMaxAgeEnvVar, tt.maxAge)
+			t.Setenv(fetchHistoryMaxRunsEnvVar, tt.maxRuns)
+
+			policy, err := FetchHistoryPolicyFromEnv()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, policy)
+		})
+	}
+}
+
+func TestFetchHistoryPruner_Prune(t *testing.T) {
+	now := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	policy := FetchHistoryPolicy{MaxAge: 24 * time.Hour, MaxRuns: 100}
+	tests := []struct {
+		name    string
+		mock    func(runs *mocks.MockFetchRunStorage)
+		want    int
+		wantErr string
+	}{
+		{
+			name: "expired and excess runs",
+			mock: func(runs *mocks.MockFetchRunStorage) {
+				runs.EXPECT().DeleteBefore(gomock.Any(), now.Add(-24*time.Hour)).Return(2, nil)
+				runs.EXPECT().DeleteBeyond(gomock.Any(), 100).Return(3, nil)
+			},
+			want: 5,
+		},
+		{
+			name: "error",
+			mock: func(runs *mocks.MockFetchRunStorage) {
+				runs.EXPECT().DeleteBefore(gomock.Any(), now.Add(-24*time.Hour)).Return(0, errors.New("connection reset"))
+			},
+			wantErr: "connection reset",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			runs := mocks.NewMockFetchRunStorage(ctrl)
+			tt.mock(runs)
+			p := NewFetchHistoryPruner(runs, policy)
+			p.now = func() time.Time { return now }
+
+			deleted, err := p.Prune(context.Background())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, deleted)
+		})
+	}
+}
+
+func Test_sourceService_GetFetchRuns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRuns := mocks.NewMockFetchRunStorage(ctrl)
+	runs := []model.FetchRun{{Id: 2, Sources: 1, Attempts: []model.FetchAttempt{{Id: 3, RunId: 2, SourceId: 1}}}}
+	attempts := []model.FetchAttempt{{Id: 3, RunId: 2, SourceId: 1}}
+	mockRuns.EXPECT().GetAll(gomock.Any(), 10).Return(runs, nil)
+	mockRuns.EXPECT().GetAttemptsBySourceID(gomock.Any(), 1, 10).Return(attempts, nil)
+
+	s := &sourceService{runs: mockRuns}
+	gotRuns, err := s.GetFetchRuns(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, runs, gotRuns)
+	gotAttempts, err := s.GetSourceFetchRuns(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, attempts, gotAttempts)
+
+	s = &sourceService{}
+	_, err = s.GetFetchRuns(context.Background(), 10)
+	assert.ErrorIs(t, err, errNoFetchHistory)
+	_, err = s.GetSourceFetchRuns(context.Background(), 1, 10)
+	assert.ErrorIs(t, err, errNoFetchHistory)
+}
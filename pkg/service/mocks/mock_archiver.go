@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/antonchaban/news-aggregator/pkg/service (interfaces: ArticleArchiver)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/mock_archiver.go -package=mocks github.com/antonchaban/news-aggregator/pkg/service ArticleArchiver
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	backuper "github.com/antonchaban/news-aggregator/pkg/backuper"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockArticleArchiver is a mock of ArticleArchiver interface.
+type MockArticleArchiver struct {
+	ctrl     *gomock.Controller
+	recorder *MockArticleArchiverMockRecorder
+}
+
+// MockArticleArchiverMockRecorder is the mock recorder for MockArticleArchiver.
+type MockArticleArchiverMockRecorder struct {
+	mock *MockArticleArchiver
+}
+
+// NewMockArticleArchiver creates a new mock instance.
+func NewMockArticleArchiver(ctrl *gomock.Controller) *MockArticleArchiver {
+	mock := &MockArticleArchiver{ctrl: ctrl}
+	mock.recorder = &MockArticleArchiverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockArticleArchiver) EXPECT() *MockArticleArchiverMockRecorder {
+	return m.recorder
+}
+
+// Archive mocks base method.
+func (m *MockArticleArchiver) Archive(arg0 backuper.ArticleIterator) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Archive", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Archive indicates an expected call of Archive.
+func (mr *MockArticleArchiverMockRecorder) Archive(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Archive", reflect.TypeOf((*MockArticleArchiver)(nil).Archive), arg0)
+}
@@ -10,8 +10,11 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
+	filter "github.com/antonchaban/news-aggregator/pkg/filter"
 	model "github.com/antonchaban/news-aggregator/pkg/model"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,89 +42,164 @@ func (m *MockArticleStorage) EXPECT() *MockArticleStorageMockRecorder {
 	return m.recorder
 }
 
+// CountByFilter mocks base method.
+func (m *MockArticleStorage) CountByFilter(arg0 context.Context, arg1 string, arg2 []any) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByFilter", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByFilter indicates an expected call of CountByFilter.
+func (mr *MockArticleStorageMockRecorder) CountByFilter(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByFilter", reflect.TypeOf((*MockArticleStorage)(nil).CountByFilter), arg0, arg1, arg2)
+}
+
 // Delete mocks base method.
-func (m *MockArticleStorage) Delete(arg0 int) error {
+func (m *MockArticleStorage) Delete(arg0 context.Context, arg1 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockArticleStorageMockRecorder) Delete(arg0 any) *gomock.Call {
+func (mr *MockArticleStorageMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockArticleStorage)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockArticleStorage)(nil).Delete), arg0, arg1)
+}
+
+// DeleteByIDs mocks base method.
+func (m *MockArticleStorage) DeleteByIDs(arg0 context.Context, arg1 []int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByIDs", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteByIDs indicates an expected call of DeleteByIDs.
+func (mr *MockArticleStorageMockRecorder) DeleteByIDs(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByIDs", reflect.TypeOf((*MockArticleStorage)(nil).DeleteByIDs), arg0, arg1)
 }
 
 // DeleteBySourceID mocks base method.
-func (m *MockArticleStorage) DeleteBySourceID(arg0 int) error {
+func (m *MockArticleStorage) DeleteBySourceID(arg0 context.Context, arg1 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteBySourceID", arg0)
+	ret := m.ctrl.Call(m, "DeleteBySourceID", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteBySourceID indicates an expected call of DeleteBySourceID.
-func (mr *MockArticleStorageMockRecorder) DeleteBySourceID(arg0 any) *gomock.Call {
+func (mr *MockArticleStorageMockRecorder) DeleteBySourceID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBySourceID", reflect.TypeOf((*MockArticleStorage)(nil).DeleteBySourceID), arg0, arg1)
+}
+
+// Dialect mocks base method.
+func (m *MockArticleStorage) Dialect() filter.Dialect {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dialect")
+	ret0, _ := ret[0].(filter.Dialect)
+	return ret0
+}
+
+// Dialect indicates an expected call of Dialect.
+func (mr *MockArticleStorageMockRecorder) Dialect() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBySourceID", reflect.TypeOf((*MockArticleStorage)(nil).DeleteBySourceID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dialect", reflect.TypeOf((*MockArticleStorage)(nil).Dialect))
+}
+
+// GetAfter mocks base method.
+func (m *MockArticleStorage) GetAfter(arg0 context.Context, arg1, arg2 int) ([]model.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAfter", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAfter indicates an expected call of GetAfter.
+func (mr *MockArticleStorageMockRecorder) GetAfter(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAfter", reflect.TypeOf((*MockArticleStorage)(nil).GetAfter), arg0, arg1, arg2)
 }
 
 // GetAll mocks base method.
-func (m *MockArticleStorage) GetAll() ([]model.Article, error) {
+func (m *MockArticleStorage) GetAll(arg0 context.Context) ([]model.Article, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAll", arg0)
 	ret0, _ := ret[0].([]model.Article)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockArticleStorageMockRecorder) GetAll() *gomock.Call {
+func (mr *MockArticleStorageMockRecorder) GetAll(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockArticleStorage)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockArticleStorage)(nil).GetAll), arg0)
 }
 
 // GetByFilter mocks base method.
-func (m *MockArticleStorage) GetByFilter(arg0 string, arg1 []any) ([]model.Article, error) {
+func (m *MockArticleStorage) GetByFilter(arg0 context.Context, arg1 string, arg2 []any) ([]model.Article, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByFilter", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetByFilter", arg0, arg1, arg2)
 	ret0, _ := ret[0].([]model.Article)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByFilter indicates an expected call of GetByFilter.
-func (mr *MockArticleStorageMockRecorder) GetByFilter(arg0, arg1 any) *gomock.Call {
+func (mr *MockArticleStorageMockRecorder) GetByFilter(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByFilter", reflect.TypeOf((*MockArticleStorage)(nil).GetByFilter), arg0, arg1, arg2)
+}
+
+// GetExpired mocks base method.
+func (m *MockArticleStorage) GetExpired(arg0 context.Context, arg1 int, arg2 time.Time, arg3 int) ([]model.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpired", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]model.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpired indicates an expected call of GetExpired.
+func (mr *MockArticleStorageMockRecorder) GetExpired(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByFilter", reflect.TypeOf((*MockArticleStorage)(nil).GetByFilter), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpired", reflect.TypeOf((*MockArticleStorage)(nil).GetExpired), arg0, arg1, arg2, arg3)
 }
 
 // Save mocks base method.
-func (m *MockArticleStorage) Save(arg0 model.Article) (model.Article, error) {
+func (m *MockArticleStorage) Save(arg0 context.Context, arg1 model.Article) (model.Article, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", arg0)
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
 	ret0, _ := ret[0].(model.Article)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockArticleStorageMockRecorder) Save(arg0 any) *gomock.Call {
+func (mr *MockArticleStorageMockRecorder) Save(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockArticleStorage)(nil).Save), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockArticleStorage)(nil).Save), arg0, arg1)
 }
 
 // SaveAll mocks base method.
-func (m *MockArticleStorage) SaveAll(arg0 []model.Article) error {
+func (m *MockArticleStorage) SaveAll(arg0 context.Context, arg1 []model.Article) (model.SaveResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveAll", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "SaveAll", arg0, arg1)
+	ret0, _ := ret[0].(model.SaveResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SaveAll indicates an expected call of SaveAll.
-func (mr *MockArticleStorageMockRecorder) SaveAll(arg0 any) *gomock.Call {
+func (mr *MockArticleStorageMockRecorder) SaveAll(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockArticleStorage)(nil).SaveAll), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockArticleStorage)(nil).SaveAll), arg0, arg1)
 }
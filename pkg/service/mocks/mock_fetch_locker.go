@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/antonchaban/news-aggregator/pkg/service (interfaces: FetchLocker)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/mock_fetch_locker.go -package=mocks github.com/antonchaban/news-aggregator/pkg/service FetchLocker
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockFetchLocker is a mock of FetchLocker interface.
+type MockFetchLocker struct {
+	ctrl     *gomock.Controller
+	recorder *MockFetchLockerMockRecorder
+}
+
+// MockFetchLockerMockRecorder is the mock recorder for MockFetchLocker.
+type MockFetchLockerMockRecorder struct {
+	mock *MockFetchLocker
+}
+
+// NewMockFetchLocker creates a new mock instance.
+func NewMockFetchLocker(ctrl *gomock.Controller) *MockFetchLocker {
+	mock := &MockFetchLocker{ctrl: ctrl}
+	mock.recorder = &MockFetchLockerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFetchLocker) EXPECT() *MockFetchLockerMockRecorder {
+	return m.recorder
+}
+
+// Acquire mocks base method.
+func (m *MockFetchLocker) Acquire(arg0 context.Context, arg1 int, arg2 time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acquire", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Acquire indicates an expected call of Acquire.
+func (mr *MockFetchLockerMockRecorder) Acquire(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acquire", reflect.TypeOf((*MockFetchLocker)(nil).Acquire), arg0, arg1, arg2)
+}
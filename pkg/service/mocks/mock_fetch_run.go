@@ -0,0 +1,117 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/antonchaban/news-aggregator/pkg/service (interfaces: FetchRunStorage)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/mock_fetch_run.go -package=mocks github.com/antonchaban/news-aggregator/pkg/service FetchRunStorage
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/antonchaban/news-aggregator/pkg/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockFetchRunStorage is a mock of FetchRunStorage interface.
+type MockFetchRunStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockFetchRunStorageMockRecorder
+}
+
+// MockFetchRunStorageMockRecorder is the mock recorder for MockFetchRunStorage.
+type MockFetchRunStorageMockRecorder struct {
+	mock *MockFetchRunStorage
+}
+
+// NewMockFetchRunStorage creates a new mock instance.
+func NewMockFetchRunStorage(ctrl *gomock.Controller) *MockFetchRunStorage {
+	mock := &MockFetchRunStorage{ctrl: ctrl}
+	mock.recorder = &MockFetchRunStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFetchRunStorage) EXPECT() *MockFetchRunStorageMockRecorder {
+	return m.recorder
+}
+
+// DeleteBefore mocks base method.
+func (m *MockFetchRunStorage) DeleteBefore(arg0 context.Context, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBefore", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBefore indicates an expected call of DeleteBefore.
+func (mr *MockFetchRunStorageMockRecorder) DeleteBefore(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBefore", reflect.TypeOf((*MockFetchRunStorage)(nil).DeleteBefore), arg0, arg1)
+}
+
+// DeleteBeyond mocks base method.
+func (m *MockFetchRunStorage) DeleteBeyond(arg0 context.Context, arg1 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBeyond", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBeyond indicates an expected call of DeleteBeyond.
+func (mr *MockFetchRunStorageMockRecorder) DeleteBeyond(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBeyond", reflect.TypeOf((*MockFetchRunStorage)(nil).DeleteBeyond), arg0, arg1)
+}
+
+// GetAll mocks base method.
+func (m *MockFetchRunStorage) GetAll(arg0 context.Context, arg1 int) ([]model.FetchRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", arg0, arg1)
+	ret0, _ := ret[0].([]model.FetchRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockFetchRunStorageMockRecorder) GetAll(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockFetchRunStorage)(nil).GetAll), arg0, arg1)
+}
+
+// GetAttemptsBySourceID mocks base method.
+func (m *MockFetchRunStorage) GetAttemptsBySourceID(arg0 context.Context, arg1, arg2 int) ([]model.FetchAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAttemptsBySourceID", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.FetchAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAttemptsBySourceID indicates an expected call of GetAttemptsBySourceID.
+func (mr *MockFetchRunStorageMockRecorder) GetAttemptsBySourceID(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAttemptsBySourceID", reflect.TypeOf((*MockFetchRunStorage)(nil).GetAttemptsBySourceID), arg0, arg1, arg2)
+}
+
+// Save mocks base method.
+func (m *MockFetchRunStorage) Save(arg0 context.Context, arg1 model.FetchRun) (model.FetchRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
+	ret0, _ := ret[0].(model.FetchRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockFetchRunStorageMockRecorder) Save(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockFetchRunStorage)(nil).Save), arg0, arg1)
+}
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	model "github.com/antonchaban/news-aggregator/pkg/model"
@@ -40,104 +41,133 @@ func (m *MockSourceStorage) EXPECT() *MockSourceStorageMockRecorder {
 }
 
 // Delete mocks base method.
-func (m *MockSourceStorage) Delete(arg0 int) error {
+func (m *MockSourceStorage) Delete(arg0 context.Context, arg1 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockSourceStorageMockRecorder) Delete(arg0 any) *gomock.Call {
+func (mr *MockSourceStorageMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSourceStorage)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSourceStorage)(nil).Delete), arg0, arg1)
 }
 
 // GetAll mocks base method.
-func (m *MockSourceStorage) GetAll() ([]model.Source, error) {
+func (m *MockSourceStorage) GetAll(arg0 context.Context) ([]model.Source, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAll", arg0)
 	ret0, _ := ret[0].([]model.Source)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockSourceStorageMockRecorder) GetAll() *gomock.Call {
+func (mr *MockSourceStorageMockRecorder) GetAll(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockSourceStorage)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockSourceStorage)(nil).GetAll), arg0)
 }
 
 // GetByID mocks base method.
-func (m *MockSourceStorage) GetByID(arg0 int) (model.Source, error) {
+func (m *MockSourceStorage) GetByID(arg0 context.Context, arg1 int) (model.Source, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByID", arg0)
+	ret := m.ctrl.Call(m, "GetByID", arg0, arg1)
 	ret0, _ := ret[0].(model.Source)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByID indicates an expected call of GetByID.
-func (mr *MockSourceStorageMockRecorder) GetByID(arg0 any) *gomock.Call {
+func (mr *MockSourceStorageMockRecorder) GetByID(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockSourceStorage)(nil).GetByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockSourceStorage)(nil).GetByID), arg0, arg1)
 }
 
 // GetByShortName mocks base method.
-func (m *MockSourceStorage) GetByShortName(arg0 string) (model.Source, error) {
+func (m *MockSourceStorage) GetByShortName(arg0 context.Context, arg1 string) (model.Source, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByShortName", arg0)
+	ret := m.ctrl.Call(m, "GetByShortName", arg0, arg1)
 	ret0, _ := ret[0].(model.Source)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByShortName indicates an expected call of GetByShortName.
-func (mr *MockSourceStorageMockRecorder) GetByShortName(arg0 any) *gomock.Call {
+func (mr *MockSourceStorageMockRecorder) GetByShortName(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByShortName", reflect.TypeOf((*MockSourceStorage)(nil).GetByShortName), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByShortName", reflect.TypeOf((*MockSourceStorage)(nil).GetByShortName), arg0, arg1)
 }
 
 // Save mocks base method.
-func (m *MockSourceStorage) Save(arg0 model.Source) (model.Source, error) {
+func (m *MockSourceStorage) Save(arg0 context.Context, arg1 model.Source) (model.Source, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", arg0)
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
 	ret0, _ := ret[0].(model.Source)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockSourceStorageMockRecorder) Save(arg0 any) *gomock.Call {
+func (mr *MockSourceStorageMockRecorder) Save(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockSourceStorage)(nil).Save), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockSourceStorage)(nil).Save), arg0, arg1)
 }
 
 // SaveAll mocks base method.
-func (m *MockSourceStorage) SaveAll(arg0 []model.Source) error {
+func (m *MockSourceStorage) SaveAll(arg0 context.Context, arg1 []model.Source) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveAll", arg0)
+	ret := m.ctrl.Call(m, "SaveAll", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveAll indicates an expected call of SaveAll.
-func (mr *MockSourceStorageMockRecorder) SaveAll(arg0 any) *gomock.Call {
+func (mr *MockSourceStorageMockRecorder) SaveAll(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockSourceStorage)(nil).SaveAll), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockSourceStorage)(nil).SaveAll), arg0, arg1)
 }
 
 // Update mocks base method.
-func (m *MockSourceStorage) Update(arg0 int, arg1 model.Source) (model.Source, error) {
+func (m *MockSourceStorage) Update(arg0 context.Context, arg1 int, arg2 model.Source) (model.Source, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2)
 	ret0, _ := ret[0].(model.Source)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockSourceStorageMockRecorder) Update(arg0, arg1 any) *gomock.Call {
+func (mr *MockSourceStorageMockRecorder) Update(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockSourceStorage)(nil).Update), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockSourceStorage)(nil).Update), arg0, arg1, arg2)
+}
+
+// UpdateHealth mocks base method.
+func (m *MockSourceStorage) UpdateHealth(arg0 context.Context, arg1 int, arg2 *model.SourceHealth, arg3 model.SourceHealth) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateHealth", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateHealth indicates an expected call of UpdateHealth.
+func (mr *MockSourceStorageMockRecorder) UpdateHealth(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateHealth", reflect.TypeOf((*MockSourceStorage)(nil).UpdateHealth), arg0, arg1, arg2, arg3)
+}
+
+// UpdateValidators mocks base method.
+func (m *MockSourceStorage) UpdateValidators(arg0 context.Context, arg1 int, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateValidators", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateValidators indicates an expected call of UpdateValidators.
+func (mr *MockSourceStorageMockRecorder) UpdateValidators(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateValidators", reflect.TypeOf((*MockSourceStorage)(nil).UpdateValidators), arg0, arg1, arg2, arg3)
 }
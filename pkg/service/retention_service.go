@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/backuper"
+	"github.com/antonchaban/news-aggregator/pkg/handler/web"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"time"
+)
+
+//go:generate mockgen -destination=mocks/mock_archiver.go -package=mocks github.com/antonchaban/news-aggregator/pkg/service ArticleArchiver
+
+const (
+	eventPruneStart           = "prune_start"
+	eventArticlesPruned       = "articles_pruned"
+	eventPruneArchived        = "prune_archived"
+	eventExpiredArticlesFound = "expired_articles_found"
+)
+
+// Environment variables holding the global retention policy, applied to the sources without their own,
+// and the directory the pruned articles are archived to.
+const (
+	retentionMaxAgeEnvVar   = "RETENTION_MAX_AGE_DAYS"
+	retentionMaxCountEnvVar = "RETENTION_MAX_COUNT"
+	archiveDirEnvVar        = "ARCHIVE_DIR"
+)
+
+// pruneBatchSize is how many expired articles are deleted at a time.
+const pruneBatchSize = 1000
+
+// ArticleArchiver archives the pruned articles before they are deleted and returns where it put them.
+// The articles are streamed to it, so they never have to be held in memory all at once.
+type ArticleArchiver interface {
+	Archive(articles backuper.ArticleIterator) (string, error)
+}
+
+// retentionService is the implementation of the RetentionService interface.
+// A nil archiver means the pruned articles are deleted without being archived.
+type retentionService struct {
+	articleStorage ArticleStorage
+	srcStorage     SourceStorage
+	policy         model.RetentionPolicy
+	archiver       ArticleArchiver
+	now            func() time.Time
+}
+
+// NewRetentionService creates a new RetentionService pruning the articles beyond the retention policy
+// of their source, or the given global policy for the sources without their own.
+func NewRetentionService(articleRepo ArticleStorage, srcRepo SourceStorage, policy model.RetentionPolicy,
+	archiver ArticleArchiver) web.RetentionService {
+	return &retentionService{
+		articleStorage: articleRepo,
+		srcStorage:     srcRepo,
+		policy:         policy,
+		archiver:       archiver,
+		now:            time.Now,
+	}
+}
+
+// NewRetentionServiceFromEnv creates a new RetentionService with the global retention policy
+// read by RetentionPolicyFromEnv. The pruned articles are archived only if ARCHIVE_DIR is set.
+func NewRetentionServiceFromEnv(articleRepo ArticleStorage, srcRepo SourceStorage) (web.RetentionService, error) {
+	policy, err := RetentionPolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	var archiver ArticleArchiver
+	if dir := os.Getenv(archiveDirEnvVar); dir != "" {
+		archiver = backuper.NewArchiver(dir)
+	}
+	return NewRetentionService(articleRepo, srcRepo, policy, archiver), nil
+}
+
+// RetentionPolicyFromEnv reads the global retention policy from the RETENTION_MAX_AGE_DAYS
+// and RETENTION_MAX_COUNT environment variables, unset ones set no limit.
+func RetentionPolicyFromEnv() (model.RetentionPolicy, error) {
+	var policy model.RetentionPolicy
+	for envVar, limit := range map[string]*int{
+		retentionMaxAgeEnvVar:   &policy.MaxAgeDays,
+		retentionMaxCountEnvVar: &policy.MaxCount,
+	} {
+		value := os.Getenv(envVar)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return model.RetentionPolicy{}, fmt.Errorf("invalid %s: %s", envVar, value)
+		}
+		*limit = n
+	}
+	return policy, nil
+}
+
+// Prune deletes the articles of every source beyond its retention policy, archiving them first
+// if there is an archiver. Nothing is deleted if archiving fails.
+// The expired articles are looked up and archived one source at a time, and deleted in batches of IDs.
+// A dry run only lists the expired articles in the report.
+func (r *retentionService) Prune(ctx context.Context, dryRun bool) (model.PruneReport, error) {
+	logrus.WithField("event_id", eventPruneStart).Info("Pruning expired articles")
+	report := model.PruneReport{DryRun: dryRun}
+	sources, err := r.srcStorage.GetAll(ctx)
+	if err != nil {
+		return model.PruneReport{}, err
+	}
+
+	now := r.now()
+	var ids []int
+	var lookupErr error
+	expired := func(yield func(model.Article) error) error {
+		for _, src := range sources {
+			policy := r.policy
+			if src.Retention != nil {
+				policy = src.Retention.Or(r.policy)
+			}
+			if policy.IsZero() {
+				continue
+			}
+
+			articles, err := r.articleStorage.GetExpired(ctx, src.Id, policy.Cutoff(now), policy.MaxCount)
+			if err != nil {
+				lookupErr = fmt.Errorf("failed to find the expired articles of source %s: %w", src.ShortName, err)
+				return lookupErr
+			}
+			if len(articles) == 0 {
+				continue
+			}
+			result := model.SourcePruneResult{Source: src, Policy: policy, Pruned: len(articles)}
+			if dryRun {
+				result.Articles = articles
+			}
+			report.Sources = append(report.Sources, result)
+			for _, article := range articles {
+				ids = append(ids, article.Id)
+				if err := yield(article); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if dryRun || r.archiver == nil {
+		err = expired(func(model.Article) error { return nil })
+	} else {
+		report.Archive, err = r.archiver.Archive(expired)
+	}
+	if lookupErr != nil {
+		return model.PruneReport{}, lookupErr
+	}
+	if err != nil {
+		return model.PruneReport{}, fmt.Errorf("failed to archive the expired articles: %w", err)
+	}
+
+	if dryRun || len(ids) == 0 {
+		logrus.WithField("event_id", eventExpiredArticlesFound).Infof("Found %d expired articles", len(ids))
+		return report, nil
+	}
+	if report.Archive != "" {
+		logrus.WithField("event_id", eventPruneArchived).Infof("Archived %d expired articles to %s", len(ids), report.Archive)
+	}
+
+	deleted := 0
+	for start := 0; start < len(ids); start += pruneBatchSize {
+		n, err := r.articleStorage.DeleteByIDs(ctx, ids[start:min(start+pruneBatchSize, len(ids))])
+		if err != nil {
+			return model.PruneReport{}, fmt.Errorf("failed to delete the expired articles: %w", err)
+		}
+		deleted += n
+	}
+	logrus.WithField("event_id", eventArticlesPruned).Infof("Pruned %d expired articles", deleted)
+	return report, nil
+}
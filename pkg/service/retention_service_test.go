@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/backuper"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestRetentionService_Prune(t *testing.T) {
+	now := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	global := model.RetentionPolicy{MaxAgeDays: 30}
+	bbc := model.Source{Id: 1, ShortName: "bbc", Retention: &model.RetentionPolicy{MaxCount: 100}}
+	abc := model.Source{Id: 2, ShortName: "abc"}
+	nbc := model.Source{Id: 3, ShortName: "nbc", Retention: &model.RetentionPolicy{MaxAgeDays: 7}}
+	bbcExpired := []model.Article{{Id: 1, Source: bbc}, {Id: 2, Source: bbc}}
+	abcExpired := []model.Article{{Id: 3, Source: abc}}
+
+	// expectExpired expects the expired articles of the sources to be looked up with their policies
+	expectExpired := func(articles *mocks.MockArticleStorage) {
+		articles.EXPECT().GetExpired(gomock.Any(), 1, now.AddDate(0, 0, -30), 100).Return(bbcExpired, nil)
+		articles.EXPECT().GetExpired(gomock.Any(), 2, now.AddDate(0, 0, -30), 0).Return(abcExpired, nil)
+		articles.EXPECT().GetExpired(gomock.Any(), 3, now.AddDate(0, 0, -7), 0).Return(nil, nil)
+	}
+
+	tests := []struct {
+		name    string
+		policy  model.RetentionPolicy
+		archive bool
+		dryRun  bool
+		mock    func(articles *mocks.MockArticleStorage, archiver *mocks.MockArticleArchiver)
+		want    model.PruneReport
+		wantErr string
+	}{
+		{
+			name:    "dry run lists the expired articles",
+			policy:  global,
+			archive: true,
+			dryRun:  true,
+			mock: func(articles *mocks.MockArticleStorage, archiver *mocks.MockArticleArchiver) {
+				expectExpired(articles)
+			},
+			want: model.PruneReport{DryRun: true, Sources: []model.SourcePruneResult{
+				{Source: bbc, Policy: model.RetentionPolicy{MaxAgeDays: 30, MaxCount: 100}, Pruned: 2, Articles: bbcExpired},
+				{Source: abc, Policy: global, Pruned: 1, Articles: abcExpired},
+			}},
+		},
+		{
+			name:    "archives and deletes",
+			policy:  global,
+			archive: true,
+			mock: func(articles *mocks.MockArticleStorage, archiver *mocks.MockArticleArchiver) {
+				expectExpired(articles)
+				gomock.InOrder(
+					archiver.EXPECT().Archive(gomock.Any()).DoAndReturn(func(articles backuper.ArticleIterator) (string, error) {
+						var archived []model.Article
+						err := articles(func(article model.Article) error {
+							archived = append(archived, article)
+							return nil
+						})
+						assert.Equal(t, append(bbcExpired, abcExpired...), archived)
+						return "articles.ndjson.gz", err
+					}),
+					articles.EXPECT().DeleteByIDs(gomock.Any(), []int{1, 2, 3}).Return(3, nil),
+				)
+			},
+			want: model.PruneReport{Archive: "articles.ndjson.gz", Sources: []model.SourcePruneResult{
+				{Source: bbc, Policy: model.RetentionPolicy{MaxAgeDays: 30, MaxCount: 100}, Pruned: 2},
+				{Source: abc, Policy: global, Pruned: 1},
+			}},
+		},
+		{
+			name:   "deletes without archiving",
+			policy: global,
+			mock: func(articles *mocks.MockArticleStorage, archiver *mocks.MockArticleArchiver) {
+				expectExpired(articles)
+				articles.EXPECT().DeleteByIDs(gomock.Any(), []int{1, 2, 3}).Return(3, nil)
+			},
+			want: model.PruneReport{Sources: []model.SourcePruneResult{
+				{Source: bbc, Policy: model.RetentionPolicy{MaxAgeDays: 30, MaxCount: 100}, Pruned: 2},
+				{Source: abc, Policy: global, Pruned: 1},
+			}},
+		},
+		{
+			name:    "failed archive deletes nothing",
+			policy:  global,
+			archive: true,
+			mock: func(articles *mocks.MockArticleStorage, archiver *mocks.MockArticleArchiver) {
+				articles.EXPECT().GetExpired(gomock.Any(), 1, now.AddDate(0, 0, -30), 100).Return(bbcExpired, nil)
+				archiver.EXPECT().Archive(gomock.Any()).DoAndReturn(func(articles backuper.ArticleIterator) (string, error) {
+					return "", articles(func(model.Article) error { return errors.New("read-only file system") })
+				})
+			},
+			wantErr: "failed to archive the expired articles: read-only file system",
+		},
+		{
+			name:    "lookup error while archiving",
+			policy:  global,
+			archive: true,
+			mock: func(articles *mocks.MockArticleStorage, archiver *mocks.MockArticleArchiver) {
+				articles.EXPECT().GetExpired(gomock.Any(), 1, gomock.Any(), 100).Return(nil, errors.New("connection reset"))
+				archiver.EXPECT().Archive(gomock.Any()).DoAndReturn(func(articles backuper.ArticleIterator) (string, error) {
+					return "", articles(func(model.Article) error { return nil })
+				})
+			},
+			wantErr: "failed to find the expired articles of source bbc: connection reset",
+		},
+		{
+			name: "sources without policies are skipped",
+			mock: func(articles *mocks.MockArticleStorage, archiver *mocks.MockArticleArchiver) {
+				articles.EXPECT().GetExpired(gomock.Any(), 1, time.Time{}, 100).Return(nil, nil)
+				articles.EXPECT().GetExpired(gomock.Any(), 3, now.AddDate(0, 0, -7), 0).Return(nil, nil)
+			},
+			want: model.PruneReport{},
+		},
+		{
+			name:   "lookup error",
+			policy: global,
+			mock: func(articles *mocks.MockArticleStorage, archiver *mocks.MockArticleArchiver) {
+				articles.EXPECT().GetExpired(gomock.Any(), 1, gomock.Any(), 100).Return(nil, errors.New("connection reset"))
+			},
+			wantErr: "failed to find the expired articles of source bbc: connection reset",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+			mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+			mockArchiver := mocks.NewMockArticleArchiver(ctrl)
+			mockSourceStorage.EXPECT().GetAll(gomock.Any()).Return([]model.Source{bbc, abc, nbc}, nil)
+			tt.mock(mockArticleStorage, mockArchiver)
+
+			rs := &retentionService{
+				articleStorage: mockArticleStorage,
+				srcStorage:     mockSourceStorage,
+				policy:         tt.policy,
+				now:            func() time.Time { return now },
+			}
+			if tt.archive {
+				rs.archiver = mockArchiver
+			}
+
+			report, err := rs.Prune(context.Background(), tt.dryRun)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, report)
+		})
+	}
+}
+
+func TestRetentionService_Prune_Batches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+	mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+	bbc := model.Source{Id: 1, ShortName: "bbc"}
+	expired := make([]model.Article, pruneBatchSize+1)
+	for i := range expired {
+		expired[i] = model.Article{Id: i + 1, Source: bbc}
+	}
+	mockSourceStorage.EXPECT().GetAll(gomock.Any()).Return([]model.Source{bbc}, nil)
+	mockArticleStorage.EXPECT().GetExpired(gomock.Any(), 1, gomock.Any(), 0).Return(expired, nil)
+	gomock.InOrder(
+		mockArticleStorage.EXPECT().DeleteByIDs(gomock.Any(), gomock.Len(pruneBatchSize)).Return(pruneBatchSize, nil),
+		mockArticleStorage.EXPECT().DeleteByIDs(gomock.Any(), []int{pruneBatchSize + 1}).Return(1, nil),
+	)
+
+	rs := NewRetentionService(mockArticleStorage, mockSourceStorage, model.RetentionPolicy{MaxAgeDays: 30}, nil)
+	report, err := rs.Prune(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.SourcePruneResult{{Source: bbc, Policy: model.RetentionPolicy{MaxAgeDays: 30}, Pruned: pruneBatchSize + 1}},
+		report.Sources)
+}
+
+func TestRetentionPolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxAge   string
+		maxCount string
+		want     model.RetentionPolicy
+		wantErr  string
+	}{
+		{name: "unset", want: model.RetentionPolicy{}},
+		{name: "both", maxAge: "30", maxCount: "1000", want: model.RetentionPolicy{MaxAgeDays: 30, MaxCount: 1000}},
+		{name: "invalid", maxAge: "30d", wantErr: "invalid RETENTION_MAX_AGE_DAYS: 30d"},
+		{name: "negative", maxCount: "-1", wantErr: "invalid RETENTION_MAX_COUNT: -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(retentionMaxAgeEnvVar, tt.maxAge)
+			t.Setenv(retentionMaxCountEnvVar, tt.maxCount)
+
+			policy, err := RetentionPolicyFromEnv()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, policy)
+		})
+	}
+}
+
+func TestNewRetentionServiceFromEnv(t *testing.T) {
+	t.Setenv(retentionMaxAgeEnvVar, "30")
+	t.Setenv(retentionMaxCountEnvVar, "")
+	t.Setenv(archiveDirEnvVar, "")
+	rs, err := NewRetentionServiceFromEnv(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, model.RetentionPolicy{MaxAgeDays: 30}, rs.(*retentionService).policy)
+	assert.Nil(t, rs.(*retentionService).archiver, "nothing is archived without ARCHIVE_DIR")
+
+	t.Setenv(archiveDirEnvVar, t.TempDir())
+	rs, err = NewRetentionServiceFromEnv(nil, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, rs.(*retentionService).archiver)
+
+	t.Setenv(retentionMaxCountEnvVar, "many")
+	_, err = NewRetentionServiceFromEnv(nil, nil)
+	assert.EqualError(t, err, "invalid RETENTION_MAX_COUNT: many")
+}
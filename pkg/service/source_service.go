@@ -1,116 +1,337 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/handler/web"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/antonchaban/news-aggregator/pkg/parser"
 	"github.com/sirupsen/logrus"
 	"log"
-	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
 //go:generate mockgen -destination=mocks/mock_source.go -package=mocks github.com/antonchaban/news-aggregator/pkg/service SourceStorage
 
 const (
-	eventErrorSavingArticles = "error_saving_articles"
+	eventErrorSavingArticles  = "error_saving_articles"
+	eventFetchSourceError     = "fetch_source_error"
+	eventFetchSourceComplete  = "fetch_source_complete"
+	eventFetchSourceUnchanged = "fetch_source_unchanged"
+	eventFetchSourceSkipped   = "fetch_source_skipped"
+	eventFetchSourceDisabled  = "fetch_source_disabled"
+	eventErrorSavingHealth    = "error_saving_health"
+	eventSourceHealthReset    = "source_health_reset"
+)
+
+const (
+	// defaultFetchWorkers is the number of sources fetched in parallel.
+	defaultFetchWorkers = 8
+	// defaultSourceTimeout is the time a single source may take to fetch.
+	defaultSourceTimeout = 30 * time.Second
 )
 
 // SourceStorage is an interface that defines the methods for interacting with the source storage.
 type SourceStorage interface {
-	GetAll() ([]model.Source, error)
-	Save(src model.Source) (model.Source, error)
-	SaveAll(sources []model.Source) error
-	Delete(id int) error
-	GetByID(id int) (model.Source, error)
-	Update(id int, src model.Source) (model.Source, error)
-	GetByShortName(shortName string) (model.Source, error)
+	GetAll(ctx context.Context) ([]model.Source, error)
+	Save(ctx context.Context, src model.Source) (model.Source, error)
+	SaveAll(ctx context.Context, sources []model.Source) error
+	Delete(ctx context.Context, id int) error
+	GetByID(ctx context.Context, id int) (model.Source, error)
+	Update(ctx context.Context, id int, src model.Source) (model.Source, error)
+	GetByShortName(ctx context.Context, shortName string) (model.Source, error)
+	UpdateValidators(ctx context.Context, id int, etag, lastModified string) error
+	// UpdateHealth stores the health of the source unless its stored health is no longer the previous one,
+	// such as after the source was updated, reporting whether it was stored.
+	UpdateHealth(ctx context.Context, id int, previous *model.SourceHealth, health model.SourceHealth) (bool, error)
 }
 
 // sourceService is the implementation of the SourceService interface.
+// The operations mutating both storages run in a unit of work,
+// the scheduled fetches are coordinated with the other instances by the locker
+// and recorded in the fetch history, unless runs is nil.
+// The zero values of the fetch settings mean the defaults.
 type sourceService struct {
 	articleStorage ArticleStorage
 	srcStorage     SourceStorage
+	uow            UnitOfWork
+	locker         FetchLocker
+	runs           FetchRunStorage
+
+	fetchWorkers  int
+	sourceTimeout time.Duration
+	saveMu        sync.Mutex
 }
 
-// NewSourceService creates a new SourceService with the given article and source repositories.
-func NewSourceService(articleRepo ArticleStorage, srcRepo SourceStorage) web.SourceService {
-	return &sourceService{articleStorage: articleRepo, srcStorage: srcRepo}
+// NewSourceService creates a new SourceService with the given article and source repositories,
+// the unit of work spanning them, the locker coordinating the fetches with the other instances
+// and the storage of the fetch history. A nil run storage means the fetches aren't recorded.
+func NewSourceService(articleRepo ArticleStorage, srcRepo SourceStorage, uow UnitOfWork, locker FetchLocker,
+	runs FetchRunStorage) web.SourceService {
+	return &sourceService{articleStorage: articleRepo, srcStorage: srcRepo, uow: uow, locker: locker, runs: runs}
 }
 
 // GetAll returns all sources from the database.
-func (s *sourceService) GetAll() ([]model.Source, error) {
-	return s.srcStorage.GetAll()
+func (s *sourceService) GetAll(ctx context.Context) ([]model.Source, error) {
+	return s.srcStorage.GetAll(ctx)
 }
 
 // UpdateSource updates the source with the given ID in the database.
-func (s *sourceService) UpdateSource(id int, source model.Source) (model.Source, error) {
-	return s.srcStorage.Update(id, source)
+func (s *sourceService) UpdateSource(ctx context.Context, id int, source model.Source) (model.Source, error) {
+	return s.srcStorage.Update(ctx, id, source)
 }
 
-// DeleteSource removes the source with the given ID from the database.
-func (s *sourceService) DeleteSource(id int) error {
-	err := s.articleStorage.DeleteBySourceID(id)
-	if err != nil {
-		return err
-	}
-	return s.srcStorage.Delete(id)
+// DeleteSource removes the source with the given ID and its articles from the database.
+// Either both are removed or neither is.
+func (s *sourceService) DeleteSource(ctx context.Context, id int) error {
+	return s.uow.Do(ctx, func(articles ArticleStorage, sources SourceStorage) error {
+		err := articles.DeleteBySourceID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return sources.Delete(ctx, id)
+	})
 }
 
 // AddSource adds a new source to the database.
-func (s *sourceService) AddSource(source model.Source) (model.Source, error) {
-	save, err := s.srcStorage.Save(source)
+func (s *sourceService) AddSource(ctx context.Context, source model.Source) (model.Source, error) {
+	save, err := s.srcStorage.Save(ctx, source)
 	if err != nil {
 		return model.Source{}, err
 	}
 	return save, nil
 }
 
-// FetchFromAllSources fetches articles from all sources.
-func (s *sourceService) FetchFromAllSources() error {
-	allSrcs, err := s.srcStorage.GetAll()
+// FetchFromAllSources fetches articles from all sources in parallel, each one within its own timeout.
+// A failing source doesn't stop the others, its error is recorded in the returned report instead.
+// An error is returned only if the sources can't be listed.
+// The fetch is recorded in the fetch history as a single run.
+// Canceling ctx aborts the fetches in progress.
+func (s *sourceService) FetchFromAllSources(ctx context.Context) (model.FetchReport, error) {
+	report := model.FetchReport{StartedAt: time.Now()}
+	allSrcs, err := s.srcStorage.GetAll(ctx)
 	if err != nil {
-		return err
+		return report, err
 	}
 
-	for _, src := range allSrcs {
-		urlParsed, err := url.Parse(src.Link)
-		if err != nil {
-			return err
-		}
-		articles, err := parser.ParseArticlesFromFeed(*urlParsed)
-		if err != nil {
-			return err
-		}
-		for i := range articles {
-			articles[i].Source = src
+	report.Sources = make([]model.SourceFetchResult, len(allSrcs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.workers(), len(allSrcs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				report.Sources[i] = s.fetchSource(ctx, allSrcs[i])
+			}
+		}()
+	}
+	for i := range allSrcs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	report.Duration = time.Since(report.StartedAt)
+	s.recordRun(ctx, report)
+	return report, nil
+}
+
+// FetchSource fetches the articles of the source with the given ID within its timeout, reporting the outcome.
+// As with FetchFromAllSources, a failed fetch is recorded in the result, an error is returned
+// only if the source can't be found. The fetch is recorded in the fetch history as a run of its own,
+// unless it's skipped as another instance holds the lease on the source.
+func (s *sourceService) FetchSource(ctx context.Context, id int) (model.SourceFetchResult, error) {
+	src, err := s.srcStorage.GetByID(ctx, id)
+	if err != nil {
+		return model.SourceFetchResult{}, err
+	}
+	result := s.fetchSource(ctx, src)
+	s.recordRun(ctx, model.FetchReport{
+		StartedAt: result.StartedAt,
+		Duration:  result.Duration,
+		Sources:   []model.SourceFetchResult{result},
+	})
+	return result, nil
+}
+
+// fetchSource fetches and saves the articles of a single source, reporting the outcome,
+// and records the outcome in the health of the source. The health isn't stored if the fetch left it unchanged,
+// or if the source was updated during the fetch, which resets its health.
+// A source disabled after failing too many times in a row isn't fetched, it's reported as FetchDisabled.
+func (s *sourceService) fetchSource(ctx context.Context, src model.Source) model.SourceFetchResult {
+	if src.Health != nil && src.Health.Status == model.SourceDisabled {
+		logrus.WithField("event_id", eventFetchSourceDisabled).WithField("source", src.ShortName).
+			Info("Source is disabled after failing too many times in a row")
+		return model.SourceFetchResult{Source: src, Status: model.FetchDisabled, StartedAt: time.Now()}
+	}
+	result := s.fetchFeed(ctx, src)
+	if result.Status == model.FetchSkipped {
+		return result
+	}
+
+	var health model.SourceHealth
+	if src.Health != nil {
+		health = *src.Health
+	}
+	health = health.Record(result, time.Now())
+	result.Source.Health = &health
+	if health.Equal(src.Health) {
+		return result
+	}
+	updated, err := s.updateHealth(ctx, src.Id, src.Health, health)
+	if err != nil {
+		logrus.WithField("event_id", eventErrorSavingHealth).WithField("source", src.ShortName).
+			Errorf("Error saving source health: %v", err)
+	} else if !updated {
+		logrus.WithField("event_id", eventSourceHealthReset).WithField("source", src.ShortName).
+			Info("Source health was reset during the fetch, keeping it")
+		return result
+	}
+	if health.Status == model.SourceDisabled {
+		logrus.WithField("event_id", eventFetchSourceDisabled).WithField("source", src.ShortName).
+			Warnf("Source is disabled after failing %d times in a row", health.ConsecutiveFailures)
+	}
+	return result
+}
+
+// fetchFeed fetches and saves the articles of a single source, reporting the outcome.
+// The source is fetched only by the instance holding its lease for the period of its schedule,
+// the others report it as FetchSkipped.
+// The feed is fetched conditionally, with the validators of the previous fetch,
+// so an unchanged feed is reported as FetchUnchanged without being downloaded again.
+func (s *sourceService) fetchFeed(ctx context.Context, src model.Source) model.SourceFetchResult {
+	start := time.Now()
+	result := model.SourceFetchResult{Source: src, Status: model.FetchOK, StartedAt: start}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+	acquired, err := s.acquire(ctx, src, start)
+	if err == nil && !acquired {
+		result.Status = model.FetchSkipped
+		result.Duration = time.Since(start)
+		logrus.WithField("event_id", eventFetchSourceSkipped).WithField("source", src.ShortName).
+			Info("Source is fetched by another instance this period")
+		return result
+	}
+	var resp parser.FeedResponse
+	if err == nil {
+		resp, err = parser.FetchFeed(ctx, src)
+		result.HTTPStatus, result.Bytes = resp.StatusCode, resp.Bytes
+	}
+	if err == nil {
+		for i := range resp.Articles {
+			resp.Articles[i].Source = src
 		}
-		err = s.articleStorage.SaveAll(articles)
+		result.Saved, err = s.saveFetched(ctx, src, resp)
 		if err != nil {
 			logrus.WithField("event_id", eventErrorSavingArticles).Errorf("Error saving articles: %v", err)
-			continue
+		} else {
+			result.Source.ETag, result.Source.LastModified = resp.ETag, resp.LastModified
 		}
 	}
-	return nil
+
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Status = model.FetchFailed
+		result.Error = err.Error()
+		logrus.WithField("event_id", eventFetchSourceError).WithField("source", src.ShortName).
+			Errorf("Error fetching source: %v", err)
+		return result
+	}
+	if resp.NotModified {
+		result.Status = model.FetchUnchanged
+		logrus.WithField("event_id", eventFetchSourceUnchanged).WithField("source", src.ShortName).
+			Info("Source wasn't modified since the previous fetch")
+		return result
+	}
+	result.Articles = len(resp.Articles)
+	logrus.WithField("event_id", eventFetchSourceComplete).WithField("source", src.ShortName).
+		Infof("Fetched %d articles, %d new, %d updated", result.Articles, result.Saved.Inserted, result.Saved.Updated)
+	return result
 }
 
-// FetchSourceByID fetches articles from the source with the given ID.
-func (s *sourceService) FetchSourceByID(id int) ([]model.Article, error) {
-	src, err := s.srcStorage.GetByID(id)
+// acquire takes the lease on fetching the source until its next fetch is due.
+func (s *sourceService) acquire(ctx context.Context, src model.Source, now time.Time) (bool, error) {
+	period, err := src.EffectiveSchedule().Period(now)
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	acquired, err := s.locker.Acquire(ctx, src.Id, period)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire the fetch lease: %w", err)
+	}
+	return acquired, nil
+}
+
+// saveFetched saves the fetched articles of a source along with the validators of its feed
+// in a unit of work, so the validators are never stored for a feed whose articles weren't saved.
+// Saves are serialized, as the storages aren't required to be safe for concurrent use.
+func (s *sourceService) saveFetched(ctx context.Context, src model.Source, resp parser.FeedResponse) (model.SaveResult, error) {
+	validatorsChanged := src.ETag != resp.ETag || src.LastModified != resp.LastModified
+	if resp.NotModified && !validatorsChanged {
+		return model.SaveResult{}, nil
 	}
-	urlParsed, err := url.Parse(src.Link)
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	var saved model.SaveResult
+	err := s.uow.Do(ctx, func(articles ArticleStorage, sources SourceStorage) error {
+		var err error
+		if !resp.NotModified {
+			saved, err = articles.SaveAll(ctx, resp.Articles)
+			if err != nil {
+				return err
+			}
+		}
+		if !validatorsChanged {
+			return nil
+		}
+		return sources.UpdateValidators(ctx, src.Id, resp.ETag, resp.LastModified)
+	})
+	if err != nil {
+		return model.SaveResult{}, err
+	}
+	return saved, nil
+}
+
+// updateHealth stores the health of a source unless it changed from previous during the fetch.
+// Like the saves, the updates are serialized.
+func (s *sourceService) updateHealth(ctx context.Context, id int, previous *model.SourceHealth, health model.SourceHealth) (bool, error) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	return s.srcStorage.UpdateHealth(ctx, id, previous, health)
+}
+
+func (s *sourceService) workers() int {
+	if s.fetchWorkers > 0 {
+		return s.fetchWorkers
+	}
+	return defaultFetchWorkers
+}
+
+func (s *sourceService) timeout() time.Duration {
+	if s.sourceTimeout > 0 {
+		return s.sourceTimeout
+	}
+	return defaultSourceTimeout
+}
+
+// FetchSourceByID fetches articles from the source with the given ID.
+func (s *sourceService) FetchSourceByID(ctx context.Context, id int) ([]model.Article, error) {
+	src, err := s.srcStorage.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	articles, err := parser.ParseArticlesFromFeed(*urlParsed)
+	articles, err := parser.ParseArticlesFromFeed(ctx, src)
 	if err != nil {
 		return nil, err
 	}
-	err = s.articleStorage.SaveAll(articles)
+	_, err = s.articleStorage.SaveAll(ctx, articles)
 	if err != nil {
 		return nil, err
 	}
@@ -1,14 +1,20 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/handler/web"
 	"github.com/antonchaban/news-aggregator/pkg/parser"
+	"github.com/robfig/cron/v3"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
-	"net/url"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,17 +23,33 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// testUnitOfWork runs the work on its storages directly, the mocks verify what it did.
+type testUnitOfWork struct {
+	articles ArticleStorage
+	sources  SourceStorage
+}
+
+func (u testUnitOfWork) Do(ctx context.Context, fn func(articles ArticleStorage, sources SourceStorage) error) error {
+	return fn(u.articles, u.sources)
+}
+
 func TestNewSourceService(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
 	mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+	uow := testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage}
+	locker := newTestLeaseTable().locker("replica")
+	runs := mocks.NewMockFetchRunStorage(ctrl)
 
 	tests := []struct {
 		name string
 		args struct {
 			articleRepo ArticleStorage
 			srcRepo     SourceStorage
+			uow         UnitOfWork
+			locker      FetchLocker
+			runs        FetchRunStorage
 		}
 		want web.SourceService
 	}{
@@ -36,21 +58,71 @@ func TestNewSourceService(t *testing.T) {
 			args: struct {
 				articleRepo ArticleStorage
 				srcRepo     SourceStorage
+				uow         UnitOfWork
+				locker      FetchLocker
+				runs        FetchRunStorage
 			}{
 				articleRepo: mockArticleStorage,
 				srcRepo:     mockSourceStorage,
+				uow:         uow,
+				locker:      locker,
+				runs:        runs,
 			},
-			want: &sourceService{articleStorage: mockArticleStorage, srcStorage: mockSourceStorage},
+			want: &sourceService{articleStorage: mockArticleStorage, srcStorage: mockSourceStorage, uow: uow, locker: locker,
+				runs: runs},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.Equalf(t, tt.want, NewSourceService(tt.args.articleRepo, tt.args.srcRepo), "NewSourceService(%v, %v)", tt.args.articleRepo, tt.args.srcRepo)
+			assert.Equalf(t, tt.want, NewSourceService(tt.args.articleRepo, tt.args.srcRepo, tt.args.uow, tt.args.locker, tt.args.runs),
+				"NewSourceService(%v, %v, %v, %v, %v)", tt.args.articleRepo, tt.args.srcRepo, tt.args.uow, tt.args.locker, tt.args.runs)
 		})
 	}
 }
 
+// testLeaseTable stands in for the lease table of the database shared by the instances, its clock is set by the tests.
+type testLeaseTable struct {
+	mu     sync.Mutex
+	now    time.Time
+	leases map[int]testLease
+}
+
+type testLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func newTestLeaseTable() *testLeaseTable {
+	return &testLeaseTable{now: time.Now(), leases: make(map[int]testLease)}
+}
+
+// locker returns the FetchLocker of the instance taking the leases on behalf of holder.
+func (l *testLeaseTable) locker(holder string) FetchLocker {
+	return testLocker{table: l, holder: holder}
+}
+
+func (l *testLeaseTable) advance(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.now = l.now.Add(d)
+}
+
+type testLocker struct {
+	table  *testLeaseTable
+	holder string
+}
+
+func (l testLocker) Acquire(ctx context.Context, sourceID int, ttl time.Duration) (bool, error) {
+	l.table.mu.Lock()
+	defer l.table.mu.Unlock()
+	if lease, ok := l.table.leases[sourceID]; ok && lease.holder != l.holder && l.table.now.Before(lease.expiresAt) {
+		return false, nil
+	}
+	l.table.leases[sourceID] = testLease{holder: l.holder, expiresAt: l.table.now.Add(ttl)}
+	return true, nil
+}
+
 func Test_getFilesInDir(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -112,7 +184,7 @@ func Test_sourceService_AddSource(t *testing.T) {
 			name:   "add source successfully",
 			source: model.Source{Link: "http://example.com"},
 			setup: func() {
-				mockSourceStorage.EXPECT().Save(gomock.Any()).Return(model.Source{Id: 1, Link: "http://example.com"}, nil)
+				mockSourceStorage.EXPECT().Save(gomock.Any(), gomock.Any()).Return(model.Source{Id: 1, Link: "http://example.com"}, nil)
 			},
 			want: model.Source{Id: 1, Link: "http://example.com"},
 			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
@@ -123,7 +195,7 @@ func Test_sourceService_AddSource(t *testing.T) {
 			name:   "add duplicate source",
 			source: model.Source{Link: "http://example.com"},
 			setup: func() {
-				mockSourceStorage.EXPECT().Save(gomock.Any()).Return(model.Source{}, errors.New("source already exists"))
+				mockSourceStorage.EXPECT().Save(gomock.Any(), gomock.Any()).Return(model.Source{}, errors.New("source already exists"))
 			},
 			want: model.Source{},
 			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
@@ -138,7 +210,7 @@ func Test_sourceService_AddSource(t *testing.T) {
 			s := &sourceService{
 				srcStorage: mockSourceStorage,
 			}
-			got, err := s.AddSource(tt.source)
+			got, err := s.AddSource(context.Background(), tt.source)
 			if !tt.wantErr(t, err, fmt.Sprintf("AddSource(%v)", tt.source)) {
 				return
 			}
@@ -163,13 +235,34 @@ func Test_sourceService_DeleteSource(t *testing.T) {
 			name: "delete source successfully",
 			id:   1,
 			setup: func() {
-				mockArticleStorage.EXPECT().DeleteBySourceID(1).Return(nil)
-				mockSourceStorage.EXPECT().Delete(1).Return(nil)
+				mockArticleStorage.EXPECT().DeleteBySourceID(gomock.Any(), 1).Return(nil)
+				mockSourceStorage.EXPECT().Delete(gomock.Any(), 1).Return(nil)
 			},
 			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
 				return assert.NoError(t, err)
 			},
 		},
+		{
+			name: "failing to delete the articles keeps the source",
+			id:   1,
+			setup: func() {
+				mockArticleStorage.EXPECT().DeleteBySourceID(gomock.Any(), 1).Return(errors.New("storage error"))
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.EqualError(t, err, "storage error")
+			},
+		},
+		{
+			name: "failing to delete the source fails the unit of work",
+			id:   1,
+			setup: func() {
+				mockArticleStorage.EXPECT().DeleteBySourceID(gomock.Any(), 1).Return(nil)
+				mockSourceStorage.EXPECT().Delete(gomock.Any(), 1).Return(errors.New("source with id 1 not found"))
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.EqualError(t, err, "source with id 1 not found")
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -178,44 +271,175 @@ func Test_sourceService_DeleteSource(t *testing.T) {
 			s := &sourceService{
 				srcStorage:     mockSourceStorage,
 				articleStorage: mockArticleStorage,
+				uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
 			}
-			tt.wantErr(t, s.DeleteSource(tt.id), fmt.Sprintf("DeleteSource(%v)", tt.id))
+			tt.wantErr(t, s.DeleteSource(context.Background(), tt.id), fmt.Sprintf("DeleteSource(%v)", tt.id))
 		})
 	}
 }
 
+// newTestFeedServer serves testdata/rss.xml at /rss and, with the ETag "v1", at /etag,
+// an HTML page with two stories at /page, fails at /broken and doesn't answer at /slow until the test ends.
+func newTestFeedServer(t *testing.T) *httptest.Server {
+	feed, err := os.ReadFile("testdata/rss.xml")
+	require.NoError(t, err)
+	release := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write(feed)
+	})
+	mux.HandleFunc("/etag", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write(feed)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><body>
+			<div class="story"><a href="/one">First story</a></div>
+			<div class="story"><a href="/two">Second story</a></div>
+		</body></html>`))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		close(release)
+		srv.Close()
+	})
+	return srv
+}
+
 func Test_sourceService_FetchFromAllSources(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-	mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
-	mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+	srv := newTestFeedServer(t)
 
 	tests := []struct {
 		name    string
-		setup   func()
+		sources []model.Source
+		setup   func(srcStorage *mocks.MockSourceStorage, artStorage *mocks.MockArticleStorage, sources []model.Source)
+		want    []model.SourceFetchResult
 		wantErr assert.ErrorAssertionFunc
 	}{
 		{
 			name: "fetch articles from all sources successfully",
-			setup: func() {
-				mockSourceStorage.EXPECT().GetAll().Return([]model.Source{
-					{Id: 1, Link: "http://rss.cnn.com/rss/cnn_topstories.rss"},
-				}, nil)
-				mockArticleStorage.EXPECT().SaveAll(gomock.Any()).Return(nil)
-				urlParsed, _ := url.Parse("http://rss.cnn.com/rss/cnn_topstories.rss")
-				_, err := parser.ParseArticlesFromFeed(*urlParsed)
-				if err != nil {
-					return
-				}
+			sources: []model.Source{
+				{Id: 1, ShortName: "one", Link: srv.URL + "/rss"},
+				{Id: 2, ShortName: "two", Link: srv.URL + "/rss"},
 			},
-			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
-				return assert.NoError(t, err)
+			setup: func(srcStorage *mocks.MockSourceStorage, artStorage *mocks.MockArticleStorage, sources []model.Source) {
+				srcStorage.EXPECT().GetAll(gomock.Any()).Return(sources, nil)
+				artStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 1, Skipped: 1}, nil).Times(2)
+			},
+			want: []model.SourceFetchResult{
+				{Status: model.FetchOK, Articles: 2, Saved: model.SaveResult{Inserted: 1, Skipped: 1}},
+				{Status: model.FetchOK, Articles: 2, Saved: model.SaveResult{Inserted: 1, Skipped: 1}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "failing sources don't stop the others",
+			sources: []model.Source{
+				{Id: 1, ShortName: "slow", Link: srv.URL + "/slow"},
+				{Id: 2, ShortName: "broken", Link: srv.URL + "/broken"},
+				{Id: 3, ShortName: "invalid", Link: "://invalid"},
+				{Id: 4, ShortName: "ok", Link: srv.URL + "/rss"},
+			},
+			setup: func(srcStorage *mocks.MockSourceStorage, artStorage *mocks.MockArticleStorage, sources []model.Source) {
+				srcStorage.EXPECT().GetAll(gomock.Any()).Return(sources, nil)
+				artStorage.EXPECT().SaveAll(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, articles []model.Article) (model.SaveResult, error) {
+					assert.Equal(t, sources[3], articles[0].Source)
+					return model.SaveResult{Inserted: 2}, nil
+				})
+			},
+			want: []model.SourceFetchResult{
+				{Status: model.FetchFailed, Error: "context deadline exceeded"},
+				{Status: model.FetchFailed, Error: "500 Internal Server Error"},
+				{Status: model.FetchFailed, Error: `parse "://invalid": missing protocol scheme`},
+				{Status: model.FetchOK, Articles: 2},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "unchanged sources are skipped",
+			sources: []model.Source{
+				{Id: 1, ShortName: "new", Link: srv.URL + "/etag"},
+				{Id: 2, ShortName: "unchanged", Link: srv.URL + "/etag", ETag: `"v1"`},
+			},
+			setup: func(srcStorage *mocks.MockSourceStorage, artStorage *mocks.MockArticleStorage, sources []model.Source) {
+				srcStorage.EXPECT().GetAll(gomock.Any()).Return(sources, nil)
+				artStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 2}, nil)
+				srcStorage.EXPECT().UpdateValidators(gomock.Any(), 1, `"v1"`, "").Return(nil)
+			},
+			want: []model.SourceFetchResult{
+				{Source: model.Source{ETag: `"v1"`}, Status: model.FetchOK, Articles: 2},
+				{Status: model.FetchUnchanged},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "HTML sources are scraped with their config",
+			sources: []model.Source{
+				{Id: 1, ShortName: "scraped", Link: srv.URL + "/page", Scrape: &model.ScrapeConfig{ArticleSelector: "div.story a"}},
+				{Id: 2, ShortName: "unconfigured", Link: srv.URL + "/page"},
+			},
+			setup: func(srcStorage *mocks.MockSourceStorage, artStorage *mocks.MockArticleStorage, sources []model.Source) {
+				srcStorage.EXPECT().GetAll(gomock.Any()).Return(sources, nil)
+				artStorage.EXPECT().SaveAll(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, articles []model.Article) (model.SaveResult, error) {
+					require.Len(t, articles, 2)
+					assert.Equal(t, "First story", articles[0].Title)
+					assert.Equal(t, srv.URL+"/one", articles[0].Link)
+					return model.SaveResult{Inserted: 2}, nil
+				})
+			},
+			want: []model.SourceFetchResult{
+				{Status: model.FetchOK, Articles: 2},
+				{Status: model.FetchFailed, Error: "no scraping config for HTML source"},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "error saving articles",
+			sources: []model.Source{
+				{Id: 1, ShortName: "one", Link: srv.URL + "/rss"},
+			},
+			setup: func(srcStorage *mocks.MockSourceStorage, artStorage *mocks.MockArticleStorage, sources []model.Source) {
+				srcStorage.EXPECT().GetAll(gomock.Any()).Return(sources, nil)
+				artStorage.EXPECT().SaveAll(gomock.Any(), gomock.Any()).Return(model.SaveResult{}, errors.New("storage error"))
 			},
+			want: []model.SourceFetchResult{
+				{Status: model.FetchFailed, Error: "storage error"},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "error saving validators fails the fetch",
+			sources: []model.Source{
+				{Id: 1, ShortName: "new", Link: srv.URL + "/etag"},
+			},
+			setup: func(srcStorage *mocks.MockSourceStorage, artStorage *mocks.MockArticleStorage, sources []model.Source) {
+				srcStorage.EXPECT().GetAll(gomock.Any()).Return(sources, nil)
+				artStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 2}, nil)
+				srcStorage.EXPECT().UpdateValidators(gomock.Any(), 1, `"v1"`, "").Return(errors.New("storage error"))
+			},
+			want: []model.SourceFetchResult{
+				{Status: model.FetchFailed, Error: "storage error"},
+			},
+			wantErr: assert.NoError,
 		},
 		{
 			name: "error fetching sources",
-			setup: func() {
-				mockSourceStorage.EXPECT().GetAll().Return(nil, errors.New("storage error"))
+			setup: func(srcStorage *mocks.MockSourceStorage, artStorage *mocks.MockArticleStorage, sources []model.Source) {
+				srcStorage.EXPECT().GetAll(gomock.Any()).Return(nil, errors.New("storage error"))
 			},
 			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
 				return assert.Error(t, err) && assert.Equal(t, "storage error", err.Error())
@@ -225,16 +449,356 @@ func Test_sourceService_FetchFromAllSources(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.setup()
+			ctrl := gomock.NewController(t)
+			mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+			mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+			tt.setup(mockSourceStorage, mockArticleStorage, tt.sources)
+			mockSourceStorage.EXPECT().UpdateHealth(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+			s := &sourceService{
+				articleStorage: mockArticleStorage,
+				srcStorage:     mockSourceStorage,
+				uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
+				locker:         newTestLeaseTable().locker("replica"),
+				fetchWorkers:   2,
+				sourceTimeout:  500 * time.Millisecond,
+			}
+
+			report, err := s.FetchFromAllSources(context.Background())
+			if !tt.wantErr(t, err, "FetchFromAllSources()") || err != nil {
+				return
+			}
+			require.Len(t, report.Sources, len(tt.want))
+			for i, want := range tt.want {
+				got := report.Sources[i]
+				wantSource := tt.sources[i]
+				if want.Source.ETag != "" {
+					wantSource.ETag = want.Source.ETag
+				}
+				wantHealth := model.SourceHealthy
+				if want.Status == model.FetchFailed {
+					wantHealth = model.SourceFailing
+				}
+				if assert.NotNil(t, got.Source.Health) {
+					assert.Equal(t, wantHealth, got.Source.Health.Status)
+				}
+				got.Source.Health = nil
+				assert.Equal(t, wantSource, got.Source)
+				assert.Equal(t, want.Status, got.Status)
+				assert.Equal(t, want.Articles, got.Articles)
+				if want.Saved != (model.SaveResult{}) {
+					assert.Equal(t, want.Saved, got.Saved)
+				}
+				if want.Error == "" {
+					assert.Empty(t, got.Error)
+				} else {
+					assert.Contains(t, got.Error, want.Error)
+				}
+				assert.Positive(t, got.Duration)
+			}
+		})
+	}
+}
+
+func Test_sourceService_FetchSource(t *testing.T) {
+	srv := newTestFeedServer(t)
+	ctrl := gomock.NewController(t)
+	mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+	mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+	s := &sourceService{
+		articleStorage: mockArticleStorage,
+		srcStorage:     mockSourceStorage,
+		uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
+		locker:         newTestLeaseTable().locker("replica"),
+	}
+
+	src := model.Source{Id: 1, ShortName: "one", Link: srv.URL + "/rss"}
+	mockSourceStorage.EXPECT().GetByID(gomock.Any(), 1).Return(src, nil)
+	mockArticleStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 2}, nil)
+	mockSourceStorage.EXPECT().UpdateHealth(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(true, nil)
+	result, err := s.FetchSource(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, src.Link, result.Source.Link)
+	assert.Equal(t, model.FetchOK, result.Status)
+	assert.Equal(t, model.SaveResult{Inserted: 2}, result.Saved)
+	require.NotNil(t, result.Source.Health)
+	assert.Equal(t, 1, result.Source.Health.Fetches)
+
+	mockSourceStorage.EXPECT().GetByID(gomock.Any(), 2).Return(model.Source{}, errors.New("source with id 2 not found"))
+	_, err = s.FetchSource(context.Background(), 2)
+	assert.EqualError(t, err, "source with id 2 not found")
+}
+
+func Test_sourceService_FetchSource_Health(t *testing.T) {
+	srv := newTestFeedServer(t)
+	lastUpdate := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name       string
+		link       string
+		health     *model.SourceHealth
+		wantStatus string
+		wantHealth func(t *testing.T, health *model.SourceHealth)
+	}{
+		{
+			name:       "first fetch",
+			link:       "/rss",
+			wantStatus: model.FetchOK,
+			wantHealth: func(t *testing.T, health *model.SourceHealth) {
+				assert.Equal(t, model.SourceHealthy, health.Status)
+				assert.Equal(t, 1, health.Fetches)
+				assert.Equal(t, 2.0, health.AverageItems)
+				assert.NotNil(t, health.LastSuccess)
+				assert.NotNil(t, health.LastUpdate)
+				assert.Zero(t, health.UpdateInterval)
+			},
+		},
+		{
+			name:       "recovered",
+			link:       "/rss",
+			health:     &model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 3, LastError: "404 Not Found", Fetches: 1, AverageItems: 4, LastUpdate: &lastUpdate},
+			wantStatus: model.FetchOK,
+			wantHealth: func(t *testing.T, health *model.SourceHealth) {
+				assert.Equal(t, model.SourceHealthy, health.Status)
+				assert.Zero(t, health.ConsecutiveFailures)
+				assert.Empty(t, health.LastError)
+				assert.Equal(t, 2, health.Fetches)
+				assert.Equal(t, 3.0, health.AverageItems)
+				assert.InDelta(t, time.Hour, health.UpdateInterval, float64(time.Minute))
+			},
+		},
+		{
+			name:       "failing",
+			link:       "/broken",
+			health:     &model.SourceHealth{Status: model.SourceHealthy, Fetches: 1, AverageItems: 4},
+			wantStatus: model.FetchFailed,
+			wantHealth: func(t *testing.T, health *model.SourceHealth) {
+				assert.Equal(t, model.SourceFailing, health.Status)
+				assert.Equal(t, 1, health.ConsecutiveFailures)
+				assert.Contains(t, health.LastError, "500 Internal Server Error")
+				assert.NotNil(t, health.LastFailure)
+				assert.Equal(t, 1, health.Fetches)
+			},
+		},
+		{
+			name:       "disabled after too many failures",
+			link:       "/broken",
+			health:     &model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: model.MaxConsecutiveFailures - 1},
+			wantStatus: model.FetchFailed,
+			wantHealth: func(t *testing.T, health *model.SourceHealth) {
+				assert.Equal(t, model.SourceDisabled, health.Status)
+				assert.Equal(t, model.MaxConsecutiveFailures, health.ConsecutiveFailures)
+			},
+		},
+		{
+			name:       "disabled",
+			link:       "/rss",
+			health:     &model.SourceHealth{Status: model.SourceDisabled, ConsecutiveFailures: model.MaxConsecutiveFailures},
+			wantStatus: model.FetchDisabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+			mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
 			s := &sourceService{
 				articleStorage: mockArticleStorage,
 				srcStorage:     mockSourceStorage,
+				uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
+				locker:         newTestLeaseTable().locker("replica"),
+			}
+
+			src := model.Source{Id: 1, ShortName: "one", Link: srv.URL + tt.link, Health: tt.health}
+			mockSourceStorage.EXPECT().GetByID(gomock.Any(), 1).Return(src, nil)
+			mockArticleStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 2}, nil).MaxTimes(1)
+			var stored *model.SourceHealth
+			if tt.wantHealth != nil {
+				mockSourceStorage.EXPECT().UpdateHealth(gomock.Any(), 1, tt.health, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, id int, previous *model.SourceHealth,
+						health model.SourceHealth) (bool, error) {
+						stored = &health
+						return true, nil
+					})
+			}
+
+			result, err := s.FetchSource(context.Background(), 1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, result.Status)
+			if tt.wantHealth != nil {
+				require.NotNil(t, stored)
+				assert.Equal(t, stored, result.Source.Health)
+				tt.wantHealth(t, stored)
+			} else {
+				assert.Equal(t, tt.health, result.Source.Health)
 			}
-			tt.wantErr(t, s.FetchFromAllSources(), "FetchFromAllSources()")
 		})
 	}
 }
 
+func Test_sourceService_FetchSource_HealthReset(t *testing.T) {
+	srv := newTestFeedServer(t)
+	ctrl := gomock.NewController(t)
+	mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+	mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+	s := &sourceService{
+		articleStorage: mockArticleStorage,
+		srcStorage:     mockSourceStorage,
+		uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
+		locker:         newTestLeaseTable().locker("replica"),
+	}
+
+	// The source is updated while it's fetched, so its stored health is no longer the one the fetch started from
+	previous := &model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 3, LastError: "404 Not Found"}
+	src := model.Source{Id: 1, ShortName: "one", Link: srv.URL + "/rss", Health: previous}
+	mockSourceStorage.EXPECT().GetByID(gomock.Any(), 1).Return(src, nil)
+	mockArticleStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 2}, nil)
+	mockSourceStorage.EXPECT().UpdateHealth(gomock.Any(), 1, previous, gomock.Any()).Return(false, nil)
+
+	result, err := s.FetchSource(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.FetchOK, result.Status)
+}
+
+func Test_sourceService_FetchSource_Replicas(t *testing.T) {
+	var requests atomic.Int32
+	srv := newTestFeedServer(t)
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Redirect(w, r, srv.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer counting.Close()
+
+	ctrl := gomock.NewController(t)
+	mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+	mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+	src := model.Source{Id: 1, ShortName: "one", Link: counting.URL + "/rss",
+		Schedule: &model.FetchSchedule{Interval: "15m", Enabled: true}}
+	mockSourceStorage.EXPECT().GetByID(gomock.Any(), 1).Return(src, nil).AnyTimes()
+	mockArticleStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 2}, nil).AnyTimes()
+	mockSourceStorage.EXPECT().UpdateHealth(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+
+	// The replicas share the storages and the lease table, as they would share the database
+	leases := newTestLeaseTable()
+	replicas := make([]*sourceService, 3)
+	for i := range replicas {
+		replicas[i] = &sourceService{
+			articleStorage: mockArticleStorage,
+			srcStorage:     mockSourceStorage,
+			uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
+			locker:         leases.locker(fmt.Sprintf("replica-%d", i)),
+		}
+	}
+	fetch := func(replica int) string {
+		result, err := replicas[replica].FetchSource(context.Background(), 1)
+		require.NoError(t, err)
+		return result.Status
+	}
+
+	// Only one of the replicas fetching the source at once gets the lease
+	statuses := make([]string, len(replicas))
+	var wg sync.WaitGroup
+	for i := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = fetch(i)
+		}()
+	}
+	wg.Wait()
+	assert.ElementsMatch(t, []string{model.FetchOK, model.FetchSkipped, model.FetchSkipped}, statuses)
+	assert.Equal(t, int32(1), requests.Load())
+	holder := slices.Index(statuses, model.FetchOK)
+	other := (holder + 1) % len(replicas)
+
+	// The holder renews its lease, the others wait for it to expire
+	leases.advance(10 * time.Minute)
+	assert.Equal(t, model.FetchSkipped, fetch(other))
+	assert.Equal(t, model.FetchOK, fetch(holder))
+	leases.advance(10 * time.Minute)
+	assert.Equal(t, model.FetchSkipped, fetch(other))
+
+	// Another replica takes over once the holder stops renewing its lease
+	leases.advance(15 * time.Minute)
+	assert.Equal(t, model.FetchOK, fetch(other))
+	assert.Equal(t, model.FetchSkipped, fetch(holder))
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func Test_sourceService_FetchSource_Lease(t *testing.T) {
+	tests := []struct {
+		name       string
+		schedule   *model.FetchSchedule
+		wantTTL    any
+		acquireErr error
+		wantStatus string
+		wantError  string
+	}{
+		{
+			name:       "default schedule",
+			wantTTL:    model.DefaultFetchInterval,
+			wantStatus: model.FetchOK,
+		},
+		{
+			name:       "cron schedule",
+			schedule:   &model.FetchSchedule{Cron: "30 * * * *", Enabled: true},
+			wantTTL:    untilNextFire(t, "30 * * * *"),
+			wantStatus: model.FetchOK,
+		},
+		{
+			name:       "uneven cron schedule",
+			schedule:   &model.FetchSchedule{Cron: "0 9,10 * * *", Enabled: true},
+			wantTTL:    untilNextFire(t, "0 9,10 * * *"),
+			wantStatus: model.FetchOK,
+		},
+		{
+			name:       "error acquiring the lease",
+			wantTTL:    model.DefaultFetchInterval,
+			acquireErr: errors.New("connection refused"),
+			wantStatus: model.FetchFailed,
+			wantError:  "failed to acquire the fetch lease: connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestFeedServer(t)
+			ctrl := gomock.NewController(t)
+			mockSourceStorage := mocks.NewMockSourceStorage(ctrl)
+			mockArticleStorage := mocks.NewMockArticleStorage(ctrl)
+			mockLocker := mocks.NewMockFetchLocker(ctrl)
+			s := &sourceService{
+				articleStorage: mockArticleStorage,
+				srcStorage:     mockSourceStorage,
+				uow:            testUnitOfWork{articles: mockArticleStorage, sources: mockSourceStorage},
+				locker:         mockLocker,
+			}
+
+			src := model.Source{Id: 1, ShortName: "one", Link: srv.URL + "/rss", Schedule: tt.schedule}
+			mockSourceStorage.EXPECT().GetByID(gomock.Any(), 1).Return(src, nil)
+			mockLocker.EXPECT().Acquire(gomock.Any(), 1, tt.wantTTL).Return(tt.acquireErr == nil, tt.acquireErr)
+			if tt.acquireErr == nil {
+				mockArticleStorage.EXPECT().SaveAll(gomock.Any(), gomock.Len(2)).Return(model.SaveResult{Inserted: 2}, nil)
+			}
+			mockSourceStorage.EXPECT().UpdateHealth(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(true, nil)
+
+			result, err := s.FetchSource(context.Background(), 1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, result.Status)
+			assert.Equal(t, tt.wantError, result.Error)
+		})
+	}
+}
+
+// untilNextFire matches a lease TTL lasting until the next fire of the cron expression,
+// rather than the gap between two later fires, which is 23h after 09:00 for "0 9,10 * * *".
+func untilNextFire(t *testing.T, expr string) gomock.Matcher {
+	schedule, err := cron.ParseStandard(expr)
+	require.NoError(t, err)
+	return gomock.Cond(func(x any) bool {
+		ttl, ok := x.(time.Duration)
+		want := max(time.Until(schedule.Next(time.Now().UTC())), model.MinFetchInterval)
+		return ok && (want-ttl).Abs() < time.Minute
+	})
+}
+
 func Test_sourceService_LoadDataFromFiles(t *testing.T) {
 	type args struct {
 		files []string
@@ -269,6 +833,7 @@ func Test_sourceService_LoadDataFromFiles(t *testing.T) {
 					Title:       "Test Title",
 					Link:        "http://testurl.com",
 					Description: "Test Description",
+					Author:      "Test Author",
 					Source:      model.Source{Name: "Test Source"},
 					PubDate:     time.Date(2023, 6, 4, 12, 0, 0, 0, time.UTC),
 				},
@@ -383,8 +948,8 @@ func Test_sourceService_UpdateSource(t *testing.T) {
 				articleStorage: tt.fields.articleStorage,
 				srcStorage:     tt.fields.srcStorage,
 			}
-			tt.fields.srcStorage.(*mocks.MockSourceStorage).EXPECT().Update(tt.args.id, tt.args.source).Return(tt.want, nil)
-			got, err := s.UpdateSource(tt.args.id, tt.args.source)
+			tt.fields.srcStorage.(*mocks.MockSourceStorage).EXPECT().Update(gomock.Any(), tt.args.id, tt.args.source).Return(tt.want, nil)
+			got, err := s.UpdateSource(context.Background(), tt.args.id, tt.args.source)
 			if !tt.wantErr(t, err, fmt.Sprintf("UpdateSource(%v, %v)", tt.args.id, tt.args.source)) {
 				return
 			}
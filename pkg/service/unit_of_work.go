@@ -0,0 +1,11 @@
+package service
+
+import "context"
+
+// UnitOfWork runs the operations mutating both the article and the source storage atomically.
+type UnitOfWork interface {
+	// Do runs fn with the article and source storages bound to the unit of work.
+	// The changes fn makes through them are kept if it returns nil and undone otherwise.
+	// fn must not use any other storages, they may wait for the unit of work to finish.
+	Do(ctx context.Context, fn func(articles ArticleStorage, sources SourceStorage) error) error
+}
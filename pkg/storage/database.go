@@ -1,24 +1,77 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/antonchaban/news-aggregator/pkg/storage/migrate"
+	"github.com/antonchaban/news-aggregator/pkg/storage/postgres"
+	"github.com/antonchaban/news-aggregator/pkg/storage/sqlite"
+	"github.com/antonchaban/news-aggregator/schema"
 	"github.com/jmoiron/sqlx"
 	"os"
 )
 
 const storTypeEnvVar = "STORAGE_TYPE"
 
+// Storage types selectable with the STORAGE_TYPE environment variable, Postgres is the default one.
+const (
+	TypePostgres = "postgres"
+	TypeSQLite   = "sqlite"
+	// TypeMemory keeps the articles and sources in memory, without a database, see RestoreMemory.
+	TypeMemory = "memory"
+)
+
+// Config holds the connection settings of the database.
+// Path is the database file of the SQLite storage, the other fields are used by Postgres.
 type Config struct {
 	Host     string
 	Username string
 	Password string
 	DBName   string
 	SSLMode  string
+	Path     string
+}
+
+// Type returns the storage type selected with the STORAGE_TYPE environment variable.
+func Type() string {
+	if t := os.Getenv(storTypeEnvVar); t != "" {
+		return t
+	}
+	return TypePostgres
 }
 
+// NewDB connects to the database of the selected storage type and applies the migrations it misses,
+// so the binaries never run against an unmigrated or stale schema.
 func NewDB(cfg Config) (*sqlx.DB, error) {
-	db, err := sqlx.Connect(os.Getenv(storTypeEnvVar), fmt.Sprintf("%s://%s:%s@%s/%s?sslmode=%s",
-		os.Getenv(storTypeEnvVar), cfg.Username, cfg.Password, cfg.Host, cfg.DBName, cfg.SSLMode))
+	db, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Migrator(db)
+	if err == nil {
+		err = m.Up(context.Background())
+	}
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate the database: %w", err)
+	}
+	return db, nil
+}
+
+// Connect connects to the database of the selected storage type without migrating it.
+// SQLite databases are created on the first connection.
+func Connect(cfg Config) (*sqlx.DB, error) {
+	switch Type() {
+	case TypeSQLite:
+		return sqlite.Open(cfg.Path)
+	case TypePostgres:
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", Type())
+	}
+
+	db, err := sqlx.Connect(TypePostgres, fmt.Sprintf("%s://%s:%s@%s/%s?sslmode=%s",
+		TypePostgres, cfg.Username, cfg.Password, cfg.Host, cfg.DBName, cfg.SSLMode))
 	if err != nil {
 		return nil, err
 	}
@@ -28,3 +81,61 @@ func NewDB(cfg Config) (*sqlx.DB, error) {
 	}
 	return db, nil
 }
+
+// Migrator creates the migrator of the database with the embedded migrations of the selected storage type.
+// Postgres migrations are serialized with an advisory lock, as the replicas share the database,
+// and some of them run Go steps.
+func Migrator(db *sqlx.DB) (*migrate.Migrator, error) {
+	if Type() == TypeSQLite {
+		return migrate.New(db, schema.SQLite, nil)
+	}
+	m, err := migrate.New(db, schema.Postgres, migrate.AdvisoryLock)
+	if err != nil {
+		return nil, err
+	}
+	if err = postgres.AddMigrationSteps(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// New creates the article and source storages of the selected storage type on top of the database.
+func New(db *sqlx.DB) (service.ArticleStorage, service.SourceStorage) {
+	if Type() == TypeSQLite {
+		return sqlite.New(db), sqlite.NewSrc(db)
+	}
+	return postgres.New(db), postgres.NewSrc(db)
+}
+
+// NewUnitOfWork creates the unit of work of the selected storage type, running the work in transactions of the database.
+func NewUnitOfWork(db *sqlx.DB) service.UnitOfWork {
+	if Type() == TypeSQLite {
+		return sqlite.NewUnitOfWork(db)
+	}
+	return postgres.NewUnitOfWork(db)
+}
+
+// NewFetchLocker creates the fetch locker of the selected storage type, taking the leases on behalf of this instance.
+func NewFetchLocker(db *sqlx.DB) service.FetchLocker {
+	if Type() == TypeSQLite {
+		return sqlite.NewFetchLocker(db, instanceID())
+	}
+	return postgres.NewFetchLocker(db, instanceID())
+}
+
+// NewFetchRunStorage creates the storage of the fetch history of the selected storage type.
+func NewFetchRunStorage(db *sqlx.DB) service.FetchRunStorage {
+	if Type() == TypeSQLite {
+		return sqlite.NewFetchRunStorage(db)
+	}
+	return postgres.NewFetchRunStorage(db)
+}
+
+// instanceID identifies this process among the instances sharing the database, such as the pods of a deployment.
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -5,4 +5,10 @@
 //
 // The storage package should be used by other layers or components to perform
 // data storage and retrieval operations, providing a clean separation of concerns.
+//
+// The database backend is selected with the STORAGE_TYPE environment variable:
+// "postgres" (the default) connects to a Postgres server, "sqlite" opens the single-file
+// SQLite database at SQLITE_PATH. NewDB applies the embedded migrations of the schema package
+// the database misses, see the migrate package. "memory" keeps the articles and sources
+// in memory, restored on startup from the snapshots and the change journal of the backuper package.
 package storage
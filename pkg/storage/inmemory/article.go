@@ -1,16 +1,24 @@
 package inmemory
 
 import (
+	"context"
 	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
+	"github.com/antonchaban/news-aggregator/pkg/filter"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/antonchaban/news-aggregator/pkg/service"
 	"github.com/sirupsen/logrus"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 )
 
 const (
 	eventArticleStorageInitialized = "article_storage_initialized"
 	eventDeleteArticlesBySourceID  = "delete_articles_by_source_id"
 	eventGetAllArticles            = "get_all_articles"
+	eventGetArticlesAfter          = "get_articles_after"
 	eventSaveArticle               = "save_article"
 	eventSaveArticleError          = "save_article_error"
 	eventArticleSaved              = "article_saved"
@@ -20,56 +28,124 @@ const (
 	eventSaveAllArticles           = "save_all_articles"
 	eventSaveAllArticlesSkip       = "save_all_articles_skip"
 	eventAllArticlesSaved          = "all_articles_saved"
+	eventGetExpiredArticles        = "get_expired_articles"
+	eventDeleteArticlesByIDs       = "delete_articles_by_ids"
 )
 
-// MemoryArticleStorage is a struct that contains the in-memory database for articles.
+// errArticleExists is returned when an article with the same canonical link is already saved.
+var errArticleExists = errors.New("article already exists")
+
+// memoryArticleStorage is a struct that contains the in-memory database for articles.
+// It's safe for concurrent use: the articles are guarded by mu and indexed
+// by their canonical link, source ID and publication day besides their ID, whose order is kept for paging.
+// The storage a unit of work runs with shares the articles, keeping the ones it changes in undo.
 type memoryArticleStorage struct {
-	Articles []model.Article
+	*articleRecords
+	// undo keeps the articles as they were before the unit of work changed them, nil outside of one
+	undo *undoLog
+}
+
+// articleRecords holds the articles and their indexes.
+type articleRecords struct {
+	mu       sync.RWMutex
+	articles map[int]model.Article
+	// ids are the IDs of the articles in ascending order, so they're paged through without sorting
+	ids      []int
+	byLink   map[string]int
+	bySource map[int]idSet
+	byDay    map[int64]idSet
 	nextID   int
+	// versions are the versions of the articles, stamped anew whenever one changes
+	versions map[int]uint64
+	version  uint64
+	// log records the changes in the journal set by SetJournal, if any
+	log *changeLog
 }
 
 func New() service.ArticleStorage {
 	logrus.WithField("event_id", eventArticleStorageInitialized).Info("Initializing Article Storage")
-	return &memoryArticleStorage{
-		Articles: []model.Article{},
+	return &memoryArticleStorage{articleRecords: &articleRecords{
+		articles: map[int]model.Article{},
+		byLink:   map[string]int{},
+		bySource: map[int]idSet{},
+		byDay:    map[int64]idSet{},
+		versions: map[int]uint64{},
 		nextID:   1, // Initializing IDs for in-memory storage, and then auto-incrementing it after saving an article
-	}
+	}}
 }
 
 // DeleteBySourceID removes all articles with the given source ID from the database.
-func (a *memoryArticleStorage) DeleteBySourceID(id int) error {
+func (a *memoryArticleStorage) DeleteBySourceID(ctx context.Context, id int) error {
 	logrus.WithField("event_id", eventDeleteArticlesBySourceID).Info("Deleting articles by source ID", id)
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	// New slice to store articles that don't match the source ID
-	newArticles := a.Articles[:0] // create a zero-length slice with the same capacity
-
-	for _, article := range a.Articles {
-		if article.Source.Id != id {
-			newArticles = append(newArticles, article)
-		}
+	for articleID := range a.bySource[id] {
+		a.remove(a.articles[articleID])
+		a.log.record(model.Change{Kind: model.ChangeDeleteArticle, Id: articleID})
 	}
-	a.Articles = newArticles
 	return nil
 }
 
-// GetAll returns all articles in the database.
-func (a *memoryArticleStorage) GetAll() ([]model.Article, error) {
+// GetAll returns all articles in the database in the order they were saved.
+func (a *memoryArticleStorage) GetAll(ctx context.Context) ([]model.Article, error) {
 	logrus.WithField("event_id", eventGetAllArticles).Info("Fetching all articles")
-	return a.Articles, nil
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	articles := make([]model.Article, 0, len(a.ids))
+	for _, id := range a.ids {
+		articles = append(articles, a.articles[id])
+	}
+	return articles, nil
+}
+
+// GetAfter returns up to limit articles with IDs greater than afterID, ordered by ID.
+func (a *memoryArticleStorage) GetAfter(ctx context.Context, afterID, limit int) ([]model.Article, error) {
+	logrus.WithField("event_id", eventGetArticlesAfter).Info("Fetching articles after ", afterID)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	start, _ := slices.BinarySearch(a.ids, afterID+1)
+	ids := a.ids[start:]
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	var articles []model.Article
+	for _, id := range ids {
+		articles = append(articles, a.articles[id])
+	}
+	return articles, nil
 }
 
 // Save adds a new article to the database.
-func (a *memoryArticleStorage) Save(article model.Article) (model.Article, error) {
+// Articles are unique by their canonical link, and a new article joins the story
+// of the most similar article published around the same time, if there is one.
+func (a *memoryArticleStorage) Save(ctx context.Context, article model.Article) (model.Article, error) {
 	logrus.WithField("event_id", eventSaveArticle).Info("Saving new article", article.Link)
-	for _, art := range a.Articles {
-		if art.Link == article.Link {
-			logrus.WithField("event_id", eventSaveArticleError).Error("Article already exists", article.Link)
-			return model.Article{}, errors.New("article already exists")
-		}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.save(article)
+}
+
+// save adds a new article to the database, the caller must hold the write lock.
+func (a *memoryArticleStorage) save(article model.Article) (model.Article, error) {
+	article.CanonicalLink = dedup.CanonicalURL(article.Link)
+	if _, ok := a.byLink[article.CanonicalLink]; ok {
+		logrus.WithField("event_id", eventSaveArticleError).Error("Article already exists", article.Link)
+		return model.Article{}, errArticleExists
 	}
+	article.Fingerprint = dedup.Fingerprint(article.Title, article.Description)
+
 	article.Id = a.nextID
 	a.nextID++
-	a.Articles = append(a.Articles, article)
+	article.StoryId = article.Id
+	if storyID, ok := dedup.FindStory(article.Fingerprint, a.storyCandidates(article.PubDate)); ok {
+		article.StoryId = storyID
+	}
+
+	a.put(article)
+	a.log.record(model.Change{Kind: model.ChangeSaveArticle, Id: article.Id, Article: &article})
 	logrus.WithFields(logrus.Fields{
 		"event_id":   eventArticleSaved,
 		"article_id": article.Id,
@@ -77,36 +153,191 @@ func (a *memoryArticleStorage) Save(article model.Article) (model.Article, error
 	return article, nil
 }
 
+// storyCandidates returns the articles published within dedup.StoryWindow of the given date,
+// looking them up by their publication day.
+func (a *memoryArticleStorage) storyCandidates(pubDate time.Time) []dedup.Candidate {
+	var candidates []dedup.Candidate
+	for d := day(pubDate.Add(-dedup.StoryWindow)); d <= day(pubDate.Add(dedup.StoryWindow)); d++ {
+		for id := range a.byDay[d] {
+			art := a.articles[id]
+			if inStoryWindow(art.PubDate, pubDate) {
+				candidates = append(candidates, dedup.Candidate{StoryId: art.StoryId, Fingerprint: art.Fingerprint})
+			}
+		}
+	}
+	return candidates
+}
+
+// put stores the article and indexes it, the caller must hold the write lock.
+func (a *memoryArticleStorage) put(article model.Article) {
+	a.keep(article.Id)
+	if _, ok := a.articles[article.Id]; !ok {
+		a.ids = insertID(a.ids, article.Id)
+	}
+	a.articles[article.Id] = article
+	a.byLink[article.CanonicalLink] = article.Id
+	addToIndex(a.bySource, article.Source.Id, article.Id)
+	addToIndex(a.byDay, day(article.PubDate), article.Id)
+	a.changed(article.Id)
+}
+
+// remove removes the article from the database and its indexes, the caller must hold the write lock.
+// The link index entry of another article with the same canonical link is kept.
+func (a *memoryArticleStorage) remove(article model.Article) {
+	a.keep(article.Id)
+	delete(a.articles, article.Id)
+	a.ids = deleteID(a.ids, article.Id)
+	if a.byLink[article.CanonicalLink] == article.Id {
+		delete(a.byLink, article.CanonicalLink)
+	}
+	removeFromIndex(a.bySource, article.Source.Id, article.Id)
+	removeFromIndex(a.byDay, day(article.PubDate), article.Id)
+	a.changed(article.Id)
+}
+
 // Delete removes the article with the given ID from the database.
-func (a *memoryArticleStorage) Delete(id int) error {
+func (a *memoryArticleStorage) Delete(ctx context.Context, id int) error {
 	logrus.WithField("event_id", eventDeleteArticle).Info("Deleting article", id)
-	for i, article := range a.Articles {
-		if article.Id == id {
-			a.Articles = append(a.Articles[:i], a.Articles[i+1:]...)
-			logrus.WithField("event_id", eventArticleDeleted).Info("Article deleted successfully", id)
-			return nil
-		}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	article, ok := a.articles[id]
+	if !ok {
+		logrus.WithField("event_id", eventDeleteArticleError).Error("Article not found", id)
+		return errors.New("article not found")
 	}
-	logrus.WithField("event_id", eventDeleteArticleError).Error("Article not found", id)
-	return errors.New("article not found")
+	a.remove(article)
+	a.log.record(model.Change{Kind: model.ChangeDeleteArticle, Id: id})
+	logrus.WithField("event_id", eventArticleDeleted).Info("Article deleted successfully", id)
+	return nil
 }
 
-func (a *memoryArticleStorage) SaveAll(articles []model.Article) error {
+// SaveAll adds the new articles to the database and updates the saved ones whose title,
+// description or publication date changed, counting what changed.
+// Readers see either none or all of the changes.
+func (a *memoryArticleStorage) SaveAll(ctx context.Context, articles []model.Article) (model.SaveResult, error) {
 	logrus.WithField("event_id", eventSaveAllArticles).Info("Saving multiple articles")
+	articles, repeated := dedup.UniqueByLink(articles)
+	result := model.SaveResult{Skipped: repeated}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	for _, article := range articles {
-		_, err := a.Save(article)
-		if err != nil {
-			if errors.Is(err, errors.New("article already exists")) {
-				logrus.WithField("event_id", eventSaveAllArticlesSkip).Warn("Article already exists, skipping", article.Link)
-				continue
+		id, ok := a.byLink[article.CanonicalLink]
+		switch {
+		case !ok:
+			if _, err := a.save(article); err != nil {
+				return result, err
 			}
+			result.Inserted++
+		case a.update(id, article):
+			result.Updated++
+		default:
+			logrus.WithField("event_id", eventSaveAllArticlesSkip).Info("Article is unchanged, skipping", article.Link)
+			result.Skipped++
 		}
 	}
 	logrus.WithField("event_id", eventAllArticlesSaved).Info("All articles processed")
-	return nil
+	return result, nil
+}
+
+// update updates the content and metadata of the saved article with the given ID,
+// reporting whether any of them changed. The caller must hold the write lock.
+func (a *memoryArticleStorage) update(id int, article model.Article) bool {
+	saved := a.articles[id]
+	if saved.Title == article.Title && saved.Description == article.Description && saved.PubDate.Equal(article.PubDate) &&
+		saved.Author == article.Author && slices.Equal(saved.Categories, article.Categories) && saved.ImageURL == article.ImageURL &&
+		saved.Content == article.Content && saved.Language == article.Language && saved.GUID == article.GUID {
+		return false
+	}
+	a.keep(id)
+	removeFromIndex(a.byDay, day(saved.PubDate), id)
+	saved.Title, saved.Description, saved.PubDate = article.Title, article.Description, article.PubDate
+	saved.Author, saved.Categories, saved.ImageURL = article.Author, article.Categories, article.ImageURL
+	saved.Content, saved.Language, saved.GUID = article.Content, article.Language, article.GUID
+	saved.Fingerprint = dedup.Fingerprint(saved.Title, saved.Description)
+	a.articles[id] = saved
+	addToIndex(a.byDay, day(saved.PubDate), id)
+	a.changed(id)
+	a.log.record(model.Change{Kind: model.ChangeSaveArticle, Id: id, Article: &saved})
+	return true
+}
+
+// GetExpired returns the articles of the source published before the cutoff or past
+// the newest maxCount ones, oldest first. The zero cutoff and maxCount set no limit.
+func (a *memoryArticleStorage) GetExpired(ctx context.Context, sourceID int, cutoff time.Time, maxCount int) ([]model.Article, error) {
+	logrus.WithField("event_id", eventGetExpiredArticles).Info("Fetching expired articles of source ", sourceID)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	articles := make([]model.Article, 0, len(a.bySource[sourceID]))
+	for id := range a.bySource[sourceID] {
+		articles = append(articles, a.articles[id])
+	}
+	// Newest first, so the articles past maxCount are the tail
+	sort.Slice(articles, func(i, j int) bool {
+		if !articles[i].PubDate.Equal(articles[j].PubDate) {
+			return articles[i].PubDate.After(articles[j].PubDate)
+		}
+		return articles[i].Id > articles[j].Id
+	})
+
+	var expired []model.Article
+	for i := len(articles) - 1; i >= 0; i-- {
+		if articles[i].PubDate.Before(cutoff) || (maxCount > 0 && i >= maxCount) {
+			expired = append(expired, articles[i])
+		}
+	}
+	return expired, nil
 }
 
-func (a *memoryArticleStorage) GetByFilter(query string, args []interface{}) ([]model.Article, error) {
+// DeleteByIDs removes the articles with the given IDs from the database and returns the number of removed ones.
+func (a *memoryArticleStorage) DeleteByIDs(ctx context.Context, ids []int) (int, error) {
+	logrus.WithField("event_id", eventDeleteArticlesByIDs).Info("Deleting articles by IDs")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		if article, ok := a.articles[id]; ok {
+			a.remove(article)
+			a.log.record(model.Change{Kind: model.ChangeDeleteArticle, Id: id})
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// inStoryWindow reports whether articles published at the given dates can report the same story.
+func inStoryWindow(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d <= dedup.StoryWindow && d >= -dedup.StoryWindow
+}
+
+// secondsPerDay is the length of the days of the publication day index.
+const secondsPerDay = 24 * 60 * 60
+
+// day returns the number of the UTC day of the date, the key of the publication day index.
+func day(date time.Time) int64 {
+	seconds := date.Unix()
+	d := seconds / secondsPerDay
+	if seconds%secondsPerDay < 0 {
+		d--
+	}
+	return d
+}
+
+func (a *memoryArticleStorage) GetByFilter(ctx context.Context, query string, args []interface{}) ([]model.Article, error) {
 	logrus.WithField("event_id", "get_by_filter_not_supported").Warn("GetByFilter operation is not supported in in-memory storage")
 	return nil, errors.New("GetByFilter operation is not supported in in-memory storage")
 }
+
+func (a *memoryArticleStorage) CountByFilter(ctx context.Context, query string, args []interface{}) (int, error) {
+	logrus.WithField("event_id", "count_by_filter_not_supported").Warn("CountByFilter operation is not supported in in-memory storage")
+	return 0, errors.New("GetByFilter operation is not supported in in-memory storage")
+}
+
+// Dialect returns nil, as the in-memory storage can't run SQL queries and the articles are filtered in memory.
+func (a *memoryArticleStorage) Dialect() filter.Dialect {
+	return nil
+}
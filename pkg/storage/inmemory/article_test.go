@@ -1,9 +1,12 @@
 package inmemory
 
 import (
+	"context"
 	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
 )
@@ -25,9 +28,9 @@ func TestArticleInMemory_Create(t *testing.T) {
 				Source:      model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"},
 				PubDate:     time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC),
 			},
-			expected: []model.Article{
-				{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
-			},
+			expected: stored(
+				model.Article{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+			),
 			expectErr: false,
 		},
 		{
@@ -39,23 +42,23 @@ func TestArticleInMemory_Create(t *testing.T) {
 				Source:      model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"},
 				PubDate:     time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC),
 			},
-			expected: []model.Article{
-				{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
-				{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
-			},
+			expected: stored(
+				model.Article{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+				model.Article{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+			),
 			expectErr: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := storage.Save(tt.articles)
+			_, err := storage.Save(context.Background(), tt.articles)
 
 			if tt.expectErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				articles, _ := storage.GetAll()
+				articles, _ := storage.GetAll(context.Background())
 				assert.Equal(t, tt.expected, articles)
 			}
 		})
@@ -78,10 +81,10 @@ func TestArticleInMemory_Delete(t *testing.T) {
 				{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
 				{Id: 3, Title: "Article 3", Description: "Description 3", Link: "http://link3.com", Source: model.Source{Id: 3, Name: "Source 3", Link: "http://source3.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
 			},
-			expected: []model.Article{
-				{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
-				{Id: 3, Title: "Article 3", Description: "Description 3", Link: "http://link3.com", Source: model.Source{Id: 3, Name: "Source 3", Link: "http://source3.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
-			},
+			expected: stored(
+				model.Article{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+				model.Article{Id: 3, Title: "Article 3", Description: "Description 3", Link: "http://link3.com", Source: model.Source{Id: 3, Name: "Source 3", Link: "http://source3.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+			),
 			expectErr: false,
 		},
 		{
@@ -94,17 +97,17 @@ func TestArticleInMemory_Delete(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := New()
-			err := storage.SaveAll(tt.articles)
+			_, err := storage.SaveAll(context.Background(), tt.articles)
 			if err != nil {
 				return
 			}
-			err = storage.Delete(tt.id)
+			err = storage.Delete(context.Background(), tt.id)
 
 			if tt.expectErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				articles, _ := storage.GetAll()
+				articles, _ := storage.GetAll(context.Background())
 				assert.Equal(t, tt.expected, articles)
 			}
 		})
@@ -124,10 +127,10 @@ func TestArticleInMemory_GetAll(t *testing.T) {
 				{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
 				{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC)},
 			},
-			expected: []model.Article{
-				{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
-				{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC)},
-			},
+			expected: stored(
+				model.Article{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+				model.Article{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC)},
+			),
 			expectErr: false,
 		},
 		{
@@ -142,11 +145,11 @@ func TestArticleInMemory_GetAll(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			storage := New()
-			err := storage.SaveAll(tt.articles)
+			_, err := storage.SaveAll(context.Background(), tt.articles)
 			if err != nil {
 				return
 			}
-			articles, err := storage.GetAll()
+			articles, err := storage.GetAll(context.Background())
 
 			if tt.expectErr {
 				assert.Error(t, err)
@@ -159,23 +162,47 @@ func TestArticleInMemory_GetAll(t *testing.T) {
 }
 
 func TestArticleInMemory_SaveAll(t *testing.T) {
+	pubDate := time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)
 	tests := []struct {
-		name      string
-		articles  []model.Article
-		expected  []model.Article
-		expectErr bool
+		name       string
+		saved      []model.Article
+		articles   []model.Article
+		expected   []model.Article
+		wantResult model.SaveResult
+		expectErr  bool
 	}{
 		{
 			name: "Save all articles successfully",
 			articles: []model.Article{
-				{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
-				{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+				{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: pubDate},
+				{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: pubDate},
 			},
-			expected: []model.Article{
-				{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
-				{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+			expected: stored(
+				model.Article{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1, Name: "Source 1", Link: "http://source1.com"}, PubDate: pubDate},
+				model.Article{Id: 2, Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 2, Name: "Source 2", Link: "http://source2.com"}, PubDate: pubDate},
+			),
+			wantResult: model.SaveResult{Inserted: 2},
+			expectErr:  false,
+		},
+		{
+			name: "Saved articles are updated if they changed",
+			saved: []model.Article{
+				{Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1}, PubDate: pubDate},
+				{Title: "Article 2", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 1}, PubDate: pubDate},
 			},
-			expectErr: false,
+			articles: []model.Article{
+				{Title: "Article 1", Description: "Description 1", Link: "https://link1.com/?utm_source=rss", Source: model.Source{Id: 1}, PubDate: pubDate},
+				{Title: "Article 2 (updated)", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 1}, PubDate: pubDate.Add(time.Hour)},
+				{Title: "Article 3", Description: "Description 3", Link: "http://link3.com", Source: model.Source{Id: 1}, PubDate: pubDate},
+				{Title: "Article 3 (repeated)", Link: "http://link3.com/", Source: model.Source{Id: 1}, PubDate: pubDate},
+			},
+			expected: stored(
+				model.Article{Id: 1, Title: "Article 1", Description: "Description 1", Link: "http://link1.com", Source: model.Source{Id: 1}, PubDate: pubDate},
+				model.Article{Id: 2, Title: "Article 2 (updated)", Description: "Description 2", Link: "http://link2.com", Source: model.Source{Id: 1}, PubDate: pubDate.Add(time.Hour)},
+				model.Article{Id: 3, Title: "Article 3", Description: "Description 3", Link: "http://link3.com", Source: model.Source{Id: 1}, PubDate: pubDate},
+			),
+			wantResult: model.SaveResult{Inserted: 1, Updated: 1, Skipped: 2},
+			expectErr:  false,
 		},
 		{
 			name:      "Save empty list of articles",
@@ -188,19 +215,99 @@ func TestArticleInMemory_SaveAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := New()
-			err := storage.SaveAll(tt.articles)
+			_, err := storage.SaveAll(context.Background(), tt.saved)
+			require.NoError(t, err)
+			result, err := storage.SaveAll(context.Background(), tt.articles)
 
 			if tt.expectErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				articles, _ := storage.GetAll()
+				assert.Equal(t, tt.wantResult, result)
+				articles, _ := storage.GetAll(context.Background())
 				assert.Equal(t, tt.expected, articles)
 			}
 		})
 	}
 }
 
+func TestArticleInMemory_Save_Dedup(t *testing.T) {
+	pubDate := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	bbc := model.Article{
+		Title:       "Trial starts for a Polish man accused of punching Danish prime minister in Copenhagen",
+		Description: "A Polish man went on trial in Copenhagen accused of punching the Danish prime minister.",
+		Link:        "https://www.bbc.com/news/trial",
+		Source:      model.Source{Id: 1},
+		PubDate:     pubDate,
+	}
+	tests := []struct {
+		name        string
+		article     model.Article
+		wantStoryId int
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		{
+			name: "Same link with tracking parameters",
+			article: model.Article{
+				Title:   "Trial starts",
+				Link:    "http://bbc.com/news/trial/?utm_source=rss#top",
+				Source:  model.Source{Id: 1},
+				PubDate: pubDate,
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "Near-duplicate joins the story",
+			article: model.Article{
+				Title:       bbc.Title,
+				Description: "A Polish man went on trial in Copenhagen on Monday accused of punching Danish Prime Minister Mette Frederiksen.",
+				Link:        "https://abcnews.go.com/trial",
+				Source:      model.Source{Id: 2},
+				PubDate:     pubDate.Add(time.Hour),
+			},
+			wantStoryId: 1,
+			wantErr:     assert.NoError,
+		},
+		{
+			name: "Near-duplicate published much later starts a story",
+			article: model.Article{
+				Title:       bbc.Title,
+				Description: bbc.Description,
+				Link:        "https://abcnews.go.com/trial-verdict",
+				Source:      model.Source{Id: 2},
+				PubDate:     pubDate.AddDate(0, 1, 0),
+			},
+			wantStoryId: 2,
+			wantErr:     assert.NoError,
+		},
+		{
+			name: "Another story",
+			article: model.Article{
+				Title:       "More than 120 people died in Tokyo from heatstroke in July",
+				Description: "Average temperatures hit record highs.",
+				Link:        "https://abcnews.go.com/heatstroke",
+				Source:      model.Source{Id: 2},
+				PubDate:     pubDate,
+			},
+			wantStoryId: 2,
+			wantErr:     assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := New()
+			_, err := storage.Save(context.Background(), bbc)
+			assert.NoError(t, err)
+
+			saved, err := storage.Save(context.Background(), tt.article)
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+			assert.Equal(t, tt.wantStoryId, saved.StoryId)
+		})
+	}
+}
+
 func Test_memoryArticleStorage_DeleteBySourceID(t *testing.T) {
 	type fields struct {
 		Articles []model.Article
@@ -230,11 +337,115 @@ func Test_memoryArticleStorage_DeleteBySourceID(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			a := &memoryArticleStorage{
-				Articles: tt.fields.Articles,
-				nextID:   tt.fields.nextID,
+			a := New().(*memoryArticleStorage)
+			for _, article := range tt.fields.Articles {
+				a.articles[article.Id] = article
+				addToIndex(a.bySource, article.Source.Id, article.Id)
+			}
+			a.nextID = tt.fields.nextID
+			tt.wantErr(t, a.DeleteBySourceID(context.Background(), tt.args.id), fmt.Sprintf("DeleteBySourceID(%v)", tt.args.id))
+			articles, _ := a.GetAll(context.Background())
+			for _, article := range articles {
+				assert.NotEqual(t, tt.args.id, article.Source.Id)
+			}
+			assert.NotContains(t, a.bySource, tt.args.id)
+		})
+	}
+}
+
+// stored fills in the fields the storage derives from the saved articles,
+// each of them reporting a story of its own.
+func stored(articles ...model.Article) []model.Article {
+	for i := range articles {
+		articles[i].CanonicalLink = dedup.CanonicalURL(articles[i].Link)
+		articles[i].Fingerprint = dedup.Fingerprint(articles[i].Title, articles[i].Description)
+		articles[i].StoryId = articles[i].Id
+	}
+	return articles
+}
+
+func TestArticleInMemory_GetExpired(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	bbc, abc := model.Source{Id: 1, ShortName: "bbc"}, model.Source{Id: 2, ShortName: "abc"}
+	base := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	// Articles of bbc published on days 0..3, the one of abc on day 0
+	for i, article := range []model.Article{
+		{Title: "Day 2", Link: "https://bbc.com/2", Source: bbc, PubDate: base.AddDate(0, 0, 2)},
+		{Title: "Day 0", Link: "https://bbc.com/0", Source: bbc, PubDate: base},
+		{Title: "Day 3", Link: "https://bbc.com/3", Source: bbc, PubDate: base.AddDate(0, 0, 3)},
+		{Title: "Day 1", Link: "https://bbc.com/1", Source: bbc, PubDate: base.AddDate(0, 0, 1)},
+		{Title: "Abc day 0", Link: "https://abc.com/0", Source: abc, PubDate: base},
+	} {
+		_, err := storage.Save(ctx, article)
+		require.NoError(t, err, "article %d", i)
+	}
+
+	tests := []struct {
+		name       string
+		cutoff     time.Time
+		maxCount   int
+		wantTitles []string
+	}{
+		{name: "no limits", wantTitles: nil},
+		{name: "max age", cutoff: base.AddDate(0, 0, 2), wantTitles: []string{"Day 0", "Day 1"}},
+		{name: "max count", maxCount: 3, wantTitles: []string{"Day 0"}},
+		{name: "both limits", cutoff: base.AddDate(0, 0, 1), maxCount: 2, wantTitles: []string{"Day 0", "Day 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expired, err := storage.GetExpired(ctx, bbc.Id, tt.cutoff, tt.maxCount)
+			require.NoError(t, err)
+			var titles []string
+			for _, article := range expired {
+				titles = append(titles, article.Title)
+			}
+			assert.Equal(t, tt.wantTitles, titles)
+		})
+	}
+
+	deleted, err := storage.DeleteByIDs(ctx, []int{2, 4, 42})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted, "unknown IDs are skipped")
+	all, err := storage.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+	expired, err := storage.GetExpired(ctx, bbc.Id, time.Time{}, 1)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "Day 2", expired[0].Title, "deleted articles are dropped from the source index")
+}
+
+func TestArticleInMemory_GetAfter(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	for i := 0; i < 5; i++ {
+		_, err := storage.Save(ctx, model.Article{Title: "Article", Link: fmt.Sprintf("https://bbc.com/%d", i),
+			Source: model.Source{Id: 1}})
+		require.NoError(t, err)
+	}
+	require.NoError(t, storage.Delete(ctx, 3))
+
+	tests := []struct {
+		name    string
+		afterID int
+		limit   int
+		wantIDs []int
+	}{
+		{name: "first page", afterID: 0, limit: 2, wantIDs: []int{1, 2}},
+		{name: "skips deleted", afterID: 2, limit: 2, wantIDs: []int{4, 5}},
+		{name: "last page", afterID: 4, limit: 2, wantIDs: []int{5}},
+		{name: "past the end", afterID: 5, limit: 2, wantIDs: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles, err := storage.GetAfter(ctx, tt.afterID, tt.limit)
+			require.NoError(t, err)
+			var ids []int
+			for _, article := range articles {
+				ids = append(ids, article.Id)
 			}
-			tt.wantErr(t, a.DeleteBySourceID(tt.args.id), fmt.Sprintf("DeleteBySourceID(%v)", tt.args.id))
+			assert.Equal(t, tt.wantIDs, ids)
 		})
 	}
 }
@@ -0,0 +1,162 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests hammer the storages from many goroutines at once,
+// run them with -race to catch unsynchronized access.
+
+const (
+	stressWorkers    = 8
+	stressIterations = 50
+)
+
+// quietLogs silences the info logs of the storages for the duration of the test.
+func quietLogs(t *testing.T) {
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.WarnLevel)
+	t.Cleanup(func() { logrus.SetLevel(level) })
+}
+
+func TestArticleInMemory_Concurrent(t *testing.T) {
+	quietLogs(t)
+	ctx := context.Background()
+	storage := New()
+	pubDate := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(4)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				// Every link is saved by two workers, only one of them must succeed
+				link := fmt.Sprintf("https://example.com/%d/%d", w/2, i)
+				_, err := storage.SaveAll(ctx, []model.Article{{
+					Title:   fmt.Sprintf("Article %d of worker %d", i, w),
+					Link:    link,
+					Source:  model.Source{Id: w % 4},
+					PubDate: pubDate.Add(time.Duration(i) * time.Hour),
+				}})
+				assert.NoError(t, err)
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				_ = storage.Delete(ctx, w*stressIterations+i)
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations/10; i++ {
+				assert.NoError(t, storage.DeleteBySourceID(ctx, 3))
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				articles, err := storage.GetAll(ctx)
+				assert.NoError(t, err)
+				assertUniqueArticles(t, articles)
+			}
+		}()
+	}
+	wg.Wait()
+
+	articles, err := storage.GetAll(ctx)
+	require.NoError(t, err)
+	assertUniqueArticles(t, articles)
+
+	// The indexes must match the articles left after the concurrent writes
+	a := storage.(*memoryArticleStorage)
+	assert.Len(t, a.byLink, len(articles))
+	indexed := 0
+	for _, ids := range a.bySource {
+		indexed += len(ids)
+	}
+	assert.Equal(t, len(articles), indexed)
+	for _, article := range articles {
+		assert.Equal(t, article.Id, a.byLink[article.CanonicalLink])
+		assert.Contains(t, a.bySource[article.Source.Id], article.Id)
+		assert.Contains(t, a.byDay[day(article.PubDate)], article.Id)
+	}
+}
+
+// assertUniqueArticles asserts that no two articles share their ID or canonical link.
+func assertUniqueArticles(t *testing.T, articles []model.Article) {
+	ids := make(map[int]bool, len(articles))
+	links := make(map[string]bool, len(articles))
+	for _, article := range articles {
+		assert.False(t, ids[article.Id], "duplicate ID %d", article.Id)
+		assert.False(t, links[article.CanonicalLink], "duplicate link %s", article.CanonicalLink)
+		ids[article.Id] = true
+		links[article.CanonicalLink] = true
+	}
+}
+
+func TestSourceInMemory_Concurrent(t *testing.T) {
+	quietLogs(t)
+	ctx := context.Background()
+	storage := NewSrc()
+
+	var wg sync.WaitGroup
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(3)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				src, err := storage.Save(ctx, model.Source{
+					Name:      fmt.Sprintf("Source %d of worker %d", i, w),
+					Link:      fmt.Sprintf("https://example.com/%d/%d", w, i),
+					ShortName: fmt.Sprintf("source-%d-%d", w, i),
+				})
+				require.NoError(t, err)
+
+				src.Name += " (updated)"
+				_, err = storage.Update(ctx, src.Id, src)
+				assert.NoError(t, err)
+				assert.NoError(t, storage.UpdateValidators(ctx, src.Id, `"v1"`, ""))
+				if i%2 == 0 {
+					assert.NoError(t, storage.Delete(ctx, src.Id))
+				}
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				if src, err := storage.GetByShortName(ctx, fmt.Sprintf("source-%d-%d", w, i)); err == nil {
+					assert.Equal(t, fmt.Sprintf("source-%d-%d", w, i), src.ShortName)
+				}
+				_, _ = storage.GetByID(ctx, w*stressIterations+i)
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				_, err := storage.GetAll(ctx)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sources, err := storage.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, sources, stressWorkers*stressIterations/2)
+	for _, src := range sources {
+		assert.Equal(t, `"v1"`, src.ETag)
+		got, err := storage.GetByShortName(ctx, src.ShortName)
+		assert.NoError(t, err)
+		assert.Equal(t, src, got)
+	}
+}
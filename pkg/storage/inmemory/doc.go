@@ -3,5 +3,11 @@
 // transient storage solution is required. It is suitable for testing,
 // development, and applications that do not require persistent storage.
 //
-// Note: As this package stores data in memory, all stored data will be lost when the application is stopped.
+// The storages are safe for concurrent use by the web handlers and the scheduler,
+// and index their records, so lookups and duplicate checks don't scan all of them.
+// A unit of work keeps the records it changes as they were, and restores only those if the work fails,
+// unless other writers changed them since.
+//
+// Note: As this package stores data in memory, all stored data will be lost when the application is stopped,
+// unless the changes are recorded in a journal set by SetJournal, which a Replayer applies back.
 package inmemory
@@ -0,0 +1,21 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"time"
+)
+
+// fetchLocker is the no-op implementation of the FetchLocker interface.
+// The in-memory storages aren't shared, so there are no other instances to coordinate with.
+type fetchLocker struct{}
+
+// NewFetchLocker creates a new FetchLocker granting every lease.
+func NewFetchLocker() service.FetchLocker {
+	return fetchLocker{}
+}
+
+// Acquire always grants the lease.
+func (fetchLocker) Acquire(ctx context.Context, sourceID int, ttl time.Duration) (bool, error) {
+	return true, nil
+}
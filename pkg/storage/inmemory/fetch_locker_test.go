@@ -0,0 +1,18 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFetchLocker_Acquire(t *testing.T) {
+	// Every instance gets the lease, as none shares its storages with another
+	for _, locker := range []service.FetchLocker{NewFetchLocker(), NewFetchLocker()} {
+		acquired, err := locker.Acquire(context.Background(), 1, time.Hour)
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+	}
+}
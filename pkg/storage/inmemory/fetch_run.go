@@ -0,0 +1,140 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryFetchRunStorage represents an in-memory storage for the fetch history.
+// It's safe for concurrent use: the runs are guarded by mu and kept in the order they were saved,
+// and their attempts are indexed by source in the same order.
+type memoryFetchRunStorage struct {
+	mu            sync.RWMutex
+	runs          []model.FetchRun
+	bySource      map[int][]model.FetchAttempt
+	nextRunID     int
+	nextAttemptID int
+}
+
+// NewFetchRunStorage creates a new instance of the in-memory fetch run storage.
+func NewFetchRunStorage() service.FetchRunStorage {
+	return &memoryFetchRunStorage{bySource: map[int][]model.FetchAttempt{}, nextRunID: 1, nextAttemptID: 1}
+}
+
+// Save stores the run and its attempts, assigning them their IDs.
+func (m *memoryFetchRunStorage) Save(ctx context.Context, run model.FetchRun) (model.FetchRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run.Id = m.nextRunID
+	m.nextRunID++
+	run.Attempts = append([]model.FetchAttempt{}, run.Attempts...)
+	for i := range run.Attempts {
+		run.Attempts[i].Id = m.nextAttemptID
+		run.Attempts[i].RunId = run.Id
+		m.nextAttemptID++
+		m.bySource[run.Attempts[i].SourceId] = append(m.bySource[run.Attempts[i].SourceId], run.Attempts[i])
+	}
+	m.runs = append(m.runs, run)
+	return run, nil
+}
+
+// GetAll returns up to limit runs with their attempts, newest first.
+func (m *memoryFetchRunStorage) GetAll(ctx context.Context, limit int) ([]model.FetchRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	runs := make([]model.FetchRun, 0, min(limit, len(m.runs)))
+	for i := len(m.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		run := m.runs[i]
+		run.Attempts = append([]model.FetchAttempt{}, run.Attempts...)
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// GetAttemptsBySourceID returns up to limit attempts of the source, newest first.
+func (m *memoryFetchRunStorage) GetAttemptsBySourceID(ctx context.Context, sourceID int, limit int) ([]model.FetchAttempt, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	saved := m.bySource[sourceID]
+	attempts := make([]model.FetchAttempt, 0, min(limit, len(saved)))
+	for i := len(saved) - 1; i >= 0 && len(attempts) < limit; i-- {
+		attempts = append(attempts, saved[i])
+	}
+	return attempts, nil
+}
+
+// DeleteBefore deletes the runs started before the cutoff along with their attempts.
+func (m *memoryFetchRunStorage) DeleteBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.delete(func(run model.FetchRun) bool { return run.StartedAt.Before(cutoff) }), nil
+}
+
+// DeleteBeyond deletes all but the newest maxRuns runs along with their attempts.
+func (m *memoryFetchRunStorage) DeleteBeyond(ctx context.Context, maxRuns int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.runs) <= maxRuns {
+		return 0, nil
+	}
+	// The runs are newest by the time they started, as the other storages order them
+	newest := make([]model.FetchRun, len(m.runs))
+	copy(newest, m.runs)
+	sort.Slice(newest, func(i, j int) bool {
+		if !newest[i].StartedAt.Equal(newest[j].StartedAt) {
+			return newest[i].StartedAt.After(newest[j].StartedAt)
+		}
+		return newest[i].Id > newest[j].Id
+	})
+	kept := make(map[int]struct{}, maxRuns)
+	for _, run := range newest[:maxRuns] {
+		kept[run.Id] = struct{}{}
+	}
+	return m.delete(func(run model.FetchRun) bool {
+		_, ok := kept[run.Id]
+		return !ok
+	}), nil
+}
+
+// delete deletes the runs matching the predicate along with their attempts and returns their number.
+// The caller must hold the write lock.
+func (m *memoryFetchRunStorage) delete(deleted func(model.FetchRun) bool) int {
+	kept := m.runs[:0]
+	deletedIDs := map[int]struct{}{}
+	for _, run := range m.runs {
+		if deleted(run) {
+			deletedIDs[run.Id] = struct{}{}
+			continue
+		}
+		kept = append(kept, run)
+	}
+	clear(m.runs[len(kept):])
+	m.runs = kept
+	if len(deletedIDs) == 0 {
+		return 0
+	}
+
+	for sourceID, attempts := range m.bySource {
+		keptAttempts := attempts[:0]
+		for _, a := range attempts {
+			if _, ok := deletedIDs[a.RunId]; !ok {
+				keptAttempts = append(keptAttempts, a)
+			}
+		}
+		if len(keptAttempts) == 0 {
+			delete(m.bySource, sourceID)
+			continue
+		}
+		m.bySource[sourceID] = keptAttempts
+	}
+	return len(deletedIDs)
+}
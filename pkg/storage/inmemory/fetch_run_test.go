@@ -0,0 +1,67 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_memoryFetchRunStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFetchRunStorage()
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	old, err := storage.Save(ctx, model.FetchRun{StartedAt: start, Sources: 1,
+		Attempts: []model.FetchAttempt{{SourceId: 1, Status: model.FetchFailed}}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, old.Id)
+	assert.Equal(t, model.FetchAttempt{Id: 1, RunId: 1, SourceId: 1, Status: model.FetchFailed}, old.Attempts[0])
+
+	recent, err := storage.Save(ctx, model.FetchRun{StartedAt: start.Add(time.Hour), Sources: 2,
+		Attempts: []model.FetchAttempt{{SourceId: 1, Status: model.FetchOK}, {SourceId: 2, Status: model.FetchOK}}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, recent.Id)
+
+	runs, err := storage.GetAll(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []model.FetchRun{recent, old}, runs)
+	runs, err = storage.GetAll(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []model.FetchRun{recent}, runs)
+
+	attempts, err := storage.GetAttemptsBySourceID(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []model.FetchAttempt{recent.Attempts[0], old.Attempts[0]}, attempts)
+	attempts, err = storage.GetAttemptsBySourceID(ctx, 3, 10)
+	require.NoError(t, err)
+	assert.Empty(t, attempts)
+
+	deleted, err := storage.DeleteBefore(ctx, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	runs, err = storage.GetAll(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []model.FetchRun{recent}, runs)
+	attempts, err = storage.GetAttemptsBySourceID(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []model.FetchAttempt{recent.Attempts[0]}, attempts, "the attempts are deleted along with their run")
+
+	newest, err := storage.Save(ctx, model.FetchRun{StartedAt: start.Add(2 * time.Hour), Sources: 1,
+		Attempts: []model.FetchAttempt{{SourceId: 2, Status: model.FetchOK}}})
+	require.NoError(t, err)
+	deleted, err = storage.DeleteBeyond(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	runs, err = storage.GetAll(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []model.FetchRun{newest}, runs)
+	attempts, err = storage.GetAttemptsBySourceID(ctx, 2, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []model.FetchAttempt{newest.Attempts[0]}, attempts)
+	deleted, err = storage.DeleteBeyond(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
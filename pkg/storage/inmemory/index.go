@@ -0,0 +1,58 @@
+package inmemory
+
+import (
+	"slices"
+	"sort"
+)
+
+// idSet is a set of IDs, the value of the indexes mapping a key to many records.
+type idSet map[int]struct{}
+
+// addToIndex adds the ID to the set of the key in the index.
+func addToIndex[K comparable](index map[K]idSet, key K, id int) {
+	ids, ok := index[key]
+	if !ok {
+		ids = idSet{}
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+// removeFromIndex removes the ID from the set of the key in the index,
+// dropping the key once its set is empty.
+func removeFromIndex[K comparable](index map[K]idSet, key K, id int) {
+	ids := index[key]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(index, key)
+	}
+}
+
+// sortedIDs returns the keys of the map in ascending order, which is the order the records were saved in.
+func sortedIDs[V any](records map[int]V) []int {
+	ids := make([]int, 0, len(records))
+	for id := range records {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// insertID inserts the ID into the ascending IDs, which is an append for a newly saved record.
+func insertID(ids []int, id int) []int {
+	if len(ids) == 0 || ids[len(ids)-1] < id {
+		return append(ids, id)
+	}
+	if i, found := slices.BinarySearch(ids, id); !found {
+		return slices.Insert(ids, i, id)
+	}
+	return ids
+}
+
+// deleteID deletes the ID from the ascending IDs.
+func deleteID(ids []int, id int) []int {
+	if i, found := slices.BinarySearch(ids, id); found {
+		return slices.Delete(ids, i, i+1)
+	}
+	return ids
+}
@@ -0,0 +1,144 @@
+package inmemory
+
+import (
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const eventJournalError = "journal_error"
+
+// errNotInMemory is returned when a storage passed for the in-memory ones is of another type.
+var errNotInMemory = errors.New("not an in-memory storage")
+
+// Journal records the changes of the in-memory storages, so they can be replayed after a crash.
+type Journal interface {
+	Append(changes ...model.Change) error
+}
+
+// changeLog passes the changes of the storages sharing it to their journal.
+type changeLog struct {
+	journal Journal
+	now     func() time.Time
+}
+
+// record stamps the change with the current time and appends it to the journal.
+// A nil changeLog records nothing. The journal errors are only logged, as the change is already made.
+func (l *changeLog) record(change model.Change) {
+	if l == nil {
+		return
+	}
+	change.At = l.now().UTC()
+	if err := l.journal.Append(change); err != nil {
+		logrus.WithField("event_id", eventJournalError).Errorf("Error journaling the %s change of %d: %v",
+			change.Kind, change.Id, err)
+	}
+}
+
+// SetJournal records the changes of the in-memory storages in the journal from now on.
+// It must be called before the storages are used, and after they are restored,
+// so the replayed changes aren't recorded again.
+func SetJournal(journal Journal, articles service.ArticleStorage, sources service.SourceStorage) error {
+	a, s, err := memoryStorages(articles, sources)
+	if err != nil {
+		return err
+	}
+	log := &changeLog{journal: journal, now: time.Now}
+	a.mu.Lock()
+	a.log = log
+	a.mu.Unlock()
+	s.mu.Lock()
+	s.log = log
+	s.mu.Unlock()
+	return nil
+}
+
+// Replayer applies the changes of a journal to the in-memory storages, keeping the IDs of the articles and sources.
+type Replayer struct {
+	articles *memoryArticleStorage
+	sources  *memorySourceStorage
+}
+
+// NewReplayer creates a new Replayer applying the changes to the given in-memory storages.
+func NewReplayer(articles service.ArticleStorage, sources service.SourceStorage) (*Replayer, error) {
+	a, s, err := memoryStorages(articles, sources)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{articles: a, sources: s}, nil
+}
+
+// Apply applies the change as it was made: the saved article or source replaces the one with the same ID,
+// and deleting one that isn't stored does nothing, so the changes can be applied more than once.
+func (r *Replayer) Apply(change model.Change) error {
+	switch change.Kind {
+	case model.ChangeSaveArticle:
+		if change.Article == nil {
+			return errors.New("save_article change without the article")
+		}
+		r.articles.apply(*change.Article, false)
+	case model.ChangeDeleteArticle:
+		r.articles.apply(model.Article{Id: change.Id}, true)
+	case model.ChangeSaveSource:
+		if change.Source == nil {
+			return errors.New("save_source change without the source")
+		}
+		r.sources.apply(*change.Source, false)
+	case model.ChangeDeleteSource:
+		r.sources.apply(model.Source{Id: change.Id}, true)
+	default:
+		return errors.New("unknown change: " + change.Kind)
+	}
+	return nil
+}
+
+// apply stores the article with its ID, replacing the stored one, or deletes the stored one.
+// The IDs handed out afterward follow the applied ones.
+func (a *memoryArticleStorage) apply(article model.Article, deleted bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if saved, ok := a.articles[article.Id]; ok {
+		a.remove(saved)
+	}
+	if deleted {
+		return
+	}
+	// The canonical link and fingerprint aren't recorded, they follow from the article
+	article.CanonicalLink = dedup.CanonicalURL(article.Link)
+	article.Fingerprint = dedup.Fingerprint(article.Title, article.Description)
+	a.put(article)
+	a.nextID = max(a.nextID, article.Id+1)
+}
+
+// apply stores the source with its ID, replacing the stored one, or deletes the stored one.
+// The IDs handed out afterward follow the applied ones.
+func (m *memorySourceStorage) apply(src model.Source, deleted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if saved, ok := m.sources[src.Id]; ok {
+		m.remove(saved)
+	}
+	if deleted {
+		return
+	}
+	m.put(src)
+	m.nextID = max(m.nextID, src.Id+1)
+}
+
+// memoryStorages returns the in-memory storages behind the interfaces.
+func memoryStorages(articles service.ArticleStorage, sources service.SourceStorage) (*memoryArticleStorage, *memorySourceStorage, error) {
+	a, ok := articles.(*memoryArticleStorage)
+	if !ok {
+		return nil, nil, errNotInMemory
+	}
+	s, ok := sources.(*memorySourceStorage)
+	if !ok {
+		return nil, nil, errNotInMemory
+	}
+	return a, s, nil
+}
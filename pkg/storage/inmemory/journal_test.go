@@ -0,0 +1,131 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeJournal keeps the changes appended to it.
+type fakeJournal struct {
+	changes []model.Change
+}
+
+func (j *fakeJournal) Append(changes ...model.Change) error {
+	j.changes = append(j.changes, changes...)
+	return nil
+}
+
+// kinds returns the kinds and IDs of the changes, in order.
+func (j *fakeJournal) kinds() []string {
+	var kinds []string
+	for _, change := range j.changes {
+		kinds = append(kinds, change.Kind+" "+strconv.Itoa(change.Id))
+	}
+	return kinds
+}
+
+func TestSetJournal(t *testing.T) {
+	ctx := context.Background()
+	articles, sources := New(), NewSrc()
+	journal := &fakeJournal{}
+	require.NoError(t, SetJournal(journal, articles, sources))
+
+	bbc, err := sources.Save(ctx, model.Source{Name: "BBC News", Link: "https://bbc.com/rss", ShortName: "bbc"})
+	require.NoError(t, err)
+	_, err = articles.SaveAll(ctx, []model.Article{
+		{Title: "Trial starts", Link: "https://bbc.com/trial", Source: bbc},
+		{Title: "Go 1.23 is released", Link: "https://bbc.com/golang", Source: bbc},
+	})
+	require.NoError(t, err)
+	require.NoError(t, articles.Delete(ctx, 1))
+	assert.Equal(t, []string{"save_source 1", "save_article 1", "save_article 2", "delete_article 1"}, journal.kinds())
+	assert.Equal(t, "Go 1.23 is released", journal.changes[2].Article.Title)
+	assert.False(t, journal.changes[0].At.IsZero())
+
+	// The changes of a failing unit of work are journaled as they're made, and undone by journaling the restored records
+	journal.changes = nil
+	uow := NewUnitOfWork(articles, sources)
+	err = uow.Do(ctx, func(articles service.ArticleStorage, sources service.SourceStorage) error {
+		if err := articles.DeleteBySourceID(ctx, bbc.Id); err != nil {
+			return err
+		}
+		return errors.New("storage error")
+	})
+	assert.EqualError(t, err, "storage error")
+	assert.Equal(t, []string{"delete_article 2", "save_article 2"}, journal.kinds())
+	assert.Equal(t, "Go 1.23 is released", journal.changes[1].Article.Title)
+
+	journal.changes = nil
+	err = uow.Do(ctx, func(articles service.ArticleStorage, sources service.SourceStorage) error {
+		if err := articles.DeleteBySourceID(ctx, bbc.Id); err != nil {
+			return err
+		}
+		return sources.Delete(ctx, bbc.Id)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"delete_article 2", "delete_source 1"}, journal.kinds())
+
+	assert.ErrorIs(t, SetJournal(journal, nil, sources), errNotInMemory)
+}
+
+func TestReplayer_Apply(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	bbc := model.Source{Id: 3, Name: "BBC News", Link: "https://bbc.com/rss", ShortName: "bbc"}
+	trial := model.Article{Id: 5, Title: "Trial starts", Link: "https://bbc.com/trial?utm_source=rss", Source: bbc}
+	changes := []model.Change{
+		{At: at, Kind: model.ChangeSaveSource, Id: bbc.Id, Source: &bbc},
+		{At: at, Kind: model.ChangeSaveArticle, Id: trial.Id, Article: &trial},
+		{At: at, Kind: model.ChangeSaveArticle, Id: 7, Article: &model.Article{Id: 7, Title: "Heatstroke", Link: "https://bbc.com/heat", Source: bbc}},
+		{At: at, Kind: model.ChangeDeleteArticle, Id: 7},
+	}
+
+	articles, sources := New(), NewSrc()
+	r, err := NewReplayer(articles, sources)
+	require.NoError(t, err)
+	// Applying the changes twice leaves the same state as applying them once
+	for i := 0; i < 2; i++ {
+		for _, change := range changes {
+			require.NoError(t, r.Apply(change))
+		}
+	}
+
+	saved, err := articles.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, trial.Id, saved[0].Id, "the IDs are kept")
+	assert.Equal(t, "https://bbc.com/trial", saved[0].CanonicalLink)
+	src, err := sources.GetByShortName(ctx, "bbc")
+	require.NoError(t, err)
+	assert.Equal(t, bbc, src)
+
+	// The IDs handed out afterward follow the applied ones
+	article, err := articles.Save(ctx, model.Article{Title: "Go 1.23 is released", Link: "https://bbc.com/golang", Source: bbc})
+	require.NoError(t, err)
+	assert.Equal(t, 8, article.Id)
+	src, err = sources.Save(ctx, model.Source{Name: "CNN", Link: "https://cnn.com/rss", ShortName: "cnn"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, src.Id)
+
+	tests := []struct {
+		name    string
+		change  model.Change
+		wantErr string
+	}{
+		{name: "article missing", change: model.Change{Kind: model.ChangeSaveArticle, Id: 1}, wantErr: "save_article change without the article"},
+		{name: "source missing", change: model.Change{Kind: model.ChangeSaveSource, Id: 1}, wantErr: "save_source change without the source"},
+		{name: "unknown kind", change: model.Change{Kind: "rename"}, wantErr: "unknown change: rename"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, r.Apply(tt.change), tt.wantErr)
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package inmemory
 
 import (
+	"context"
 	"errors"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/antonchaban/news-aggregator/pkg/service"
 	"github.com/sirupsen/logrus"
+	"sync"
 )
 
 const (
@@ -25,41 +27,76 @@ const (
 	eventUpdateSource             = "update_source"
 	eventSourceUpdated            = "source_updated"
 	eventUpdateSourceError        = "update_source_error"
+	eventUpdateValidators         = "update_source_validators"
+	eventUpdateHealth             = "update_source_health"
 )
 
+// errSourceExists is returned when a source with the same link is already saved.
+var errSourceExists = errors.New("source already exists")
+
 // memorySourceStorage represents an in-memory storage for sources.
+// It's safe for concurrent use: the sources are guarded by mu and indexed
+// by their link and short name besides their ID.
+// The storage a unit of work runs with shares the sources, keeping the ones it changes in undo.
 type memorySourceStorage struct {
-	Sources []model.Source
-	nextID  int
+	*sourceRecords
+	// undo keeps the sources as they were before the unit of work changed them, nil outside of one
+	undo *undoLog
+}
+
+// sourceRecords holds the sources and their indexes.
+type sourceRecords struct {
+	mu          sync.RWMutex
+	sources     map[int]model.Source
+	byLink      map[string]int
+	byShortName map[string]int
+	nextID      int
+	// versions are the versions of the sources, stamped anew whenever one changes
+	versions map[int]uint64
+	version  uint64
+	// log records the changes in the journal set by SetJournal, if any
+	log *changeLog
 }
 
 // NewSrc creates a new instance of the in-memory source storage.
 func NewSrc() service.SourceStorage {
 	logrus.WithField("event_id", eventSourceStorageInitialized).Info("Initializing Source Storage")
-	return &memorySourceStorage{
-		Sources: []model.Source{},
-		nextID:  1, // Initializing IDs for in-memory storage, and then auto-incrementing it after saving a source
-	}
+	return &memorySourceStorage{sourceRecords: &sourceRecords{
+		sources:     map[int]model.Source{},
+		byLink:      map[string]int{},
+		byShortName: map[string]int{},
+		versions:    map[int]uint64{},
+		nextID:      1, // Initializing IDs for in-memory storage, and then auto-incrementing it after saving a source
+	}}
 }
 
-// GetAll returns all sources from the in-memory storage.
-func (m *memorySourceStorage) GetAll() ([]model.Source, error) {
+// GetAll returns all sources from the in-memory storage in the order they were saved.
+func (m *memorySourceStorage) GetAll(ctx context.Context) ([]model.Source, error) {
 	logrus.WithField("event_id", eventGetAllSources).Info("Fetching all sources")
-	return m.Sources, nil
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sources := make([]model.Source, 0, len(m.sources))
+	for _, id := range sortedIDs(m.sources) {
+		sources = append(sources, m.sources[id])
+	}
+	return sources, nil
 }
 
 // Save saves a new source to the in-memory storage, if it is not a duplicate.
-func (m *memorySourceStorage) Save(src model.Source) (model.Source, error) {
+func (m *memorySourceStorage) Save(ctx context.Context, src model.Source) (model.Source, error) {
 	logrus.WithField("event_id", eventSaveSource).Info("Saving new source", src.Link)
-	for _, s := range m.Sources {
-		if s.Link == src.Link {
-			logrus.WithField("event_id", eventSaveSourceError).Error("Source already exists", src.Link)
-			return model.Source{}, errors.New("source already exists")
-		}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byLink[src.Link]; ok {
+		logrus.WithField("event_id", eventSaveSourceError).Error("Source already exists", src.Link)
+		return model.Source{}, errSourceExists
 	}
 	src.Id = m.nextID
 	m.nextID++
-	m.Sources = append(m.Sources, src)
+	m.put(src)
+	m.log.record(model.Change{Kind: model.ChangeSaveSource, Id: src.Id, Source: &src})
 	logrus.WithFields(logrus.Fields{
 		"event_id":  eventSourceSaved,
 		"source_id": src.Id,
@@ -68,12 +105,12 @@ func (m *memorySourceStorage) Save(src model.Source) (model.Source, error) {
 }
 
 // SaveAll saves multiple sources to the in-memory storage.
-func (m *memorySourceStorage) SaveAll(sources []model.Source) error {
+func (m *memorySourceStorage) SaveAll(ctx context.Context, sources []model.Source) error {
 	logrus.WithField("event_id", eventSaveAllSources).Info("Saving multiple sources")
 	for _, src := range sources {
-		_, err := m.Save(src)
+		_, err := m.Save(ctx, src)
 		if err != nil {
-			if errors.Is(err, errors.New("source already exists")) {
+			if errors.Is(err, errSourceExists) {
 				logrus.WithField("event_id", eventSaveAllSourcesSkip).Warn("Source already exists, skipping", src.Link)
 				continue
 			}
@@ -86,52 +123,137 @@ func (m *memorySourceStorage) SaveAll(sources []model.Source) error {
 }
 
 // Delete removes a source from the in-memory storage by its ID.
-func (m *memorySourceStorage) Delete(id int) error {
+func (m *memorySourceStorage) Delete(ctx context.Context, id int) error {
 	logrus.WithField("event_id", eventDeleteSource).Info("Deleting source", id)
-	for i, s := range m.Sources {
-		if s.Id == id {
-			m.Sources = append(m.Sources[:i], m.Sources[i+1:]...)
-			logrus.WithField("event_id", eventSourceDeleted).Info("Source deleted successfully", id)
-			return nil
-		}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	src, ok := m.sources[id]
+	if !ok {
+		logrus.WithField("event_id", eventDeleteSourceError).Error("Source not found", id)
+		return errors.New("source not found")
 	}
-	logrus.WithField("event_id", eventDeleteSourceError).Error("Source not found", id)
-	return errors.New("source not found")
+	m.remove(src)
+	m.log.record(model.Change{Kind: model.ChangeDeleteSource, Id: id})
+	logrus.WithField("event_id", eventSourceDeleted).Info("Source deleted successfully", id)
+	return nil
 }
 
 // GetByID retrieves a source from the in-memory storage by its ID.
-func (m *memorySourceStorage) GetByID(id int) (model.Source, error) {
+func (m *memorySourceStorage) GetByID(ctx context.Context, id int) (model.Source, error) {
 	logrus.WithField("event_id", eventGetSourceByID).Info("Fetching source by ID", id)
-	for _, s := range m.Sources {
-		if s.Id == id {
-			return s, nil
-		}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if src, ok := m.sources[id]; ok {
+		return src, nil
 	}
 	logrus.WithField("event_id", eventGetSourceByIDError).Error("Source not found", id)
 	return model.Source{}, errors.New("source not found")
 }
 
 // Update updates a source in the in-memory storage by its ID.
-func (m *memorySourceStorage) Update(id int, src model.Source) (model.Source, error) {
+// The validators of the source are kept, unless its link changes.
+func (m *memorySourceStorage) Update(ctx context.Context, id int, src model.Source) (model.Source, error) {
 	logrus.WithField("event_id", eventUpdateSource).Info("Updating source", id)
-	for i, s := range m.Sources {
-		if s.Id == id {
-			m.Sources[i] = src
-			logrus.WithField("event_id", eventSourceUpdated).Info("Source updated successfully", id)
-			return src, nil
-		}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sources[id]
+	if !ok {
+		logrus.WithField("event_id", eventUpdateSourceError).Error("Source not found", id)
+		return model.Source{}, errors.New("source not found")
 	}
-	logrus.WithField("event_id", eventUpdateSourceError).Error("Source not found", id)
-	return model.Source{}, errors.New("source not found")
+	src.ETag, src.LastModified = "", ""
+	if s.Link == src.Link {
+		src.ETag, src.LastModified = s.ETag, s.LastModified
+	}
+	src.Id = id
+	src.Health = nil
+	m.remove(s)
+	m.put(src)
+	m.log.record(model.Change{Kind: model.ChangeSaveSource, Id: id, Source: &src})
+	logrus.WithField("event_id", eventSourceUpdated).Info("Source updated successfully", id)
+	return src, nil
 }
 
-func (m *memorySourceStorage) GetByShortName(shortName string) (model.Source, error) {
+func (m *memorySourceStorage) GetByShortName(ctx context.Context, shortName string) (model.Source, error) {
 	logrus.WithField("event_id", eventGetSourceByID).Info("Fetching source by short name", shortName)
-	for _, s := range m.Sources {
-		if s.ShortName == shortName {
-			return s, nil
-		}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if id, ok := m.byShortName[shortName]; ok {
+		return m.sources[id], nil
 	}
 	logrus.WithField("event_id", eventGetSourceByIDError).Error("Source not found", shortName)
 	return model.Source{}, errors.New("source not found")
 }
+
+// UpdateValidators stores the ETag and Last-Modified validators of the latest fetch of the source.
+func (m *memorySourceStorage) UpdateValidators(ctx context.Context, id int, etag, lastModified string) error {
+	logrus.WithField("event_id", eventUpdateValidators).Info("Updating source validators", id)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	src, ok := m.sources[id]
+	if !ok {
+		logrus.WithField("event_id", eventUpdateSourceError).Error("Source not found", id)
+		return errors.New("source not found")
+	}
+	src.ETag = etag
+	src.LastModified = lastModified
+	m.keep(id)
+	m.sources[id] = src
+	m.changed(id)
+	m.log.record(model.Change{Kind: model.ChangeSaveSource, Id: id, Source: &src})
+	return nil
+}
+
+// UpdateHealth stores the health of the source after its latest fetch, unless its stored health
+// is no longer the previous one.
+func (m *memorySourceStorage) UpdateHealth(ctx context.Context, id int, previous *model.SourceHealth,
+	health model.SourceHealth) (bool, error) {
+	logrus.WithField("event_id", eventUpdateHealth).Info("Updating source health", id)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	src, ok := m.sources[id]
+	if !ok {
+		logrus.WithField("event_id", eventUpdateSourceError).Error("Source not found", id)
+		return false, errors.New("source not found")
+	}
+	if !src.Health.Equal(previous) {
+		return false, nil
+	}
+	src.Health = &health
+	m.keep(id)
+	m.sources[id] = src
+	m.changed(id)
+	m.log.record(model.Change{Kind: model.ChangeSaveSource, Id: id, Source: &src})
+	return true, nil
+}
+
+// put stores the source and indexes it, the caller must hold the write lock.
+func (m *memorySourceStorage) put(src model.Source) {
+	m.keep(src.Id)
+	m.sources[src.Id] = src
+	m.byLink[src.Link] = src.Id
+	if src.ShortName != "" {
+		m.byShortName[src.ShortName] = src.Id
+	}
+	m.changed(src.Id)
+}
+
+// remove removes the source and its index entries, the caller must hold the write lock.
+// The index entries of other sources with the same link or short name are kept.
+func (m *memorySourceStorage) remove(src model.Source) {
+	m.keep(src.Id)
+	delete(m.sources, src.Id)
+	if m.byLink[src.Link] == src.Id {
+		delete(m.byLink, src.Link)
+	}
+	if m.byShortName[src.ShortName] == src.Id {
+		delete(m.byShortName, src.ShortName)
+	}
+	m.changed(src.Id)
+}
@@ -1,6 +1,7 @@
 package inmemory
 
 import (
+	"context"
 	"fmt"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/antonchaban/news-aggregator/pkg/service"
@@ -15,10 +16,7 @@ func TestNewSrc(t *testing.T) {
 	}{
 		{
 			name: "initialize in-memory storage",
-			want: &memorySourceStorage{
-				Sources: []model.Source{},
-				nextID:  1,
-			},
+			want: newSrcStorage(nil, 1),
 		},
 	}
 
@@ -71,11 +69,8 @@ func Test_memorySourceStorage_Delete(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &memorySourceStorage{
-				Sources: tt.fields.Sources,
-				nextID:  tt.fields.nextID,
-			}
-			tt.wantErr(t, m.Delete(tt.args.id), fmt.Sprintf("Delete(%v)", tt.args.id))
+			m := newSrcStorage(tt.fields.Sources, tt.fields.nextID)
+			tt.wantErr(t, m.Delete(context.Background(), tt.args.id), fmt.Sprintf("Delete(%v)", tt.args.id))
 		})
 	}
 }
@@ -121,11 +116,8 @@ func Test_memorySourceStorage_GetAll(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &memorySourceStorage{
-				Sources: tt.fields.Sources,
-				nextID:  tt.fields.nextID,
-			}
-			got, err := m.GetAll()
+			m := newSrcStorage(tt.fields.Sources, tt.fields.nextID)
+			got, err := m.GetAll(context.Background())
 			if !tt.wantErr(t, err, "GetAll()") {
 				return
 			}
@@ -179,11 +171,8 @@ func Test_memorySourceStorage_GetByID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &memorySourceStorage{
-				Sources: tt.fields.Sources,
-				nextID:  tt.fields.nextID,
-			}
-			got, err := m.GetByID(tt.args.id)
+			m := newSrcStorage(tt.fields.Sources, tt.fields.nextID)
+			got, err := m.GetByID(context.Background(), tt.args.id)
 			if !tt.wantErr(t, err, fmt.Sprintf("GetByID(%v)", tt.args.id)) {
 				return
 			}
@@ -237,11 +226,8 @@ func Test_memorySourceStorage_Save(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &memorySourceStorage{
-				Sources: tt.fields.Sources,
-				nextID:  tt.fields.nextID,
-			}
-			got, err := m.Save(tt.args.src)
+			m := newSrcStorage(tt.fields.Sources, tt.fields.nextID)
+			got, err := m.Save(context.Background(), tt.args.src)
 			if !tt.wantErr(t, err, fmt.Sprintf("Save(%v)", tt.args.src)) {
 				return
 			}
@@ -282,11 +268,8 @@ func Test_memorySourceStorage_SaveAll(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &memorySourceStorage{
-				Sources: tt.fields.Sources,
-				nextID:  tt.fields.nextID,
-			}
-			tt.wantErr(t, m.SaveAll(tt.args.sources), fmt.Sprintf("SaveAll(%v)", tt.args.sources))
+			m := newSrcStorage(tt.fields.Sources, tt.fields.nextID)
+			tt.wantErr(t, m.SaveAll(context.Background(), tt.args.sources), fmt.Sprintf("SaveAll(%v)", tt.args.sources))
 		})
 	}
 }
@@ -323,14 +306,43 @@ func Test_memorySourceStorage_Update(t *testing.T) {
 				return assert.NoError(t, err)
 			},
 		},
+		{
+			name: "validators are kept while the link is the same",
+			fields: fields{
+				Sources: []model.Source{
+					{Id: 1, Name: "Example", Link: "http://example.com", ETag: `"v1"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"},
+				},
+			},
+			args: args{
+				id:  1,
+				src: model.Source{Id: 1, Name: "Example News", Link: "http://example.com"},
+			},
+			want: model.Source{Id: 1, Name: "Example News", Link: "http://example.com", ETag: `"v1"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.NoError(t, err)
+			},
+		},
+		{
+			name: "validators are reset when the link changes",
+			fields: fields{
+				Sources: []model.Source{
+					{Id: 1, Link: "http://example.com", ETag: `"v1"`},
+				},
+			},
+			args: args{
+				id:  1,
+				src: model.Source{Id: 1, Link: "http://example.org", ETag: `"v1"`},
+			},
+			want: model.Source{Id: 1, Link: "http://example.org"},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.NoError(t, err)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := &memorySourceStorage{
-				Sources: tt.fields.Sources,
-				nextID:  tt.fields.nextID,
-			}
-			got, err := m.Update(tt.args.id, tt.args.src)
+			m := newSrcStorage(tt.fields.Sources, tt.fields.nextID)
+			got, err := m.Update(context.Background(), tt.args.id, tt.args.src)
 			if !tt.wantErr(t, err, fmt.Sprintf("Update(%v, %v)", tt.args.id, tt.args.src)) {
 				return
 			}
@@ -338,3 +350,48 @@ func Test_memorySourceStorage_Update(t *testing.T) {
 		})
 	}
 }
+
+func Test_memorySourceStorage_UpdateValidators(t *testing.T) {
+	m := newSrcStorage([]model.Source{{Id: 1, Link: "http://example.com"}}, 2)
+
+	err := m.UpdateValidators(context.Background(), 1, `"v1"`, "Mon, 02 Jan 2006 15:04:05 GMT")
+	assert.NoError(t, err)
+	assert.Equal(t, `"v1"`, m.sources[1].ETag)
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", m.sources[1].LastModified)
+
+	err = m.UpdateValidators(context.Background(), 2, `"v1"`, "")
+	assert.EqualError(t, err, "source not found")
+}
+
+// newSrcStorage creates a source storage holding the given sources.
+func newSrcStorage(sources []model.Source, nextID int) *memorySourceStorage {
+	m := NewSrc().(*memorySourceStorage)
+	for _, src := range sources {
+		m.put(src)
+	}
+	m.nextID = nextID
+	return m
+}
+
+func Test_memorySourceStorage_UpdateHealth(t *testing.T) {
+	m := newSrcStorage([]model.Source{{Id: 1, Link: "http://example.com"}}, 2)
+	health := model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 1, LastError: "404 Not Found"}
+
+	updated, err := m.UpdateHealth(context.Background(), 1, nil, health)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, &health, m.sources[1].Health)
+
+	_, err = m.Update(context.Background(), 1, model.Source{Link: "http://example.com"})
+	assert.NoError(t, err)
+	assert.Nil(t, m.sources[1].Health, "updating the source resets its health")
+
+	failed := model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 2, LastError: "404 Not Found"}
+	updated, err = m.UpdateHealth(context.Background(), 1, &health, failed)
+	assert.NoError(t, err)
+	assert.False(t, updated, "the health was reset since the fetch started")
+	assert.Nil(t, m.sources[1].Health)
+
+	_, err = m.UpdateHealth(context.Background(), 2, nil, health)
+	assert.EqualError(t, err, "source not found")
+}
@@ -0,0 +1,195 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"sync"
+)
+
+// unitOfWork is the implementation of the UnitOfWork interface for the in-memory storages.
+// The units of work are serialized by mu.
+type unitOfWork struct {
+	mu       sync.Mutex
+	articles service.ArticleStorage
+	sources  service.SourceStorage
+}
+
+// NewUnitOfWork creates a new UnitOfWork spanning the given in-memory article and source storages.
+func NewUnitOfWork(articles service.ArticleStorage, sources service.SourceStorage) service.UnitOfWork {
+	return &unitOfWork{articles: articles, sources: sources}
+}
+
+// undoLog keeps the articles and sources a unit of work changes as they were before its first change,
+// nil for the ones it created, along with the versions its latest changes left them at, 0 for the removed ones.
+// The articles are guarded by the lock of their storage, and the sources by theirs.
+type undoLog struct {
+	articles        map[int]*model.Article
+	articleVersions map[int]uint64
+	sources         map[int]*model.Source
+	sourceVersions  map[int]uint64
+}
+
+func newUndoLog() *undoLog {
+	return &undoLog{
+		articles:        map[int]*model.Article{},
+		articleVersions: map[int]uint64{},
+		sources:         map[int]*model.Source{},
+		sourceVersions:  map[int]uint64{},
+	}
+}
+
+// Do runs fn with the storages, undoing the changes fn made if it returns an error.
+// The changes are visible to the readers of the storages before fn returns, and only the articles
+// and sources fn changed are restored, so the changes made outside the unit of work are kept.
+// The writes made outside the unit of work aren't blocked while it runs, and win over its own:
+// the records changed outside since the unit of work last changed them aren't restored,
+// nor are the ones whose link or short name has been taken by another record since.
+// The changes are journaled as they're made, and the restored records are journaled again when undone.
+func (u *unitOfWork) Do(ctx context.Context, fn func(articles service.ArticleStorage, sources service.SourceStorage) error) error {
+	articles, sources, err := memoryStorages(u.articles, u.sources)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	undo := newUndoLog()
+	err = fn(&memoryArticleStorage{articleRecords: articles.articleRecords, undo: undo},
+		&memorySourceStorage{sourceRecords: sources.sourceRecords, undo: undo})
+	if err != nil {
+		articles.restore(undo.articles, undo.articleVersions)
+		sources.restore(undo.sources, undo.sourceVersions)
+	}
+	return err
+}
+
+// keep keeps the article with the given ID in the undo log, unless it's kept already.
+// The caller must hold the write lock.
+func (a *memoryArticleStorage) keep(id int) {
+	if a.undo == nil {
+		return
+	}
+	if _, ok := a.undo.articles[id]; ok {
+		return
+	}
+	var kept *model.Article
+	if article, ok := a.articles[id]; ok {
+		kept = &article
+	}
+	a.undo.articles[id] = kept
+}
+
+// keep keeps the source with the given ID in the undo log, unless it's kept already.
+// The caller must hold the write lock.
+func (m *memorySourceStorage) keep(id int) {
+	if m.undo == nil {
+		return
+	}
+	if _, ok := m.undo.sources[id]; ok {
+		return
+	}
+	var kept *model.Source
+	if src, ok := m.sources[id]; ok {
+		kept = &src
+	}
+	m.undo.sources[id] = kept
+}
+
+// changed stamps the article with the given ID with a new version after it changed, or drops its version
+// if it was removed, noting the version in the undo log of the unit of work. The caller must hold the write lock.
+func (a *memoryArticleStorage) changed(id int) {
+	var version uint64
+	if _, ok := a.articles[id]; ok {
+		a.version++
+		version = a.version
+		a.versions[id] = version
+	} else {
+		delete(a.versions, id)
+	}
+	if a.undo != nil {
+		a.undo.articleVersions[id] = version
+	}
+}
+
+// changed stamps the source with the given ID with a new version after it changed, or drops its version
+// if it was removed, noting the version in the undo log of the unit of work. The caller must hold the write lock.
+func (m *memorySourceStorage) changed(id int) {
+	var version uint64
+	if _, ok := m.sources[id]; ok {
+		m.version++
+		version = m.version
+		m.versions[id] = version
+	} else {
+		delete(m.versions, id)
+	}
+	if m.undo != nil {
+		m.undo.sourceVersions[id] = version
+	}
+}
+
+// restore restores the kept articles still at the versions the unit of work left them at, deleting
+// the ones kept as nil. A kept article whose canonical link another article has taken since isn't put back.
+// The IDs handed out since aren't reused.
+func (a *memoryArticleStorage) restore(kept map[int]*model.Article, versions map[int]uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// The changed articles are removed first, so the restored ones get their canonical links back
+	for id := range kept {
+		if a.versions[id] != versions[id] {
+			delete(kept, id)
+			continue
+		}
+		if article, ok := a.articles[id]; ok {
+			a.remove(article)
+			if kept[id] == nil {
+				a.log.record(model.Change{Kind: model.ChangeDeleteArticle, Id: id})
+			}
+		}
+	}
+	for id, article := range kept {
+		if article == nil {
+			continue
+		}
+		if owner, ok := a.byLink[article.CanonicalLink]; ok && owner != id {
+			continue
+		}
+		a.put(*article)
+		a.log.record(model.Change{Kind: model.ChangeSaveArticle, Id: id, Article: article})
+	}
+}
+
+// restore restores the kept sources still at the versions the unit of work left them at, deleting
+// the ones kept as nil. A kept source whose link or short name another source has taken since isn't put back.
+// The IDs handed out since aren't reused.
+func (m *memorySourceStorage) restore(kept map[int]*model.Source, versions map[int]uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id := range kept {
+		if m.versions[id] != versions[id] {
+			delete(kept, id)
+			continue
+		}
+		if src, ok := m.sources[id]; ok {
+			m.remove(src)
+			if kept[id] == nil {
+				m.log.record(model.Change{Kind: model.ChangeDeleteSource, Id: id})
+			}
+		}
+	}
+	for id, src := range kept {
+		if src == nil {
+			continue
+		}
+		if owner, ok := m.byLink[src.Link]; ok && owner != id {
+			continue
+		}
+		if owner, ok := m.byShortName[src.ShortName]; ok && src.ShortName != "" && owner != id {
+			continue
+		}
+		m.put(*src)
+		m.log.record(model.Change{Kind: model.ChangeSaveSource, Id: id, Source: src})
+	}
+}
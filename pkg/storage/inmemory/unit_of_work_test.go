@@ -0,0 +1,153 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestUnitOfWork_Do(t *testing.T) {
+	ctx := context.Background()
+	articles, sources := New(), NewSrc()
+	bbc, err := sources.Save(ctx, model.Source{Name: "BBC News", Link: "https://bbc.com/rss", ShortName: "bbc"})
+	require.NoError(t, err)
+	pubDate := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	_, err = articles.SaveAll(ctx, []model.Article{
+		{Title: "Trial starts", Link: "https://bbc.com/trial", Source: bbc, PubDate: pubDate},
+		{Title: "Go 1.23 is released", Link: "https://bbc.com/golang", Source: bbc, PubDate: pubDate},
+	})
+	require.NoError(t, err)
+	uow := NewUnitOfWork(articles, sources)
+
+	// A failing unit of work is rolled back
+	err = uow.Do(ctx, func(articles service.ArticleStorage, sources service.SourceStorage) error {
+		if _, err := articles.SaveAll(ctx, []model.Article{{Title: "Heatstroke", Link: "https://bbc.com/heat", Source: bbc}}); err != nil {
+			return err
+		}
+		if err := articles.DeleteBySourceID(ctx, bbc.Id); err != nil {
+			return err
+		}
+		if err := sources.Delete(ctx, bbc.Id); err != nil {
+			return err
+		}
+		return errors.New("storage error")
+	})
+	assert.EqualError(t, err, "storage error")
+	saved, err := articles.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, saved, 2)
+	assert.Equal(t, []int{1, 2}, []int{saved[0].Id, saved[1].Id})
+	page, err := articles.GetAfter(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 1, "the restored articles are paged through in order")
+	assert.Equal(t, 2, page[0].Id)
+	src, err := sources.GetByShortName(ctx, "bbc")
+	require.NoError(t, err)
+	assert.Equal(t, bbc, src)
+	expired, err := articles.GetExpired(ctx, bbc.Id, pubDate.Add(time.Hour), 0)
+	require.NoError(t, err)
+	assert.Len(t, expired, 2, "the indexes are restored along with the articles")
+
+	err = uow.Do(ctx, func(articles service.ArticleStorage, sources service.SourceStorage) error {
+		if err := articles.DeleteBySourceID(ctx, bbc.Id); err != nil {
+			return err
+		}
+		return sources.Delete(ctx, bbc.Id)
+	})
+	require.NoError(t, err)
+	saved, err = articles.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+	_, err = sources.GetByShortName(ctx, "bbc")
+	assert.Error(t, err)
+}
+
+func TestUnitOfWork_Do_KeepsOutsideChanges(t *testing.T) {
+	ctx := context.Background()
+	articles, sources := New(), NewSrc()
+	bbc, err := sources.Save(ctx, model.Source{Name: "BBC News", Link: "https://bbc.com/rss", ShortName: "bbc"})
+	require.NoError(t, err)
+	trial, err := articles.Save(ctx, model.Article{Title: "Trial starts", Link: "https://bbc.com/trial", Source: bbc})
+	require.NoError(t, err)
+
+	var outside model.Article
+	err = NewUnitOfWork(articles, sources).Do(ctx, func(txArticles service.ArticleStorage, txSources service.SourceStorage) error {
+		if _, err := txArticles.SaveAll(ctx, []model.Article{{Title: "Heatstroke", Link: "https://bbc.com/heat", Source: bbc}}); err != nil {
+			return err
+		}
+		if err := txSources.UpdateValidators(ctx, bbc.Id, `"v2"`, ""); err != nil {
+			return err
+		}
+		// Another request saves an article while the work runs
+		var err error
+		if outside, err = articles.Save(ctx, model.Article{Title: "Go 1.23 is released", Link: "https://bbc.com/golang", Source: bbc}); err != nil {
+			return err
+		}
+		return errors.New("storage error")
+	})
+	assert.EqualError(t, err, "storage error")
+
+	saved, err := articles.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Article{trial, outside}, saved, "only the articles of the unit of work are undone")
+	src, err := sources.GetByID(ctx, bbc.Id)
+	require.NoError(t, err)
+	assert.Equal(t, bbc, src, "the sources the unit of work changed are restored")
+}
+
+func TestUnitOfWork_Do_ConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	articles, sources := New(), NewSrc()
+	bbc, err := sources.Save(ctx, model.Source{Name: "BBC News", Link: "https://bbc.com/rss", ShortName: "bbc"})
+	require.NoError(t, err)
+	trial, err := articles.Save(ctx, model.Article{Title: "Trial starts", Link: "https://bbc.com/trial", Source: bbc})
+	require.NoError(t, err)
+	golang, err := articles.Save(ctx, model.Article{Title: "Go 1.23 is released", Link: "https://bbc.com/golang", Source: bbc})
+	require.NoError(t, err)
+
+	var resaved model.Article
+	var renamed model.Source
+	err = NewUnitOfWork(articles, sources).Do(ctx, func(txArticles service.ArticleStorage, txSources service.SourceStorage) error {
+		if err := txArticles.Delete(ctx, trial.Id); err != nil {
+			return err
+		}
+		if _, err := txArticles.SaveAll(ctx, []model.Article{{Title: "Go 1.23 is out", Link: golang.Link, Source: bbc}}); err != nil {
+			return err
+		}
+		if err := txSources.UpdateValidators(ctx, bbc.Id, `"v2"`, ""); err != nil {
+			return err
+		}
+
+		// Another request saves the deleted article again and renames the source while the work runs
+		done := make(chan error)
+		go func() {
+			var err error
+			if resaved, err = articles.Save(ctx, model.Article{Title: "Trial starts today", Link: trial.Link, Source: bbc}); err != nil {
+				done <- err
+				return
+			}
+			renamed, err = sources.Update(ctx, bbc.Id, model.Source{Name: "BBC", Link: bbc.Link, ShortName: "bbc"})
+			done <- err
+		}()
+		if err := <-done; err != nil {
+			return err
+		}
+		return errors.New("storage error")
+	})
+	assert.EqualError(t, err, "storage error")
+
+	saved, err := articles.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Article{golang, resaved}, saved,
+		"the deleted article isn't restored over the one saved with its link since")
+	_, err = articles.Save(ctx, model.Article{Title: "Trial starts", Link: trial.Link, Source: bbc})
+	assert.ErrorIs(t, err, errArticleExists, "the link stays owned by the article saved since")
+	src, err := sources.GetByShortName(ctx, "bbc")
+	require.NoError(t, err)
+	assert.Equal(t, renamed, src, "the source updated since isn't restored")
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/backuper"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/antonchaban/news-aggregator/pkg/storage/inmemory"
+	"time"
+)
+
+// RestoreMemory creates the in-memory article and source storages holding the articles and sources
+// as of the given time, the latest ones if it's zero, restored from the snapshots and the journal
+// in the directory specified by the SAVES_DIR environment variable.
+func RestoreMemory(ctx context.Context, at time.Time) (service.ArticleStorage, service.SourceStorage, model.RestoreReport, error) {
+	articles, sources := inmemory.New(), inmemory.NewSrc()
+	replayer, err := inmemory.NewReplayer(articles, sources)
+	if err != nil {
+		return nil, nil, model.RestoreReport{}, err
+	}
+	report, err := backuper.Restore(at, replayer)
+	if err != nil {
+		return nil, nil, report, err
+	}
+
+	restoredArticles, err := articles.GetAll(ctx)
+	if err != nil {
+		return nil, nil, report, err
+	}
+	restoredSources, err := sources.GetAll(ctx)
+	if err != nil {
+		return nil, nil, report, err
+	}
+	report.Articles, report.Sources = len(restoredArticles), len(restoredSources)
+	return articles, sources, report, nil
+}
@@ -0,0 +1,8 @@
+// Package migrate applies the versioned schema migrations of the SQL storages.
+//
+// Migrations are read from a file system, usually the embedded schema.Postgres or schema.SQLite,
+// named the golang-migrate way: <version>_<name>.up.sql and <version>_<name>.down.sql.
+// Every migration runs in a transaction together with the update of the schema version,
+// so a failed migration leaves the database at the previous version.
+// A migration may also run a Go step after its up script, added with Migrator.AddStep.
+package migrate
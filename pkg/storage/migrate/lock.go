@@ -0,0 +1,26 @@
+package migrate
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+)
+
+// advisoryLockID identifies the Postgres advisory lock of the migrations,
+// it only has to differ from the other advisory locks taken on the database.
+const advisoryLockID int64 = 7325411948273616
+
+// AdvisoryLock serializes the migrations with a Postgres session-level advisory lock,
+// so replicas starting together wait for the first one to migrate the database.
+var AdvisoryLock Locker = advisoryLock{}
+
+type advisoryLock struct{}
+
+func (advisoryLock) Lock(ctx context.Context, conn *sqlx.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockID)
+	return err
+}
+
+func (advisoryLock) Unlock(ctx context.Context, conn *sqlx.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockID)
+	return err
+}
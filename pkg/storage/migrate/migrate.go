@@ -0,0 +1,297 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	eventMigrationApplied  = "migration_applied"
+	eventMigrationReverted = "migration_reverted"
+)
+
+// fileName matches the golang-migrate migration file names, e.g. 000002_init_mg.up.sql.
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change with the scripts applying and reverting it.
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+	// step runs after the up script, nil if the migration has none
+	step Step
+}
+
+// Step is Go code a migration runs after its up script in the same transaction,
+// for the changes SQL can't make, such as filling a column with values computed by the application.
+type Step func(ctx context.Context, tx *sqlx.Tx) error
+
+// String returns the file name of the migration without the direction and extension.
+func (m Migration) String() string {
+	return fmt.Sprintf("%06d_%s", m.Version, m.Name)
+}
+
+// Status describes the schema version of the database.
+type Status struct {
+	// Version is the version of the latest applied migration, 0 if none is applied.
+	Version int64
+	// Dirty reports a migration that failed halfway, the schema must be fixed by hand then.
+	Dirty   bool
+	Latest  int64
+	Pending []Migration
+}
+
+// Locker serializes the migrations run by the replicas sharing the database.
+type Locker interface {
+	Lock(ctx context.Context, conn *sqlx.Conn) error
+	Unlock(ctx context.Context, conn *sqlx.Conn) error
+}
+
+// Migrator applies and reverts the migrations of a database.
+// The schema version is kept in the schema_migrations table the same way golang-migrate keeps it,
+// so databases migrated by the migrate/migrate image are picked up where it left off.
+type Migrator struct {
+	db         *sqlx.DB
+	lock       Locker
+	migrations []Migration
+}
+
+// New reads the migrations in the root of the file system and creates a migrator of the database.
+// The lock is held while migrating, a nil lock suits databases with a single writer.
+func New(db *sqlx.DB, migrations fs.FS, lock Locker) (*Migrator, error) {
+	files, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		match := fileName.FindStringSubmatch(file.Name())
+		if file.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", file.Name(), err)
+		}
+		script, err := fs.ReadFile(migrations, file.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", m.Name, match[2], version)
+		}
+		if match[3] == "up" {
+			m.up = string(script)
+		} else {
+			m.down = string(script)
+		}
+	}
+
+	mg := &Migrator{db: db, lock: lock}
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %s has no up script", m)
+		}
+		mg.migrations = append(mg.migrations, *m)
+	}
+	sort.Slice(mg.migrations, func(i, j int) bool { return mg.migrations[i].Version < mg.migrations[j].Version })
+	return mg, nil
+}
+
+// AddStep sets the Go step run after the up script of the migration of the version.
+// The step doesn't run when reverting the migration, its down script must undo the changes of both.
+func (mg *Migrator) AddStep(version int64, step Step) error {
+	i := mg.index(version)
+	if i < 0 {
+		return fmt.Errorf("no migration of version %d to add a step to", version)
+	}
+	mg.migrations[i].step = step
+	return nil
+}
+
+// Up applies the migrations newer than the schema version of the database in order.
+func (mg *Migrator) Up(ctx context.Context) error {
+	return mg.run(ctx, func(conn *sqlx.Conn) error {
+		for _, m := range mg.migrations {
+			applied, err := mg.step(ctx, conn, m.step, func(version int64) (int64, string, bool, error) {
+				return m.Version, m.up, m.Version > version, nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", m, err)
+			}
+			if applied {
+				logrus.WithField("event_id", eventMigrationApplied).Infof("Applied migration %s", m)
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the given number of the latest applied migrations.
+func (mg *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return errors.New("the number of migrations to revert must be positive")
+	}
+	return mg.run(ctx, func(conn *sqlx.Conn) error {
+		for ; steps > 0; steps-- {
+			var reverted Migration
+			ok, err := mg.step(ctx, conn, nil, func(version int64) (int64, string, bool, error) {
+				if version == 0 {
+					return 0, "", false, nil
+				}
+				i := mg.index(version)
+				if i < 0 {
+					return 0, "", false, fmt.Errorf("no migration of version %d to revert", version)
+				}
+				reverted = mg.migrations[i]
+				if reverted.down == "" {
+					return 0, "", false, fmt.Errorf("migration %s has no down script", reverted)
+				}
+				if i == 0 {
+					return 0, reverted.down, true, nil
+				}
+				return mg.migrations[i-1].Version, reverted.down, true, nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert the migrations: %w", err)
+			}
+			if !ok {
+				return nil
+			}
+			logrus.WithField("event_id", eventMigrationReverted).Infof("Reverted migration %s", reverted)
+		}
+		return nil
+	})
+}
+
+// Status returns the schema version of the database and the migrations it misses.
+func (mg *Migrator) Status(ctx context.Context) (Status, error) {
+	var status Status
+	err := mg.run(ctx, func(conn *sqlx.Conn) error {
+		var err error
+		status.Version, status.Dirty, err = version(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return Status{}, err
+	}
+	for _, m := range mg.migrations {
+		status.Latest = m.Version
+		if m.Version > status.Version {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+	return status, nil
+}
+
+// run calls fn on a connection holding the lock, with the schema_migrations table created.
+func (mg *Migrator) run(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := mg.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if mg.lock != nil {
+		if err = mg.lock.Lock(ctx, conn); err != nil {
+			return fmt.Errorf("failed to lock the migrations: %w", err)
+		}
+		defer func() {
+			if err := mg.lock.Unlock(context.Background(), conn); err != nil {
+				logrus.Errorf("failed to unlock the migrations: %s", err.Error())
+			}
+		}()
+	}
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// step runs a script, and the Go step if it isn't nil, in a transaction and sets the schema version in it.
+// The next func gets the version read within the transaction, so a replica migrating
+// the database meanwhile is noticed, and returns the new version, the script and whether to run it.
+func (mg *Migrator) step(ctx context.Context, conn *sqlx.Conn, goStep Step,
+	next func(version int64) (int64, string, bool, error)) (bool, error) {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	current, dirty, err := version(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	if dirty {
+		return false, fmt.Errorf("database is dirty at version %d, fix the schema and the schema_migrations table by hand", current)
+	}
+	newVersion, script, ok, err := next(current)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return false, err
+	}
+	if goStep != nil {
+		if err = goStep(ctx, tx); err != nil {
+			return false, err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return false, err
+	}
+	// golang-migrate keeps no row at all when no migration is applied
+	if newVersion > 0 {
+		_, err = tx.ExecContext(ctx, mg.db.Rebind(`INSERT INTO schema_migrations (version, dirty) VALUES (?, FALSE)`), newVersion)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// index returns the index of the migration of the version, -1 if there is none.
+func (mg *Migrator) index(version int64) int {
+	for i, m := range mg.migrations {
+		if m.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// version reads the schema version from the schema_migrations table.
+func version(ctx context.Context, q sqlx.QueryerContext) (int64, bool, error) {
+	var row struct {
+		Version int64 `db:"version"`
+		Dirty   bool  `db:"dirty"`
+	}
+	err := sqlx.GetContext(ctx, q, &row, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return row.Version, row.Dirty, nil
+}
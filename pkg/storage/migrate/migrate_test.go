@@ -0,0 +1,244 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/antonchaban/news-aggregator/schema"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"000001_sources.up.sql":        {Data: []byte(`CREATE TABLE sources (id integer PRIMARY KEY, name text)`)},
+	"000001_sources.down.sql":      {Data: []byte(`DROP TABLE sources`)},
+	"000003_sources_link.up.sql":   {Data: []byte(`ALTER TABLE sources ADD COLUMN link text`)},
+	"000003_sources_link.down.sql": {Data: []byte(`ALTER TABLE sources DROP COLUMN link`)},
+	"000004_articles.up.sql":       {Data: []byte(`CREATE TABLE articles (id integer PRIMARY KEY); CREATE INDEX articles_id_idx ON articles (id)`)},
+	"000004_articles.down.sql":     {Data: []byte(`DROP TABLE articles`)},
+	"README.md":                    {Data: []byte(`not a migration`)},
+	"sqlite/000001_ignored.up.sql": {Data: []byte(`not run`)},
+}
+
+// newTestDB opens a new database in a temporary file.
+func newTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func schemaVersion(t *testing.T, db *sqlx.DB) []int64 {
+	var versions []int64
+	require.NoError(t, db.Select(&versions, `SELECT version FROM schema_migrations`))
+	return versions
+}
+
+func TestMigrator_UpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	m, err := New(db, testMigrations, nil)
+	require.NoError(t, err)
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), status.Version)
+	assert.Equal(t, int64(4), status.Latest)
+	require.Len(t, status.Pending, 3)
+	assert.Equal(t, "sources_link", status.Pending[1].Name)
+
+	require.NoError(t, m.Up(ctx))
+	assert.Equal(t, []int64{4}, schemaVersion(t, db))
+	_, err = db.Exec(`INSERT INTO sources (name, link) VALUES ('BBC', 'https://bbc.com/rss')`)
+	require.NoError(t, err)
+
+	// Applying the migrations again changes nothing
+	require.NoError(t, m.Up(ctx))
+	status, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Status{Version: 4, Latest: 4}, status)
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT count(*) FROM sources`))
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, m.Down(ctx, 2))
+	assert.Equal(t, []int64{1}, schemaVersion(t, db))
+	_, err = db.Exec(`SELECT link FROM sources`)
+	assert.Error(t, err, "the link column is dropped")
+
+	// Reverting more migrations than applied stops at the first one, leaving no version
+	require.NoError(t, m.Down(ctx, 5))
+	assert.Empty(t, schemaVersion(t, db))
+	_, err = db.Exec(`SELECT * FROM sources`)
+	assert.Error(t, err)
+
+	assert.Error(t, m.Down(ctx, 0))
+}
+
+func TestMigrator_FailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	migrations := fstest.MapFS{
+		"000001_sources.up.sql": testMigrations["000001_sources.up.sql"],
+		"000002_broken.up.sql":  {Data: []byte(`CREATE TABLE articles (id integer); CREATE TABLE sources (id integer)`)},
+	}
+	m, err := New(db, migrations, nil)
+	require.NoError(t, err)
+
+	err = m.Up(ctx)
+	assert.ErrorContains(t, err, "failed to apply migration 000002_broken")
+	assert.Equal(t, []int64{1}, schemaVersion(t, db), "the migrations before the broken one are kept")
+	_, err = db.Exec(`SELECT * FROM articles`)
+	assert.Error(t, err, "the broken migration is rolled back")
+
+	// The migration of version 2 has no down script
+	_, err = db.Exec(`UPDATE schema_migrations SET version = 2`)
+	require.NoError(t, err)
+	assert.ErrorContains(t, m.Down(ctx, 1), "has no down script")
+
+	// Dirty databases left by golang-migrate must be fixed by hand
+	_, err = db.Exec(`UPDATE schema_migrations SET version = 1, dirty = TRUE`)
+	require.NoError(t, err)
+	assert.ErrorContains(t, m.Up(ctx), "database is dirty at version 1")
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Dirty)
+}
+
+func TestMigrator_AddStep(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	m, err := New(db, testMigrations, nil)
+	require.NoError(t, err)
+	assert.EqualError(t, m.AddStep(2, nil), "no migration of version 2 to add a step to")
+
+	var steps int
+	require.NoError(t, m.AddStep(3, func(ctx context.Context, tx *sqlx.Tx) error {
+		steps++
+		// The step sees the changes of the up script
+		_, err := tx.ExecContext(ctx, `INSERT INTO sources (name, link) VALUES ('BBC', 'https://bbc.com/rss')`)
+		if err != nil {
+			return err
+		}
+		if steps == 1 {
+			return errors.New("step failed")
+		}
+		return nil
+	}))
+
+	err = m.Up(ctx)
+	assert.ErrorContains(t, err, "failed to apply migration 000003_sources_link: step failed")
+	assert.Equal(t, []int64{1}, schemaVersion(t, db), "the script of a migration with a failed step is rolled back")
+
+	require.NoError(t, m.Up(ctx))
+	assert.Equal(t, []int64{4}, schemaVersion(t, db))
+	var links []string
+	require.NoError(t, db.Select(&links, `SELECT link FROM sources`))
+	assert.Equal(t, []string{"https://bbc.com/rss"}, links)
+
+	require.NoError(t, m.Down(ctx, 2))
+	assert.Equal(t, 2, steps, "steps don't run when reverting")
+}
+
+func TestNew_InvalidMigrations(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations fstest.MapFS
+		wantErr    string
+	}{
+		{
+			name:       "no up script",
+			migrations: fstest.MapFS{"000001_init.down.sql": {}},
+			wantErr:    "migration 000001_init has no up script",
+		},
+		{
+			name:       "shared version",
+			migrations: fstest.MapFS{"000001_init.up.sql": {}, "000001_other.up.sql": {}},
+			wantErr:    "migrations init and other share version 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(nil, tt.migrations, nil)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMigrator_EmbeddedSQLiteSchema(t *testing.T) {
+	db := newTestDB(t)
+	m, err := New(db, schema.SQLite, nil)
+	require.NoError(t, err)
+	require.NoError(t, m.Up(context.Background()))
+
+	var tables []string
+	require.NoError(t, db.Select(&tables, `SELECT name FROM sqlite_master WHERE name IN ('sources', 'articles', 'articles_fts') ORDER BY name`))
+	assert.Equal(t, []string{"articles", "articles_fts", "sources"}, tables)
+
+	require.NoError(t, m.Down(context.Background(), len(m.migrations)))
+	tables = nil
+	require.NoError(t, db.Select(&tables, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')`))
+	assert.Empty(t, tables)
+}
+
+func TestMigrator_AdvisoryLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		mock    func(mock sqlmock.Sqlmock)
+		wantErr string
+	}{
+		{
+			name: "migrates holding the lock",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(advisoryLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(3, false))
+				mock.ExpectRollback()
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(3, false))
+				mock.ExpectExec(`CREATE TABLE articles`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`DELETE FROM schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(int64(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(advisoryLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "lock error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(advisoryLockID).WillReturnError(errors.New("canceled"))
+			},
+			wantErr: "failed to lock the migrations: canceled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.Newx()
+			require.NoError(t, err)
+			defer db.Close()
+			tt.mock(mock)
+
+			m, err := New(db, fstest.MapFS{
+				"000003_sources_link.up.sql": testMigrations["000003_sources_link.up.sql"],
+				"000004_articles.up.sql":     testMigrations["000004_articles.up.sql"],
+			}, AdvisoryLock)
+			require.NoError(t, err)
+			err = m.Up(context.Background())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
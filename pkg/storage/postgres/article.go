@@ -1,110 +1,363 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
+	"github.com/antonchaban/news-aggregator/pkg/filter"
 	"github.com/antonchaban/news-aggregator/pkg/model"
 	"github.com/antonchaban/news-aggregator/pkg/service"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strings"
+	"time"
 )
 
 type postgresArticleStorage struct {
-	db *sqlx.DB
+	db dbtx
 }
 
 func New(db *sqlx.DB) service.ArticleStorage {
 	return &postgresArticleStorage{db: db}
 }
 
-func (pa *postgresArticleStorage) GetAll() ([]model.Article, error) {
-	var articles []model.Article
-	query := `SELECT a.id, a.title, a.description, a.link, a.pub_date,
-			       s.id AS source_id, s.name AS source_name, s.link AS source_link, s.short_name AS source_short_name
+func (pa *postgresArticleStorage) GetAll(ctx context.Context) ([]model.Article, error) {
+	query := `SELECT ` + articleColumns + `
 			FROM articles a
 			JOIN sources s ON a.source_id = s.id`
-	rows, err := pa.db.Queryx(query)
+	return pa.GetByFilter(ctx, query, nil)
+}
+
+// GetAfter returns up to limit articles with IDs greater than afterID, ordered by ID.
+func (pa *postgresArticleStorage) GetAfter(ctx context.Context, afterID, limit int) ([]model.Article, error) {
+	query := `SELECT ` + articleColumns + `
+			FROM articles a
+			JOIN sources s ON a.source_id = s.id
+			WHERE a.id > $1
+			ORDER BY a.id
+			LIMIT $2`
+	return pa.GetByFilter(ctx, query, []interface{}{afterID, limit})
+}
+
+// Save adds a new article to the database.
+// Articles are unique by their canonical link, and a new article joins the story
+// of the most similar article published around the same time, if there is one.
+func (pa *postgresArticleStorage) Save(ctx context.Context, article model.Article) (model.Article, error) {
+	article.CanonicalLink = dedup.CanonicalURL(article.Link)
+	article.Fingerprint = dedup.Fingerprint(article.Title, article.Description)
+
+	err := pa.db.QueryRowContext(ctx, `SELECT nextval(pg_get_serial_sequence('articles', 'id'))`).Scan(&article.Id)
 	if err != nil {
-		return nil, err
+		return model.Article{}, err
+	}
+	article.StoryId = article.Id
+	nearest, ok, err := nearestStory(ctx, pa.db, article)
+	if err != nil {
+		return model.Article{}, err
+	}
+	if ok {
+		article.StoryId = nearest.StoryId
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var article model.Article
-		var source model.Source
+	createQuery := `INSERT INTO articles (` + insertColumns + `)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+	_, err = pa.db.ExecContext(ctx, createQuery, insertArgs(article)...)
+	if err != nil {
+		return model.Article{}, err
+	}
+	return article, nil
+}
 
-		err := rows.Scan(&article.Id, &article.Title, &article.Description, &article.Link, &article.PubDate,
-			&source.Id, &source.Name, &source.Link, &source.ShortName)
-		if err != nil {
-			return nil, err
-		}
-		article.Source = source
-		articles = append(articles, article)
+// nearestStory returns the saved article with the fingerprint nearest to the given one among the similar articles
+// published within dedup.StoryWindow of it, reporting whether there is any.
+// The distances of the fingerprints are counted by the database, which returns the nearest article only.
+func nearestStory(ctx context.Context, q sqlx.QueryerContext, article model.Article) (dedup.Candidate, bool, error) {
+	if article.Fingerprint == 0 {
+		return dedup.Candidate{}, false, nil
+	}
+	query := `SELECT story_id, fingerprint FROM (
+			    SELECT id, story_id, fingerprint, length(replace((fingerprint # $3)::bit(64)::text, '0', '')) AS distance
+			    FROM articles WHERE fingerprint <> 0 AND pub_date BETWEEN $1 AND $2) c
+			WHERE distance <= $4 ORDER BY distance, id LIMIT 1`
+	var c dedup.Candidate
+	var fingerprint int64
+	err := q.QueryRowxContext(ctx, query, article.PubDate.Add(-dedup.StoryWindow),
+		article.PubDate.Add(dedup.StoryWindow), int64(article.Fingerprint), dedup.MaxDistance).Scan(&c.StoryId, &fingerprint)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dedup.Candidate{}, false, nil
+	}
+	if err != nil {
+		return dedup.Candidate{}, false, err
 	}
+	c.Fingerprint = uint64(fingerprint)
+	return c, true, nil
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+// saveAllBatchSize is the number of articles upserted by a single statement,
+// which keeps the statement well under the limit of 65535 bind parameters.
+const saveAllBatchSize = 500
+
+// SaveAll upserts the articles in batches within a single transaction, counting what changed.
+// New articles are inserted, and the saved articles linking to the same page are updated
+// if their content or metadata changed, keeping their ID and story.
+func (pa *postgresArticleStorage) SaveAll(ctx context.Context, articles []model.Article) (model.SaveResult, error) {
+	articles, repeated := dedup.UniqueByLink(articles)
+	result := model.SaveResult{Skipped: repeated}
+	if len(articles) == 0 {
+		return result, nil
 	}
 
-	return articles, nil
+	err := inTx(ctx, pa.db, func(tx *sqlx.Tx) error {
+		for start := 0; start < len(articles); start += saveAllBatchSize {
+			batch := articles[start:min(start+saveAllBatchSize, len(articles))]
+			batchResult, err := upsertBatch(ctx, tx, batch)
+			if err != nil {
+				return err
+			}
+			result.Add(batchResult)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.SaveResult{}, err
+	}
+	return result, nil
+}
+
+// storyCandidate is an article of the batch the next articles can join the story of.
+type storyCandidate struct {
+	dedup.Candidate
+	pubDate time.Time
 }
 
-func (pa *postgresArticleStorage) Save(article model.Article) (model.Article, error) {
-	var id int
-	createQuery := `INSERT INTO articles (title, description, link, source_id, pub_date) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+// upsertBatch upserts the batch of articles with unique canonical links in a single statement.
+// Every article gets an ID, the ones already saved keep their story,
+// and the new ones join the story of the most similar article saved or earlier in the batch.
+// The database looks up the nearest saved article of every new one, so a batch doesn't load the whole window.
+func upsertBatch(ctx context.Context, tx *sqlx.Tx, batch []model.Article) (model.SaveResult, error) {
+	links := make([]string, len(batch))
+	for i, article := range batch {
+		links[i] = article.CanonicalLink
+	}
 
-	err := pa.db.QueryRow(createQuery, article.Title, article.Description, article.Link, article.Source.Id, article.PubDate).Scan(&id)
+	stories, err := savedStories(ctx, tx, links)
 	if err != nil {
-		return model.Article{}, err
+		return model.SaveResult{}, err
 	}
-	article.Id = id
-	return article, nil
+	var ids []int
+	err = tx.SelectContext(ctx, &ids, `SELECT nextval(pg_get_serial_sequence('articles', 'id')) FROM generate_series(1, $1)`, len(batch))
+	if err != nil {
+		return model.SaveResult{}, err
+	}
+
+	// The articles earlier in the batch aren't saved yet, they are candidates along the nearest saved one
+	var batchCandidates []storyCandidate
+	var sb strings.Builder
+	args := make([]interface{}, 0, len(batch)*insertColumnCount)
+	sb.WriteString(`INSERT INTO articles (` + insertColumns + `) VALUES `)
+	for i, article := range batch {
+		article.Id = ids[i]
+		article.StoryId = article.Id
+		if storyID, ok := stories[article.CanonicalLink]; ok {
+			article.StoryId = storyID
+		} else {
+			nearest, ok, err := nearestStory(ctx, tx, article)
+			if err != nil {
+				return model.SaveResult{}, err
+			}
+			var candidates []dedup.Candidate
+			if ok {
+				candidates = append(candidates, nearest)
+			}
+			candidates = append(candidates, inWindow(batchCandidates, article.PubDate)...)
+			if storyID, ok := dedup.FindStory(article.Fingerprint, candidates); ok {
+				article.StoryId = storyID
+			}
+		}
+		batchCandidates = append(batchCandidates, storyCandidate{
+			Candidate: dedup.Candidate{StoryId: article.StoryId, Fingerprint: article.Fingerprint},
+			pubDate:   article.PubDate,
+		})
+
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		for n := len(args) + 1; n <= len(args)+insertColumnCount; n++ {
+			if n > len(args)+1 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "$%d", n)
+		}
+		sb.WriteString(")")
+		args = append(args, insertArgs(article)...)
+	}
+	// Unchanged articles aren't updated and so aren't returned,
+	// xmax is zero for the inserted rows and holds the updating transaction for the updated ones
+	sb.WriteString(` ON CONFLICT (canonical_link) DO UPDATE
+		SET title = excluded.title, description = excluded.description,
+		    pub_date = excluded.pub_date, fingerprint = excluded.fingerprint,
+		    author = excluded.author, categories = excluded.categories, image_url = excluded.image_url,
+		    content = excluded.content, language = excluded.language, guid = excluded.guid
+		WHERE (articles.title, articles.description, articles.pub_date, articles.author, articles.categories,
+		       articles.image_url, articles.content, articles.language, articles.guid)
+		      IS DISTINCT FROM (excluded.title, excluded.description, excluded.pub_date, excluded.author, excluded.categories,
+		       excluded.image_url, excluded.content, excluded.language, excluded.guid)
+		RETURNING xmax = 0`)
+
+	var inserted []bool
+	err = tx.SelectContext(ctx, &inserted, sb.String(), args...)
+	if err != nil {
+		return model.SaveResult{}, err
+	}
+	result := model.SaveResult{Skipped: len(batch) - len(inserted)}
+	for _, ok := range inserted {
+		if ok {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+	return result, nil
 }
 
-func (pa *postgresArticleStorage) SaveAll(articles []model.Article) error {
-	for _, article := range articles {
-		_, err := pa.Save(article)
-		if err != nil {
-			return err
+// savedStories returns the stories of the saved articles with the given canonical links.
+func savedStories(ctx context.Context, tx *sqlx.Tx, links []string) (map[string]int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT canonical_link, story_id FROM articles WHERE canonical_link = ANY($1)`, pq.Array(links))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stories := make(map[string]int)
+	for rows.Next() {
+		var link string
+		var storyID int
+		if err := rows.Scan(&link, &storyID); err != nil {
+			return nil, err
+		}
+		stories[link] = storyID
+	}
+	return stories, rows.Err()
+}
+
+// inWindow returns the candidates published within dedup.StoryWindow of the given date.
+func inWindow(candidates []storyCandidate, pubDate time.Time) []dedup.Candidate {
+	var inWindow []dedup.Candidate
+	for _, c := range candidates {
+		if d := c.pubDate.Sub(pubDate); d >= -dedup.StoryWindow && d <= dedup.StoryWindow {
+			inWindow = append(inWindow, c.Candidate)
 		}
 	}
-	return nil
+	return inWindow
 }
 
-func (pa *postgresArticleStorage) Delete(id int) error {
+func (pa *postgresArticleStorage) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM articles WHERE id = $1`
-	_, err := pa.db.Exec(query, id)
+	_, err := pa.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (pa *postgresArticleStorage) DeleteBySourceID(id int) error {
+func (pa *postgresArticleStorage) DeleteBySourceID(ctx context.Context, id int) error {
 	query := `DELETE FROM articles WHERE source_id = $1`
-	_, err := pa.db.Exec(query, id)
+	_, err := pa.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
 	return err
 }
 
-func (pa *postgresArticleStorage) GetByFilter(query string, args []interface{}) ([]model.Article, error) {
-	rows, err := pa.db.Query(query, args...)
+// GetExpired returns the articles of the source published before the cutoff or past
+// the newest maxCount ones, oldest first. The zero cutoff and maxCount set no limit.
+func (pa *postgresArticleStorage) GetExpired(ctx context.Context, sourceID int, cutoff time.Time, maxCount int) ([]model.Article, error) {
+	query := `SELECT ` + articleColumns + `
+			FROM (SELECT articles.*, ROW_NUMBER() OVER (ORDER BY pub_date DESC, id DESC) AS newest
+			      FROM articles WHERE source_id = $1) a
+			JOIN sources s ON a.source_id = s.id
+			WHERE a.pub_date < $2 OR ($3 > 0 AND a.newest > $3)
+			ORDER BY a.pub_date, a.id`
+	return pa.GetByFilter(ctx, query, []interface{}{sourceID, cutoff, maxCount})
+}
+
+// DeleteByIDs deletes the articles with the given IDs and returns the number of deleted ones.
+func (pa *postgresArticleStorage) DeleteByIDs(ctx context.Context, ids []int) (int, error) {
+	query := `DELETE FROM articles WHERE id = ANY($1)`
+	res, err := pa.db.ExecContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// articleColumns are the article and source columns selected by the filter queries, in the order they are scanned.
+const articleColumns = `a.id, a.title, a.description, a.link, a.pub_date, a.story_id,
+			a.author, a.categories, a.image_url, a.content, a.language, a.guid,
+			s.id, s.name, s.link, s.short_name`
+
+// articleColumnCount is the number of article and source columns selected by the filter queries.
+const articleColumnCount = 16
+
+// insertColumns are the columns of the inserted articles, insertArgs returns their values.
+const (
+	insertColumns = `id, title, description, link, source_id, pub_date, canonical_link, fingerprint, story_id,
+			author, categories, image_url, content, language, guid`
+	insertColumnCount = 15
+)
+
+// insertArgs returns the values of the insertColumns of the article.
+func insertArgs(article model.Article) []interface{} {
+	return []interface{}{article.Id, article.Title, article.Description, article.Link, article.Source.Id,
+		article.PubDate, article.CanonicalLink, int64(article.Fingerprint), article.StoryId,
+		article.Author, pq.Array(categories(article.Categories)), article.ImageURL, article.Content, article.Language, article.GUID}
+}
+
+// categories returns the categories of an article, an empty list rather than nil, as the column is not null.
+func categories(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func (pa *postgresArticleStorage) GetByFilter(ctx context.Context, query string, args []interface{}) ([]model.Article, error) {
+	rows, err := pa.db.QueryxContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error executing query: %w", err)
 	}
 	defer rows.Close()
 
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("error reading columns: %w", err)
+	}
+	// Keyword searches select the relevance of every article as an additional column
+	ranked := len(columns) > articleColumnCount
+
 	var articles []model.Article
 
 	for rows.Next() {
 		var article model.Article
 		var source model.Source
 
-		err := rows.Scan(
-			&article.Id, &article.Title, &article.Description, &article.Link, &article.PubDate,
+		dest := []interface{}{
+			&article.Id, &article.Title, &article.Description, &article.Link, &article.PubDate, &article.StoryId,
+			&article.Author, pq.Array(&article.Categories), &article.ImageURL, &article.Content, &article.Language, &article.GUID,
 			&source.Id, &source.Name, &source.Link, &source.ShortName,
-		)
+		}
+		if ranked {
+			dest = append(dest, &article.Relevance)
+		}
+		err := rows.Scan(dest...)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
 
+		if len(article.Categories) == 0 {
+			article.Categories = nil
+		}
 		article.Source = source
 		articles = append(articles, article)
 	}
@@ -116,3 +369,17 @@ func (pa *postgresArticleStorage) GetByFilter(query string, args []interface{})
 
 	return articles, nil
 }
+
+func (pa *postgresArticleStorage) CountByFilter(ctx context.Context, query string, args []interface{}) (int, error) {
+	var count int
+	err := pa.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error executing query: %w", err)
+	}
+	return count, nil
+}
+
+// Dialect returns the Postgres dialect of the filter queries.
+func (pa *postgresArticleStorage) Dialect() filter.Dialect {
+	return filter.Postgres
+}
@@ -1,10 +1,14 @@
 package postgres
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
 	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	sqlmock "github.com/zhashkevych/go-sqlxmock"
 )
@@ -18,14 +22,16 @@ func TestPostgresArticleStorage_GetAll(t *testing.T) {
 
 	storage := New(db)
 
-	rows := sqlmock.NewRows([]string{"id", "title", "description", "link", "pub_date", "source_id", "source_name", "source_link", "source_short_name"}).
-		AddRow(1, "title1", "description1", "link1", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 1, "source1", "source_link1", "short1").
-		AddRow(2, "title2", "description2", "link2", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), 2, "source2", "source_link2", "short2")
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "link", "pub_date", "story_id", "author", "categories", "image_url", "content", "language", "guid", "source_id", "source_name", "source_link", "source_short_name"}).
+		AddRow(1, "title1", "description1", "link1", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 1,
+			"Jane Doe", "{World,Europe}", "https://example.com/1.jpg", "content1", "en", "guid1", 1, "source1", "source_link1", "short1").
+		AddRow(2, "title2", "description2", "link2", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), 1,
+			"", "{}", "", "", "", "", 2, "source2", "source_link2", "short2")
 
-	mock.ExpectQuery("SELECT a.id, a.title, a.description, a.link, a.pub_date, s.id AS source_id, s.name AS source_name, s.link AS source_link, s.short_name AS source_short_name FROM articles a JOIN sources s ON a.source_id = s.id").
+	mock.ExpectQuery(`SELECT a.id, a.title, a.description, a.link, a.pub_date, a.story_id,\s+a.author, a.categories, a.image_url, a.content, a.language, a.guid,\s+s.id, s.name, s.link, s.short_name\s+FROM articles a\s+JOIN sources s ON a.source_id = s.id`).
 		WillReturnRows(rows)
 
-	articles, err := storage.GetAll()
+	articles, err := storage.GetAll(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, articles, 2)
 
@@ -36,6 +42,13 @@ func TestPostgresArticleStorage_GetAll(t *testing.T) {
 			Description: "description1",
 			Link:        "link1",
 			PubDate:     time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+			StoryId:     1,
+			Author:      "Jane Doe",
+			Categories:  []string{"World", "Europe"},
+			ImageURL:    "https://example.com/1.jpg",
+			Content:     "content1",
+			Language:    "en",
+			GUID:        "guid1",
 			Source: model.Source{
 				Id:        1,
 				Name:      "source1",
@@ -49,6 +62,7 @@ func TestPostgresArticleStorage_GetAll(t *testing.T) {
 			Description: "description2",
 			Link:        "link2",
 			PubDate:     time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			StoryId:     1,
 			Source: model.Source{
 				Id:        2,
 				Name:      "source2",
@@ -64,6 +78,13 @@ func TestPostgresArticleStorage_GetAll(t *testing.T) {
 		assert.Equal(t, expectedArticles[i].Description, article.Description)
 		assert.Equal(t, expectedArticles[i].Link, article.Link)
 		assert.WithinDuration(t, expectedArticles[i].PubDate, article.PubDate, time.Second)
+		assert.Equal(t, expectedArticles[i].StoryId, article.StoryId)
+		assert.Equal(t, expectedArticles[i].Author, article.Author)
+		assert.Equal(t, expectedArticles[i].Categories, article.Categories)
+		assert.Equal(t, expectedArticles[i].ImageURL, article.ImageURL)
+		assert.Equal(t, expectedArticles[i].Content, article.Content)
+		assert.Equal(t, expectedArticles[i].Language, article.Language)
+		assert.Equal(t, expectedArticles[i].GUID, article.GUID)
 		assert.Equal(t, expectedArticles[i].Source.Id, article.Source.Id)
 		assert.Equal(t, expectedArticles[i].Source.Name, article.Source.Name)
 		assert.Equal(t, expectedArticles[i].Source.Link, article.Source.Link)
@@ -82,21 +103,66 @@ func TestPostgresArticleStorage_Save(t *testing.T) {
 
 	storage := New(db)
 
-	mock.ExpectQuery("INSERT INTO articles").
-		WithArgs("title1", "description1", "link1", 1, sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-
 	article := model.Article{
 		Title:       "title1",
 		Description: "description1",
-		Link:        "link1",
+		Link:        "http://www.example.com/link1/?utm_source=rss#top",
 		Source:      model.Source{Id: 1},
 		PubDate:     time.Now(),
 	}
+	fingerprint := dedup.Fingerprint(article.Title, article.Description)
+
+	mock.ExpectQuery(`SELECT nextval`).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	mock.ExpectQuery(`SELECT story_id, fingerprint FROM \(.+WHERE fingerprint <> 0 AND pub_date BETWEEN \$1 AND \$2\) c\s+WHERE distance <= \$4 ORDER BY distance, id LIMIT 1`).
+		WithArgs(article.PubDate.Add(-dedup.StoryWindow), article.PubDate.Add(dedup.StoryWindow),
+			int64(fingerprint), dedup.MaxDistance).
+		WillReturnRows(sqlmock.NewRows([]string{"story_id", "fingerprint"}))
+	mock.ExpectExec("INSERT INTO articles").
+		WithArgs(1, "title1", "description1", article.Link, 1, sqlmock.AnyArg(),
+			"https://example.com/link1", int64(fingerprint), 1, "", pq.Array([]string{}), "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	savedArticle, err := storage.Save(article)
+	savedArticle, err := storage.Save(context.Background(), article)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, savedArticle.Id)
+	assert.Equal(t, 1, savedArticle.StoryId, "an article unlike the others starts a story of its own")
+	assert.Equal(t, "https://example.com/link1", savedArticle.CanonicalLink)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresArticleStorage_Save_JoinsStory(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+
+	article := model.Article{
+		Title:       "Trial starts for a Polish man accused of punching Danish prime minister in Copenhagen",
+		Description: "A Polish man went on trial in Copenhagen accused of punching the Danish prime minister.",
+		Link:        "https://example.com/trial",
+		Source:      model.Source{Id: 2},
+		PubDate:     time.Now(),
+	}
+	fingerprint := dedup.Fingerprint(article.Title, article.Description)
+
+	mock.ExpectQuery(`SELECT nextval`).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(8))
+	mock.ExpectQuery(`SELECT story_id, fingerprint FROM`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(fingerprint), dedup.MaxDistance).
+		WillReturnRows(sqlmock.NewRows([]string{"story_id", "fingerprint"}).AddRow(5, int64(fingerprint)))
+	mock.ExpectExec("INSERT INTO articles").
+		WithArgs(8, article.Title, article.Description, article.Link, 2, sqlmock.AnyArg(),
+			article.Link, int64(fingerprint), 5, "", pq.Array([]string{}), "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(8, 1))
+
+	savedArticle, err := storage.Save(context.Background(), article)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, savedArticle.Id)
+	assert.Equal(t, 5, savedArticle.StoryId)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -108,25 +174,118 @@ func TestPostgresArticleStorage_SaveAll(t *testing.T) {
 	defer db.Close()
 
 	storage := New(db)
+	pubDate := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	articles := []model.Article{
+		{Title: "title1", Description: "description1", Link: "https://example.com/1", Source: model.Source{Id: 1}, PubDate: pubDate},
+		{Title: "title2", Description: "updated", Link: "https://example.com/2", Source: model.Source{Id: 1}, PubDate: pubDate},
+		{Title: "title3", Description: "description3", Link: "https://example.com/3", Source: model.Source{Id: 2}, PubDate: pubDate,
+			Author: "Jane Doe", Categories: []string{"World"}, ImageURL: "https://example.com/3.jpg", Content: "content3", Language: "en", GUID: "3"},
+		{Title: "title1 again", Link: "https://www.example.com/1?utm_source=rss", Source: model.Source{Id: 1}, PubDate: pubDate},
+	}
 
-	mock.ExpectQuery("INSERT INTO articles").
-		WithArgs("title1", "description1", "link1", 1, sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT canonical_link, story_id FROM articles WHERE canonical_link = ANY\(\$1\)`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"canonical_link", "story_id"}).
+			AddRow("https://example.com/2", 4).
+			AddRow("https://example.com/3", 6))
+	mock.ExpectQuery(`SELECT nextval\(pg_get_serial_sequence\('articles', 'id'\)\) FROM generate_series\(1, \$1\)`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(10).AddRow(11).AddRow(12))
+	// Only the new article looks up the nearest saved one, it starts its own story and the saved ones keep theirs
+	mock.ExpectQuery(`SELECT story_id, fingerprint FROM \(`).
+		WithArgs(pubDate.Add(-dedup.StoryWindow), pubDate.Add(dedup.StoryWindow),
+			int64(dedup.Fingerprint("title1", "description1")), dedup.MaxDistance).
+		WillReturnRows(sqlmock.NewRows([]string{"story_id", "fingerprint"}))
+	mock.ExpectQuery(`INSERT INTO articles \(id, title, description, link, source_id, pub_date, canonical_link, fingerprint, story_id,\s+`+
+		`author, categories, image_url, content, language, guid\) `+
+		`VALUES \(\$1, .*, \$15\), \(\$16, .*, \$30\), \(\$31, .*, \$45\) ON CONFLICT \(canonical_link\) DO UPDATE .* RETURNING xmax = 0`).
+		WithArgs(
+			10, "title1", "description1", "https://example.com/1", 1, pubDate, "https://example.com/1", sqlmock.AnyArg(), 10,
+			"", pq.Array([]string{}), "", "", "", "",
+			11, "title2", "updated", "https://example.com/2", 1, pubDate, "https://example.com/2", sqlmock.AnyArg(), 4,
+			"", pq.Array([]string{}), "", "", "", "",
+			12, "title3", "description3", "https://example.com/3", 2, pubDate, "https://example.com/3", sqlmock.AnyArg(), 6,
+			"Jane Doe", pq.Array([]string{"World"}), "https://example.com/3.jpg", "content3", "en", "3").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(true).AddRow(false))
+	mock.ExpectCommit()
 
-	mock.ExpectQuery("INSERT INTO articles").
-		WithArgs("title2", "description2", "link2", 2, sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	result, err := storage.SaveAll(context.Background(), articles)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SaveResult{Inserted: 1, Updated: 1, Skipped: 2}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
+func TestPostgresArticleStorage_SaveAll_JoinsStory(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+	pubDate := time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+	title := "Trial starts for a Polish man accused of punching Danish prime minister in Copenhagen"
 	articles := []model.Article{
-		{Title: "title1", Description: "description1", Link: "link1", Source: model.Source{Id: 1}, PubDate: time.Now()},
-		{Title: "title2", Description: "description2", Link: "link2", Source: model.Source{Id: 2}, PubDate: time.Now()},
+		{Title: title, Description: "A Polish man went on trial in Copenhagen accused of punching the Danish prime minister.",
+			Link: "https://example.com/trial", Source: model.Source{Id: 1}, PubDate: pubDate},
+		{Title: title, Description: "The Danish prime minister was punched in June by a Polish man.",
+			Link: "https://example.org/trial", Source: model.Source{Id: 2}, PubDate: pubDate},
 	}
 
-	err = storage.SaveAll(articles)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT canonical_link, story_id FROM articles`).
+		WillReturnRows(sqlmock.NewRows([]string{"canonical_link", "story_id"}))
+	mock.ExpectQuery(`SELECT nextval`).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(10).AddRow(11))
+	// No saved article is similar, the second article joins the story of the first one in the batch
+	mock.ExpectQuery(`SELECT story_id, fingerprint FROM \(`).
+		WillReturnRows(sqlmock.NewRows([]string{"story_id", "fingerprint"}))
+	mock.ExpectQuery(`SELECT story_id, fingerprint FROM \(`).
+		WillReturnRows(sqlmock.NewRows([]string{"story_id", "fingerprint"}))
+	mock.ExpectQuery(`INSERT INTO articles`).
+		WithArgs(
+			10, title, articles[0].Description, articles[0].Link, 1, pubDate, articles[0].Link, sqlmock.AnyArg(), 10,
+			"", pq.Array([]string{}), "", "", "", "",
+			11, title, articles[1].Description, articles[1].Link, 2, pubDate, articles[1].Link, sqlmock.AnyArg(), 10,
+			"", pq.Array([]string{}), "", "", "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(true).AddRow(true))
+	mock.ExpectCommit()
+
+	result, err := storage.SaveAll(context.Background(), articles)
 	assert.NoError(t, err)
+	assert.Equal(t, model.SaveResult{Inserted: 2}, result)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestPostgresArticleStorage_SaveAll_Error(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT canonical_link, story_id FROM articles`).
+		WillReturnRows(sqlmock.NewRows([]string{"canonical_link", "story_id"}))
+	mock.ExpectQuery(`SELECT nextval`).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	mock.ExpectQuery(`SELECT story_id, fingerprint FROM \(`).
+		WillReturnRows(sqlmock.NewRows([]string{"story_id", "fingerprint"}))
+	mock.ExpectQuery(`INSERT INTO articles`).
+		WillReturnError(fmt.Errorf("connection reset"))
+	mock.ExpectRollback()
+
+	result, err := storage.SaveAll(context.Background(), []model.Article{
+		{Title: "title1", Link: "https://example.com/1", Source: model.Source{Id: 1}, PubDate: time.Now()},
+	})
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, model.SaveResult{}, result)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the transaction is rolled back")
+}
+
 func TestPostgresArticleStorage_Delete(t *testing.T) {
 	db, mock, err := sqlmock.Newx()
 	if err != nil {
@@ -140,7 +299,7 @@ func TestPostgresArticleStorage_Delete(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = storage.Delete(1)
+	err = storage.Delete(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -158,7 +317,170 @@ func TestPostgresArticleStorage_DeleteBySourceID(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = storage.DeleteBySourceID(1)
+	err = storage.DeleteBySourceID(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresArticleStorage_GetExpired(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+	cutoff := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	pubDate := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "link", "pub_date", "story_id", "author", "categories", "image_url", "content", "language", "guid", "id", "name", "link", "short_name"}).
+		AddRow(3, "Title", "Description", "https://bbc.com/3", pubDate, 3, "", "{}", "", "", "", "", 1, "BBC", "https://bbc.com/rss", "bbc")
+	mock.ExpectQuery(`ROW_NUMBER\(\) OVER \(ORDER BY pub_date DESC, id DESC\) AS newest\s+FROM articles WHERE source_id = \$1\) a .* WHERE a.pub_date < \$2 OR \(\$3 > 0 AND a.newest > \$3\)`).
+		WithArgs(1, cutoff, 100).
+		WillReturnRows(rows)
+
+	expired, err := storage.GetExpired(context.Background(), 1, cutoff, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Article{{Id: 3, Title: "Title", Description: "Description", Link: "https://bbc.com/3",
+		PubDate: pubDate, StoryId: 3, Source: model.Source{Id: 1, Name: "BBC", Link: "https://bbc.com/rss", ShortName: "bbc"}}}, expired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresArticleStorage_GetAfter(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+	pubDate := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "link", "pub_date", "story_id", "author", "categories", "image_url", "content", "language", "guid", "id", "name", "link", "short_name"}).
+		AddRow(11, "Title", "Description", "https://bbc.com/11", pubDate, 11, "", "{world}", "", "", "", "", 1, "BBC", "https://bbc.com/rss", "bbc")
+	mock.ExpectQuery(`WHERE a.id > \$1\s+ORDER BY a.id\s+LIMIT \$2`).
+		WithArgs(10, 2).
+		WillReturnRows(rows)
+
+	articles, err := storage.GetAfter(context.Background(), 10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Article{{Id: 11, Title: "Title", Description: "Description", Link: "https://bbc.com/11",
+		PubDate: pubDate, StoryId: 11, Categories: []string{"world"},
+		Source: model.Source{Id: 1, Name: "BBC", Link: "https://bbc.com/rss", ShortName: "bbc"}}}, articles)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresArticleStorage_DeleteByIDs(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+
+	mock.ExpectExec("DELETE FROM articles WHERE id = ANY\\(\\$1\\)").
+		WithArgs(pq.Array([]int{1, 2, 3})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	deleted, err := storage.DeleteByIDs(context.Background(), []int{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresArticleStorage_GetByFilter(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "link", "pub_date", "story_id", "author", "categories", "image_url", "content", "language", "guid", "id", "name", "link", "short_name"}).
+		AddRow(1, "title1", "description1", "link1", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 1,
+			"", "{}", "", "", "", "", 1, "source1", "source_link1", "bbc")
+
+	query := "SELECT a.id, a.title, a.description, a.link, a.pub_date, a.story_id," +
+		" a.author, a.categories, a.image_url, a.content, a.language, a.guid, s.id, s.name, s.link, s.short_name FROM articles a JOIN sources s ON a.source_id = s.id WHERE (s.short_name IN ($1))"
+	mock.ExpectQuery(`WHERE \(s.short_name IN \(\$1\)\)`).
+		WithArgs("bbc' OR '1'='1").
+		WillReturnRows(rows)
+
+	articles, err := storage.GetByFilter(context.Background(), query, []interface{}{"bbc' OR '1'='1"})
+	assert.NoError(t, err)
+	assert.Len(t, articles, 1)
+	assert.Equal(t, "bbc", articles[0].Source.ShortName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresArticleStorage_GetByFilter_Canceled(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+
+	rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
+	mock.ExpectQuery("SELECT a.id FROM articles a").
+		WillDelayFor(time.Second).
+		WillReturnRows(rows)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = storage.GetByFilter(ctx, "SELECT a.id FROM articles a", nil)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second, "the query must be aborted once the context is done")
+}
+
+func TestPostgresArticleStorage_GetByFilter_Relevance(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "link", "pub_date", "story_id", "author", "categories", "image_url", "content", "language", "guid", "id", "name", "link", "short_name", "relevance"}).
+		AddRow(1, "title1", "description1", "link1", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 1,
+			"", "{}", "", "", "", "", 1, "source1", "source_link1", "bbc", 0.75)
+
+	query := "SELECT a.id, a.title, a.description, a.link, a.pub_date, a.story_id," +
+		" a.author, a.categories, a.image_url, a.content, a.language, a.guid, s.id, s.name, s.link, s.short_name," +
+		" ts_rank(a.search_vector, websearch_to_tsquery('english', $1)) AS relevance" +
+		" FROM articles a JOIN sources s ON a.source_id = s.id WHERE (a.search_vector @@ websearch_to_tsquery('english', $2))"
+	mock.ExpectQuery(`AS relevance`).
+		WithArgs("ukraine", "ukraine").
+		WillReturnRows(rows)
+
+	articles, err := storage.GetByFilter(context.Background(), query, []interface{}{"ukraine", "ukraine"})
+	assert.NoError(t, err)
+	assert.Len(t, articles, 1)
+	assert.Equal(t, 0.75, articles[0].Relevance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresArticleStorage_CountByFilter(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := New(db)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM articles a JOIN sources s ON a.source_id = s.id WHERE`).
+		WithArgs("bbc").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+	count, err := storage.CountByFilter(context.Background(), "SELECT COUNT(*) FROM articles a JOIN sources s ON a.source_id = s.id WHERE (s.short_name IN ($1))", []interface{}{"bbc"})
 	assert.NoError(t, err)
+	assert.Equal(t, 42, count)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
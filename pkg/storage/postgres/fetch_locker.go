@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// fetchLocker is the implementation of the FetchLocker interface keeping the leases in the fetch_leases table.
+// The leases expire by the clock of the database, which the instances share.
+type fetchLocker struct {
+	db     *sqlx.DB
+	holder string
+}
+
+// NewFetchLocker creates a new FetchLocker taking the leases on behalf of the holder, which must be unique to the instance.
+func NewFetchLocker(db *sqlx.DB, holder string) service.FetchLocker {
+	return &fetchLocker{db: db, holder: holder}
+}
+
+// Acquire inserts the lease on the source, or takes it over if it's expired or already held by the holder.
+func (l *fetchLocker) Acquire(ctx context.Context, sourceID int, ttl time.Duration) (bool, error) {
+	query := `INSERT INTO fetch_leases (source_id, holder, expires_at) VALUES ($1, $2, now() + $3 * interval '1 microsecond')
+		ON CONFLICT (source_id) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE fetch_leases.holder = excluded.holder OR fetch_leases.expires_at <= now()`
+	res, err := l.db.ExecContext(ctx, query, sourceID, l.holder, ttl.Microseconds())
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
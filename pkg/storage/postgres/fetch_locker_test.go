@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestFetchLocker_Acquire(t *testing.T) {
+	tests := []struct {
+		name    string
+		mock    func(mock sqlmock.Sqlmock)
+		want    bool
+		wantErr string
+	}{
+		{
+			name: "acquired",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`INSERT INTO fetch_leases \(source_id, holder, expires_at\) VALUES \(\$1, \$2, now\(\) \+ \$3 \* interval '1 microsecond'\)\s+`+
+					`ON CONFLICT \(source_id\) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at\s+`+
+					`WHERE fetch_leases.holder = excluded.holder OR fetch_leases.expires_at <= now\(\)`).
+					WithArgs(1, "replica-1", int64(15*60*1000000)).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: true,
+		},
+		{
+			name: "held by another instance",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`INSERT INTO fetch_leases`).
+					WithArgs(1, "replica-1", int64(15*60*1000000)).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			want: false,
+		},
+		{
+			name: "error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`INSERT INTO fetch_leases`).
+					WithArgs(1, "replica-1", int64(15*60*1000000)).WillReturnError(errors.New("connection reset"))
+			},
+			wantErr: "connection reset",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.Newx()
+			assert.NoError(t, err)
+			defer db.Close()
+			tt.mock(mock)
+
+			got, err := NewFetchLocker(db, "replica-1").Acquire(context.Background(), 1, 15*time.Minute)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestPostgresFetchRunStorage_Save(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	run := model.FetchRun{StartedAt: start, FinishedAt: start.Add(time.Second), Sources: 1, Inserted: 2,
+		Attempts: []model.FetchAttempt{{SourceId: 3, SourceName: "bbc", StartedAt: start, FinishedAt: start.Add(time.Second),
+			Status: model.FetchOK, HTTPStatus: 200, Bytes: 2048, Articles: 10, Inserted: 2}}}
+
+	tests := []struct {
+		name    string
+		mock    func(mock sqlmock.Sqlmock)
+		want    model.FetchRun
+		wantErr string
+	}{
+		{
+			name: "saved",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO fetch_runs \(started_at, finished_at, sources, failed, inserted, updated\)\s+`+
+					`VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id`).
+					WithArgs(start, start.Add(time.Second), 1, 0, 2, 0).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				mock.ExpectQuery(`INSERT INTO fetch_attempts \(run_id, source_id, source_name, started_at, finished_at, status, http_status, bytes,\s+`+
+					`articles, inserted, updated, error\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11, \$12\) RETURNING id`).
+					WithArgs(7, 3, "bbc", start, start.Add(time.Second), model.FetchOK, 200, int64(2048), 10, 2, 0, "").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+				mock.ExpectCommit()
+			},
+			want: func() model.FetchRun {
+				want := run
+				want.Id = 7
+				want.Attempts = []model.FetchAttempt{run.Attempts[0]}
+				want.Attempts[0].Id, want.Attempts[0].RunId = 11, 7
+				return want
+			}(),
+		},
+		{
+			name: "rolled back",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO fetch_runs`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				mock.ExpectQuery(`INSERT INTO fetch_attempts`).WillReturnError(errors.New("connection reset"))
+				mock.ExpectRollback()
+			},
+			wantErr: "connection reset",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.Newx()
+			assert.NoError(t, err)
+			defer db.Close()
+			tt.mock(mock)
+
+			attempts := append([]model.FetchAttempt{}, run.Attempts...)
+			r := run
+			r.Attempts = attempts
+			got, err := NewFetchRunStorage(db).Save(context.Background(), r)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresFetchRunStorage_GetAll(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	runColumns := []string{"id", "started_at", "finished_at", "sources", "failed", "inserted", "updated"}
+	attemptColumns := []string{"id", "run_id", "source_id", "source_name", "started_at", "finished_at", "status",
+		"http_status", "bytes", "articles", "inserted", "updated", "error"}
+	mock.ExpectQuery(`SELECT id, started_at, finished_at, sources, failed, inserted, updated FROM fetch_runs\s+` +
+		`ORDER BY started_at DESC, id DESC LIMIT \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(runColumns).
+			AddRow(2, start.Add(time.Hour), start.Add(time.Hour), 1, 0, 0, 0).
+			AddRow(1, start, start, 2, 1, 0, 0))
+	mock.ExpectQuery(`SELECT id, run_id, .+ FROM fetch_attempts WHERE run_id IN\s+` +
+		`\(SELECT id FROM fetch_runs ORDER BY started_at DESC, id DESC LIMIT \$1\) ORDER BY id`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(attemptColumns).
+			AddRow(1, 1, 3, "bbc", start, start, model.FetchFailed, 503, 0, 0, 0, 0, "503 Service Unavailable").
+			AddRow(2, 1, 4, "cnn", start, start, model.FetchOK, 200, 1024, 5, 1, 0, "").
+			AddRow(3, 2, 3, "bbc", start, start, model.FetchOK, 200, 2048, 10, 2, 0, ""))
+
+	runs, err := NewFetchRunStorage(db).GetAll(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.Equal(t, 2, runs[0].Id)
+	assert.Equal(t, []int{3}, attemptIDs(runs[0].Attempts))
+	assert.Equal(t, 1, runs[1].Id)
+	assert.Equal(t, []int{1, 2}, attemptIDs(runs[1].Attempts))
+	assert.Equal(t, "503 Service Unavailable", runs[1].Attempts[0].Error)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresFetchRunStorage_GetAttemptsBySourceID(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, run_id, .+ FROM fetch_attempts WHERE source_id = \$1\s+ORDER BY started_at DESC, id DESC LIMIT \$2`).
+		WithArgs(3, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "run_id", "source_id", "source_name", "started_at", "finished_at",
+			"status", "http_status", "bytes", "articles", "inserted", "updated", "error"}).
+			AddRow(3, 2, 3, "bbc", start, start, model.FetchOK, 200, 2048, 10, 2, 0, ""))
+
+	attempts, err := NewFetchRunStorage(db).GetAttemptsBySourceID(context.Background(), 3, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.FetchAttempt{{Id: 3, RunId: 2, SourceId: 3, SourceName: "bbc", StartedAt: start, FinishedAt: start,
+		Status: model.FetchOK, HTTPStatus: 200, Bytes: 2048, Articles: 10, Inserted: 2}}, attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresFetchRunStorage_DeleteBefore(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cutoff := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM fetch_runs WHERE started_at < \$1`).
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	deleted, err := NewFetchRunStorage(db).DeleteBefore(context.Background(), cutoff)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresFetchRunStorage_DeleteBeyond(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM fetch_runs WHERE id IN \(SELECT id FROM fetch_runs ORDER BY started_at DESC, id DESC OFFSET \$1\)`).
+		WithArgs(100).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	deleted, err := NewFetchRunStorage(db).DeleteBeyond(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func attemptIDs(attempts []model.FetchAttempt) []int {
+	ids := make([]int, len(attempts))
+	for i, a := range attempts {
+		ids[i] = a.Id
+	}
+	return ids
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
+	"github.com/antonchaban/news-aggregator/pkg/storage/migrate"
+	"github.com/jmoiron/sqlx"
+)
+
+// storiesMigration is the version of the migration adding the canonical links, fingerprints and stories.
+const storiesMigration = 6
+
+// backfillBatchSize is the number of articles read at a time by the backfill of the stories migration.
+const backfillBatchSize = 1000
+
+// AddMigrationSteps adds the Go steps of the Postgres migrations to the migrator.
+func AddMigrationSteps(m *migrate.Migrator) error {
+	return m.AddStep(storiesMigration, backfillStories)
+}
+
+// backfillStories sets the canonical links and fingerprints of the articles saved before the stories migration,
+// so fetching them again updates them and new articles can join their stories.
+// An article linking to the same page as an article backfilled before it is a copy of it,
+// it keeps its link as its canonical link.
+func backfillStories(ctx context.Context, tx *sqlx.Tx) error {
+	var lastID int64
+	for {
+		var articles []struct {
+			Id          int64          `db:"id"`
+			Title       sql.NullString `db:"title"`
+			Description sql.NullString `db:"description"`
+			Link        string         `db:"link"`
+		}
+		query := `SELECT id, title, description, link FROM articles WHERE id > $1 ORDER BY id LIMIT $2`
+		if err := tx.SelectContext(ctx, &articles, query, lastID, backfillBatchSize); err != nil {
+			return err
+		}
+
+		for _, article := range articles {
+			query := `UPDATE articles SET fingerprint = $1,
+			    canonical_link = CASE WHEN EXISTS (SELECT 1 FROM articles WHERE canonical_link = $2)
+			                          THEN canonical_link ELSE $2 END
+			    WHERE id = $3`
+			fingerprint := dedup.Fingerprint(article.Title.String, article.Description.String)
+			_, err := tx.ExecContext(ctx, query, int64(fingerprint), dedup.CanonicalURL(article.Link), article.Id)
+			if err != nil {
+				return err
+			}
+		}
+		if len(articles) < backfillBatchSize {
+			return nil
+		}
+		lastID = articles[len(articles)-1].Id
+	}
+}
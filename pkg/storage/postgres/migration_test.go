@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/storage/migrate"
+	"github.com/antonchaban/news-aggregator/schema"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestStoriesMigration_LegacyArticles(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	require.NoError(t, err)
+	defer db.Close()
+
+	script, err := fs.ReadFile(schema.Postgres, "000006_articles_stories.up.sql")
+	require.NoError(t, err)
+	m, err := migrate.New(db, fstest.MapFS{"000006_articles_stories.up.sql": {Data: script}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, AddMigrationSteps(m))
+
+	// An article saved before the migration, with its link as the feed had it
+	legacy := model.Article{Id: 3, Title: "Trial starts", Description: "A man went on trial in Copenhagen.",
+		Link: "http://www.example.com/trial/?utm_source=rss", Source: model.Source{Id: 1},
+		PubDate: time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)}
+	fingerprint := dedup.Fingerprint(legacy.Title, legacy.Description)
+
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(5, false))
+	mock.ExpectExec(`(?s)add column canonical_link.*drop constraint articles_link_key`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT id, title, description, link FROM articles WHERE id > \$1 ORDER BY id LIMIT \$2`).
+		WithArgs(0, backfillBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "link"}).
+			AddRow(legacy.Id, legacy.Title, legacy.Description, legacy.Link))
+	mock.ExpectExec(`UPDATE articles SET fingerprint = \$1,\s+canonical_link = CASE WHEN EXISTS`).
+		WithArgs(int64(fingerprint), "https://example.com/trial", legacy.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(int64(6)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, m.Up(context.Background()))
+
+	// Fetched again with another tracking parameter, the article conflicts on its backfilled canonical link
+	// and keeps its story
+	refetched := legacy
+	refetched.Link = "https://example.com/trial?utm_medium=email"
+	refetched.Description = "A man went on trial in Copenhagen on Monday."
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT canonical_link, story_id FROM articles WHERE canonical_link = ANY\(\$1\)`).
+		WithArgs(pq.Array([]string{"https://example.com/trial"})).
+		WillReturnRows(sqlmock.NewRows([]string{"canonical_link", "story_id"}).AddRow("https://example.com/trial", legacy.Id))
+	mock.ExpectQuery(`SELECT nextval`).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(10))
+	mock.ExpectQuery(`INSERT INTO articles .* ON CONFLICT \(canonical_link\) DO UPDATE`).
+		WithArgs(10, refetched.Title, refetched.Description, refetched.Link, 1, refetched.PubDate,
+			"https://example.com/trial", sqlmock.AnyArg(), legacy.Id, "", pq.Array([]string{}), "", "", "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(false))
+	mock.ExpectCommit()
+
+	result, err := New(db).SaveAll(context.Background(), []model.Article{refetched})
+	require.NoError(t, err)
+	assert.Equal(t, model.SaveResult{Updated: 1}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -10,17 +11,17 @@ import (
 )
 
 type postgresSrcStorage struct {
-	db *sqlx.DB
+	db dbtx
 }
 
 func NewSrc(db *sqlx.DB) service.SourceStorage {
 	return &postgresSrcStorage{db: db}
 }
 
-func (psrc *postgresSrcStorage) GetAll() ([]model.Source, error) {
+func (psrc *postgresSrcStorage) GetAll(ctx context.Context) ([]model.Source, error) {
 	var sources []model.Source
-	query := `SELECT id, name, link, short_name FROM sources`
-	err := psrc.db.Select(&sources, query)
+	query := `SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources`
+	err := psrc.db.SelectContext(ctx, &sources, query)
 	if err != nil {
 		return nil, err
 
@@ -28,10 +29,10 @@ func (psrc *postgresSrcStorage) GetAll() ([]model.Source, error) {
 	return sources, nil
 }
 
-func (psrc *postgresSrcStorage) Save(src model.Source) (model.Source, error) {
+func (psrc *postgresSrcStorage) Save(ctx context.Context, src model.Source) (model.Source, error) {
 	var id int
-	createQuery := `INSERT INTO sources (name, link, short_name) VALUES ($1, $2, $3) RETURNING id`
-	err := psrc.db.QueryRow(createQuery, src.Name, src.Link, src.ShortName).Scan(&id)
+	createQuery := `INSERT INTO sources (name, link, short_name, scrape_config, retention, schedule) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	err := psrc.db.QueryRowContext(ctx, createQuery, src.Name, src.Link, src.ShortName, src.Scrape, src.Retention, src.Schedule).Scan(&id)
 	if err != nil {
 		return model.Source{}, err
 	}
@@ -39,9 +40,9 @@ func (psrc *postgresSrcStorage) Save(src model.Source) (model.Source, error) {
 	return src, nil
 }
 
-func (psrc *postgresSrcStorage) SaveAll(sources []model.Source) error {
+func (psrc *postgresSrcStorage) SaveAll(ctx context.Context, sources []model.Source) error {
 	for _, src := range sources {
-		_, err := psrc.Save(src)
+		_, err := psrc.Save(ctx, src)
 		if err != nil {
 			return err
 		}
@@ -49,16 +50,16 @@ func (psrc *postgresSrcStorage) SaveAll(sources []model.Source) error {
 	return nil
 }
 
-func (psrc *postgresSrcStorage) Delete(id int) error {
+func (psrc *postgresSrcStorage) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM sources WHERE id = $1`
-	_, err := psrc.db.Exec(query, id)
+	_, err := psrc.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (psrc *postgresSrcStorage) GetByID(id int) (model.Source, error) {
+func (psrc *postgresSrcStorage) GetByID(ctx context.Context, id int) (model.Source, error) {
 	var src model.Source
-	query := `SELECT id, name, link FROM sources WHERE id = $1`
-	err := psrc.db.Get(&src, query, id)
+	query := `SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources WHERE id = $1`
+	err := psrc.db.GetContext(ctx, &src, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.Source{}, fmt.Errorf("source with id %d not found", id)
@@ -68,10 +69,10 @@ func (psrc *postgresSrcStorage) GetByID(id int) (model.Source, error) {
 	return src, nil
 }
 
-func (psrc *postgresSrcStorage) GetByShortName(shortName string) (model.Source, error) {
+func (psrc *postgresSrcStorage) GetByShortName(ctx context.Context, shortName string) (model.Source, error) {
 	var src model.Source
-	query := `SELECT id, name, link, short_name FROM sources WHERE short_name = $1`
-	err := psrc.db.Get(&src, query, shortName)
+	query := `SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources WHERE short_name = $1`
+	err := psrc.db.GetContext(ctx, &src, query, shortName)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.Source{}, fmt.Errorf("source with short name %s not found", shortName)
@@ -81,12 +82,44 @@ func (psrc *postgresSrcStorage) GetByShortName(shortName string) (model.Source,
 	return src, nil
 }
 
-func (psrc *postgresSrcStorage) Update(id int, src model.Source) (model.Source, error) {
-	query := `UPDATE sources SET name = $1, link = $2, short_name = $3 WHERE id = $4`
-	_, err := psrc.db.Exec(query, src.Name, src.Link, src.ShortName, id)
+// Update updates the source with the given ID. The validators of the source
+// are kept, unless its link changes, as they belong to the old feed then.
+func (psrc *postgresSrcStorage) Update(ctx context.Context, id int, src model.Source) (model.Source, error) {
+	query := `UPDATE sources SET name = $1, link = $2, short_name = $3, scrape_config = $4, retention = $5, schedule = $7, health = NULL,
+		etag = CASE WHEN link = $2 THEN etag ELSE '' END,
+		last_modified = CASE WHEN link = $2 THEN last_modified ELSE '' END
+		WHERE id = $6 RETURNING etag, last_modified`
+	err := psrc.db.QueryRowContext(ctx, query, src.Name, src.Link, src.ShortName, src.Scrape, src.Retention, id, src.Schedule).Scan(&src.ETag, &src.LastModified)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Source{}, fmt.Errorf("source with id %d not found", id)
+		}
 		return model.Source{}, err
 	}
 	src.Id = id
+	src.Health = nil
 	return src, nil
 }
+
+// UpdateValidators stores the ETag and Last-Modified validators of the latest fetch of the source.
+func (psrc *postgresSrcStorage) UpdateValidators(ctx context.Context, id int, etag, lastModified string) error {
+	query := `UPDATE sources SET etag = $1, last_modified = $2 WHERE id = $3`
+	_, err := psrc.db.ExecContext(ctx, query, etag, lastModified, id)
+	return err
+}
+
+// UpdateHealth stores the health of the source after its latest fetch, unless its stored health
+// is no longer the previous one. The JSON values compare by their content.
+func (psrc *postgresSrcStorage) UpdateHealth(ctx context.Context, id int, previous *model.SourceHealth,
+	health model.SourceHealth) (bool, error) {
+	query := `UPDATE sources SET health = $1 WHERE id = $2 AND health IS NOT DISTINCT FROM $3::jsonb`
+	res, err := psrc.db.ExecContext(ctx, query, health, id, previous)
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return updated > 0, nil
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 
@@ -18,20 +19,27 @@ func TestPostgresSrcStorage_GetAll(t *testing.T) {
 
 	storage := NewSrc(db)
 
-	rows := sqlmock.NewRows([]string{"id", "name", "link", "short_name"}).
-		AddRow(1, "source1", "link1", "short1").
-		AddRow(2, "source2", "link2", "short2")
+	rows := sqlmock.NewRows([]string{"id", "name", "link", "short_name", "etag", "last_modified", "scrape_config", "retention", "schedule", "health"}).
+		AddRow(1, "source1", "link1", "short1", `"v1"`, "Mon, 02 Jan 2006 15:04:05 GMT", nil, nil, nil, nil).
+		AddRow(2, "source2", "link2", "short2", "", "", []byte(`{"article_selector":"div.story","date_formats":["Jan 02, 2006"]}`),
+			[]byte(`{"max_age_days":30}`), []byte(`{"cron":"*/30 6-22 * * *","enabled":false}`),
+			[]byte(`{"status":"failing","consecutive_failures":2,"last_error":"404 Not Found","fetches":5,"average_items":12.5}`))
 
-	mock.ExpectQuery(`SELECT id, name, link, short_name FROM sources`).
+	mock.ExpectQuery(`SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources`).
 		WillReturnRows(rows)
 
-	sources, err := storage.GetAll()
+	sources, err := storage.GetAll(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, sources, 2)
 
 	expectedSources := []model.Source{
-		{Id: 1, Name: "source1", Link: "link1", ShortName: "short1"},
-		{Id: 2, Name: "source2", Link: "link2", ShortName: "short2"},
+		{Id: 1, Name: "source1", Link: "link1", ShortName: "short1", ETag: `"v1"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"},
+		{Id: 2, Name: "source2", Link: "link2", ShortName: "short2",
+			Scrape:    &model.ScrapeConfig{ArticleSelector: "div.story", DateFormats: []string{"Jan 02, 2006"}},
+			Retention: &model.RetentionPolicy{MaxAgeDays: 30},
+			Schedule:  &model.FetchSchedule{Cron: "*/30 6-22 * * *"},
+			Health: &model.SourceHealth{Status: model.SourceFailing, ConsecutiveFailures: 2, LastError: "404 Not Found",
+				Fetches: 5, AverageItems: 12.5}},
 	}
 
 	assert.Equal(t, expectedSources, sources)
@@ -47,12 +55,16 @@ func TestPostgresSrcStorage_Save(t *testing.T) {
 
 	storage := NewSrc(db)
 
-	mock.ExpectQuery(`INSERT INTO sources \(name, link, short_name\) VALUES \(\$1, \$2\, \$3\) RETURNING id`).
-		WithArgs("source1", "link1", "short1").
+	mock.ExpectQuery(`INSERT INTO sources \(name, link, short_name, scrape_config, retention, schedule\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id`).
+		WithArgs("source1", "link1", "short1", []byte(`{"article_selector":"div.story","base_url":"https://example.com"}`),
+			[]byte(`{"max_count":100}`), []byte(`{"interval":"15m","enabled":true}`)).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	source := model.Source{Name: "source1", Link: "link1", ShortName: "short1"}
-	savedSource, err := storage.Save(source)
+	source := model.Source{Name: "source1", Link: "link1", ShortName: "short1",
+		Scrape:    &model.ScrapeConfig{ArticleSelector: "div.story", BaseURL: "https://example.com"},
+		Retention: &model.RetentionPolicy{MaxCount: 100},
+		Schedule:  &model.FetchSchedule{Interval: "15m", Enabled: true}}
+	savedSource, err := storage.Save(context.Background(), source)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, savedSource.Id)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -67,12 +79,12 @@ func TestPostgresSrcStorage_SaveAll(t *testing.T) {
 
 	storage := NewSrc(db)
 
-	mock.ExpectQuery(`INSERT INTO sources \(name, link, short_name\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
-		WithArgs("source1", "link1", "short1").
+	mock.ExpectQuery(`INSERT INTO sources \(name, link, short_name, scrape_config, retention, schedule\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id`).
+		WithArgs("source1", "link1", "short1", nil, nil, nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	mock.ExpectQuery(`INSERT INTO sources \(name, link, short_name\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
-		WithArgs("source2", "link2", "short2").
+	mock.ExpectQuery(`INSERT INTO sources \(name, link, short_name, scrape_config, retention, schedule\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id`).
+		WithArgs("source2", "link2", "short2", nil, nil, nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
 
 	sources := []model.Source{
@@ -80,7 +92,7 @@ func TestPostgresSrcStorage_SaveAll(t *testing.T) {
 		{Name: "source2", Link: "link2", ShortName: "short2"},
 	}
 
-	err = storage.SaveAll(sources)
+	err = storage.SaveAll(context.Background(), sources)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -98,7 +110,7 @@ func TestPostgresSrcStorage_Delete(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = storage.Delete(1)
+	err = storage.Delete(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -112,16 +124,16 @@ func TestPostgresSrcStorage_GetByID(t *testing.T) {
 
 	storage := NewSrc(db)
 
-	rows := sqlmock.NewRows([]string{"id", "name", "link"}).
-		AddRow(1, "source1", "link1")
+	rows := sqlmock.NewRows([]string{"id", "name", "link", "short_name", "etag", "last_modified", "scrape_config", "retention", "schedule", "health"}).
+		AddRow(1, "source1", "link1", "short1", "", "", nil, nil, nil, nil)
 
-	mock.ExpectQuery(`SELECT id, name, link FROM sources WHERE id = \$1`).
+	mock.ExpectQuery(`SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources WHERE id = \$1`).
 		WithArgs(1).
 		WillReturnRows(rows)
 
-	source, err := storage.GetByID(1)
+	source, err := storage.GetByID(context.Background(), 1)
 	assert.NoError(t, err)
-	assert.Equal(t, model.Source{Id: 1, Name: "source1", Link: "link1"}, source)
+	assert.Equal(t, model.Source{Id: 1, Name: "source1", Link: "link1", ShortName: "short1"}, source)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -134,14 +146,80 @@ func TestPostgresSrcStorage_Update(t *testing.T) {
 
 	storage := NewSrc(db)
 
-	mock.ExpectExec(`UPDATE sources SET name = \$1, link = \$2, short_name = \$3 WHERE id = \$4`).
-		WithArgs("updated source", "updated link", "updated short name", 1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE sources SET name = \$1, link = \$2, short_name = \$3, scrape_config = \$4, retention = \$5, schedule = \$7, health = NULL, .* WHERE id = \$6 RETURNING etag, last_modified`).
+		WithArgs("updated source", "updated link", "updated short name", nil, nil, 1, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"etag", "last_modified"}).AddRow(`"v1"`, ""))
 
 	source := model.Source{Name: "updated source", Link: "updated link", ShortName: "updated short name"}
-	updatedSource, err := storage.Update(1, source)
+	updatedSource, err := storage.Update(context.Background(), 1, source)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, updatedSource.Id)
+	assert.Equal(t, `"v1"`, updatedSource.ETag)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSrcStorage_Update_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := NewSrc(db)
+
+	mock.ExpectQuery(`UPDATE sources SET`).
+		WithArgs("updated source", "updated link", "updated short name", nil, nil, 1, nil).
+		WillReturnError(sql.ErrNoRows)
+
+	source := model.Source{Name: "updated source", Link: "updated link", ShortName: "updated short name"}
+	_, err = storage.Update(context.Background(), 1, source)
+	assert.EqualError(t, err, "source with id 1 not found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSrcStorage_UpdateValidators(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := NewSrc(db)
+
+	mock.ExpectExec(`UPDATE sources SET etag = \$1, last_modified = \$2 WHERE id = \$3`).
+		WithArgs(`"v2"`, "Mon, 02 Jan 2006 15:04:05 GMT", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = storage.UpdateValidators(context.Background(), 1, `"v2"`, "Mon, 02 Jan 2006 15:04:05 GMT")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSrcStorage_UpdateHealth(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	storage := NewSrc(db)
+	health := model.SourceHealth{Status: model.SourceHealthy, Fetches: 1, AverageItems: 10}
+
+	previous := model.SourceHealth{Status: model.SourceHealthy}
+	query := `UPDATE sources SET health = \$1 WHERE id = \$2 AND health IS NOT DISTINCT FROM \$3::jsonb`
+	mock.ExpectExec(query).
+		WithArgs(health, 1, &previous).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).
+		WithArgs(health, 1, &previous).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	updated, err := storage.UpdateHealth(context.Background(), 1, &previous, health)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	updated, err = storage.UpdateHealth(context.Background(), 1, &previous, health)
+	assert.NoError(t, err)
+	assert.False(t, updated, "the health changed since it was read")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -154,11 +232,11 @@ func TestPostgresSrcStorage_GetByID_NotFound(t *testing.T) {
 
 	storage := NewSrc(db)
 
-	mock.ExpectQuery(`SELECT id, name, link FROM sources WHERE id = \$1`).
+	mock.ExpectQuery(`SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources WHERE id = \$1`).
 		WithArgs(1).
 		WillReturnError(sql.ErrNoRows)
 
-	_, err = storage.GetByID(1)
+	_, err = storage.GetByID(context.Background(), 1)
 	assert.Error(t, err)
 	assert.Equal(t, "source with id 1 not found", err.Error())
 	assert.NoError(t, mock.ExpectationsWereMet())
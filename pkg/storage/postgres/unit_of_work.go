@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/jmoiron/sqlx"
+)
+
+// dbtx runs the queries of the storages, either on the database
+// or within the transaction of the unit of work they are bound to.
+type dbtx interface {
+	sqlx.ExtContext
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// inTx runs fn in a new transaction, committed if fn returns nil and rolled back otherwise.
+// Storages bound to a unit of work run fn in its transaction instead, which the unit of work commits.
+func inTx(ctx context.Context, db dbtx, fn func(tx *sqlx.Tx) error) error {
+	if tx, ok := db.(*sqlx.Tx); ok {
+		return fn(tx)
+	}
+	tx, err := db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// unitOfWork is the implementation of the UnitOfWork interface running the work in a database transaction.
+type unitOfWork struct {
+	db *sqlx.DB
+}
+
+// NewUnitOfWork creates a new UnitOfWork running the work in transactions of the database.
+func NewUnitOfWork(db *sqlx.DB) service.UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+// Do runs fn with the article and source storages bound to a single transaction,
+// committed if fn returns nil and rolled back otherwise.
+func (u *unitOfWork) Do(ctx context.Context, fn func(articles service.ArticleStorage, sources service.SourceStorage) error) error {
+	return inTx(ctx, u.db, func(tx *sqlx.Tx) error {
+		return fn(&postgresArticleStorage{db: tx}, &postgresSrcStorage{db: tx})
+	})
+}
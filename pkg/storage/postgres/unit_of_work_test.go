@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestUnitOfWork_Do(t *testing.T) {
+	tests := []struct {
+		name    string
+		mock    func(mock sqlmock.Sqlmock)
+		fn      func(ctx context.Context, articles service.ArticleStorage, sources service.SourceStorage) error
+		wantErr string
+	}{
+		{
+			name: "commits",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`DELETE FROM articles WHERE source_id = \$1`).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(`DELETE FROM sources WHERE id = \$1`).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, articles service.ArticleStorage, sources service.SourceStorage) error {
+				if err := articles.DeleteBySourceID(ctx, 1); err != nil {
+					return err
+				}
+				return sources.Delete(ctx, 1)
+			},
+		},
+		{
+			name: "rolls back",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`DELETE FROM articles WHERE source_id = \$1`).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(`DELETE FROM sources WHERE id = \$1`).WithArgs(1).WillReturnError(errors.New("connection reset"))
+				mock.ExpectRollback()
+			},
+			fn: func(ctx context.Context, articles service.ArticleStorage, sources service.SourceStorage) error {
+				if err := articles.DeleteBySourceID(ctx, 1); err != nil {
+					return err
+				}
+				return sources.Delete(ctx, 1)
+			},
+			wantErr: "connection reset",
+		},
+		{
+			name: "saving articles joins the transaction",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT canonical_link, story_id FROM articles`).
+					WillReturnRows(sqlmock.NewRows([]string{"canonical_link", "story_id"}))
+				mock.ExpectQuery(`SELECT nextval`).
+					WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+				mock.ExpectQuery(`SELECT story_id, fingerprint FROM \(`).
+					WillReturnRows(sqlmock.NewRows([]string{"story_id", "fingerprint"}))
+				mock.ExpectQuery(`INSERT INTO articles`).
+					WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(true))
+				mock.ExpectExec(`UPDATE sources SET etag = \$1, last_modified = \$2 WHERE id = \$3`).
+					WithArgs(`"v1"`, "", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, articles service.ArticleStorage, sources service.SourceStorage) error {
+				_, err := articles.SaveAll(ctx, []model.Article{{Title: "title1", Link: "https://example.com/1", Source: model.Source{Id: 1}}})
+				if err != nil {
+					return err
+				}
+				return sources.UpdateValidators(ctx, 1, `"v1"`, "")
+			},
+		},
+		{
+			name: "begin error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(errors.New("too many connections"))
+			},
+			fn: func(ctx context.Context, articles service.ArticleStorage, sources service.SourceStorage) error {
+				t.Error("the work runs without a transaction")
+				return nil
+			},
+			wantErr: "too many connections",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.Newx()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			tt.mock(mock)
+
+			ctx := context.Background()
+			err = NewUnitOfWork(db).Do(ctx, func(articles service.ArticleStorage, sources service.SourceStorage) error {
+				return tt.fn(ctx, articles, sources)
+			})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
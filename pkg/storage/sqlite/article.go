@@ -0,0 +1,314 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
+	"github.com/antonchaban/news-aggregator/pkg/filter"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type sqliteArticleStorage struct {
+	db dbtx
+}
+
+func New(db *sqlx.DB) service.ArticleStorage {
+	return &sqliteArticleStorage{db: db}
+}
+
+func (sa *sqliteArticleStorage) GetAll(ctx context.Context) ([]model.Article, error) {
+	query := `SELECT ` + articleColumns + `
+			FROM articles a
+			JOIN sources s ON a.source_id = s.id
+			ORDER BY a.id`
+	return sa.GetByFilter(ctx, query, nil)
+}
+
+// GetAfter returns up to limit articles with IDs greater than afterID, ordered by ID.
+func (sa *sqliteArticleStorage) GetAfter(ctx context.Context, afterID, limit int) ([]model.Article, error) {
+	query := `SELECT ` + articleColumns + `
+			FROM articles a
+			JOIN sources s ON a.source_id = s.id
+			WHERE a.id > ?1
+			ORDER BY a.id
+			LIMIT ?2`
+	return sa.GetByFilter(ctx, query, []interface{}{afterID, limit})
+}
+
+// Save adds a new article to the database.
+// Articles are unique by their canonical link, and a new article joins the story
+// of the most similar article published around the same time, if there is one.
+func (sa *sqliteArticleStorage) Save(ctx context.Context, article model.Article) (model.Article, error) {
+	article.CanonicalLink = dedup.CanonicalURL(article.Link)
+	article.Fingerprint = dedup.Fingerprint(article.Title, article.Description)
+
+	err := inTx(ctx, sa.db, func(tx *sqlx.Tx) error {
+		var err error
+		article, err = insert(ctx, tx, article)
+		return err
+	})
+	if err != nil {
+		return model.Article{}, err
+	}
+	return article, nil
+}
+
+// insert inserts the article with its canonical link and fingerprint set.
+// Publication dates are stored in UTC, so they sort as text in the queries.
+func insert(ctx context.Context, tx *sqlx.Tx, article model.Article) (model.Article, error) {
+	article.PubDate = article.PubDate.UTC()
+	storyID, ok, err := findStory(ctx, tx, article)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	createQuery := `INSERT INTO articles (title, description, link, source_id, pub_date, canonical_link, fingerprint, story_id,
+			                      author, categories, image_url, content, language, guid)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14)`
+	res, err := tx.ExecContext(ctx, createQuery, article.Title, article.Description, article.Link,
+		article.Source.Id, article.PubDate, article.CanonicalLink, int64(article.Fingerprint), storyID,
+		article.Author, jsonList(article.Categories), article.ImageURL, article.Content, article.Language, article.GUID)
+	if err != nil {
+		return model.Article{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return model.Article{}, err
+	}
+	article.Id = int(id)
+	article.StoryId = storyID
+
+	// An article that joins no story starts its own one
+	if !ok {
+		article.StoryId = article.Id
+		_, err = tx.ExecContext(ctx, `UPDATE articles SET story_id = ?1 WHERE id = ?1`, article.Id)
+		if err != nil {
+			return model.Article{}, err
+		}
+	}
+	return article, nil
+}
+
+// findStory returns the story of the saved article most similar to the given one
+// among the articles published within dedup.StoryWindow of it.
+// The distances of the fingerprints are counted by the database, which returns the nearest article only.
+func findStory(ctx context.Context, tx *sqlx.Tx, article model.Article) (int, bool, error) {
+	if article.Fingerprint == 0 {
+		return 0, false, nil
+	}
+	query := `SELECT story_id FROM articles
+			WHERE fingerprint <> 0 AND pub_date BETWEEN ?1 AND ?2 AND ` + distanceFunction + `(fingerprint, ?3) <= ?4
+			ORDER BY ` + distanceFunction + `(fingerprint, ?3), id LIMIT 1`
+	var storyID int
+	err := tx.GetContext(ctx, &storyID, query, article.PubDate.Add(-dedup.StoryWindow),
+		article.PubDate.Add(dedup.StoryWindow), int64(article.Fingerprint), dedup.MaxDistance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return storyID, true, nil
+}
+
+// SaveAll upserts the articles within a single transaction, counting what changed.
+// New articles are inserted, and the saved articles linking to the same page are updated
+// if their content or metadata changed, keeping their ID and story.
+func (sa *sqliteArticleStorage) SaveAll(ctx context.Context, articles []model.Article) (model.SaveResult, error) {
+	articles, repeated := dedup.UniqueByLink(articles)
+	result := model.SaveResult{Skipped: repeated}
+
+	err := inTx(ctx, sa.db, func(tx *sqlx.Tx) error {
+		for _, article := range articles {
+			var id int
+			err := tx.GetContext(ctx, &id, `SELECT id FROM articles WHERE canonical_link = ?1`, article.CanonicalLink)
+			if errors.Is(err, sql.ErrNoRows) {
+				if _, err = insert(ctx, tx, article); err != nil {
+					return err
+				}
+				result.Inserted++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			updateQuery := `UPDATE articles SET title = ?1, description = ?2, pub_date = ?3, fingerprint = ?4,
+					    author = ?6, categories = ?7, image_url = ?8, content = ?9, language = ?10, guid = ?11
+					WHERE id = ?5 AND (title IS NOT ?1 OR description IS NOT ?2 OR pub_date IS NOT ?3
+					    OR author IS NOT ?6 OR categories IS NOT ?7 OR image_url IS NOT ?8
+					    OR content IS NOT ?9 OR language IS NOT ?10 OR guid IS NOT ?11)`
+			res, err := tx.ExecContext(ctx, updateQuery, article.Title, article.Description, article.PubDate.UTC(),
+				int64(article.Fingerprint), id, article.Author, jsonList(article.Categories), article.ImageURL,
+				article.Content, article.Language, article.GUID)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n > 0 {
+				result.Updated++
+			} else {
+				result.Skipped++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return model.SaveResult{}, err
+	}
+	return result, nil
+}
+
+func (sa *sqliteArticleStorage) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM articles WHERE id = ?1`
+	_, err := sa.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (sa *sqliteArticleStorage) DeleteBySourceID(ctx context.Context, id int) error {
+	query := `DELETE FROM articles WHERE source_id = ?1`
+	_, err := sa.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// GetExpired returns the articles of the source published before the cutoff or past
+// the newest maxCount ones, oldest first. The zero cutoff and maxCount set no limit.
+func (sa *sqliteArticleStorage) GetExpired(ctx context.Context, sourceID int, cutoff time.Time, maxCount int) ([]model.Article, error) {
+	query := `SELECT ` + articleColumns + `
+			FROM (SELECT articles.*, ROW_NUMBER() OVER (ORDER BY pub_date DESC, id DESC) AS newest
+			      FROM articles WHERE source_id = ?1) a
+			JOIN sources s ON a.source_id = s.id
+			WHERE a.pub_date < ?2 OR (?3 > 0 AND a.newest > ?3)
+			ORDER BY a.pub_date, a.id`
+	return sa.GetByFilter(ctx, query, []interface{}{sourceID, cutoff.UTC(), maxCount})
+}
+
+// DeleteByIDs deletes the articles with the given IDs in a single transaction
+// and returns the number of deleted ones.
+func (sa *sqliteArticleStorage) DeleteByIDs(ctx context.Context, ids []int) (int, error) {
+	deleted := 0
+	err := inTx(ctx, sa.db, func(tx *sqlx.Tx) error {
+		for _, id := range ids {
+			res, err := tx.ExecContext(ctx, `DELETE FROM articles WHERE id = ?1`, id)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			deleted += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// articleColumns are the article and source columns selected by the filter queries, in the order they are scanned.
+const articleColumns = `a.id, a.title, a.description, a.link, a.pub_date, a.story_id,
+			a.author, a.categories, a.image_url, a.content, a.language, a.guid,
+			s.id, s.name, s.link, s.short_name`
+
+// articleColumnCount is the number of article and source columns selected by the filter queries.
+const articleColumnCount = 16
+
+// jsonList stores a list of strings as a JSON array, as SQLite has no array columns.
+type jsonList []string
+
+// Value stores the list as a JSON array, an empty one for the nil list.
+func (l jsonList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+// Scan reads the list from its JSON array, leaving it nil if the array is empty.
+func (l *jsonList) Scan(src interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case []byte:
+		b = src
+	case string:
+		b = []byte(src)
+	default:
+		return fmt.Errorf("can't scan %T into a list", src)
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		list = nil
+	}
+	*l = list
+	return nil
+}
+
+func (sa *sqliteArticleStorage) GetByFilter(ctx context.Context, query string, args []interface{}) ([]model.Article, error) {
+	rows, err := sa.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("error reading columns: %w", err)
+	}
+	// Keyword searches select the relevance of every article as an additional column
+	ranked := len(columns) > articleColumnCount
+
+	var articles []model.Article
+	for rows.Next() {
+		var article model.Article
+		var source model.Source
+
+		dest := []interface{}{
+			&article.Id, &article.Title, &article.Description, &article.Link, &article.PubDate, &article.StoryId,
+			&article.Author, (*jsonList)(&article.Categories), &article.ImageURL, &article.Content, &article.Language, &article.GUID,
+			&source.Id, &source.Name, &source.Link, &source.ShortName,
+		}
+		if ranked {
+			dest = append(dest, &article.Relevance)
+		}
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+
+		article.Source = source
+		articles = append(articles, article)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return articles, nil
+}
+
+func (sa *sqliteArticleStorage) CountByFilter(ctx context.Context, query string, args []interface{}) (int, error) {
+	var count int
+	err := sa.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error executing query: %w", err)
+	}
+	return count, nil
+}
+
+// Dialect returns the SQLite dialect of the filter queries.
+func (sa *sqliteArticleStorage) Dialect() filter.Dialect {
+	return filter.SQLite
+}
@@ -0,0 +1,325 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antonchaban/news-aggregator/pkg/filter"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPubDate = time.Date(2024, 8, 5, 9, 0, 0, 0, time.UTC)
+
+// seedSources saves the bbc (ID 1) and abc (ID 2) sources.
+func seedSources(t *testing.T, db *sqlx.DB) (model.Source, model.Source) {
+	sources := NewSrc(db)
+	bbc, err := sources.Save(context.Background(), model.Source{Name: "BBC News", Link: "https://bbc.com/rss", ShortName: "bbc"})
+	require.NoError(t, err)
+	abc, err := sources.Save(context.Background(), model.Source{Name: "ABC News", Link: "https://abcnews.go.com/rss", ShortName: "abc"})
+	require.NoError(t, err)
+	return bbc, abc
+}
+
+// testArticles returns articles of two stories: the trial reported by both sources
+// and the heatstroke reported by abc only, plus an unrelated golang article of bbc.
+func testArticles(bbc, abc model.Source) []model.Article {
+	return []model.Article{
+		{
+			Title:       "Trial starts for a Polish man accused of punching Danish prime minister in Copenhagen",
+			Description: "A Polish man went on trial in Copenhagen accused of punching the Danish prime minister.",
+			Link:        "https://www.bbc.com/news/trial",
+			Source:      bbc,
+			PubDate:     testPubDate,
+			Author:      "Jane Doe",
+			Categories:  []string{"World", "Europe"},
+			ImageURL:    "https://www.bbc.com/news/trial.jpg",
+			Language:    "en-gb",
+			GUID:        "bbc-trial",
+		},
+		{
+			Title:       "Trial starts for a Polish man accused of punching Danish prime minister in Copenhagen",
+			Description: "A Polish man went on trial in Copenhagen on Monday accused of punching Danish Prime Minister Mette Frederiksen.",
+			Link:        "https://abcnews.go.com/trial",
+			Source:      abc,
+			PubDate:     testPubDate.Add(time.Hour),
+			Author:      "John Smith, Mary Doe",
+			Categories:  []string{"International"},
+		},
+		{
+			Title:       "More than 120 people died in Tokyo from heatstroke in July",
+			Description: "Average temperatures hit record highs in the Danish capital and Tokyo.",
+			Link:        "https://abcnews.go.com/heatstroke",
+			Source:      abc,
+			PubDate:     testPubDate.Add(2 * time.Hour),
+			Categories:  []string{"world"},
+		},
+		{
+			Title:       "Go 1.23 is released",
+			Description: "The new golang release brings range over functions.",
+			Link:        "https://www.bbc.com/news/golang",
+			Source:      bbc,
+			PubDate:     testPubDate.AddDate(0, 0, 2),
+		},
+	}
+}
+
+func TestSQLiteArticleStorage_Save(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	bbc, abc := seedSources(t, db)
+	storage := New(db)
+
+	var saved []model.Article
+	for _, article := range testArticles(bbc, abc) {
+		s, err := storage.Save(ctx, article)
+		require.NoError(t, err)
+		saved = append(saved, s)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4}, []int{saved[0].Id, saved[1].Id, saved[2].Id, saved[3].Id})
+	assert.Equal(t, []int{1, 1, 3, 4}, []int{saved[0].StoryId, saved[1].StoryId, saved[2].StoryId, saved[3].StoryId},
+		"the near-duplicate joins the story of the first article")
+
+	_, err := storage.Save(ctx, model.Article{Title: "Trial starts", Link: "http://bbc.com/news/trial/?utm_source=rss#top", Source: bbc})
+	assert.Error(t, err, "articles are unique by their canonical link")
+
+	all, err := storage.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	assert.Equal(t, saved[1].Title, all[1].Title)
+	assert.Equal(t, abc, all[1].Source)
+	assert.True(t, saved[1].PubDate.Equal(all[1].PubDate))
+	assert.Equal(t, []string{"World", "Europe"}, all[0].Categories)
+	assert.Equal(t, "https://www.bbc.com/news/trial.jpg", all[0].ImageURL)
+	assert.Equal(t, "en-gb", all[0].Language)
+	assert.Equal(t, "bbc-trial", all[0].GUID)
+	assert.Nil(t, all[3].Categories)
+
+	require.NoError(t, storage.Delete(ctx, saved[0].Id))
+	require.NoError(t, storage.DeleteBySourceID(ctx, abc.Id))
+	all, err = storage.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, saved[3].Id, all[0].Id)
+}
+
+func TestSQLiteArticleStorage_SaveAll(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	bbc, abc := seedSources(t, db)
+	storage := New(db)
+	articles := testArticles(bbc, abc)
+
+	result, err := storage.SaveAll(ctx, articles)
+	require.NoError(t, err)
+	assert.Equal(t, model.SaveResult{Inserted: 4}, result)
+
+	// Saving the feed again only updates the changed articles, keeping their IDs and stories
+	articles[1].Description = "A Polish man went on trial in Copenhagen on Monday accused of punching Danish Prime Minister."
+	articles[3].PubDate = articles[3].PubDate.Add(time.Hour)
+	articles[2].Categories = append(articles[2].Categories, "Asia")
+	articles = append(articles, model.Article{
+		Title:   "Go 1.23 is released",
+		Link:    "http://bbc.com/news/golang/?utm_source=rss",
+		Source:  bbc,
+		PubDate: testPubDate,
+	})
+	result, err = storage.SaveAll(ctx, articles)
+	require.NoError(t, err)
+	assert.Equal(t, model.SaveResult{Updated: 3, Skipped: 2}, result)
+
+	all, err := storage.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	assert.Equal(t, articles[1].Description, all[1].Description)
+	assert.Equal(t, 1, all[1].StoryId)
+	assert.True(t, articles[3].PubDate.Equal(all[3].PubDate))
+	assert.Equal(t, []string{"world", "Asia"}, all[2].Categories)
+
+	// The updated description is found by the keyword search
+	found, err := service.New(storage).GetByFilter(ctx, filter.Filters{Keyword: "monday"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, all[1].Id, found[0].Id)
+}
+
+func TestSQLiteArticleStorage_GetByFilter(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	bbc, abc := seedSources(t, db)
+	storage := New(db)
+	_, err := storage.SaveAll(ctx, testArticles(bbc, abc))
+	require.NoError(t, err)
+	articleService := service.New(storage)
+
+	tests := []struct {
+		name      string
+		f         filter.Filters
+		wantLinks []string
+	}{
+		{
+			name: "keyword in any field",
+			f:    filter.Filters{Keyword: "danish"},
+			wantLinks: []string{"https://abcnews.go.com/heatstroke", "https://abcnews.go.com/trial",
+				"https://www.bbc.com/news/trial"},
+		},
+		{
+			name:      "stemmed keyword scoped to the title and negated",
+			f:         filter.Filters{Keyword: `title:"punched danish" -frederiksen`},
+			wantLinks: []string{"https://www.bbc.com/news/trial"},
+		},
+		{
+			name:      "keywords without words",
+			f:         filter.Filters{Keyword: `"+"`},
+			wantLinks: nil,
+		},
+		{
+			name:      "sources and dates",
+			f:         filter.Filters{Source: "bbc", StartDate: "2024-08-06"},
+			wantLinks: []string{"https://www.bbc.com/news/golang"},
+		},
+		{
+			name:      "authors",
+			f:         filter.Filters{Author: "jane,mary"},
+			wantLinks: []string{"https://abcnews.go.com/trial", "https://www.bbc.com/news/trial"},
+		},
+		{
+			name:      "categories",
+			f:         filter.Filters{Category: "WORLD"},
+			wantLinks: []string{"https://abcnews.go.com/heatstroke", "https://www.bbc.com/news/trial"},
+		},
+		{
+			name:      "collapsed stories",
+			f:         filter.Filters{Keyword: "trial", Collapse: true},
+			wantLinks: []string{"https://www.bbc.com/news/trial"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles, err := articleService.GetByFilter(ctx, tt.f)
+			require.NoError(t, err)
+			var links []string
+			for _, article := range articles {
+				links = append(links, article.Link)
+			}
+			assert.ElementsMatch(t, tt.wantLinks, links)
+			if tt.f.Collapse {
+				assert.Equal(t, []model.Source{abc}, articles[0].OtherSources)
+			}
+		})
+	}
+}
+
+func TestSQLiteArticleStorage_GetPage(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	bbc, abc := seedSources(t, db)
+	storage := New(db)
+	_, err := storage.SaveAll(ctx, testArticles(bbc, abc))
+	require.NoError(t, err)
+	articleService := service.New(storage)
+
+	// The title match of the trial articles ranks them above the heatstroke one
+	page, err := articleService.GetPage(ctx, filter.Filters{Keyword: "danish"},
+		filter.Page{Limit: 2, Sort: filter.SortRelevance})
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	require.Len(t, page.Articles, 2)
+	assert.Contains(t, page.Articles[0].Link, "trial")
+	assert.Contains(t, page.Articles[1].Link, "trial")
+	assert.Greater(t, page.Articles[1].Relevance, 0.0)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = articleService.GetPage(ctx, filter.Filters{Keyword: "danish"},
+		filter.Page{Limit: 2, Sort: filter.SortRelevance, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Articles, 1)
+	assert.Equal(t, "https://abcnews.go.com/heatstroke", page.Articles[0].Link)
+	assert.Empty(t, page.NextCursor)
+
+	// Publication dates sort and paginate in time order
+	var links []string
+	p := filter.Page{Limit: 3}
+	for {
+		page, err = articleService.GetPage(ctx, filter.Filters{}, p)
+		require.NoError(t, err)
+		for _, article := range page.Articles {
+			links = append(links, article.Link)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		p.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"https://www.bbc.com/news/golang", "https://abcnews.go.com/heatstroke",
+		"https://abcnews.go.com/trial", "https://www.bbc.com/news/trial"}, links)
+}
+
+func TestSQLiteArticleStorage_GetExpired(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	bbc, abc := seedSources(t, db)
+	storage := New(db)
+	_, err := storage.SaveAll(ctx, testArticles(bbc, abc))
+	require.NoError(t, err)
+
+	// The bbc trial is older than a day before the golang article
+	expired, err := storage.GetExpired(ctx, bbc.Id, testPubDate.AddDate(0, 0, 1), 0)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "https://www.bbc.com/news/trial", expired[0].Link)
+
+	// Only the newest abc article is kept
+	expiredAbc, err := storage.GetExpired(ctx, abc.Id, time.Time{}, 1)
+	require.NoError(t, err)
+	require.Len(t, expiredAbc, 1)
+	assert.Equal(t, "https://abcnews.go.com/trial", expiredAbc[0].Link)
+
+	deleted, err := storage.DeleteByIDs(ctx, []int{expired[0].Id, expiredAbc[0].Id, 42})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	remaining, err := storage.GetAll(ctx)
+	require.NoError(t, err)
+	var links []string
+	for _, article := range remaining {
+		links = append(links, article.Link)
+	}
+	assert.ElementsMatch(t, []string{"https://abcnews.go.com/heatstroke", "https://www.bbc.com/news/golang"}, links)
+}
+
+func TestSQLiteArticleStorage_GetAfter(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	bbc, abc := seedSources(t, db)
+	storage := New(db)
+	_, err := storage.SaveAll(ctx, testArticles(bbc, abc))
+	require.NoError(t, err)
+
+	// Reading two at a time pages through all the articles in the order of their IDs
+	var ids []int
+	afterID := 0
+	for {
+		articles, err := storage.GetAfter(ctx, afterID, 2)
+		require.NoError(t, err)
+		if len(articles) == 0 {
+			break
+		}
+		assert.LessOrEqual(t, len(articles), 2)
+		for _, article := range articles {
+			ids = append(ids, article.Id)
+		}
+		afterID = articles[len(articles)-1].Id
+	}
+	assert.Equal(t, []int{1, 2, 3, 4}, ids)
+
+	articles, err := storage.GetAfter(ctx, 3, 10)
+	require.NoError(t, err)
+	require.Len(t, articles, 1)
+	assert.Equal(t, "https://www.bbc.com/news/golang", articles[0].Link)
+	assert.Equal(t, bbc, articles[0].Source)
+}
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"github.com/antonchaban/news-aggregator/pkg/dedup"
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+)
+
+const (
+	// driverName is the name the pure Go SQLite driver is registered with.
+	driverName = "sqlite"
+	// dsnParams enforce the foreign keys the articles of a source are deleted by,
+	// let concurrent readers work along the writer and store times in a sortable format.
+	dsnParams = "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+)
+
+// distanceFunction is the SQL function counting the differing bits of two fingerprints.
+const distanceFunction = "fingerprint_distance"
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction(distanceFunction, 2,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			a, _ := args[0].(int64)
+			b, _ := args[1].(int64)
+			return int64(dedup.Distance(uint64(a), uint64(b))), nil
+		})
+}
+
+// Open opens the SQLite database in the given file, creating it if it doesn't exist.
+// The schema is applied by the schema.SQLite migrations.
+func Open(file string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect(driverName, file+dsnParams)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer at a time, sharing one connection
+	// serializes the writes instead of failing them with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
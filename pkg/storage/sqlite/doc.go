@@ -0,0 +1,9 @@
+// Package sqlite provides a single-file SQLite storage implementation.
+// It's meant for small deployments and development, where running a Postgres server
+// is too much but the articles must survive restarts.
+//
+// The database schema is migrated by storage.NewDB with the schema.SQLite migrations.
+// The keyword search runs on an FTS5 index of the articles, see filter.SQLite.
+//
+// The pure Go driver is used, so the binaries stay statically linked.
+package sqlite
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchLocker_Acquire(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	src, err := NewSrc(db).Save(ctx, model.Source{Name: "BBC", Link: "https://bbc.com/rss", ShortName: "bbc"})
+	require.NoError(t, err)
+	// The replicas share the database
+	first, second := NewFetchLocker(db, "replica-1"), NewFetchLocker(db, "replica-2")
+
+	acquired, err := first.Acquire(ctx, src.Id, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = second.Acquire(ctx, src.Id, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, acquired, "the lease is held by the first replica")
+
+	acquired, err = first.Acquire(ctx, src.Id, time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, acquired, "the holder renews its lease")
+
+	time.Sleep(5 * time.Millisecond)
+	acquired, err = second.Acquire(ctx, src.Id, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired, "the expired lease is taken over")
+
+	acquired, err = first.Acquire(ctx, src.Id, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// The leases are deleted along with their source
+	require.NoError(t, NewSrc(db).Delete(ctx, src.Id))
+	var leases int
+	require.NoError(t, db.Get(&leases, `SELECT count(*) FROM fetch_leases`))
+	assert.Zero(t, leases)
+}
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// fetchAttemptColumns are the columns of the fetch attempts, in the order they are inserted.
+const fetchAttemptColumns = `run_id, source_id, source_name, started_at, finished_at, status, http_status, bytes,
+	articles, inserted, updated, error`
+
+// sqliteFetchRunStorage is the implementation of the FetchRunStorage interface keeping the runs
+// in the fetch_runs table and their attempts in the fetch_attempts table.
+// The times are stored in UTC, so they compare in the order they happened.
+type sqliteFetchRunStorage struct {
+	db dbtx
+}
+
+// NewFetchRunStorage creates a new FetchRunStorage on top of the database.
+func NewFetchRunStorage(db *sqlx.DB) service.FetchRunStorage {
+	return &sqliteFetchRunStorage{db: db}
+}
+
+// Save inserts the run and its attempts in a single transaction.
+func (sr *sqliteFetchRunStorage) Save(ctx context.Context, run model.FetchRun) (model.FetchRun, error) {
+	err := inTx(ctx, sr.db, func(tx *sqlx.Tx) error {
+		query := `INSERT INTO fetch_runs (started_at, finished_at, sources, failed, inserted, updated)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6) RETURNING id`
+		err := tx.QueryRowContext(ctx, query, run.StartedAt.UTC(), run.FinishedAt.UTC(), run.Sources, run.Failed,
+			run.Inserted, run.Updated).Scan(&run.Id)
+		if err != nil {
+			return err
+		}
+		query = `INSERT INTO fetch_attempts (` + fetchAttemptColumns + `)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12) RETURNING id`
+		for i := range run.Attempts {
+			a := &run.Attempts[i]
+			a.RunId = run.Id
+			err = tx.QueryRowContext(ctx, query, a.RunId, a.SourceId, a.SourceName, a.StartedAt.UTC(), a.FinishedAt.UTC(),
+				a.Status, a.HTTPStatus, a.Bytes, a.Articles, a.Inserted, a.Updated, a.Error).Scan(&a.Id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return model.FetchRun{}, err
+	}
+	return run, nil
+}
+
+// GetAll returns up to limit runs with their attempts, newest first.
+func (sr *sqliteFetchRunStorage) GetAll(ctx context.Context, limit int) ([]model.FetchRun, error) {
+	var runs []model.FetchRun
+	query := `SELECT id, started_at, finished_at, sources, failed, inserted, updated FROM fetch_runs
+		ORDER BY started_at DESC, id DESC LIMIT ?1`
+	if err := sr.db.SelectContext(ctx, &runs, query, limit); err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return runs, nil
+	}
+
+	var attempts []model.FetchAttempt
+	query = `SELECT id, ` + fetchAttemptColumns + ` FROM fetch_attempts WHERE run_id IN
+		(SELECT id FROM fetch_runs ORDER BY started_at DESC, id DESC LIMIT ?1) ORDER BY id`
+	if err := sr.db.SelectContext(ctx, &attempts, query, limit); err != nil {
+		return nil, err
+	}
+	return withAttempts(runs, attempts), nil
+}
+
+// GetAttemptsBySourceID returns up to limit attempts of the source, newest first.
+func (sr *sqliteFetchRunStorage) GetAttemptsBySourceID(ctx context.Context, sourceID int, limit int) ([]model.FetchAttempt, error) {
+	var attempts []model.FetchAttempt
+	query := `SELECT id, ` + fetchAttemptColumns + ` FROM fetch_attempts WHERE source_id = ?1
+		ORDER BY started_at DESC, id DESC LIMIT ?2`
+	if err := sr.db.SelectContext(ctx, &attempts, query, sourceID, limit); err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// DeleteBefore deletes the runs started before the cutoff, their attempts are deleted by the foreign key.
+func (sr *sqliteFetchRunStorage) DeleteBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	res, err := sr.db.ExecContext(ctx, `DELETE FROM fetch_runs WHERE started_at < ?1`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
+// DeleteBeyond deletes all but the newest maxRuns runs, their attempts are deleted by the foreign key.
+func (sr *sqliteFetchRunStorage) DeleteBeyond(ctx context.Context, maxRuns int) (int, error) {
+	query := `DELETE FROM fetch_runs WHERE id IN
+		(SELECT id FROM fetch_runs ORDER BY started_at DESC, id DESC LIMIT -1 OFFSET ?1)`
+	res, err := sr.db.ExecContext(ctx, query, maxRuns)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
+// withAttempts assigns the attempts to the runs they belong to, keeping the order of both.
+func withAttempts(runs []model.FetchRun, attempts []model.FetchAttempt) []model.FetchRun {
+	byID := make(map[int]int, len(runs))
+	for i := range runs {
+		runs[i].Attempts = []model.FetchAttempt{}
+		byID[runs[i].Id] = i
+	}
+	for _, a := range attempts {
+		if i, ok := byID[a.RunId]; ok {
+			runs[i].Attempts = append(runs[i].Attempts, a)
+		}
+	}
+	return runs
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteFetchRunStorage(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	storage := NewFetchRunStorage(db)
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("EEST", 3*60*60))
+	newRun := func(startedAt time.Time, attempts ...model.FetchAttempt) model.FetchRun {
+		for i := range attempts {
+			attempts[i].StartedAt, attempts[i].FinishedAt = startedAt, startedAt.Add(time.Second)
+		}
+		return model.FetchRun{StartedAt: startedAt, FinishedAt: startedAt.Add(time.Second), Sources: len(attempts),
+			Attempts: attempts}
+	}
+
+	old, err := storage.Save(ctx, newRun(start,
+		model.FetchAttempt{SourceId: 1, SourceName: "bbc", Status: model.FetchFailed, HTTPStatus: 503,
+			Error: "unexpected status fetching feed: 503 Service Unavailable"}))
+	require.NoError(t, err)
+	assert.NotZero(t, old.Id)
+	assert.Equal(t, old.Id, old.Attempts[0].RunId)
+	assert.NotZero(t, old.Attempts[0].Id)
+
+	recent, err := storage.Save(ctx, newRun(start.Add(time.Hour),
+		model.FetchAttempt{SourceId: 1, SourceName: "bbc", Status: model.FetchOK, HTTPStatus: 200, Bytes: 2048,
+			Articles: 10, Inserted: 2, Updated: 1},
+		model.FetchAttempt{SourceId: 2, SourceName: "cnn", Status: model.FetchUnchanged, HTTPStatus: 304}))
+	require.NoError(t, err)
+
+	runs, err := storage.GetAll(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, recent.Id, runs[0].Id, "the newest run comes first")
+	assert.True(t, recent.StartedAt.Equal(runs[0].StartedAt))
+	require.Len(t, runs[0].Attempts, 2)
+	assert.Equal(t, "bbc", runs[0].Attempts[0].SourceName)
+	assert.Equal(t, int64(2048), runs[0].Attempts[0].Bytes)
+	assert.Equal(t, 2, runs[0].Attempts[0].Inserted)
+	assert.Equal(t, "cnn", runs[0].Attempts[1].SourceName)
+	assert.Equal(t, old.Id, runs[1].Id)
+	require.Len(t, runs[1].Attempts, 1)
+	assert.Equal(t, old.Attempts[0].Error, runs[1].Attempts[0].Error)
+
+	runs, err = storage.GetAll(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Len(t, runs[0].Attempts, 2)
+
+	attempts, err := storage.GetAttemptsBySourceID(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, model.FetchOK, attempts[0].Status)
+	assert.Equal(t, model.FetchFailed, attempts[1].Status)
+	assert.Equal(t, 503, attempts[1].HTTPStatus)
+
+	deleted, err := storage.DeleteBefore(ctx, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	runs, err = storage.GetAll(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, recent.Id, runs[0].Id)
+
+	// The attempts are deleted along with their run
+	attempts, err = storage.GetAttemptsBySourceID(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, attempts, 1)
+
+	newest, err := storage.Save(ctx, model.FetchRun{StartedAt: start.Add(2 * time.Hour), FinishedAt: start.Add(2 * time.Hour),
+		Sources: 1, Attempts: []model.FetchAttempt{{SourceId: 1, Status: model.FetchOK}}})
+	require.NoError(t, err)
+	deleted, err = storage.DeleteBeyond(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	runs, err = storage.GetAll(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, newest.Id, runs[0].Id)
+	attempts, err = storage.GetAttemptsBySourceID(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, newest.Attempts[0].Id, attempts[0].Id)
+}
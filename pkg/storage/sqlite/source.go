@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/jmoiron/sqlx"
+)
+
+type sqliteSrcStorage struct {
+	db dbtx
+}
+
+func NewSrc(db *sqlx.DB) service.SourceStorage {
+	return &sqliteSrcStorage{db: db}
+}
+
+func (ssrc *sqliteSrcStorage) GetAll(ctx context.Context) ([]model.Source, error) {
+	var sources []model.Source
+	query := `SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources ORDER BY id`
+	err := ssrc.db.SelectContext(ctx, &sources, query)
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+func (ssrc *sqliteSrcStorage) Save(ctx context.Context, src model.Source) (model.Source, error) {
+	var id int
+	createQuery := `INSERT INTO sources (name, link, short_name, scrape_config, retention, schedule) VALUES (?1, ?2, ?3, ?4, ?5, ?6) RETURNING id`
+	err := ssrc.db.QueryRowContext(ctx, createQuery, src.Name, src.Link, src.ShortName, src.Scrape, src.Retention, src.Schedule).Scan(&id)
+	if err != nil {
+		return model.Source{}, err
+	}
+	src.Id = id
+	return src, nil
+}
+
+func (ssrc *sqliteSrcStorage) SaveAll(ctx context.Context, sources []model.Source) error {
+	for _, src := range sources {
+		_, err := ssrc.Save(ctx, src)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ssrc *sqliteSrcStorage) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM sources WHERE id = ?1`
+	_, err := ssrc.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (ssrc *sqliteSrcStorage) GetByID(ctx context.Context, id int) (model.Source, error) {
+	var src model.Source
+	query := `SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources WHERE id = ?1`
+	err := ssrc.db.GetContext(ctx, &src, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Source{}, fmt.Errorf("source with id %d not found", id)
+		}
+		return model.Source{}, err
+	}
+	return src, nil
+}
+
+func (ssrc *sqliteSrcStorage) GetByShortName(ctx context.Context, shortName string) (model.Source, error) {
+	var src model.Source
+	query := `SELECT id, name, link, short_name, etag, last_modified, scrape_config, retention, schedule, health FROM sources WHERE short_name = ?1`
+	err := ssrc.db.GetContext(ctx, &src, query, shortName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Source{}, fmt.Errorf("source with short name %s not found", shortName)
+		}
+		return model.Source{}, err
+	}
+	return src, nil
+}
+
+// Update updates the source with the given ID. The validators of the source
+// are kept, unless its link changes, as they belong to the old feed then.
+func (ssrc *sqliteSrcStorage) Update(ctx context.Context, id int, src model.Source) (model.Source, error) {
+	query := `UPDATE sources SET name = ?1, link = ?2, short_name = ?3, scrape_config = ?4, retention = ?5, schedule = ?7, health = NULL,
+		etag = CASE WHEN link = ?2 THEN etag ELSE '' END,
+		last_modified = CASE WHEN link = ?2 THEN last_modified ELSE '' END
+		WHERE id = ?6 RETURNING etag, last_modified`
+	err := ssrc.db.QueryRowContext(ctx, query, src.Name, src.Link, src.ShortName, src.Scrape, src.Retention, id, src.Schedule).Scan(&src.ETag, &src.LastModified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Source{}, fmt.Errorf("source with id %d not found", id)
+		}
+		return model.Source{}, err
+	}
+	src.Id = id
+	src.Health = nil
+	return src, nil
+}
+
+// UpdateValidators stores the ETag and Last-Modified validators of the latest fetch of the source.
+func (ssrc *sqliteSrcStorage) UpdateValidators(ctx context.Context, id int, etag, lastModified string) error {
+	query := `UPDATE sources SET etag = ?1, last_modified = ?2 WHERE id = ?3`
+	_, err := ssrc.db.ExecContext(ctx, query, etag, lastModified, id)
+	return err
+}
+
+// UpdateHealth stores the health of the source after its latest fetch, unless its stored health
+// is no longer the previous one. The JSON text is compared, it's always encoded the same way.
+func (ssrc *sqliteSrcStorage) UpdateHealth(ctx context.Context, id int, previous *model.SourceHealth,
+	health model.SourceHealth) (bool, error) {
+	query := `UPDATE sources SET health = ?1 WHERE id = ?2 AND health IS ?3`
+	res, err := ssrc.db.ExecContext(ctx, query, health, id, previous)
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return updated > 0, nil
+}
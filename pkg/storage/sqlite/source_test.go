@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antonchaban/news-aggregator/pkg/model"
+	"github.com/antonchaban/news-aggregator/pkg/storage/migrate"
+	"github.com/antonchaban/news-aggregator/schema"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB opens a new migrated database in a temporary file.
+func newTestDB(t *testing.T) *sqlx.DB {
+	db, err := Open(filepath.Join(t.TempDir(), "news.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	m, err := migrate.New(db, schema.SQLite, nil)
+	require.NoError(t, err)
+	require.NoError(t, m.Up(context.Background()))
+	return db
+}
+
+func TestSQLiteSrcStorage_SaveAndGet(t *testing.T) {
+	ctx := context.Background()
+	storage := NewSrc(newTestDB(t))
+
+	scrape := &model.ScrapeConfig{ArticleSelector: "div.story", DateFormats: []string{"Jan 02, 2006"}}
+	retention := &model.RetentionPolicy{MaxAgeDays: 30}
+	schedule := &model.FetchSchedule{Cron: "0 * * * *", Enabled: true}
+	saved, err := storage.Save(ctx, model.Source{Name: "USA Today", Link: "https://usatoday.com", ShortName: "usatoday",
+		Scrape: scrape, Retention: retention, Schedule: schedule})
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved.Id)
+	require.NoError(t, storage.SaveAll(ctx, []model.Source{{Name: "BBC", Link: "https://bbc.com/rss", ShortName: "bbc"}}))
+
+	byID, err := storage.GetByID(ctx, saved.Id)
+	require.NoError(t, err)
+	assert.Equal(t, saved, byID)
+
+	byShortName, err := storage.GetByShortName(ctx, "bbc")
+	require.NoError(t, err)
+	assert.Equal(t, model.Source{Id: 2, Name: "BBC", Link: "https://bbc.com/rss", ShortName: "bbc"}, byShortName)
+
+	all, err := storage.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Source{saved, byShortName}, all)
+
+	_, err = storage.Save(ctx, model.Source{Name: "BBC again", Link: "https://bbc.co.uk/rss", ShortName: "bbc"})
+	assert.Error(t, err, "short names must be unique")
+
+	_, err = storage.GetByID(ctx, 42)
+	assert.EqualError(t, err, "source with id 42 not found")
+	_, err = storage.GetByShortName(ctx, "cnn")
+	assert.EqualError(t, err, "source with short name cnn not found")
+}
+
+func TestSQLiteSrcStorage_Update(t *testing.T) {
+	ctx := context.Background()
+	storage := NewSrc(newTestDB(t))
+	src, err := storage.Save(ctx, model.Source{Name: "BBC", Link: "https://bbc.com/rss", ShortName: "bbc"})
+	require.NoError(t, err)
+	require.NoError(t, storage.UpdateValidators(ctx, src.Id, `"v1"`, "Mon, 02 Jan 2006 15:04:05 GMT"))
+	lastFailure := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	health := model.SourceHealth{Status: model.SourceDisabled, ConsecutiveFailures: 10, LastFailure: &lastFailure,
+		LastError: "404 Not Found", UpdateInterval: time.Hour}
+	updatedHealth, err := storage.UpdateHealth(ctx, src.Id, nil, health)
+	require.NoError(t, err)
+	assert.True(t, updatedHealth)
+	byID, err := storage.GetByID(ctx, src.Id)
+	require.NoError(t, err)
+	assert.Equal(t, &health, byID.Health)
+	updatedHealth, err = storage.UpdateHealth(ctx, src.Id, nil, model.SourceHealth{Status: model.SourceHealthy})
+	require.NoError(t, err)
+	assert.False(t, updatedHealth, "the health is no longer the previous one")
+	updatedHealth, err = storage.UpdateHealth(ctx, src.Id, &health, health)
+	require.NoError(t, err)
+	assert.True(t, updatedHealth, "the stored health matches the previous one")
+
+	src.Name = "BBC News"
+	src.Schedule = &model.FetchSchedule{Interval: "1h"}
+	updated, err := storage.Update(ctx, src.Id, src)
+	require.NoError(t, err)
+	byID, err = storage.GetByID(ctx, src.Id)
+	require.NoError(t, err)
+	assert.Equal(t, src.Schedule, byID.Schedule)
+	assert.Nil(t, byID.Health, "updating the source resets its health")
+	updatedHealth, err = storage.UpdateHealth(ctx, src.Id, &health, health)
+	require.NoError(t, err)
+	assert.False(t, updatedHealth, "a reset health isn't overwritten")
+	assert.Equal(t, `"v1"`, updated.ETag, "validators are kept while the link is the same")
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", updated.LastModified)
+
+	src.Link = "https://feeds.bbci.co.uk/news/rss.xml"
+	updated, err = storage.Update(ctx, src.Id, src)
+	require.NoError(t, err)
+	assert.Empty(t, updated.ETag, "validators of the old feed are dropped")
+	assert.Empty(t, updated.LastModified)
+
+	got, err := storage.GetByID(ctx, src.Id)
+	require.NoError(t, err)
+	assert.Equal(t, updated, got)
+
+	_, err = storage.Update(ctx, 42, src)
+	assert.EqualError(t, err, "source with id 42 not found")
+}
+
+func TestSQLiteSrcStorage_Delete(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	sources, articles := NewSrc(db), New(db)
+	src, err := sources.Save(ctx, model.Source{Name: "BBC", Link: "https://bbc.com/rss", ShortName: "bbc"})
+	require.NoError(t, err)
+	_, err = articles.Save(ctx, model.Article{Title: "Title", Link: "https://bbc.com/1", Source: src})
+	require.NoError(t, err)
+
+	require.NoError(t, sources.Delete(ctx, src.Id))
+
+	_, err = sources.GetByID(ctx, src.Id)
+	assert.Error(t, err)
+	all, err := articles.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, all, "the articles of the source are deleted with it")
+}
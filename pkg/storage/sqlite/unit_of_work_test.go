@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antonchaban/news-aggregator/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitOfWork_Do(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	bbc, abc := seedSources(t, db)
+	articles, sources := New(db), NewSrc(db)
+	_, err := articles.SaveAll(ctx, testArticles(bbc, abc))
+	require.NoError(t, err)
+	uow := NewUnitOfWork(db)
+
+	// A failing unit of work keeps the source along with its articles
+	err = uow.Do(ctx, func(articles service.ArticleStorage, sources service.SourceStorage) error {
+		if err := articles.DeleteBySourceID(ctx, bbc.Id); err != nil {
+			return err
+		}
+		if err := sources.UpdateValidators(ctx, bbc.Id, `"v2"`, ""); err != nil {
+			return err
+		}
+		return errors.New("storage error")
+	})
+	assert.EqualError(t, err, "storage error")
+	saved, err := articles.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, saved, 4)
+	src, err := sources.GetByID(ctx, bbc.Id)
+	require.NoError(t, err)
+	assert.Empty(t, src.ETag)
+
+	// Saving articles joins the transaction, the single connection would wait for it otherwise
+	err = uow.Do(ctx, func(articles service.ArticleStorage, sources service.SourceStorage) error {
+		if err := articles.DeleteBySourceID(ctx, bbc.Id); err != nil {
+			return err
+		}
+		_, err := articles.SaveAll(ctx, testArticles(abc, abc)[:1])
+		if err != nil {
+			return err
+		}
+		return sources.Delete(ctx, bbc.Id)
+	})
+	require.NoError(t, err)
+	saved, err = articles.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, saved, 3)
+	_, err = sources.GetByID(ctx, bbc.Id)
+	assert.EqualError(t, err, "source with id 1 not found")
+}
@@ -0,0 +1,3 @@
+drop index articles_search_vector_idx;
+
+alter table articles drop column search_vector;
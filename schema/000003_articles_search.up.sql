@@ -0,0 +1,7 @@
+alter table articles
+    add column search_vector tsvector generated always as (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        ) stored;
+
+create index articles_search_vector_idx on articles using gin (search_vector);
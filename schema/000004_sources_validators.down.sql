@@ -0,0 +1,3 @@
+alter table sources
+    drop column etag,
+    drop column last_modified;
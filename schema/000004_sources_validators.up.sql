@@ -0,0 +1,3 @@
+alter table sources
+    add column etag          varchar(1024) not null default '',
+    add column last_modified varchar(128)  not null default '';
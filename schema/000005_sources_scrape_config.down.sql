@@ -0,0 +1,2 @@
+alter table sources
+    drop column scrape_config;
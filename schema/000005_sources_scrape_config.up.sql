@@ -0,0 +1,13 @@
+alter table sources
+    add column scrape_config jsonb;
+
+update sources
+set scrape_config = '{
+  "article_selector": "div.gnt_m.gnt_m_flm > a.gnt_m_flm_a",
+  "description_attribute": "data-c-br",
+  "pub_date_selector": "div.gnt_m_flm_sbt",
+  "date_attribute": "data-c-dt",
+  "date_formats": ["3:04 p.m. ET January 2, 2006", "2006-01-02 15:04", "Jan 02, 2006"],
+  "base_url": "https://www.usatoday.com"
+}'
+where link like 'https://www.usatoday.com/%';
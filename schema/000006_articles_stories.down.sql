@@ -0,0 +1,11 @@
+drop index articles_pub_date_idx;
+drop index articles_story_id_idx;
+drop index articles_canonical_link_idx;
+
+alter table articles
+    add constraint articles_link_key unique (link);
+
+alter table articles
+    drop column canonical_link,
+    drop column fingerprint,
+    drop column story_id;
@@ -0,0 +1,22 @@
+alter table articles
+    add column canonical_link varchar(16384),
+    add column fingerprint    bigint not null default 0,
+    add column story_id       bigint;
+
+-- The canonical links and fingerprints of the saved articles are computed by the Go step of the migration,
+-- the links as they are are unique until then
+update articles
+set canonical_link = link,
+    story_id       = id;
+
+alter table articles
+    alter column canonical_link set not null,
+    alter column story_id set not null;
+
+-- Articles are unique by their canonical link, links differing in tracking parameters are the same page
+alter table articles
+    drop constraint articles_link_key;
+
+create unique index articles_canonical_link_idx on articles (canonical_link);
+create index articles_story_id_idx on articles (story_id);
+create index articles_pub_date_idx on articles (pub_date);
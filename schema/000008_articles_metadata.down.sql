@@ -0,0 +1,7 @@
+alter table articles
+    drop column author,
+    drop column categories,
+    drop column image_url,
+    drop column content,
+    drop column language,
+    drop column guid;
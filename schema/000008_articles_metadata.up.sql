@@ -0,0 +1,7 @@
+alter table articles
+    add column author     varchar(2048)  not null default '',
+    add column categories text[]         not null default '{}',
+    add column image_url  varchar(16384) not null default '',
+    add column content    text           not null default '',
+    add column language   varchar(64)    not null default '',
+    add column guid       varchar(16384) not null default '';
@@ -0,0 +1 @@
+drop table fetch_leases;
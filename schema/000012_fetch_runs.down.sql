@@ -0,0 +1,2 @@
+drop table fetch_attempts;
+drop table fetch_runs;
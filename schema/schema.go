@@ -0,0 +1,22 @@
+// Package schema embeds the database migrations, so the binaries migrate the database they connect to.
+// The migrations follow the golang-migrate naming, and the Postgres ones are also shipped
+// in the migrate/migrate image built from this directory. The image runs the scripts only, not the Go steps
+// the binaries add to some migrations, so a database it upgrades past 000006 keeps the links of the articles
+// saved before as their canonical links.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+)
+
+var (
+	// Postgres holds the migrations of the Postgres storage.
+	//go:embed *.sql
+	Postgres embed.FS
+
+	//go:embed sqlite/*.sql
+	sqliteFiles embed.FS
+	// SQLite holds the migrations of the SQLite storage.
+	SQLite, _ = fs.Sub(sqliteFiles, "sqlite")
+)
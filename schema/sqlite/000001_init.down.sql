@@ -0,0 +1,6 @@
+drop trigger articles_fts_update;
+drop trigger articles_fts_delete;
+drop trigger articles_fts_insert;
+drop table articles_fts;
+drop table articles;
+drop table sources;
@@ -0,0 +1,63 @@
+create table sources
+(
+    id            integer
+        primary key autoincrement,
+    name          varchar(2048),
+    link          varchar(16384),
+    short_name    varchar(512) unique,
+    etag          varchar(1024) not null default '',
+    last_modified varchar(128)  not null default '',
+    scrape_config text
+);
+
+create table articles
+(
+    id             integer
+        primary key autoincrement,
+    title          varchar(2048),
+    description    varchar(16384),
+    link           varchar(16384) unique,
+    source_id      bigint
+        references sources (id) on delete cascade,
+    pub_date       timestamp default current_timestamp,
+    canonical_link varchar(16384) not null unique,
+    fingerprint    bigint not null default 0,
+    story_id       bigint not null default 0
+);
+
+create index articles_story_id_idx on articles (story_id);
+create index articles_pub_date_idx on articles (pub_date);
+create index articles_source_id_idx on articles (source_id);
+
+create virtual table articles_fts using fts5
+(
+    title,
+    description,
+    content = 'articles',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+create trigger articles_fts_insert
+    after insert
+    on articles
+begin
+    insert into articles_fts (rowid, title, description) values (new.id, new.title, new.description);
+end;
+
+create trigger articles_fts_delete
+    after delete
+    on articles
+begin
+    insert into articles_fts (articles_fts, rowid, title, description)
+    values ('delete', old.id, old.title, old.description);
+end;
+
+create trigger articles_fts_update
+    after update of title, description
+    on articles
+begin
+    insert into articles_fts (articles_fts, rowid, title, description)
+    values ('delete', old.id, old.title, old.description);
+    insert into articles_fts (rowid, title, description) values (new.id, new.title, new.description);
+end;
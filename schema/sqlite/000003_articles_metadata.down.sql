@@ -0,0 +1,6 @@
+alter table articles drop column guid;
+alter table articles drop column language;
+alter table articles drop column content;
+alter table articles drop column image_url;
+alter table articles drop column categories;
+alter table articles drop column author;
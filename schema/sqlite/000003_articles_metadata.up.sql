@@ -0,0 +1,6 @@
+alter table articles add column author varchar(2048) not null default '';
+alter table articles add column categories text not null default '[]';
+alter table articles add column image_url varchar(16384) not null default '';
+alter table articles add column content text not null default '';
+alter table articles add column language varchar(64) not null default '';
+alter table articles add column guid varchar(16384) not null default '';
@@ -0,0 +1,2 @@
+alter table sources
+    add column schedule text;
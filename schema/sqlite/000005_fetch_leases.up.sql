@@ -0,0 +1,8 @@
+create table fetch_leases
+(
+    source_id  integer
+        primary key
+        references sources (id) on delete cascade,
+    holder     varchar(512) not null,
+    expires_at integer      not null
+);
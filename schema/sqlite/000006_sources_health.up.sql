@@ -0,0 +1,2 @@
+alter table sources
+    add column health text;
@@ -0,0 +1,35 @@
+create table fetch_runs
+(
+    id          integer
+        primary key autoincrement,
+    started_at  timestamp not null,
+    finished_at timestamp not null,
+    sources     integer   not null default 0,
+    failed      integer   not null default 0,
+    inserted    integer   not null default 0,
+    updated     integer   not null default 0
+);
+
+create index fetch_runs_started_at_idx on fetch_runs (started_at);
+
+create table fetch_attempts
+(
+    id          integer
+        primary key autoincrement,
+    run_id      integer      not null
+        references fetch_runs (id) on delete cascade,
+    source_id   integer      not null,
+    source_name varchar(512) not null default '',
+    started_at  timestamp    not null,
+    finished_at timestamp    not null,
+    status      varchar(32)  not null,
+    http_status integer      not null default 0,
+    bytes       integer      not null default 0,
+    articles    integer      not null default 0,
+    inserted    integer      not null default 0,
+    updated     integer      not null default 0,
+    error       text         not null default ''
+);
+
+create index fetch_attempts_run_id_idx on fetch_attempts (run_id);
+create index fetch_attempts_source_id_idx on fetch_attempts (source_id, started_at);